@@ -0,0 +1,135 @@
+// Package authz refuses requests to the operations of the spec declaring
+// required permissions, unless the role of their session grants them all.
+// Operations declare them as a list under x-required-permissions:
+//
+//	x-required-permissions:
+//	  - users:read
+package authz
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/fenky-ng/swt-pro/constant"
+	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+)
+
+const ExtensionRequiredPermissions = "x-required-permissions"
+
+type MiddlewareOptions struct {
+	// RequiredPermissions maps operationIds to the permissions they require,
+	// see RequiredPermissions.
+	RequiredPermissions map[string][]string
+	// Operations maps "<METHOD> <path>" of every route to its operationId,
+	// see ratelimit.OperationIDs.
+	Operations map[string]string
+	// Role returns the current role of the user of the session of the
+	// request, ok is false without a valid session.
+	Role func(ctx echo.Context) (role string, ok bool, err error)
+	// Permissions returns the permissions granted to a role.
+	Permissions func(ctx context.Context, role string) (permissions []string, err error)
+	// Logger defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+type errorResponse struct {
+	Header generated.ResponseHeader `json:"header"`
+}
+
+// Middleware answers requests to operations requiring permissions with 403
+// when they carry no session or one lacking a permission. Unlike rate
+// limiting, a failed role or permission lookup refuses the request.
+func Middleware(opts MiddlewareOptions) echo.MiddlewareFunc {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			required := opts.RequiredPermissions[opts.Operations[ctx.Request().Method+" "+ctx.Path()]]
+			if len(required) == 0 {
+				return next(ctx)
+			}
+
+			role, ok, err := opts.Role(ctx)
+			if err != nil {
+				opts.Logger.ErrorContext(ctx.Request().Context(), "Role error", "func", "Authorize", "error", err)
+				return respondSystemError(ctx)
+			}
+			if !ok {
+				return respondForbidden(ctx, constant.ErrorCodeAuthorization, i18n.M(i18n.SessionNone))
+			}
+
+			var granted []string
+			if role != "" {
+				granted, err = opts.Permissions(ctx.Request().Context(), role)
+				if err != nil {
+					opts.Logger.ErrorContext(ctx.Request().Context(), "Permissions error", "func", "Authorize", "role", role, "error", err)
+					return respondSystemError(ctx)
+				}
+			}
+
+			for _, permission := range required {
+				if !slices.Contains(granted, permission) {
+					return respondForbidden(ctx, constant.ErrorCodePermission, i18n.M(i18n.PermissionDenied, permission))
+				}
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+func respondForbidden(ctx echo.Context, errorCode int, message i18n.Message) error {
+	errorMessageIDs, errorMessages := i18n.LocalizeAll(i18n.FromContext(ctx.Request().Context()), []i18n.Message{message})
+	return ctx.JSON(http.StatusForbidden, errorResponse{
+		Header: generated.ResponseHeader{
+			ErrorCode:       &errorCode,
+			ErrorMessageIds: &errorMessageIDs,
+			ErrorMessages:   &errorMessages,
+		},
+	})
+}
+
+func respondSystemError(ctx echo.Context) error {
+	errorCode := constant.ErrorCodeGeneral
+	errorMessageIDs, errorMessages := i18n.LocalizeAll(i18n.FromContext(ctx.Request().Context()), []i18n.Message{i18n.M(i18n.SystemError)})
+	return ctx.JSON(http.StatusInternalServerError, errorResponse{
+		Header: generated.ResponseHeader{
+			ErrorCode:       &errorCode,
+			ErrorMessageIds: &errorMessageIDs,
+			ErrorMessages:   &errorMessages,
+		},
+	})
+}
+
+// RequiredPermissions reads the x-required-permissions of every operation
+// in the spec, keyed by operationId. Operations without it are left out.
+func RequiredPermissions(swagger *openapi3.T) (map[string][]string, error) {
+	required := map[string][]string{}
+	for path, pathItem := range swagger.Paths {
+		for method, operation := range pathItem.Operations() {
+			value, ok := operation.Extensions[ExtensionRequiredPermissions]
+			if !ok {
+				continue
+			}
+			items, ok := value.([]interface{})
+			if !ok || len(items) == 0 {
+				return nil, fmt.Errorf("%s %s: %s must be a non-empty list", method, path, ExtensionRequiredPermissions)
+			}
+			for _, item := range items {
+				permission, ok := item.(string)
+				if !ok || permission == "" {
+					return nil, fmt.Errorf("%s %s: %s must only list permission names", method, path, ExtensionRequiredPermissions)
+				}
+				required[operation.OperationID] = append(required[operation.OperationID], permission)
+			}
+		}
+	}
+	return required, nil
+}
@@ -1,31 +1,375 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/fenky-ng/swt-pro/authz"
+	"github.com/fenky-ng/swt-pro/config"
+	"github.com/fenky-ng/swt-pro/export"
 	"github.com/fenky-ng/swt-pro/generated"
 	"github.com/fenky-ng/swt-pro/handler"
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/fenky-ng/swt-pro/keyring"
+	"github.com/fenky-ng/swt-pro/logging"
+	"github.com/fenky-ng/swt-pro/metrics"
+	"github.com/fenky-ng/swt-pro/migration"
+	"github.com/fenky-ng/swt-pro/notifier"
+	"github.com/fenky-ng/swt-pro/problem"
+	"github.com/fenky-ng/swt-pro/purge"
+	"github.com/fenky-ng/swt-pro/ratelimit"
 	"github.com/fenky-ng/swt-pro/repository"
+	"github.com/fenky-ng/swt-pro/tracing"
+	"github.com/fenky-ng/swt-pro/validation"
 
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path of a YAML or JSON config file")
+	printConfig := flag.Bool("print-config", false, "print the effective config with secrets redacted and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [migrate up|down [steps]|status]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cfg, err := config.Load(config.LoadOptions{
+		Path:    *configPath,
+		Environ: os.Environ(),
+	})
+	if err != nil {
+		fatal(err)
+	}
+	if *printConfig {
+		err = config.Print(os.Stdout, cfg)
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+	err = cfg.Validate()
+	if err != nil {
+		fatal(err)
+	}
+
+	level, _ := cfg.Log.SlogLevel()
+	logger := logging.New(os.Stdout, level)
+	slog.SetDefault(logger)
+
+	repo := repository.NewRepository(repository.NewRepositoryOptions{
+		Dsn:             cfg.Database.URL,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		Logger:          logger,
+	})
+
+	if flag.NArg() != 0 {
+		if flag.Arg(0) != "migrate" {
+			flag.Usage()
+			os.Exit(2)
+		}
+		err = migrate(context.Background(), repo.Db, flag.Args()[1:])
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if cfg.Database.AutoMigrate {
+		err = migrate(context.Background(), repo.Db, []string{"up"})
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	swagger, err := generated.GetSwagger()
+	if err != nil {
+		fatal(err)
+	}
+	operations := ratelimit.OperationIDs(swagger)
+	requiredPermissions, err := authz.RequiredPermissions(swagger)
+	if err != nil {
+		fatal(err)
+	}
+
+	tracerProvider := newTracerProvider(cfg)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
 	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.IPExtractor, err = ratelimit.IPExtractor(cfg.Server.TrustedProxies)
+	if err != nil {
+		fatal(err)
+	}
+	e.Use(tracing.Middleware(tracing.MiddlewareOptions{
+		Operations: operations,
+	}))
+	e.Use(logging.Middleware(logging.MiddlewareOptions{
+		Logger:     logger,
+		Operations: operations,
+	}))
+
+	registry := metrics.NewRegistry()
+	metrics.RegisterDBStats(registry, repo.Db)
+	e.Use(metrics.Middleware(metrics.MiddlewareOptions{
+		Registry:   registry,
+		Operations: operations,
+	}))
+	e.GET("/metrics", echo.WrapHandler(registry))
+
+	e.Use(i18n.Middleware(i18n.MiddlewareOptions{
+		DefaultLanguage: cfg.Server.DefaultLanguage,
+	}))
+	e.Use(problem.Middleware(problem.MiddlewareOptions{
+		TypeBaseURI: cfg.Server.ProblemTypeBaseURI,
+	}))
 
-	var server generated.ServerInterface = newServer()
+	// without a worker, the exports queued here are built by another instance
+	var dataExportTrigger export.Trigger
+	if cfg.DataExport.Worker {
+		dataExportTrigger = export.NewTrigger()
+	}
+
+	server := newServer(cfg, repo, registry, logger, dataExportTrigger)
+	e.Use(newRateLimitMiddleware(cfg, server, repo.Db, operations))
+	e.Use(authz.Middleware(authz.MiddlewareOptions{
+		RequiredPermissions: requiredPermissions,
+		Operations:          operations,
+		Role:                server.SessionRole,
+		Permissions:         server.Repository.GetRolePermissions,
+		Logger:              logger,
+	}))
+	e.Use(validation.Middleware(validation.MiddlewareOptions{
+		Swagger:           swagger,
+		Operations:        operations,
+		ValidateResponses: cfg.Validation.Responses,
+		Logger:            logger,
+	}))
 	generated.RegisterHandlers(e, server)
 
-	e.Logger.Fatal(e.Start(":1323"))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// workers are waited for at shutdown, they use the database
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		purge.Run(ctx, purge.Options{
+			Repository:    server.Repository,
+			GracePeriod:   cfg.AccountDeletion.GracePeriod,
+			UnverifiedTTL: cfg.AccountDeletion.UnverifiedTTL,
+			Interval:      cfg.AccountDeletion.PurgeInterval,
+			BatchSize:     cfg.AccountDeletion.PurgeBatchSize,
+			Logger:        logger,
+		})
+	}()
+	if cfg.DataExport.Worker {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			export.Run(ctx, export.Options{
+				Repository: server.Repository,
+				Interval:   cfg.DataExport.WorkerInterval,
+				StaleAfter: cfg.DataExport.StaleAfter,
+				Trigger:    dataExportTrigger,
+				Logger:     logger,
+			})
+		}()
+	}
+
+	go func() {
+		slog.Info("Listening", "address", cfg.Server.Address)
+		err := e.Start(cfg.Server.Address)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	// a second signal stops the process right away
+	stop()
+	shutdown(e, cfg, server, repo, tracerProvider, &workers)
 }
 
-func newServer() *handler.Server {
-	dbDsn := os.Getenv("DATABASE_URL")
-	var repo repository.RepositoryInterface = repository.NewRepository(repository.NewRepositoryOptions{
-		Dsn: dbDsn,
+// shutdown fails readiness, keeps serving for the shutdown delay, then waits
+// for requests in flight and the workers before flushing the spans and
+// closing the database.
+func shutdown(e *echo.Echo, cfg config.Config, server *handler.Server, repo *repository.Repository, tracerProvider *sdktrace.TracerProvider, workers *sync.WaitGroup) {
+	slog.Info("Shutting down")
+	server.BeginShutdown()
+	time.Sleep(cfg.Server.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	err := e.Shutdown(ctx)
+	if err != nil {
+		slog.Error("Shutdown error", "error", err)
+	}
+
+	// the workers return once the signal context is done
+	stopped := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Error("Workers shutdown error", "error", ctx.Err())
+	}
+
+	err = tracerProvider.Shutdown(ctx)
+	if err != nil {
+		slog.Error("Tracer shutdown error", "error", err)
+	}
+
+	err = repo.Close()
+	if err != nil {
+		slog.Error("Close error", "error", err)
+	}
+}
+
+// migrate runs the migrate subcommand: up, down [steps] or status.
+func migrate(ctx context.Context, db *sql.DB, args []string) error {
+	migrations, err := migration.Embedded()
+	if err != nil {
+		return err
+	}
+	migrator := migration.NewMigrator(db, migrations)
+
+	command := "up"
+	if len(args) != 0 {
+		command = args[0]
+	}
+	switch {
+	case command == "up" && len(args) <= 1:
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case command == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case command == "status" && len(args) == 1:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied at " + status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Up == "" {
+				state += ", file missing"
+			}
+			fmt.Printf("%04d_%s: %s\n", status.Version, status.Name, state)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate command %q, expected up, down [steps] or status", strings.Join(args, " "))
+}
+
+func newServer(cfg config.Config, repo *repository.Repository, registry *metrics.Registry, logger *slog.Logger, dataExportTrigger export.Trigger) *handler.Server {
+	keyRing, isDefault, err := keyring.Load(keyring.LoadOptions{
+		Dir:           cfg.Keys.Dir,
+		Environ:       os.Environ(),
+		ActiveKeyID:   cfg.Keys.ActiveKeyID,
+		RetiredKeyIDs: cfg.Keys.RetiredKeyIDs,
 	})
+	if err != nil {
+		fatal(err)
+	}
+	if isDefault {
+		slog.Warn("No JWT signing key configured, using the built-in development key")
+	}
+
+	var smsNotifier notifier.Notifier = notifier.NewLogNotifier(logger)
+	if cfg.Notifier.SMSFile != "" {
+		smsNotifier = notifier.NewFileNotifier(cfg.Notifier.SMSFile)
+	}
+
 	opts := handler.NewServerOptions{
-		Repository: repo,
+		Repository:        repository.NewInstrumentedRepository(repo, registry),
+		KeyRing:           keyRing,
+		Notifier:          smsNotifier,
+		LoginAttempts:     repo,
+		Logger:            logger,
+		Config:            cfg,
+		Metrics:           registry,
+		DataExportTrigger: dataExportTrigger,
 	}
 	return handler.NewServer(opts)
 }
+
+func newTracerProvider(cfg config.Config) *sdktrace.TracerProvider {
+	exporter, err := tracing.NewExporter(context.Background(), tracing.ExporterOptions{
+		Name:         cfg.Tracing.Exporter,
+		Writer:       os.Stdout,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+	})
+	if err != nil {
+		fatal(err)
+	}
+	return tracing.NewProvider(tracing.ProviderOptions{
+		Exporter:    exporter,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+}
+
+func newRateLimitMiddleware(cfg config.Config, server *handler.Server, db *sql.DB, operations map[string]string) echo.MiddlewareFunc {
+	rules, err := ratelimit.ParseRules(cfg.RateLimit.Rules)
+	if err != nil {
+		fatal(err)
+	}
+
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == "postgres" {
+		store = ratelimit.NewPostgresStore(db)
+	}
+
+	return ratelimit.Middleware(ratelimit.MiddlewareOptions{
+		Store:      store,
+		Rules:      rules,
+		Operations: operations,
+		UserID:     server.SessionUserID,
+		Logger:     server.Logger,
+	})
+}
+
+func fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(1)
+}
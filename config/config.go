@@ -0,0 +1,382 @@
+// Package config holds every setting of the service. Settings start from
+// Default, are overridden by an optional YAML or JSON file and then by
+// environment variables, see Load.
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/fenky-ng/swt-pro/phone"
+	"github.com/fenky-ng/swt-pro/ratelimit"
+)
+
+// Fields are named in files by their yaml tag and in the environment by
+// their env tag. The env tag of a nested struct is a prefix for the env tags
+// of its fields. Fields tagged secret are redacted by Print; "url" only
+// hides the password of a URL.
+type Config struct {
+	Server          ServerConfig          `yaml:"server"`
+	Database        DatabaseConfig        `yaml:"database"`
+	Keys            KeysConfig            `yaml:"keys"`
+	Auth            AuthConfig            `yaml:"auth"`
+	Validation      ValidationConfig      `yaml:"validation"`
+	LoginThrottle   LoginThrottleConfig   `yaml:"login_throttle" env:"LOGIN_"`
+	AccountDeletion AccountDeletionConfig `yaml:"account_deletion" env:"ACCOUNT_DELETION_"`
+	DataExport      DataExportConfig      `yaml:"data_export" env:"DATA_EXPORT_"`
+	RateLimit       RateLimitConfig       `yaml:"rate_limit"`
+	Notifier        NotifierConfig        `yaml:"notifier"`
+	Log             LogConfig             `yaml:"log"`
+	Tracing         TracingConfig         `yaml:"tracing" env:"TRACING_"`
+}
+
+// ServerConfig configures the HTTP server. On shutdown the server keeps
+// serving for ShutdownDelay with readiness failing, so load balancers stop
+// routing to it, then waits up to ShutdownTimeout for requests in flight.
+type ServerConfig struct {
+	Address          string        `yaml:"address" env:"SERVER_ADDRESS"`
+	ShutdownDelay    time.Duration `yaml:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY"`
+	ShutdownTimeout  time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	ReadinessTimeout time.Duration `yaml:"readiness_timeout" env:"SERVER_READINESS_TIMEOUT"`
+	// ProblemTypeBaseURI prefixes the type of application/problem+json
+	// errors, see problem.Middleware
+	ProblemTypeBaseURI string `yaml:"problem_type_base_uri" env:"PROBLEM_TYPE_BASE_URI"`
+	// DefaultLanguage answers requests whose Accept-Language names no
+	// supported language, see i18n.Middleware
+	DefaultLanguage string `yaml:"default_language" env:"DEFAULT_LANGUAGE"`
+	// TrustedProxies lists the CIDRs of the proxies whose X-Forwarded-For
+	// is believed. Without any, the client ip is the address of the
+	// connection, see ratelimit.IPExtractor.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES"`
+}
+
+type DatabaseConfig struct {
+	URL             string        `yaml:"url" env:"DATABASE_URL" secret:"url"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DATABASE_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DATABASE_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DATABASE_CONN_MAX_LIFETIME"`
+	// AutoMigrate applies pending migrations on startup
+	AutoMigrate bool `yaml:"auto_migrate" env:"DATABASE_AUTO_MIGRATE"`
+}
+
+// KeysConfig locates the JWT signing keys. Private keys may also be passed
+// as JWT_PRIVATE_KEY_<KID> environment variables, see keyring.Load.
+type KeysConfig struct {
+	Dir           string   `yaml:"dir" env:"JWT_KEYS_DIR"`
+	ActiveKeyID   string   `yaml:"active_key_id" env:"JWT_ACTIVE_KEY_ID"`
+	RetiredKeyIDs []string `yaml:"retired_key_ids" env:"JWT_RETIRED_KEY_IDS"`
+}
+
+type AuthConfig struct {
+	AccessTokenTTL     time.Duration `yaml:"access_token_ttl" env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL    time.Duration `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL"`
+	RevocationCacheTTL time.Duration `yaml:"revocation_cache_ttl" env:"REVOCATION_CACHE_TTL"`
+	MfaChallengeTTL    time.Duration `yaml:"mfa_challenge_ttl" env:"MFA_CHALLENGE_TTL"`
+	BcryptCost         int           `yaml:"bcrypt_cost" env:"BCRYPT_COST"`
+	RecoveryCodeCount  int           `yaml:"recovery_code_count" env:"RECOVERY_CODE_COUNT"`
+	PasswordReset      CodeConfig    `yaml:"password_reset" env:"PASSWORD_RESET_"`
+	PhoneVerification  CodeConfig    `yaml:"phone_verification" env:"PHONE_VERIFICATION_"`
+}
+
+// CodeConfig configures one-time codes sent by SMS.
+type CodeConfig struct {
+	CodeLength     int           `yaml:"code_length" env:"CODE_LENGTH"`
+	CodeTTL        time.Duration `yaml:"code_ttl" env:"CODE_TTL"`
+	ResendInterval time.Duration `yaml:"resend_interval" env:"RESEND_INTERVAL"`
+	MaxAttempts    int           `yaml:"max_attempts" env:"MAX_ATTEMPTS"`
+}
+
+type ValidationConfig struct {
+	// PhoneCountries lists the ISO 3166-1 alpha-2 codes of the countries
+	// whose phone numbers are accepted, see phone.LookupCountry
+	PhoneCountries    []string `yaml:"phone_countries" env:"PHONE_COUNTRIES"`
+	FullNameMinLength int      `yaml:"full_name_min_length" env:"FULL_NAME_MIN_LENGTH"`
+	FullNameMaxLength int      `yaml:"full_name_max_length" env:"FULL_NAME_MAX_LENGTH"`
+	PasswordMinLength int      `yaml:"password_min_length" env:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength int      `yaml:"password_max_length" env:"PASSWORD_MAX_LENGTH"`
+	// Responses logs the responses not matching api.yml, which costs a
+	// copy of every response body
+	Responses bool `yaml:"responses" env:"VALIDATE_RESPONSES"`
+}
+
+// LoginThrottleConfig configures how failed logins slow down and lock out
+// further attempts. Failures are counted per user and per client ip within
+// Window. From BackoffThreshold failures on, every failure blocks the next
+// attempt for BackoffBaseDelay doubled per extra failure, up to
+// BackoffMaxDelay. From LockoutThreshold failures on, the account is locked
+// for LockoutDuration. Client ips are only ever backed off, starting from
+// IPBackoffThreshold, since many users may share one.
+type LoginThrottleConfig struct {
+	Window             time.Duration `yaml:"window" env:"ATTEMPT_WINDOW"`
+	BackoffThreshold   int           `yaml:"backoff_threshold" env:"BACKOFF_THRESHOLD"`
+	BackoffBaseDelay   time.Duration `yaml:"backoff_base_delay" env:"BACKOFF_BASE_DELAY"`
+	BackoffMaxDelay    time.Duration `yaml:"backoff_max_delay" env:"BACKOFF_MAX_DELAY"`
+	LockoutThreshold   int           `yaml:"lockout_threshold" env:"LOCKOUT_THRESHOLD"`
+	LockoutDuration    time.Duration `yaml:"lockout_duration" env:"LOCKOUT_DURATION"`
+	IPBackoffThreshold int           `yaml:"ip_backoff_threshold" env:"IP_BACKOFF_THRESHOLD"`
+}
+
+// AccountDeletionConfig configures how long deleted accounts are kept. A
+// deleted account is restored by logging in within GracePeriod. Once it is
+// over, the account is purged by a worker running every PurgeInterval,
+// removing up to PurgeBatchSize accounts per statement. The same worker
+// purges the accounts whose phone number is not verified within
+// UnverifiedTTL of registering.
+type AccountDeletionConfig struct {
+	GracePeriod    time.Duration `yaml:"grace_period" env:"GRACE_PERIOD"`
+	UnverifiedTTL  time.Duration `yaml:"unverified_ttl" env:"UNVERIFIED_TTL"`
+	PurgeInterval  time.Duration `yaml:"purge_interval" env:"PURGE_INTERVAL"`
+	PurgeBatchSize int           `yaml:"purge_batch_size" env:"PURGE_BATCH_SIZE"`
+}
+
+// DataExportConfig configures the personal data exports. Requested exports
+// are downloaded with their token until DownloadTTL later, then removed.
+// They are built by a worker, when Worker is set, looking for them every
+// WorkerInterval and as soon as this instance queues one. Exports left
+// processing for StaleAfter, by an instance that stopped, are built again.
+type DataExportConfig struct {
+	DownloadTTL    time.Duration `yaml:"download_ttl" env:"DOWNLOAD_TTL"`
+	Worker         bool          `yaml:"worker" env:"WORKER"`
+	WorkerInterval time.Duration `yaml:"worker_interval" env:"WORKER_INTERVAL"`
+	StaleAfter     time.Duration `yaml:"stale_after" env:"STALE_AFTER"`
+}
+
+type RateLimitConfig struct {
+	// Store is either "memory" or "postgres"
+	Store string `yaml:"store" env:"RATE_LIMIT_STORE"`
+	// Rules uses the syntax of ratelimit.ParseRules
+	Rules string `yaml:"rules" env:"RATE_LIMIT_RULES"`
+}
+
+type NotifierConfig struct {
+	// SMSFile appends messages to a file instead of the log when set
+	SMSFile string `yaml:"sms_file" env:"SMS_NOTIFIER_FILE"`
+}
+
+type LogConfig struct {
+	// Level is one of debug, info, warn or error
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+}
+
+// SlogLevel parses Level.
+func (c LogConfig) SlogLevel() (level slog.Level, err error) {
+	err = level.UnmarshalText([]byte(c.Level))
+	return level, err
+}
+
+// TracingConfig configures OpenTelemetry tracing. The otlp exporter also
+// honours the standard OTEL_EXPORTER_OTLP_* environment variables.
+type TracingConfig struct {
+	// Exporter is one of none, stdout or otlp
+	Exporter string `yaml:"exporter" env:"EXPORTER"`
+	// OTLPEndpoint is the host:port of the OTLP/HTTP collector, the
+	// exporter default when empty
+	OTLPEndpoint string  `yaml:"otlp_endpoint" env:"OTLP_ENDPOINT"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" env:"OTLP_INSECURE"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"SAMPLE_RATIO"`
+	ServiceName  string  `yaml:"service_name" env:"SERVICE_NAME"`
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Address:            ":1323",
+			ShutdownTimeout:    time.Duration(30) * time.Second,
+			ReadinessTimeout:   time.Duration(2) * time.Second,
+			ProblemTypeBaseURI: "/problems/",
+			DefaultLanguage:    i18n.English,
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:     time.Duration(15) * time.Minute,
+			RefreshTokenTTL:    time.Duration(30*24) * time.Hour,
+			RevocationCacheTTL: time.Duration(30) * time.Second,
+			MfaChallengeTTL:    time.Duration(5) * time.Minute,
+			BcryptCost:         4,
+			RecoveryCodeCount:  10,
+			PasswordReset: CodeConfig{
+				CodeLength:     6,
+				CodeTTL:        time.Duration(10) * time.Minute,
+				ResendInterval: time.Duration(1) * time.Minute,
+				MaxAttempts:    5,
+			},
+			PhoneVerification: CodeConfig{
+				CodeLength:     6,
+				CodeTTL:        time.Duration(10) * time.Minute,
+				ResendInterval: time.Duration(1) * time.Minute,
+				MaxAttempts:    5,
+			},
+		},
+		Validation: ValidationConfig{
+			PhoneCountries:    []string{"ID"},
+			FullNameMinLength: 3,
+			FullNameMaxLength: 60,
+			PasswordMinLength: 6,
+			PasswordMaxLength: 64,
+		},
+		LoginThrottle: LoginThrottleConfig{
+			Window:             time.Duration(15) * time.Minute,
+			BackoffThreshold:   3,
+			BackoffBaseDelay:   time.Duration(1) * time.Second,
+			BackoffMaxDelay:    time.Duration(5) * time.Minute,
+			LockoutThreshold:   10,
+			LockoutDuration:    time.Duration(15) * time.Minute,
+			IPBackoffThreshold: 20,
+		},
+		AccountDeletion: AccountDeletionConfig{
+			GracePeriod:    time.Duration(30*24) * time.Hour,
+			UnverifiedTTL:  time.Duration(7*24) * time.Hour,
+			PurgeInterval:  time.Duration(1) * time.Hour,
+			PurgeBatchSize: 100,
+		},
+		DataExport: DataExportConfig{
+			DownloadTTL:    time.Duration(24) * time.Hour,
+			Worker:         true,
+			WorkerInterval: time.Duration(1) * time.Minute,
+			StaleAfter:     time.Duration(10) * time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Store: "memory",
+			Rules: "register=ip:10/1h;" +
+				"login=ip:30/1m,phone_number:10/1m;" +
+				"login-mfa=ip:30/1m;" +
+				"refresh-token=ip:60/1m;" +
+				"verify-phone=ip:30/1h,phone_number:10/1h;" +
+				"forgot-password=ip:10/1h,phone_number:5/1h;" +
+				"reset-password=ip:30/1h,phone_number:10/1h;" +
+				"update-profile=user:30/1m;" +
+				"change-password=user:10/1h;" +
+				"delete-profile=user:10/1h;" +
+				"export-profile=user:5/24h;" +
+				"download-profile-export=ip:60/1m;" +
+				"confirm-totp=user:10/1m;" +
+				"disable-totp=user:10/1m",
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+			ServiceName: "swt-pro",
+		},
+	}
+}
+
+// ValidationError lists every problem found by Validate.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the config as a whole and returns a *ValidationError
+// listing every problem found.
+func (c Config) Validate() error {
+	v := &validator{}
+
+	v.require(c.Server.Address != "", "server.address is required")
+	v.require(c.Server.ShutdownDelay >= 0, "server.shutdown_delay must not be negative")
+	v.positive(c.Server.ShutdownTimeout, "server.shutdown_timeout")
+	v.positive(c.Server.ReadinessTimeout, "server.readiness_timeout")
+	if _, err := url.Parse(c.Server.ProblemTypeBaseURI); err != nil || c.Server.ProblemTypeBaseURI == "" {
+		v.problems = append(v.problems, "server.problem_type_base_uri must be a URI reference")
+	}
+	v.require(i18n.Supported(c.Server.DefaultLanguage), fmt.Sprintf("server.default_language must be one of %s, %s", i18n.English, i18n.Indonesian))
+	if _, err := ratelimit.IPExtractor(c.Server.TrustedProxies); err != nil {
+		v.problems = append(v.problems, "server.trusted_proxies: "+err.Error())
+	}
+	v.require(c.Database.URL != "", "database.url is required")
+	v.require(c.Database.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	v.require(c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	v.require(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+
+	v.positive(c.Auth.AccessTokenTTL, "auth.access_token_ttl")
+	v.positive(c.Auth.RefreshTokenTTL, "auth.refresh_token_ttl")
+	v.positive(c.Auth.RevocationCacheTTL, "auth.revocation_cache_ttl")
+	v.positive(c.Auth.MfaChallengeTTL, "auth.mfa_challenge_ttl")
+	v.between(c.Auth.BcryptCost, 4, 31, "auth.bcrypt_cost")
+	v.between(c.Auth.RecoveryCodeCount, 1, 100, "auth.recovery_code_count")
+	v.code(c.Auth.PasswordReset, "auth.password_reset")
+	v.code(c.Auth.PhoneVerification, "auth.phone_verification")
+
+	v.require(len(c.Validation.PhoneCountries) != 0, "validation.phone_countries is required")
+	for _, code := range c.Validation.PhoneCountries {
+		_, ok := phone.LookupCountry(code)
+		v.require(ok, fmt.Sprintf("validation.phone_countries: unsupported country %q", code))
+	}
+	v.length(c.Validation.FullNameMinLength, c.Validation.FullNameMaxLength, "validation.full_name")
+	v.length(c.Validation.PasswordMinLength, c.Validation.PasswordMaxLength, "validation.password")
+	// the password length is checked with a regexp repeat, capped at 1000
+	v.require(c.Validation.PasswordMaxLength <= 1000, "validation.password_max_length must not exceed 1000")
+
+	v.positive(c.LoginThrottle.Window, "login_throttle.window")
+	v.between(c.LoginThrottle.BackoffThreshold, 1, 1000, "login_throttle.backoff_threshold")
+	v.positive(c.LoginThrottle.BackoffBaseDelay, "login_throttle.backoff_base_delay")
+	v.require(c.LoginThrottle.BackoffMaxDelay >= c.LoginThrottle.BackoffBaseDelay, "login_throttle.backoff_max_delay must not be less than login_throttle.backoff_base_delay")
+	v.between(c.LoginThrottle.LockoutThreshold, 1, 1000, "login_throttle.lockout_threshold")
+	v.positive(c.LoginThrottle.LockoutDuration, "login_throttle.lockout_duration")
+	v.between(c.LoginThrottle.IPBackoffThreshold, 1, 1000, "login_throttle.ip_backoff_threshold")
+
+	v.positive(c.AccountDeletion.GracePeriod, "account_deletion.grace_period")
+	v.positive(c.AccountDeletion.UnverifiedTTL, "account_deletion.unverified_ttl")
+	v.positive(c.AccountDeletion.PurgeInterval, "account_deletion.purge_interval")
+	v.between(c.AccountDeletion.PurgeBatchSize, 1, 10000, "account_deletion.purge_batch_size")
+
+	v.positive(c.DataExport.DownloadTTL, "data_export.download_ttl")
+	v.positive(c.DataExport.WorkerInterval, "data_export.worker_interval")
+	v.positive(c.DataExport.StaleAfter, "data_export.stale_after")
+
+	v.require(c.RateLimit.Store == "memory" || c.RateLimit.Store == "postgres", `rate_limit.store must be "memory" or "postgres"`)
+	if _, err := ratelimit.ParseRules(c.RateLimit.Rules); err != nil {
+		v.problems = append(v.problems, "rate_limit.rules: "+err.Error())
+	}
+
+	if _, err := c.Log.SlogLevel(); err != nil {
+		v.problems = append(v.problems, "log.level must be debug, info, warn or error")
+	}
+
+	v.require(c.Tracing.Exporter == "none" || c.Tracing.Exporter == "stdout" || c.Tracing.Exporter == "otlp", `tracing.exporter must be "none", "stdout" or "otlp"`)
+	v.require(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+	v.require(c.Tracing.ServiceName != "", "tracing.service_name is required")
+
+	if len(v.problems) != 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) require(ok bool, problem string) {
+	if !ok {
+		v.problems = append(v.problems, problem)
+	}
+}
+
+func (v *validator) positive(d time.Duration, name string) {
+	v.require(d > 0, name+" must be positive")
+}
+
+func (v *validator) between(n int, min int, max int, name string) {
+	v.require(n >= min && n <= max, fmt.Sprintf("%s must be between %d and %d", name, min, max))
+}
+
+func (v *validator) length(min int, max int, name string) {
+	v.require(min > 0, name+"_min_length must be positive")
+	v.require(max >= min, name+"_max_length must not be less than "+name+"_min_length")
+}
+
+func (v *validator) code(c CodeConfig, name string) {
+	v.between(c.CodeLength, 4, 12, name+".code_length")
+	v.positive(c.CodeTTL, name+".code_ttl")
+	v.require(c.ResendInterval >= 0, name+".resend_interval must not be negative")
+	v.between(c.MaxAttempts, 1, 100, name+".max_attempts")
+}
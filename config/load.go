@@ -0,0 +1,215 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnknownSetting = errors.New("unknown setting")
+	ErrInvalidValue   = errors.New("invalid value")
+)
+
+type LoadOptions struct {
+	// Path of a YAML or JSON file, JSON being picked by a .json extension.
+	// No file is read when empty.
+	Path string
+	// Environ is a list of KEY=value pairs such as os.Environ.
+	Environ []string
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load returns Default overridden by the file and then the environment. The
+// result is not validated, see Config.Validate.
+func Load(opts LoadOptions) (Config, error) {
+	cfg := Default()
+
+	if opts.Path != "" {
+		settings, err := readFile(opts.Path)
+		if err != nil {
+			return cfg, err
+		}
+		err = applySettings(reflect.ValueOf(&cfg).Elem(), settings, "")
+		if err != nil {
+			return cfg, fmt.Errorf("%s: %w", opts.Path, err)
+		}
+	}
+
+	env := map[string]string{}
+	for _, pair := range opts.Environ {
+		if name, value, found := strings.Cut(pair, "="); found {
+			env[name] = value
+		}
+	}
+	err := applyEnv(reflect.ValueOf(&cfg).Elem(), env, "")
+	if err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+func readFile(path string) (settings map[string]interface{}, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&settings)
+	} else {
+		err = yaml.Unmarshal(data, &settings)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return settings, nil
+}
+
+// applySettings sets the fields of v named by the yaml tags in settings.
+func applySettings(v reflect.Value, settings map[string]interface{}, path string) error {
+	for name, raw := range settings {
+		field, ok := fieldByTag(v, "yaml", name)
+		if !ok {
+			return fmt.Errorf("%w %s%s", ErrUnknownSetting, path, name)
+		}
+
+		if isSection(field) {
+			section, ok := raw.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%w for %s%s: expected a section", ErrInvalidValue, path, name)
+			}
+			err := applySettings(field, section, path+name+".")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		var value string
+		switch raw := raw.(type) {
+		case nil:
+			continue
+		case []interface{}:
+			items := make([]string, 0, len(raw))
+			for _, item := range raw {
+				items = append(items, fmt.Sprint(item))
+			}
+			value = strings.Join(items, ",")
+		case map[string]interface{}:
+			return fmt.Errorf("%w for %s%s: unexpected section", ErrInvalidValue, path, name)
+		default:
+			value = fmt.Sprint(raw)
+		}
+
+		err := setValue(field, value)
+		if err != nil {
+			return fmt.Errorf("%w for %s%s: %s", ErrInvalidValue, path, name, err.Error())
+		}
+	}
+	return nil
+}
+
+// applyEnv sets the fields of v whose env tag, behind prefix, is in env.
+func applyEnv(v reflect.Value, env map[string]string, prefix string) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		tag := v.Type().Field(i).Tag.Get("env")
+
+		if isSection(field) {
+			err := applyEnv(field, env, prefix+tag)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if tag == "" {
+			continue
+		}
+		value, ok := env[prefix+tag]
+		if !ok {
+			continue
+		}
+		err := setValue(field, value)
+		if err != nil {
+			return fmt.Errorf("%w for %s: %s", ErrInvalidValue, prefix+tag, err.Error())
+		}
+	}
+	return nil
+}
+
+func isSection(field reflect.Value) bool {
+	return field.Kind() == reflect.Struct
+}
+
+func fieldByTag(v reflect.Value, key string, name string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get(key) == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setValue parses value into field. Lists are comma separated.
+func setValue(field reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("os.WriteFile() error = %s", err.Error())
+	}
+	return path
+}
+
+func Test_Load(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		environ []string
+		want    func(cfg *Config)
+		wantErr error
+	}{
+		{
+			name: "defaults",
+			want: func(cfg *Config) {},
+		},
+		{
+			name: "yaml file",
+			file: "config.yaml",
+			content: `
+server:
+  address: ":8080"
+database:
+  url: postgres://localhost/db
+auth:
+  access_token_ttl: 5m
+  password_reset:
+    code_length: 8
+keys:
+  retired_key_ids: [old, older]
+`,
+			want: func(cfg *Config) {
+				cfg.Server.Address = ":8080"
+				cfg.Database.URL = "postgres://localhost/db"
+				cfg.Auth.AccessTokenTTL = time.Duration(5) * time.Minute
+				cfg.Auth.PasswordReset.CodeLength = 8
+				cfg.Keys.RetiredKeyIDs = []string{"old", "older"}
+			},
+		},
+		{
+			name:    "json file",
+			file:    "config.json",
+			content: `{"auth": {"bcrypt_cost": 12}, "login_throttle": {"window": "1h"}, "tracing": {"sample_ratio": 0.5}}`,
+			want: func(cfg *Config) {
+				cfg.Auth.BcryptCost = 12
+				cfg.LoginThrottle.Window = time.Hour
+				cfg.Tracing.SampleRatio = 0.5
+			},
+		},
+		{
+			name:    "environment overrides file",
+			file:    "config.yaml",
+			content: "auth:\n  bcrypt_cost: 12\n",
+			environ: []string{
+				"BCRYPT_COST=10",
+				"DATABASE_URL=postgres://localhost/env",
+				"PASSWORD_RESET_CODE_LENGTH=8",
+				"LOGIN_LOCKOUT_THRESHOLD=5",
+				"JWT_RETIRED_KEY_IDS=old, older",
+				"TRACING_SAMPLE_RATIO=0.25",
+				"UNRELATED=value",
+			},
+			want: func(cfg *Config) {
+				cfg.Auth.BcryptCost = 10
+				cfg.Database.URL = "postgres://localhost/env"
+				cfg.Auth.PasswordReset.CodeLength = 8
+				cfg.LoginThrottle.LockoutThreshold = 5
+				cfg.Keys.RetiredKeyIDs = []string{"old", "older"}
+				cfg.Tracing.SampleRatio = 0.25
+			},
+		},
+		{
+			name:    "unknown setting",
+			file:    "config.yaml",
+			content: "auth:\n  bcrypt_rounds: 12\n",
+			wantErr: ErrUnknownSetting,
+		},
+		{
+			name:    "invalid value in file",
+			file:    "config.yaml",
+			content: "auth:\n  access_token_ttl: soon\n",
+			wantErr: ErrInvalidValue,
+		},
+		{
+			name:    "invalid value in environment",
+			environ: []string{"LOGIN_LOCKOUT_THRESHOLD=many"},
+			wantErr: ErrInvalidValue,
+		},
+		{
+			name:    "value in place of a section",
+			file:    "config.yaml",
+			content: "auth: 5\n",
+			wantErr: ErrInvalidValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := LoadOptions{
+				Environ: tt.environ,
+			}
+			if tt.file != "" {
+				opts.Path = writeTestFile(t, tt.file, tt.content)
+			}
+			got, err := Load(opts)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Load() gotErr = %v, wantErr = %s", err, tt.wantErr.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() gotErr = %s, wantErr = nil", err.Error())
+			}
+			want := Default()
+			tt.want(&want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Load() got = %+v, want = %+v", got, want)
+			}
+		})
+	}
+}
+
+func Test_Load_missingFile(t *testing.T) {
+	_, err := Load(LoadOptions{Path: filepath.Join(t.TempDir(), "missing.yaml")})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load() gotErr = %v, wantErr = %s", err, os.ErrNotExist.Error())
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "REDACTED"
+
+// Print writes cfg as YAML, in the layout Load reads, with secrets redacted.
+func Print(w io.Writer, cfg Config) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	err := encoder.Encode(section(reflect.ValueOf(cfg)))
+	if err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func section(v reflect.Value) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		structField := v.Type().Field(i)
+
+		var value *yaml.Node
+		if isSection(field) {
+			value = section(field)
+		} else {
+			value = scalar(field, structField.Tag.Get("secret"))
+		}
+
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: structField.Tag.Get("yaml")},
+			value,
+		)
+	}
+	return node
+}
+
+func scalar(field reflect.Value, secret string) *yaml.Node {
+	if field.Type() == durationType {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(field.Interface())}
+	}
+
+	switch field.Kind() {
+	case reflect.Int:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(field.Int(), 10)}
+	case reflect.Float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: strconv.FormatFloat(field.Float(), 'g', -1, 64)}
+	case reflect.Bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(field.Bool())}
+	case reflect.Slice:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for i := 0; i < field.Len(); i++ {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field.Index(i).String()})
+		}
+		return node
+	}
+
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: redact(field.String(), secret)}
+}
+
+// redact hides value according to the secret tag of its field. A "url"
+// secret keeps everything but the password, whether it is written as a URL
+// or as key=value pairs.
+func redact(value string, secret string) string {
+	if value == "" || secret == "" {
+		return value
+	}
+	if secret != "url" {
+		return redacted
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		return passwordPair.ReplaceAllString(value, "${1}"+redacted)
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	if query := u.Query(); query.Has("password") {
+		query.Set("password", redacted)
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+var passwordPair = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
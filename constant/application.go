@@ -1,10 +1,6 @@
 package constant
 
-import (
-	"time"
-)
-
 const (
-	ApplicationName         = "swt-pro"
-	LoginExpirationDuration = time.Duration(24) * time.Hour
+	ApplicationName          = "swt-pro"
+	TokenPurposeMfaChallenge = "mfa_challenge"
 )
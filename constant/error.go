@@ -1,11 +1,25 @@
 package constant
 
 const (
-	ErrorCodeGeneral       = 1000
-	ErrorCodeUnmarshal     = 1001
-	ErrorCodeValidation    = 1002
-	ErrorCodeHashAndSalt   = 1003
-	ErrorCodeDatabase      = 1004
-	ErrorCodeJWT           = 1005
-	ErrorCodeAuthorization = 1006
+	ErrorCodeGeneral           = 1000
+	ErrorCodeUnmarshal         = 1001
+	ErrorCodeValidation        = 1002
+	ErrorCodeHashAndSalt       = 1003
+	ErrorCodeDatabase          = 1004
+	ErrorCodeJWT               = 1005
+	ErrorCodeAuthorization     = 1006
+	ErrorCodeRefreshToken      = 1007
+	ErrorCodePasswordReset     = 1008
+	ErrorCodePhoneNotVerified  = 1009
+	ErrorCodePhoneVerification = 1010
+	ErrorCodeMfa               = 1011
+	ErrorCodeAccountLocked     = 1012
+	ErrorCodeLoginThrottled    = 1013
+	ErrorCodeRateLimited       = 1014
+	ErrorCodeUnavailable       = 1015
+	ErrorCodeNotFound          = 1016
+	ErrorCodeConflict          = 1017
+	ErrorCodeDataExport        = 1018
+	ErrorCodePermission        = 1019
+	ErrorCodePasswordExpired   = 1020
 )
@@ -0,0 +1,187 @@
+package export
+
+import (
+	"time"
+
+	"github.com/fenky-ng/swt-pro/repository"
+)
+
+// Document is what an export tells about a user. It is the JSON archive as
+// is, and the CSV archive has a file per section. Secrets, such as the
+// password or the hashes of codes and tokens, are never part of it.
+type Document struct {
+	ExportedAt         time.Time           `json:"exported_at"`
+	Profile            Profile             `json:"profile"`
+	LoginHistory       []Login             `json:"login_history"`
+	Sessions           []Session           `json:"sessions"`
+	PasswordResets     []PasswordReset     `json:"password_resets"`
+	PhoneVerifications []PhoneVerification `json:"phone_verifications"`
+	// TwoFactorAuthentication is nil when it was never set up
+	TwoFactorAuthentication *TwoFactorAuthentication `json:"two_factor_authentication"`
+	// FailedLogins is nil when no failed login is on record
+	FailedLogins *FailedLogins `json:"failed_logins"`
+	AuditEvents  []AuditEvent  `json:"audit_events"`
+}
+
+type Profile struct {
+	ID              int64      `json:"id"`
+	PhoneNumber     string     `json:"phone_number"`
+	FullName        string     `json:"full_name"`
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at"`
+	LoginCount      int64      `json:"login_count"`
+	// Role is empty for users without any permissions
+	Role string `json:"role"`
+	// LockedAt is set while the account is locked by support
+	LockedAt *time.Time `json:"locked_at"`
+	// PasswordResetRequiredAt is set until the password is reset
+	PasswordResetRequiredAt *time.Time `json:"password_reset_required_at"`
+	// DeletedAt is set while the account is deleted within its grace period
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+// Login is a login still on record, as long as its refresh tokens are.
+// SignedOutAt is set once the session was revoked.
+type Login struct {
+	SessionID   string     `json:"session_id"`
+	LoggedInAt  time.Time  `json:"logged_in_at"`
+	SignedOutAt *time.Time `json:"signed_out_at"`
+}
+
+// Session is a login that can still be refreshed.
+type Session struct {
+	SessionID       string    `json:"session_id"`
+	LastRefreshedAt time.Time `json:"last_refreshed_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+type PasswordReset struct {
+	RequestedAt time.Time  `json:"requested_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	Attempts    int        `json:"attempts"`
+	UsedAt      *time.Time `json:"used_at"`
+}
+
+type PhoneVerification struct {
+	PhoneNumber string     `json:"phone_number"`
+	RequestedAt time.Time  `json:"requested_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	Attempts    int        `json:"attempts"`
+	VerifiedAt  *time.Time `json:"verified_at"`
+}
+
+type TwoFactorAuthentication struct {
+	Method     string     `json:"method"`
+	EnrolledAt time.Time  `json:"enrolled_at"`
+	EnabledAt  *time.Time `json:"enabled_at"`
+}
+
+// FailedLogins counts the failed logins to the account since the last
+// successful one. BlockedUntil is set while logins are refused because of
+// them.
+type FailedLogins struct {
+	FailedCount  int        `json:"failed_count"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	BlockedUntil *time.Time `json:"blocked_until"`
+}
+
+// AuditEvent is an administrative or security change made to the account,
+// by the user or, when ByStaff, by a staff member.
+type AuditEvent struct {
+	Action     string    `json:"action"`
+	ByStaff    bool      `json:"by_staff"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// NewDocument tells about userData as of now. Every refresh token family
+// is a login, its tokens are rotated on refresh; the unused token of a
+// family not revoked nor expired is a session.
+func NewDocument(userData repository.UserData, now time.Time) Document {
+	doc := Document{
+		ExportedAt: now,
+		Profile: Profile{
+			ID:                      userData.User.ID,
+			PhoneNumber:             userData.User.PhoneNumber,
+			FullName:                userData.User.FullName,
+			PhoneVerifiedAt:         userData.User.PhoneVerifiedAt,
+			LoginCount:              userData.LoginCount,
+			Role:                    userData.User.Role,
+			LockedAt:                userData.User.LockedAt,
+			PasswordResetRequiredAt: userData.User.PasswordResetRequiredAt,
+			DeletedAt:               userData.User.DeletedAt,
+		},
+		LoginHistory:       []Login{},
+		Sessions:           []Session{},
+		PasswordResets:     []PasswordReset{},
+		PhoneVerifications: []PhoneVerification{},
+		AuditEvents:        []AuditEvent{},
+	}
+
+	// refresh tokens come ordered by id, so the first of a family is its login
+	logins := map[string]int{}
+	for _, refreshToken := range userData.RefreshTokens {
+		i, ok := logins[refreshToken.FamilyID]
+		if !ok {
+			i = len(doc.LoginHistory)
+			logins[refreshToken.FamilyID] = i
+			doc.LoginHistory = append(doc.LoginHistory, Login{
+				SessionID:  refreshToken.FamilyID,
+				LoggedInAt: refreshToken.CreatedAt,
+			})
+		}
+		if refreshToken.RevokedAt != nil && doc.LoginHistory[i].SignedOutAt == nil {
+			doc.LoginHistory[i].SignedOutAt = refreshToken.RevokedAt
+		}
+		if refreshToken.UsedAt == nil && refreshToken.RevokedAt == nil && refreshToken.ExpiresAt.After(now) {
+			doc.Sessions = append(doc.Sessions, Session{
+				SessionID:       refreshToken.FamilyID,
+				LastRefreshedAt: refreshToken.CreatedAt,
+				ExpiresAt:       refreshToken.ExpiresAt,
+			})
+		}
+	}
+
+	for _, passwordReset := range userData.PasswordResets {
+		doc.PasswordResets = append(doc.PasswordResets, PasswordReset{
+			RequestedAt: passwordReset.CreatedAt,
+			ExpiresAt:   passwordReset.ExpiresAt,
+			Attempts:    passwordReset.Attempts,
+			UsedAt:      passwordReset.UsedAt,
+		})
+	}
+
+	for _, phoneVerification := range userData.PhoneVerifications {
+		doc.PhoneVerifications = append(doc.PhoneVerifications, PhoneVerification{
+			PhoneNumber: phoneVerification.PhoneNumber,
+			RequestedAt: phoneVerification.CreatedAt,
+			ExpiresAt:   phoneVerification.ExpiresAt,
+			Attempts:    phoneVerification.Attempts,
+			VerifiedAt:  phoneVerification.VerifiedAt,
+		})
+	}
+
+	if userData.UserTOTP != nil {
+		doc.TwoFactorAuthentication = &TwoFactorAuthentication{
+			Method:     "totp",
+			EnrolledAt: userData.UserTOTP.CreatedAt,
+			EnabledAt:  userData.UserTOTP.ConfirmedAt,
+		}
+	}
+
+	if userData.LoginAttempt != nil {
+		doc.FailedLogins = &FailedLogins{
+			FailedCount:  userData.LoginAttempt.FailedCount,
+			LastFailedAt: userData.LoginAttempt.LastFailedAt,
+			BlockedUntil: userData.LoginAttempt.BlockedUntil,
+		}
+	}
+
+	for _, auditEvent := range userData.AuditEvents {
+		doc.AuditEvents = append(doc.AuditEvents, AuditEvent{
+			Action:     auditEvent.Action,
+			ByStaff:    auditEvent.ActorID != nil,
+			OccurredAt: auditEvent.CreatedAt,
+		})
+	}
+
+	return doc
+}
@@ -0,0 +1,157 @@
+// Package export builds the archives of the personal data exports users
+// request. Handlers only queue exports in the database, Run builds them in
+// process. Any number of instances may run it, each export is claimed by
+// one of them, and an export left behind by an instance that stopped is
+// claimed again once it is stale.
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/fenky-ng/swt-pro/repository"
+)
+
+// deleteBatchSize caps the expired exports removed by one statement.
+const deleteBatchSize = 100
+
+type Options struct {
+	Repository repository.RepositoryInterface
+	// Interval is how often queued exports are looked for
+	Interval time.Duration
+	// StaleAfter is how long an export may stay processing before it is
+	// claimed again
+	StaleAfter time.Duration
+	// Trigger wakes Run up before Interval is over, when an export is
+	// queued by this instance. It may be nil.
+	Trigger Trigger
+	// Logger defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+// Trigger tells Run an export was queued. The zero value never fires.
+type Trigger chan struct{}
+
+func NewTrigger() Trigger {
+	return make(Trigger, 1)
+}
+
+// Notify wakes Run up without waiting for it, nor for a nil Trigger.
+func (t Trigger) Notify() {
+	select {
+	case t <- struct{}{}:
+	default:
+	}
+}
+
+// Run builds the queued exports and removes the expired ones right away,
+// then every Interval or Trigger until ctx is done.
+func Run(ctx context.Context, opts Options) {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		deleted, err := DeleteExpired(ctx, opts, now)
+		if err != nil && ctx.Err() == nil {
+			opts.Logger.ErrorContext(ctx, "DeleteExpiredDataExports error", "func", "DeleteExpired", "error", err)
+		}
+		if deleted != 0 {
+			opts.Logger.InfoContext(ctx, "Deleted expired data exports", "func", "DeleteExpired", "count", deleted)
+		}
+
+		processed, err := Process(ctx, opts, now)
+		if err != nil && ctx.Err() == nil {
+			opts.Logger.ErrorContext(ctx, "Process error", "func", "Process", "error", err)
+		}
+		if processed != 0 {
+			opts.Logger.InfoContext(ctx, "Processed data exports", "func", "Process", "count", processed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-opts.Trigger:
+		}
+	}
+}
+
+// Process builds the queued exports one by one until none is left, and
+// returns how many were. Exports that cannot be built, because the user is
+// gone or the format is unknown, are marked failed. On a database error the
+// export is left processing, to be claimed again once stale.
+func Process(ctx context.Context, opts Options, now time.Time) (processed int, err error) {
+	for {
+		dataExport, err := opts.Repository.ClaimDataExport(ctx, now, now.Add(-opts.StaleAfter))
+		if errors.Is(err, repository.ErrNotFound) {
+			return processed, nil
+		}
+		if err != nil {
+			return processed, err
+		}
+
+		err = build(ctx, opts, &dataExport, now)
+		if err != nil {
+			return processed, err
+		}
+
+		// false when it was claimed again meanwhile, the other claim wins
+		_, err = opts.Repository.FinishDataExport(ctx, dataExport)
+		if err != nil {
+			return processed, err
+		}
+		processed++
+	}
+}
+
+// build sets the status, archive and completion time of a claimed export.
+func build(ctx context.Context, opts Options, dataExport *repository.DataExport, now time.Time) error {
+	dataExport.Status = repository.DataExportFailed
+	dataExport.CompletedAt = &now
+
+	format, ok := LookupFormat(dataExport.Format)
+	if !ok {
+		opts.Logger.ErrorContext(ctx, "Unknown data export format", "func", "Process", "format", dataExport.Format)
+		return nil
+	}
+
+	userData, err := opts.Repository.GetUserData(ctx, dataExport.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var archive bytes.Buffer
+	err = format.Write(&archive, NewDocument(userData, now))
+	if err != nil {
+		opts.Logger.ErrorContext(ctx, "Write error", "func", "Process", "format", dataExport.Format, "error", err)
+		return nil
+	}
+
+	dataExport.Status = repository.DataExportReady
+	dataExport.Archive = archive.Bytes()
+	return nil
+}
+
+// DeleteExpired removes the exports expired at or before now, ready or not,
+// batch by batch until none is left, and returns how many were.
+func DeleteExpired(ctx context.Context, opts Options, now time.Time) (deleted int64, err error) {
+	for {
+		count, err := opts.Repository.DeleteExpiredDataExports(ctx, now, deleteBatchSize)
+		deleted += count
+		if err != nil {
+			return deleted, err
+		}
+		if count < deleteBatchSize {
+			return deleted, nil
+		}
+	}
+}
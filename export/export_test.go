@@ -0,0 +1,278 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	errorHelper "github.com/fenky-ng/swt-pro/helper/error"
+	"github.com/fenky-ng/swt-pro/repository"
+	"github.com/golang/mock/gomock"
+)
+
+func Test_Process(t *testing.T) {
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	staleBefore := time.Date(2023, 11, 30, 23, 50, 0, 0, time.UTC)
+	claimed := func(format string) repository.DataExport {
+		return repository.DataExport{
+			ID:        1,
+			UserID:    2,
+			Format:    format,
+			Status:    repository.DataExportProcessing,
+			StartedAt: &now,
+		}
+	}
+	type fields struct {
+		mockRepository *repository.MockRepositoryInterface
+	}
+	tests := []struct {
+		name          string
+		mock          func(fields *fields)
+		wantProcessed int
+		wantErr       error
+	}{
+		{
+			name: "nothing queued",
+			mock: func(fields *fields) {
+				fields.mockRepository.EXPECT().
+					ClaimDataExport(gomock.Any(), now, staleBefore).
+					Return(repository.DataExport{}, repository.ErrNotFound)
+			},
+			wantProcessed: 0,
+			wantErr:       nil,
+		},
+		{
+			name: "claim error",
+			mock: func(fields *fields) {
+				fields.mockRepository.EXPECT().
+					ClaimDataExport(gomock.Any(), now, staleBefore).
+					Return(repository.DataExport{}, errors.New("expected error"))
+			},
+			wantProcessed: 0,
+			wantErr:       errors.New("expected error"),
+		},
+		{
+			name: "get user data error",
+			mock: func(fields *fields) {
+				fields.mockRepository.EXPECT().
+					ClaimDataExport(gomock.Any(), now, staleBefore).
+					Return(claimed("json"), nil)
+				fields.mockRepository.EXPECT().
+					GetUserData(gomock.Any(), int64(2)).
+					Return(repository.UserData{}, errors.New("expected error"))
+			},
+			wantProcessed: 0,
+			wantErr:       errors.New("expected error"),
+		},
+		{
+			name: "user gone",
+			mock: func(fields *fields) {
+				gomock.InOrder(
+					fields.mockRepository.EXPECT().
+						ClaimDataExport(gomock.Any(), now, staleBefore).
+						Return(claimed("json"), nil),
+					fields.mockRepository.EXPECT().
+						GetUserData(gomock.Any(), int64(2)).
+						Return(repository.UserData{}, repository.ErrNotFound),
+					fields.mockRepository.EXPECT().
+						FinishDataExport(gomock.Any(), repository.DataExport{
+							ID:          1,
+							UserID:      2,
+							Format:      "json",
+							Status:      repository.DataExportFailed,
+							StartedAt:   &now,
+							CompletedAt: &now,
+						}).
+						Return(true, nil),
+					fields.mockRepository.EXPECT().
+						ClaimDataExport(gomock.Any(), now, staleBefore).
+						Return(repository.DataExport{}, repository.ErrNotFound),
+				)
+			},
+			wantProcessed: 1,
+			wantErr:       nil,
+		},
+		{
+			name: "unknown format",
+			mock: func(fields *fields) {
+				gomock.InOrder(
+					fields.mockRepository.EXPECT().
+						ClaimDataExport(gomock.Any(), now, staleBefore).
+						Return(claimed("xml"), nil),
+					fields.mockRepository.EXPECT().
+						FinishDataExport(gomock.Any(), repository.DataExport{
+							ID:          1,
+							UserID:      2,
+							Format:      "xml",
+							Status:      repository.DataExportFailed,
+							StartedAt:   &now,
+							CompletedAt: &now,
+						}).
+						Return(true, nil),
+					fields.mockRepository.EXPECT().
+						ClaimDataExport(gomock.Any(), now, staleBefore).
+						Return(repository.DataExport{}, repository.ErrNotFound),
+				)
+			},
+			wantProcessed: 1,
+			wantErr:       nil,
+		},
+		{
+			name: "finish error",
+			mock: func(fields *fields) {
+				fields.mockRepository.EXPECT().
+					ClaimDataExport(gomock.Any(), now, staleBefore).
+					Return(claimed("json"), nil)
+				fields.mockRepository.EXPECT().
+					GetUserData(gomock.Any(), int64(2)).
+					Return(repository.UserData{User: repository.User{ID: 2}}, nil)
+				fields.mockRepository.EXPECT().
+					FinishDataExport(gomock.Any(), gomock.Any()).
+					Return(false, errors.New("expected error"))
+			},
+			wantProcessed: 0,
+			wantErr:       errors.New("expected error"),
+		},
+		{
+			name: "several built",
+			mock: func(fields *fields) {
+				gomock.InOrder(
+					fields.mockRepository.EXPECT().
+						ClaimDataExport(gomock.Any(), now, staleBefore).
+						Return(claimed("json"), nil),
+					fields.mockRepository.EXPECT().
+						GetUserData(gomock.Any(), int64(2)).
+						Return(repository.UserData{User: repository.User{ID: 2, FullName: "Sawit"}}, nil),
+					fields.mockRepository.EXPECT().
+						FinishDataExport(gomock.Any(), gomock.Any()).
+						DoAndReturn(func(_ context.Context, data repository.DataExport) (bool, error) {
+							var doc Document
+							err := json.Unmarshal(data.Archive, &doc)
+							if err != nil || data.Status != repository.DataExportReady || doc.Profile.FullName != "Sawit" {
+								t.Errorf("Process() finished %s export with archive %s, err = %v", data.Status, data.Archive, err)
+							}
+							return true, nil
+						}),
+					fields.mockRepository.EXPECT().
+						ClaimDataExport(gomock.Any(), now, staleBefore).
+						Return(claimed("csv"), nil),
+					fields.mockRepository.EXPECT().
+						GetUserData(gomock.Any(), int64(2)).
+						Return(repository.UserData{User: repository.User{ID: 2}}, nil),
+					// claimed again meanwhile, still processed here
+					fields.mockRepository.EXPECT().
+						FinishDataExport(gomock.Any(), gomock.Any()).
+						Return(false, nil),
+					fields.mockRepository.EXPECT().
+						ClaimDataExport(gomock.Any(), now, staleBefore).
+						Return(repository.DataExport{}, repository.ErrNotFound),
+				)
+			},
+			wantProcessed: 2,
+			wantErr:       nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			fields := fields{
+				mockRepository: repository.NewMockRepositoryInterface(ctrl),
+			}
+			tt.mock(&fields)
+
+			gotProcessed, gotErr := Process(context.Background(), Options{
+				Repository: fields.mockRepository,
+				StaleAfter: time.Duration(10) * time.Minute,
+				Logger:     slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+			}, now)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Process() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotProcessed != tt.wantProcessed {
+				t.Errorf("Process() gotProcessed = %d, wantProcessed = %d", gotProcessed, tt.wantProcessed)
+			}
+		})
+	}
+}
+
+func Test_DeleteExpired(t *testing.T) {
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	gomock.InOrder(
+		mockRepository.EXPECT().
+			DeleteExpiredDataExports(gomock.Any(), now, deleteBatchSize).
+			Return(int64(deleteBatchSize), nil),
+		mockRepository.EXPECT().
+			DeleteExpiredDataExports(gomock.Any(), now, deleteBatchSize).
+			Return(int64(1), nil),
+	)
+
+	gotDeleted, gotErr := DeleteExpired(context.Background(), Options{Repository: mockRepository}, now)
+	if gotErr != nil {
+		t.Errorf("DeleteExpired() gotErr = %s", gotErr.Error())
+	}
+	if gotDeleted != deleteBatchSize+1 {
+		t.Errorf("DeleteExpired() gotDeleted = %d, wantDeleted = %d", gotDeleted, deleteBatchSize+1)
+	}
+}
+
+func Test_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	trigger := NewTrigger()
+	// the first pass runs right away, the trigger starts the second one
+	// long before the interval is over, which stops the worker
+	mockRepository.EXPECT().
+		DeleteExpiredDataExports(gomock.Any(), gomock.Any(), deleteBatchSize).
+		Return(int64(0), nil).
+		Times(2)
+	gomock.InOrder(
+		mockRepository.EXPECT().
+			ClaimDataExport(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(context.Context, time.Time, time.Time) (repository.DataExport, error) {
+				trigger.Notify()
+				trigger.Notify()
+				return repository.DataExport{}, repository.ErrNotFound
+			}),
+		mockRepository.EXPECT().
+			ClaimDataExport(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(context.Context, time.Time, time.Time) (repository.DataExport, error) {
+				cancel()
+				return repository.DataExport{}, context.Canceled
+			}),
+	)
+
+	var logs bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, Options{
+			Repository: mockRepository,
+			Interval:   time.Hour,
+			StaleAfter: time.Minute,
+			Trigger:    trigger,
+			Logger:     slog.New(slog.NewTextHandler(&logs, nil)),
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not stop once ctx was done")
+	}
+	if strings.Contains(logs.String(), "error") {
+		t.Errorf("Run() logged the cancellation as an error, logs =\n%s", logs.String())
+	}
+}
@@ -0,0 +1,176 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Format writes a Document as an archive.
+type Format interface {
+	ContentType() string
+	// Extension names the downloaded file, without the dot
+	Extension() string
+	Write(w io.Writer, doc Document) error
+}
+
+// formats are keyed by the format of the export request in api.yml.
+var formats = map[string]Format{
+	"json": jsonFormat{},
+	"csv":  csvFormat{},
+}
+
+// LookupFormat returns the format named by an export request.
+func LookupFormat(name string) (format Format, ok bool) {
+	format, ok = formats[name]
+	return format, ok
+}
+
+type jsonFormat struct{}
+
+func (jsonFormat) ContentType() string { return "application/json" }
+
+func (jsonFormat) Extension() string { return "json" }
+
+func (jsonFormat) Write(w io.Writer, doc Document) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// csvFormat zips a CSV file per section of the document, each with a header
+// row. Times are in RFC 3339, unset ones are left empty.
+type csvFormat struct{}
+
+func (csvFormat) ContentType() string { return "application/zip" }
+
+func (csvFormat) Extension() string { return "zip" }
+
+func (csvFormat) Write(w io.Writer, doc Document) error {
+	files := []struct {
+		name   string
+		header []string
+		rows   [][]string
+	}{
+		{
+			name:   "profile.csv",
+			header: []string{"id", "phone_number", "full_name", "phone_verified_at", "login_count", "role", "locked_at", "password_reset_required_at", "deleted_at", "exported_at"},
+			rows: [][]string{{
+				strconv.FormatInt(doc.Profile.ID, 10),
+				doc.Profile.PhoneNumber,
+				doc.Profile.FullName,
+				formatTime(doc.Profile.PhoneVerifiedAt),
+				strconv.FormatInt(doc.Profile.LoginCount, 10),
+				doc.Profile.Role,
+				formatTime(doc.Profile.LockedAt),
+				formatTime(doc.Profile.PasswordResetRequiredAt),
+				formatTime(doc.Profile.DeletedAt),
+				formatTime(&doc.ExportedAt),
+			}},
+		},
+		{
+			name:   "login_history.csv",
+			header: []string{"session_id", "logged_in_at", "signed_out_at"},
+			rows: rows(doc.LoginHistory, func(login Login) []string {
+				return []string{login.SessionID, formatTime(&login.LoggedInAt), formatTime(login.SignedOutAt)}
+			}),
+		},
+		{
+			name:   "sessions.csv",
+			header: []string{"session_id", "last_refreshed_at", "expires_at"},
+			rows: rows(doc.Sessions, func(session Session) []string {
+				return []string{session.SessionID, formatTime(&session.LastRefreshedAt), formatTime(&session.ExpiresAt)}
+			}),
+		},
+		{
+			name:   "password_resets.csv",
+			header: []string{"requested_at", "expires_at", "attempts", "used_at"},
+			rows: rows(doc.PasswordResets, func(passwordReset PasswordReset) []string {
+				return []string{
+					formatTime(&passwordReset.RequestedAt),
+					formatTime(&passwordReset.ExpiresAt),
+					strconv.Itoa(passwordReset.Attempts),
+					formatTime(passwordReset.UsedAt),
+				}
+			}),
+		},
+		{
+			name:   "phone_verifications.csv",
+			header: []string{"phone_number", "requested_at", "expires_at", "attempts", "verified_at"},
+			rows: rows(doc.PhoneVerifications, func(phoneVerification PhoneVerification) []string {
+				return []string{
+					phoneVerification.PhoneNumber,
+					formatTime(&phoneVerification.RequestedAt),
+					formatTime(&phoneVerification.ExpiresAt),
+					strconv.Itoa(phoneVerification.Attempts),
+					formatTime(phoneVerification.VerifiedAt),
+				}
+			}),
+		},
+		{
+			name:   "audit_events.csv",
+			header: []string{"action", "by_staff", "occurred_at"},
+			rows: rows(doc.AuditEvents, func(auditEvent AuditEvent) []string {
+				return []string{auditEvent.Action, strconv.FormatBool(auditEvent.ByStaff), formatTime(&auditEvent.OccurredAt)}
+			}),
+		},
+		{
+			name:   "two_factor_authentication.csv",
+			header: []string{"method", "enrolled_at", "enabled_at"},
+		},
+		{
+			name:   "failed_logins.csv",
+			header: []string{"failed_count", "last_failed_at", "blocked_until"},
+		},
+	}
+	if tfa := doc.TwoFactorAuthentication; tfa != nil {
+		files[len(files)-2].rows = [][]string{{tfa.Method, formatTime(&tfa.EnrolledAt), formatTime(tfa.EnabledAt)}}
+	}
+	if failedLogins := doc.FailedLogins; failedLogins != nil {
+		files[len(files)-1].rows = [][]string{{
+			strconv.Itoa(failedLogins.FailedCount),
+			formatTime(&failedLogins.LastFailedAt),
+			formatTime(failedLogins.BlockedUntil),
+		}}
+	}
+
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: doc.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+		cw := csv.NewWriter(fw)
+		err = cw.Write(file.header)
+		if err != nil {
+			return err
+		}
+		err = cw.WriteAll(file.rows)
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func rows[T any](items []T, row func(T) []string) [][]string {
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, row(item))
+	}
+	return rows
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,182 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fenky-ng/swt-pro/repository"
+)
+
+func testUserData() repository.UserData {
+	loggedInAt := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	refreshedAt := time.Date(2023, 11, 2, 0, 0, 0, 0, time.UTC)
+	signedOutAt := time.Date(2023, 11, 3, 0, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	actorID := int64(2)
+	return repository.UserData{
+		User: repository.User{
+			ID:                      1,
+			PhoneNumber:             "+628223344556",
+			FullName:                "Sawit, Pro",
+			PhoneVerifiedAt:         &loggedInAt,
+			Role:                    "support",
+			PasswordResetRequiredAt: &signedOutAt,
+		},
+		LoginCount: 2,
+		RefreshTokens: []repository.RefreshToken{
+			{ID: 1, FamilyID: "first", CreatedAt: loggedInAt, ExpiresAt: expiresAt, UsedAt: &refreshedAt},
+			{ID: 2, FamilyID: "second", CreatedAt: loggedInAt, ExpiresAt: expiresAt, UsedAt: &refreshedAt, RevokedAt: &signedOutAt},
+			{ID: 3, FamilyID: "first", CreatedAt: refreshedAt, ExpiresAt: expiresAt},
+			{ID: 4, FamilyID: "second", CreatedAt: refreshedAt, ExpiresAt: expiresAt, RevokedAt: &signedOutAt},
+		},
+		PasswordResets: []repository.PasswordReset{
+			{ID: 1, CodeHash: "<code_hash>", Attempts: 1, CreatedAt: loggedInAt, ExpiresAt: refreshedAt},
+		},
+		UserTOTP: &repository.UserTOTP{
+			Secret:      "<secret>",
+			CreatedAt:   loggedInAt,
+			ConfirmedAt: &refreshedAt,
+		},
+		LoginAttempt: &repository.LoginAttempt{
+			Key:          "user:1",
+			FailedCount:  3,
+			LastFailedAt: signedOutAt,
+			BlockedUntil: &expiresAt,
+		},
+		AuditEvents: []repository.AuditEvent{
+			{ID: 1, UserID: 1, ActorID: &actorID, Action: repository.AuditUserLocked, CreatedAt: refreshedAt},
+			{ID: 2, UserID: 1, Action: repository.AuditPasswordChanged, CreatedAt: signedOutAt},
+		},
+	}
+}
+
+func Test_NewDocument(t *testing.T) {
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	loggedInAt := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	refreshedAt := time.Date(2023, 11, 2, 0, 0, 0, 0, time.UTC)
+	signedOutAt := time.Date(2023, 11, 3, 0, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	want := Document{
+		ExportedAt: now,
+		Profile: Profile{
+			ID:                      1,
+			PhoneNumber:             "+628223344556",
+			FullName:                "Sawit, Pro",
+			PhoneVerifiedAt:         &loggedInAt,
+			LoginCount:              2,
+			Role:                    "support",
+			PasswordResetRequiredAt: &signedOutAt,
+		},
+		LoginHistory: []Login{
+			{SessionID: "first", LoggedInAt: loggedInAt},
+			{SessionID: "second", LoggedInAt: loggedInAt, SignedOutAt: &signedOutAt},
+		},
+		Sessions: []Session{
+			{SessionID: "first", LastRefreshedAt: refreshedAt, ExpiresAt: expiresAt},
+		},
+		PasswordResets: []PasswordReset{
+			{RequestedAt: loggedInAt, ExpiresAt: refreshedAt, Attempts: 1},
+		},
+		PhoneVerifications: []PhoneVerification{},
+		TwoFactorAuthentication: &TwoFactorAuthentication{
+			Method:     "totp",
+			EnrolledAt: loggedInAt,
+			EnabledAt:  &refreshedAt,
+		},
+		FailedLogins: &FailedLogins{
+			FailedCount:  3,
+			LastFailedAt: signedOutAt,
+			BlockedUntil: &expiresAt,
+		},
+		AuditEvents: []AuditEvent{
+			{Action: repository.AuditUserLocked, ByStaff: true, OccurredAt: refreshedAt},
+			{Action: repository.AuditPasswordChanged, OccurredAt: signedOutAt},
+		},
+	}
+	if got := NewDocument(testUserData(), now); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewDocument() got = %+v, want = %+v", got, want)
+	}
+}
+
+func Test_jsonFormat(t *testing.T) {
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	format, ok := LookupFormat("json")
+	if !ok {
+		t.Fatal("LookupFormat() json not found")
+	}
+
+	var archive bytes.Buffer
+	err := format.Write(&archive, NewDocument(repository.UserData{}, now))
+	if err != nil {
+		t.Fatalf("Write() gotErr = %s", err.Error())
+	}
+	var got map[string]interface{}
+	err = json.Unmarshal(archive.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("Write() wrote invalid JSON: %s", err.Error())
+	}
+	// empty sections are listed, so the archive tells there is nothing
+	if got["login_history"] == nil || got["phone_verifications"] == nil || got["two_factor_authentication"] != nil || got["failed_logins"] != nil || got["audit_events"] == nil {
+		t.Errorf("Write() got = %s", archive.String())
+	}
+}
+
+func Test_csvFormat(t *testing.T) {
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	format, ok := LookupFormat("csv")
+	if !ok {
+		t.Fatal("LookupFormat() csv not found")
+	}
+	if format.ContentType() != "application/zip" || format.Extension() != "zip" {
+		t.Errorf("LookupFormat() got %s .%s", format.ContentType(), format.Extension())
+	}
+
+	var archive bytes.Buffer
+	err := format.Write(&archive, NewDocument(testUserData(), now))
+	if err != nil {
+		t.Fatalf("Write() gotErr = %s", err.Error())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatalf("Write() wrote an invalid zip: %s", err.Error())
+	}
+
+	got := map[string]string{}
+	for _, file := range zr.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("Open() %s gotErr = %s", file.Name, err.Error())
+		}
+		content, _ := io.ReadAll(r)
+		r.Close()
+		got[file.Name] = string(content)
+	}
+	want := map[string]string{
+		"profile.csv": "id,phone_number,full_name,phone_verified_at,login_count,role,locked_at,password_reset_required_at,deleted_at,exported_at\n" +
+			"1,+628223344556,\"Sawit, Pro\",2023-11-01T00:00:00Z,2,support,,2023-11-03T00:00:00Z,,2023-12-01T00:00:00Z\n",
+		"login_history.csv": "session_id,logged_in_at,signed_out_at\n" +
+			"first,2023-11-01T00:00:00Z,\n" +
+			"second,2023-11-01T00:00:00Z,2023-11-03T00:00:00Z\n",
+		"sessions.csv": "session_id,last_refreshed_at,expires_at\n" +
+			"first,2023-11-02T00:00:00Z,2023-12-31T00:00:00Z\n",
+		"password_resets.csv": "requested_at,expires_at,attempts,used_at\n" +
+			"2023-11-01T00:00:00Z,2023-11-02T00:00:00Z,1,\n",
+		"phone_verifications.csv": "phone_number,requested_at,expires_at,attempts,verified_at\n",
+		"two_factor_authentication.csv": "method,enrolled_at,enabled_at\n" +
+			"totp,2023-11-01T00:00:00Z,2023-11-02T00:00:00Z\n",
+		"audit_events.csv": "action,by_staff,occurred_at\n" +
+			"user_locked,true,2023-11-02T00:00:00Z\n" +
+			"password_changed,false,2023-11-03T00:00:00Z\n",
+		"failed_logins.csv": "failed_count,last_failed_at,blocked_until\n" +
+			"3,2023-11-03T00:00:00Z,2023-12-31T00:00:00Z\n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Write() got = %v, want = %v", got, want)
+	}
+}
@@ -8,18 +8,200 @@ import (
 	"compress/gzip"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/labstack/echo/v4"
+	"github.com/oapi-codegen/runtime"
 )
 
 const (
 	BearerAuthScopes = "BearerAuth.Scopes"
 )
 
+// Defines values for DataExportStatus.
+const (
+	Pending    DataExportStatus = "pending"
+	Processing DataExportStatus = "processing"
+)
+
+// Defines values for ExportProfileRequestFormat.
+const (
+	Csv  ExportProfileRequestFormat = "csv"
+	Json ExportProfileRequestFormat = "json"
+)
+
+// Defines values for LoginChallengeType.
+const (
+	MfaRequired LoginChallengeType = "mfa_required"
+)
+
+// Defines values for SearchUsersParamsSort.
+const (
+	FullName    SearchUsersParamsSort = "full_name"
+	Id          SearchUsersParamsSort = "id"
+	PhoneNumber SearchUsersParamsSort = "phone_number"
+)
+
+// Defines values for SearchUsersParamsOrder.
+const (
+	Asc  SearchUsersParamsOrder = "asc"
+	Desc SearchUsersParamsOrder = "desc"
+)
+
+// AdminUser defines model for AdminUser.
+type AdminUser struct {
+	FullName string     `json:"full_name"`
+	Id       int64      `json:"id"`
+	LockedAt *time.Time `json:"locked_at,omitempty"`
+
+	// PasswordResetRequiredAt Set until the user resets its password
+	PasswordResetRequiredAt *time.Time `json:"password_reset_required_at,omitempty"`
+
+	// PendingPhoneNumber Phone number awaiting verification before it replaces the current one, only returned by UpdateUser
+	PendingPhoneNumber *string    `json:"pending_phone_number,omitempty"`
+	PhoneNumber        string     `json:"phone_number"`
+	PhoneVerifiedAt    *time.Time `json:"phone_verified_at,omitempty"`
+
+	// Role Absent for users without any permissions
+	Role *string `json:"role,omitempty"`
+}
+
+// AdminUserResponse defines model for AdminUserResponse.
+type AdminUserResponse struct {
+	Data   *AdminUser     `json:"data,omitempty"`
+	Header ResponseHeader `json:"header"`
+}
+
+// ChangePasswordRequest defines model for ChangePasswordRequest.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ChangePasswordResponse defines model for ChangePasswordResponse.
+type ChangePasswordResponse struct {
+	Data   *ChangePasswordResponseData `json:"data,omitempty"`
+	Header ResponseHeader              `json:"header"`
+}
+
+// ChangePasswordResponseData defines model for ChangePasswordResponseData.
+type ChangePasswordResponseData struct {
+	ExpiresIn int64  `json:"expires_in"`
+	Jwt       string `json:"jwt"`
+}
+
+// ConfirmTotpResponse defines model for ConfirmTotpResponse.
+type ConfirmTotpResponse struct {
+	Data   *ConfirmTotpResponseData `json:"data,omitempty"`
+	Header ResponseHeader           `json:"header"`
+}
+
+// ConfirmTotpResponseData defines model for ConfirmTotpResponseData.
+type ConfirmTotpResponseData struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// DataExportStatus defines model for DataExportStatus.
+type DataExportStatus string
+
+// DeleteProfileRequest defines model for DeleteProfileRequest.
+type DeleteProfileRequest struct {
+	Password string `json:"password"`
+}
+
+// DeleteProfileResponse defines model for DeleteProfileResponse.
+type DeleteProfileResponse struct {
+	Data   *DeleteProfileResponseData `json:"data,omitempty"`
+	Header ResponseHeader             `json:"header"`
+}
+
+// DeleteProfileResponseData defines model for DeleteProfileResponseData.
+type DeleteProfileResponseData struct {
+	RestorableUntil time.Time `json:"restorable_until"`
+}
+
+// DisableTotpResponse defines model for DisableTotpResponse.
+type DisableTotpResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// DownloadProfileExportResponse defines model for DownloadProfileExportResponse.
+type DownloadProfileExportResponse struct {
+	Data   *DownloadProfileExportResponseData `json:"data,omitempty"`
+	Header ResponseHeader                     `json:"header"`
+}
+
+// DownloadProfileExportResponseData defines model for DownloadProfileExportResponseData.
+type DownloadProfileExportResponseData struct {
+	ExpiresAt time.Time        `json:"expires_at"`
+	Id        int64            `json:"id"`
+	Status    DataExportStatus `json:"status"`
+}
+
+// EnrollTotpResponse defines model for EnrollTotpResponse.
+type EnrollTotpResponse struct {
+	Data   *EnrollTotpResponseData `json:"data,omitempty"`
+	Header ResponseHeader          `json:"header"`
+}
+
+// EnrollTotpResponseData defines model for EnrollTotpResponseData.
+type EnrollTotpResponseData struct {
+	OtpauthUri string `json:"otpauth_uri"`
+	Secret     string `json:"secret"`
+}
+
+// ErrorResponse defines model for ErrorResponse.
+type ErrorResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// ExportProfileRequest defines model for ExportProfileRequest.
+type ExportProfileRequest struct {
+	Format *ExportProfileRequestFormat `json:"format,omitempty"`
+}
+
+// ExportProfileRequestFormat defines model for ExportProfileRequest.Format.
+type ExportProfileRequestFormat string
+
+// ExportProfileResponse defines model for ExportProfileResponse.
+type ExportProfileResponse struct {
+	Data   *ExportProfileResponseData `json:"data,omitempty"`
+	Header ResponseHeader             `json:"header"`
+}
+
+// ExportProfileResponseData defines model for ExportProfileResponseData.
+type ExportProfileResponseData struct {
+	DownloadToken string           `json:"download_token"`
+	ExpiresAt     time.Time        `json:"expires_at"`
+	Id            int64            `json:"id"`
+	Status        DataExportStatus `json:"status"`
+}
+
+// FieldError defines model for FieldError.
+type FieldError struct {
+	// Field Dotted path of the field, such as phone_number
+	Field   string `json:"field"`
+	Message string `json:"message"`
+
+	// MessageId The stable ID of message
+	MessageId string `json:"message_id"`
+}
+
+// ForgotPasswordRequest defines model for ForgotPasswordRequest.
+type ForgotPasswordRequest struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+// ForgotPasswordResponse defines model for ForgotPasswordResponse.
+type ForgotPasswordResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
 // GetProfileResponse defines model for GetProfileResponse.
 type GetProfileResponse struct {
 	Data   *GetProfileResponseData `json:"data,omitempty"`
@@ -32,6 +214,57 @@ type GetProfileResponseData struct {
 	PhoneNumber string `json:"phone_number"`
 }
 
+// HealthResponse defines model for HealthResponse.
+type HealthResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// JSONWebKey defines model for JSONWebKey.
+type JSONWebKey struct {
+	Alg string `json:"alg"`
+	E   string `json:"e"`
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	Use string `json:"use"`
+}
+
+// JSONWebKeySet defines model for JSONWebKeySet.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// ListUsersResponse defines model for ListUsersResponse.
+type ListUsersResponse struct {
+	Data   *ListUsersResponseData `json:"data,omitempty"`
+	Header ResponseHeader         `json:"header"`
+}
+
+// ListUsersResponseData defines model for ListUsersResponseData.
+type ListUsersResponseData struct {
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+	Total    int64       `json:"total"`
+	Users    []AdminUser `json:"users"`
+}
+
+// LoginChallenge defines model for LoginChallenge.
+type LoginChallenge struct {
+	ExpiresIn int64              `json:"expires_in"`
+	MfaToken  string             `json:"mfa_token"`
+	Type      LoginChallengeType `json:"type"`
+}
+
+// LoginChallengeType defines model for LoginChallenge.Type.
+type LoginChallengeType string
+
+// LoginMfaRequest defines model for LoginMfaRequest.
+type LoginMfaRequest struct {
+	// Code Code from the authenticator app or an unused recovery code
+	Code     string `json:"code"`
+	MfaToken string `json:"mfa_token"`
+}
+
 // LoginRequest defines model for LoginRequest.
 type LoginRequest struct {
 	Password    string `json:"password"`
@@ -40,14 +273,75 @@ type LoginRequest struct {
 
 // LoginResponse defines model for LoginResponse.
 type LoginResponse struct {
-	Data   *LoginResponseData `json:"data,omitempty"`
-	Header ResponseHeader     `json:"header"`
+	Challenge *LoginChallenge    `json:"challenge,omitempty"`
+	Data      *LoginResponseData `json:"data,omitempty"`
+	Header    ResponseHeader     `json:"header"`
 }
 
 // LoginResponseData defines model for LoginResponseData.
 type LoginResponseData struct {
-	Id  int64  `json:"id"`
-	Jwt string `json:"jwt"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Id           int64  `json:"id"`
+	Jwt          string `json:"jwt"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LogoutResponse defines model for LogoutResponse.
+type LogoutResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// Problem An RFC 7807 problem document
+type Problem struct {
+	// Detail The error messages of this occurrence
+	Detail    *string `json:"detail,omitempty"`
+	ErrorCode *int    `json:"error_code,omitempty"`
+
+	// Errors The fields of the request that failed validation
+	Errors *[]FieldError `json:"errors,omitempty"`
+	Status int           `json:"status"`
+
+	// Title Short summary of the problem type
+	Title string `json:"title"`
+
+	// Type URI reference naming the problem, one per error code, such as /problems/validation
+	Type string `json:"type"`
+}
+
+// ReadinessCheck defines model for ReadinessCheck.
+type ReadinessCheck struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+}
+
+// ReadinessResponse defines model for ReadinessResponse.
+type ReadinessResponse struct {
+	Data   ReadinessResponseData `json:"data"`
+	Header ResponseHeader        `json:"header"`
+}
+
+// ReadinessResponseData defines model for ReadinessResponseData.
+type ReadinessResponseData struct {
+	Checks []ReadinessCheck `json:"checks"`
+}
+
+// RefreshTokenRequest defines model for RefreshTokenRequest.
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshTokenResponse defines model for RefreshTokenResponse.
+type RefreshTokenResponse struct {
+	Data   *RefreshTokenResponseData `json:"data,omitempty"`
+	Header ResponseHeader            `json:"header"`
+}
+
+// RefreshTokenResponseData defines model for RefreshTokenResponseData.
+type RefreshTokenResponseData struct {
+	ExpiresIn    int64  `json:"expires_in"`
+	Id           int64  `json:"id"`
+	Jwt          string `json:"jwt"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 // RegistrationRequest defines model for RegistrationRequest.
@@ -68,13 +362,51 @@ type RegistrationResponseData struct {
 	Id int64 `json:"id"`
 }
 
+// ResetPasswordRequest defines model for ResetPasswordRequest.
+type ResetPasswordRequest struct {
+	Code        string `json:"code"`
+	Password    string `json:"password"`
+	PhoneNumber string `json:"phone_number"`
+}
+
+// ResetPasswordResponse defines model for ResetPasswordResponse.
+type ResetPasswordResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
 // ResponseHeader defines model for ResponseHeader.
 type ResponseHeader struct {
-	ErrorCode     *int      `json:"error_code,omitempty"`
+	ErrorCode *int `json:"error_code,omitempty"`
+
+	// ErrorFields The fields of the request that failed validation
+	ErrorFields *[]FieldError `json:"error_fields,omitempty"`
+
+	// ErrorMessageIds The stable IDs of error_messages, in the same order
+	ErrorMessageIds *[]string `json:"error_message_ids,omitempty"`
+
+	// ErrorMessages The messages of the error, in the language negotiated from Accept-Language
 	ErrorMessages *[]string `json:"error_messages,omitempty"`
 	Successful    *bool     `json:"successful,omitempty"`
 }
 
+// SearchUsersResponse defines model for SearchUsersResponse.
+type SearchUsersResponse struct {
+	Data   *SearchUsersResponseData `json:"data,omitempty"`
+	Header ResponseHeader           `json:"header"`
+}
+
+// SearchUsersResponseData defines model for SearchUsersResponseData.
+type SearchUsersResponseData struct {
+	// NextCursor Continues the search, left out on the last page
+	NextCursor *string     `json:"next_cursor,omitempty"`
+	Users      []AdminUser `json:"users"`
+}
+
+// TotpCodeRequest defines model for TotpCodeRequest.
+type TotpCodeRequest struct {
+	Code string `json:"code"`
+}
+
 // UpdateProfileRequest defines model for UpdateProfileRequest.
 type UpdateProfileRequest struct {
 	FullName    *string `json:"full_name,omitempty"`
@@ -83,32 +415,195 @@ type UpdateProfileRequest struct {
 
 // UpdateProfileResponse defines model for UpdateProfileResponse.
 type UpdateProfileResponse struct {
+	Data   *UpdateProfileResponseData `json:"data,omitempty"`
+	Header ResponseHeader             `json:"header"`
+}
+
+// UpdateProfileResponseData defines model for UpdateProfileResponseData.
+type UpdateProfileResponseData struct {
+	// PendingPhoneNumber Phone number awaiting verification before it replaces the current one
+	PendingPhoneNumber *string `json:"pending_phone_number,omitempty"`
+}
+
+// VerifyPhoneRequest defines model for VerifyPhoneRequest.
+type VerifyPhoneRequest struct {
+	Code        string `json:"code"`
+	PhoneNumber string `json:"phone_number"`
+}
+
+// VerifyPhoneResponse defines model for VerifyPhoneResponse.
+type VerifyPhoneResponse struct {
 	Header ResponseHeader `json:"header"`
 }
 
+// ErrorApplicationJSON defines model for Error.
+type ErrorApplicationJSON = ErrorResponse
+
+// ErrorApplicationProblemPlusJSON An RFC 7807 problem document
+type ErrorApplicationProblemPlusJSON = Problem
+
+// ListUsersParams defines parameters for ListUsers.
+type ListUsersParams struct {
+	Role          *string `form:"role,omitempty" json:"role,omitempty"`
+	Locked        *bool   `form:"locked,omitempty" json:"locked,omitempty"`
+	PhoneVerified *bool   `form:"phone_verified,omitempty" json:"phone_verified,omitempty"`
+	Page          *int    `form:"page,omitempty" json:"page,omitempty"`
+	PageSize      *int    `form:"page_size,omitempty" json:"page_size,omitempty"`
+}
+
+// SearchUsersParams defines parameters for SearchUsers.
+type SearchUsersParams struct {
+	// PhoneNumber International, such as +62812, separators allowed
+	PhoneNumber *string                 `form:"phone_number,omitempty" json:"phone_number,omitempty"`
+	FullName    *string                 `form:"full_name,omitempty" json:"full_name,omitempty"`
+	Sort        *SearchUsersParamsSort  `form:"sort,omitempty" json:"sort,omitempty"`
+	Order       *SearchUsersParamsOrder `form:"order,omitempty" json:"order,omitempty"`
+	Cursor      *string                 `form:"cursor,omitempty" json:"cursor,omitempty"`
+	Limit       *int                    `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// SearchUsersParamsSort defines parameters for SearchUsers.
+type SearchUsersParamsSort string
+
+// SearchUsersParamsOrder defines parameters for SearchUsers.
+type SearchUsersParamsOrder string
+
+// DownloadProfileExportParams defines parameters for DownloadProfileExport.
+type DownloadProfileExportParams struct {
+	Token string `form:"token" json:"token"`
+}
+
+// UpdateUserJSONRequestBody defines body for UpdateUser for application/json ContentType.
+type UpdateUserJSONRequestBody = UpdateProfileRequest
+
 // LoginJSONRequestBody defines body for Login for application/json ContentType.
 type LoginJSONRequestBody = LoginRequest
 
+// LoginMfaJSONRequestBody defines body for LoginMfa for application/json ContentType.
+type LoginMfaJSONRequestBody = LoginMfaRequest
+
+// ConfirmTotpJSONRequestBody defines body for ConfirmTotp for application/json ContentType.
+type ConfirmTotpJSONRequestBody = TotpCodeRequest
+
+// DisableTotpJSONRequestBody defines body for DisableTotp for application/json ContentType.
+type DisableTotpJSONRequestBody = TotpCodeRequest
+
+// ForgotPasswordJSONRequestBody defines body for ForgotPassword for application/json ContentType.
+type ForgotPasswordJSONRequestBody = ForgotPasswordRequest
+
+// ResetPasswordJSONRequestBody defines body for ResetPassword for application/json ContentType.
+type ResetPasswordJSONRequestBody = ResetPasswordRequest
+
+// VerifyPhoneJSONRequestBody defines body for VerifyPhone for application/json ContentType.
+type VerifyPhoneJSONRequestBody = VerifyPhoneRequest
+
+// DeleteProfileJSONRequestBody defines body for DeleteProfile for application/json ContentType.
+type DeleteProfileJSONRequestBody = DeleteProfileRequest
+
 // UpdateProfileJSONRequestBody defines body for UpdateProfile for application/json ContentType.
 type UpdateProfileJSONRequestBody = UpdateProfileRequest
 
+// ExportProfileJSONRequestBody defines body for ExportProfile for application/json ContentType.
+type ExportProfileJSONRequestBody = ExportProfileRequest
+
+// ChangePasswordJSONRequestBody defines body for ChangePassword for application/json ContentType.
+type ChangePasswordJSONRequestBody = ChangePasswordRequest
+
 // RegisterJSONRequestBody defines body for Register for application/json ContentType.
 type RegisterJSONRequestBody = RegistrationRequest
 
+// RefreshTokenJSONRequestBody defines body for RefreshToken for application/json ContentType.
+type RefreshTokenJSONRequestBody = RefreshTokenRequest
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// GetJwks
+	// (GET /.well-known/jwks.json)
+	GetJwks(ctx echo.Context) error
+	// ListUsers
+	// (GET /admin/users)
+	ListUsers(ctx echo.Context, params ListUsersParams) error
+	// SearchUsers
+	// (GET /admin/users/search)
+	SearchUsers(ctx echo.Context, params SearchUsersParams) error
+	// GetUser
+	// (GET /admin/users/{id})
+	GetUser(ctx echo.Context, id int64) error
+	// UpdateUser
+	// (PATCH /admin/users/{id})
+	UpdateUser(ctx echo.Context, id int64) error
+	// UnlockUser
+	// (DELETE /admin/users/{id}/lock)
+	UnlockUser(ctx echo.Context, id int64) error
+	// LockUser
+	// (POST /admin/users/{id}/lock)
+	LockUser(ctx echo.Context, id int64) error
+	// ForcePasswordReset
+	// (POST /admin/users/{id}/password-reset)
+	ForcePasswordReset(ctx echo.Context, id int64) error
+	// RevokeUserSessions
+	// (DELETE /admin/users/{id}/sessions)
+	RevokeUserSessions(ctx echo.Context, id int64) error
+	// Healthz
+	// (GET /healthz)
+	Healthz(ctx echo.Context) error
 	// Login
 	// (POST /login)
 	Login(ctx echo.Context) error
+	// LoginMfa
+	// (POST /login/mfa)
+	LoginMfa(ctx echo.Context) error
+	// Logout
+	// (POST /logout)
+	Logout(ctx echo.Context) error
+	// LogoutAll
+	// (POST /logout-all)
+	LogoutAll(ctx echo.Context) error
+	// EnrollTotp
+	// (POST /mfa/totp)
+	EnrollTotp(ctx echo.Context) error
+	// ConfirmTotp
+	// (POST /mfa/totp/confirm)
+	ConfirmTotp(ctx echo.Context) error
+	// DisableTotp
+	// (POST /mfa/totp/disable)
+	DisableTotp(ctx echo.Context) error
+	// ForgotPassword
+	// (POST /password/forgot)
+	ForgotPassword(ctx echo.Context) error
+	// ResetPassword
+	// (POST /password/reset)
+	ResetPassword(ctx echo.Context) error
+	// VerifyPhone
+	// (POST /phone/verify)
+	VerifyPhone(ctx echo.Context) error
+	// DeleteProfile
+	// (DELETE /profile)
+	DeleteProfile(ctx echo.Context) error
 	// GetProfile
 	// (GET /profile)
 	GetProfile(ctx echo.Context) error
 	// UpdateProfile
 	// (PATCH /profile)
 	UpdateProfile(ctx echo.Context) error
+	// ExportProfile
+	// (POST /profile/export)
+	ExportProfile(ctx echo.Context) error
+	// DownloadProfileExport
+	// (GET /profile/export/{id})
+	DownloadProfileExport(ctx echo.Context, id int64, params DownloadProfileExportParams) error
+	// ChangePassword
+	// (PUT /profile/password)
+	ChangePassword(ctx echo.Context) error
+	// Readyz
+	// (GET /readyz)
+	Readyz(ctx echo.Context) error
 	// Register
 	// (POST /register)
 	Register(ctx echo.Context) error
+	// RefreshToken
+	// (POST /token/refresh)
+	RefreshToken(ctx echo.Context) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -116,6 +611,235 @@ type ServerInterfaceWrapper struct {
 	Handler ServerInterface
 }
 
+// GetJwks converts echo context to params.
+func (w *ServerInterfaceWrapper) GetJwks(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetJwks(ctx)
+	return err
+}
+
+// ListUsers converts echo context to params.
+func (w *ServerInterfaceWrapper) ListUsers(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListUsersParams
+	// ------------- Optional query parameter "role" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "role", ctx.QueryParams(), &params.Role)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter role: %s", err))
+	}
+
+	// ------------- Optional query parameter "locked" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "locked", ctx.QueryParams(), &params.Locked)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter locked: %s", err))
+	}
+
+	// ------------- Optional query parameter "phone_verified" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "phone_verified", ctx.QueryParams(), &params.PhoneVerified)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter phone_verified: %s", err))
+	}
+
+	// ------------- Optional query parameter "page" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "page", ctx.QueryParams(), &params.Page)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter page: %s", err))
+	}
+
+	// ------------- Optional query parameter "page_size" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "page_size", ctx.QueryParams(), &params.PageSize)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter page_size: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ListUsers(ctx, params)
+	return err
+}
+
+// SearchUsers converts echo context to params.
+func (w *ServerInterfaceWrapper) SearchUsers(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params SearchUsersParams
+	// ------------- Optional query parameter "phone_number" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "phone_number", ctx.QueryParams(), &params.PhoneNumber)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter phone_number: %s", err))
+	}
+
+	// ------------- Optional query parameter "full_name" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "full_name", ctx.QueryParams(), &params.FullName)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter full_name: %s", err))
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", ctx.QueryParams(), &params.Sort)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sort: %s", err))
+	}
+
+	// ------------- Optional query parameter "order" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "order", ctx.QueryParams(), &params.Order)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter order: %s", err))
+	}
+
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", ctx.QueryParams(), &params.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter cursor: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.SearchUsers(ctx, params)
+	return err
+}
+
+// GetUser converts echo context to params.
+func (w *ServerInterfaceWrapper) GetUser(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetUser(ctx, id)
+	return err
+}
+
+// UpdateUser converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateUser(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.UpdateUser(ctx, id)
+	return err
+}
+
+// UnlockUser converts echo context to params.
+func (w *ServerInterfaceWrapper) UnlockUser(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.UnlockUser(ctx, id)
+	return err
+}
+
+// LockUser converts echo context to params.
+func (w *ServerInterfaceWrapper) LockUser(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.LockUser(ctx, id)
+	return err
+}
+
+// ForcePasswordReset converts echo context to params.
+func (w *ServerInterfaceWrapper) ForcePasswordReset(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ForcePasswordReset(ctx, id)
+	return err
+}
+
+// RevokeUserSessions converts echo context to params.
+func (w *ServerInterfaceWrapper) RevokeUserSessions(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RevokeUserSessions(ctx, id)
+	return err
+}
+
+// Healthz converts echo context to params.
+func (w *ServerInterfaceWrapper) Healthz(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.Healthz(ctx)
+	return err
+}
+
 // Login converts echo context to params.
 func (w *ServerInterfaceWrapper) Login(ctx echo.Context) error {
 	var err error
@@ -125,6 +849,108 @@ func (w *ServerInterfaceWrapper) Login(ctx echo.Context) error {
 	return err
 }
 
+// LoginMfa converts echo context to params.
+func (w *ServerInterfaceWrapper) LoginMfa(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.LoginMfa(ctx)
+	return err
+}
+
+// Logout converts echo context to params.
+func (w *ServerInterfaceWrapper) Logout(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.Logout(ctx)
+	return err
+}
+
+// LogoutAll converts echo context to params.
+func (w *ServerInterfaceWrapper) LogoutAll(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.LogoutAll(ctx)
+	return err
+}
+
+// EnrollTotp converts echo context to params.
+func (w *ServerInterfaceWrapper) EnrollTotp(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.EnrollTotp(ctx)
+	return err
+}
+
+// ConfirmTotp converts echo context to params.
+func (w *ServerInterfaceWrapper) ConfirmTotp(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ConfirmTotp(ctx)
+	return err
+}
+
+// DisableTotp converts echo context to params.
+func (w *ServerInterfaceWrapper) DisableTotp(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.DisableTotp(ctx)
+	return err
+}
+
+// ForgotPassword converts echo context to params.
+func (w *ServerInterfaceWrapper) ForgotPassword(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ForgotPassword(ctx)
+	return err
+}
+
+// ResetPassword converts echo context to params.
+func (w *ServerInterfaceWrapper) ResetPassword(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ResetPassword(ctx)
+	return err
+}
+
+// VerifyPhone converts echo context to params.
+func (w *ServerInterfaceWrapper) VerifyPhone(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.VerifyPhone(ctx)
+	return err
+}
+
+// DeleteProfile converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteProfile(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.DeleteProfile(ctx)
+	return err
+}
+
 // GetProfile converts echo context to params.
 func (w *ServerInterfaceWrapper) GetProfile(ctx echo.Context) error {
 	var err error
@@ -147,6 +973,62 @@ func (w *ServerInterfaceWrapper) UpdateProfile(ctx echo.Context) error {
 	return err
 }
 
+// ExportProfile converts echo context to params.
+func (w *ServerInterfaceWrapper) ExportProfile(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ExportProfile(ctx)
+	return err
+}
+
+// DownloadProfileExport converts echo context to params.
+func (w *ServerInterfaceWrapper) DownloadProfileExport(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DownloadProfileExportParams
+	// ------------- Required query parameter "token" -------------
+
+	err = runtime.BindQueryParameter("form", true, true, "token", ctx.QueryParams(), &params.Token)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter token: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.DownloadProfileExport(ctx, id, params)
+	return err
+}
+
+// ChangePassword converts echo context to params.
+func (w *ServerInterfaceWrapper) ChangePassword(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ChangePassword(ctx)
+	return err
+}
+
+// Readyz converts echo context to params.
+func (w *ServerInterfaceWrapper) Readyz(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.Readyz(ctx)
+	return err
+}
+
 // Register converts echo context to params.
 func (w *ServerInterfaceWrapper) Register(ctx echo.Context) error {
 	var err error
@@ -156,6 +1038,15 @@ func (w *ServerInterfaceWrapper) Register(ctx echo.Context) error {
 	return err
 }
 
+// RefreshToken converts echo context to params.
+func (w *ServerInterfaceWrapper) RefreshToken(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RefreshToken(ctx)
+	return err
+}
+
 // This is a simple interface which specifies echo.Route addition functions which
 // are present on both echo.Echo and echo.Group, since we want to allow using
 // either of them for path registration
@@ -184,27 +1075,105 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 		Handler: si,
 	}
 
+	router.GET(baseURL+"/.well-known/jwks.json", wrapper.GetJwks)
+	router.GET(baseURL+"/admin/users", wrapper.ListUsers)
+	router.GET(baseURL+"/admin/users/search", wrapper.SearchUsers)
+	router.GET(baseURL+"/admin/users/:id", wrapper.GetUser)
+	router.PATCH(baseURL+"/admin/users/:id", wrapper.UpdateUser)
+	router.DELETE(baseURL+"/admin/users/:id/lock", wrapper.UnlockUser)
+	router.POST(baseURL+"/admin/users/:id/lock", wrapper.LockUser)
+	router.POST(baseURL+"/admin/users/:id/password-reset", wrapper.ForcePasswordReset)
+	router.DELETE(baseURL+"/admin/users/:id/sessions", wrapper.RevokeUserSessions)
+	router.GET(baseURL+"/healthz", wrapper.Healthz)
 	router.POST(baseURL+"/login", wrapper.Login)
+	router.POST(baseURL+"/login/mfa", wrapper.LoginMfa)
+	router.POST(baseURL+"/logout", wrapper.Logout)
+	router.POST(baseURL+"/logout-all", wrapper.LogoutAll)
+	router.POST(baseURL+"/mfa/totp", wrapper.EnrollTotp)
+	router.POST(baseURL+"/mfa/totp/confirm", wrapper.ConfirmTotp)
+	router.POST(baseURL+"/mfa/totp/disable", wrapper.DisableTotp)
+	router.POST(baseURL+"/password/forgot", wrapper.ForgotPassword)
+	router.POST(baseURL+"/password/reset", wrapper.ResetPassword)
+	router.POST(baseURL+"/phone/verify", wrapper.VerifyPhone)
+	router.DELETE(baseURL+"/profile", wrapper.DeleteProfile)
 	router.GET(baseURL+"/profile", wrapper.GetProfile)
 	router.PATCH(baseURL+"/profile", wrapper.UpdateProfile)
+	router.POST(baseURL+"/profile/export", wrapper.ExportProfile)
+	router.GET(baseURL+"/profile/export/:id", wrapper.DownloadProfileExport)
+	router.PUT(baseURL+"/profile/password", wrapper.ChangePassword)
+	router.GET(baseURL+"/readyz", wrapper.Readyz)
 	router.POST(baseURL+"/register", wrapper.Register)
+	router.POST(baseURL+"/token/refresh", wrapper.RefreshToken)
 
 }
 
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/7xWTW/bMAz9KwO3Y1BnH9jBtxUDtgIbMGTrqQgKVWZsFbKkUnSLoPB/HyQ7H46VZmub",
-	"nFqr1OPj4yPVR5C2dtagYQ/5I3hZYS3ir9+Qf5FdKI0z9M4aj+HUkXVIrDDGFIJF+PmOcAE5vM02aFkP",
-	"lY1xvoZb7QQqFAXSofurW9+76LadAOFdowgLyK9WIPMJ8NIh5GBvblFywN+TeVTFotH62og6FtijeCZl",
-	"yoDiKmvw2jT1Tcd1J2CHzgZr52aK4A9bKjPDuwY9j2k54f2DpeIVWA2iJxvkJ0i9pOUDiFN1e5x0xF1F",
-	"LReWasGQgzL8+ROsoZRhLEPOCdw+8GFRVQFdZIrNDEvlmQQru7/DB4x3tP4PTPqUF4ZVvMQSKaRTOWNv",
-	"7ucaZGyEdNoB4VEyJLJ0LW2x3f4tE3Z/r9F7Ufb0GGuftEN/IIjEMnz7Rkr0ftHorfAbazUKE/mP2F66",
-	"QjCu9+XzHHvQlIfS7rPYcS0SApVZ2ICvlcSeQlcn/Lz4EyVWrMPnpUd68xvpXskwP/dIXlkDObw/m55N",
-	"Q6R1aIRTkMPHeBSGjKtYR6bDlor12U7fUGV05kUBebfEoKONns9tsQxB0hpGE+OFc1rJeCO79dZsXu1/",
-	"3MpdZ9uhOEwNxoNOwMj1w3T62rn79rZtTO+buha0XJcdzjLXWSEAlphQaPOowxH5Jv75WZFG2ZDiJeRX",
-	"j3COgpC+NFxBfjVv59s1bRGNy5xlNa5mYP8j9T052Sfuf3rM/1fSoVrRLhR3e79ekyM1W0UcR93UQ39i",
-	"cZOv9GjG1jr0mlPYXFHyhjTkUDG7PMu0lUJXQcl23v4NAAD//5wndlcfDAAA",
+	"H4sIAAAAAAAC/+w8XXfbNrJ/BYf3vpW2nLS37fF9SpPmNr3pbtZO2ofWRwcihxJiEmAB0I6So/++ZwB+",
+	"gCQoypbIdLP7JpHgYDBfGMwHPgWRyHLBgWsVXH4KJKhccAXmz49SCok/IsE1cI0/aZ6nLKKaCb54rwTH",
+	"ZyraQEbx139LSILL4L8WDdSFfasWBtpVCT/Y7cIWrFyKVQrZVw+D+cZ+FewQXAwqkixHcMFl8IwTwBlD",
+	"ooBrQhXRGyDV+sgGaAySAL+DVOQQEiFxDOXk6uVz8t33F9+REiUSi6jIEIYWJEoZIkBoFEGuGV+ToUX8",
+	"wc0aS0xxIc/ijPF3CgxJcylykJpZSidFmi45zQD/6G0OwWWgtGR8HezCgMVmjJAZ1cFlwLj+9psgrMYx",
+	"rmENEgemIrqFeEl1a3xMNZxplkEQ9mHnVKl7IeOlBAV6KeHPgskaRpuk16BJwTVLDSkLBZKYrxRhWpEK",
+	"UhAeOjXwmPH1Mt8IDkteZCuQ/Unf4Fti3xJ6T5mh+h1IlpRkJytIhATCNJGQpzQCy+uokBLZJjiyl6db",
+	"IkEXkkNMVlvyLkfkDDt8uHVwGhhg0XggyaVIob/OZysjp4mQhrKK3DO9EYUmlG9JDjJjSjHBVR8igizZ",
+	"Flz+jtLSwT905Oum/lys3kOkEaFaMGvt7AloTPWoMtZgEKbVr7FPqgl/sqO7SymB+HB+vqF8DW9KmbuC",
+	"PwtQ1kDFMUOS0vSNs4KEpgrCzqJKAVnWkutjNIf7fQM6GPdAdgAcspRjeOCH9QK//AxMcWbvrQY+5EyC",
+	"WjJ+oHF7f6/H6Y+DQhe2F03BEyazt0LnR1K7D2g2Ug9M3VuHhEjcgdwuIxHbJ0xDpryyXj6gUtJtD5kO",
+	"IB9SiMGPH3Ih9bWmurB85kWGn5fWPjBKGIFS+OfGYx5fQAoa3kiRsBQep9iH6+texexgcoykeEHNJSvD",
+	"k3ukRWkh6SqFpdnpD93VerLSAeNFiykcsV8NJ6eNuOepoHFJHSu9R/J6H8jZeD6KxKBFfogvc7BrqmqD",
+	"sJd0XQPidW5KWKGLsY8GP3Ip0vR4M9+HMxcXB2burULonBZ6sywk8xp2BZGEA7bPclzYAuhFrHWYm11r",
+	"rYwctUdUMot+eEKLVAeXgTl8hvWWVf6N1J1no9qNo3WUzPlAzSZ2g5P311LamaUWt8C9wvevZ1c6ixo1",
+	"NC8ZpHEdLenIGb7rH/deCK0hJjnVGyISc141I0OiimiDoYjOEa5HogyUomt/xKB8t2Semd9ugCiNOy95",
+	"9QLnrgCNbep2Jc3ErWm8ZBFyLfRxZ7SRg3jXnXNHH4LS5zJg/wenMRN9OHPZiIGZHxjaehh7G1jhOKt/",
+	"Aprqzedj8c/Xf//bb7D6f9j256bp2m8qvU9vmT80cau33ud+M1woGKcwgrRDQ4OknRxBInL7l3kNur/S",
+	"W9i2D537iN3AGj2NGrg+fF4zpTEIpY5TrB6YufTKP3FvDXnb9Ds7Ir5ZKvZx4LUWmqYHbq4mEHkw91rx",
+	"v73Ms3AtqoGLcYWelzBizfjzDU1T4Gs4QUApS+ger8U+aOIXOLpew83YXmneulOMBqXM8n5J6CNDmSL2",
+	"xJSfixhIIkVm/At06IFrjJpjsiPPTc6Dk4IXCmJSxXeIgRUGGeOvga/1Jrh84nM/XOrtHdshjUsTM9Ug",
+	"NU4e/AmPcSfC/cGiEuMhmxO5grvX8LTFHPNah5grd/bZTFVv0uOV8mDH3x8ORtwTCWozqNk+x99Gjdtf",
+	"HqKwotCfz7+ocp/9TNKeHGbQ1ZYYNGVpHwieEkz+tDoeKHtMYYqIyGY5Iu/JzXy0rOxRn2/mvfJPaE4Y",
+	"qjoPSav+RG+oJgllKcTkjqYsNlm/IDxsV3LOZr1tyT0+9jHVTPsSddcbITVRRZZRua1wrehc2v3B3aQN",
+	"6t3VKyIhAUNMwmmGqU0HHKYtgeQgS1YgVZsDYpVuVosWVeADzXJEPBgYcNDOZRdfE8gngFdAY8ZBqecb",
+	"iG77CjDo9Eugseu5roRIgfIeKqWnb4fvxeA4X68HZgoDWtrxg5bht6URkvlwd6zDnjGfrITux88Yxrdo",
+	"Fx+3J/eM8kMchvbH4wgeJwp9SHNtp4Nz/zvtqlewZkpLY6seGdjdH3CYzDtsBSf2eYrtJR4nrX1I80nr",
+	"wNy9lRwofH0R8k+r4MiQYuWbjBxwppOU8oA1IiStdX4uJ7PzZW/+g7y9pfXr/io+n8WpiV2rkRi5Qa71",
+	"kQoJ4wZfRTMgQtoN/tDCig4KA/O3/W6oqhnLiVPK1wVdA+GwFppRTCaYk/4zU5l49rp8/yC0VBFFoFRS",
+	"pAPeWU8+roHKaHOCeJsH0FyWbGjq3jo4fNDLqJBKSF+shWvGi7L2UBmYIUkh0QSr+ETFNqVJ7s22TB1u",
+	"8y0dM8sYJDrOkI6UxA3FeGwF5nFJ3COTC2M4HSPRXlBzyfTw5L21zFiGGxyURf8VgW7NhNNu8Efv4Tdj",
+	"2H+ebdsWfBSS6e01grJz/gBUgnxWIDk+BSvz72XllP3829ugLFY3Nt+8bdi10Tq3RfaMJwK/T1kE5dKs",
+	"/gW/vHrrRE0CtEzkGuQdM3GiO5DKStKT84vzCxwpcuA0Z8Fl8LV5hB6R3hhcF+f3kKZnt1zc88X7+1t1",
+	"XvUFrG2SCYlppO5VHFxiLvLn+1tlThtO/8LTi4uTdS+001y7suGgLBzxf1rjsihdEbPDmrCRgzM+XVC0",
+	"5Yva/peLbGsgJoZUXXmvQhJDCrjtCw6q3mhC643YGncWE2p2G0I1oUSzDM7JS5ZqkIpQCSQS2YpxiM//",
+	"4EHYIWqdiDKMkTQDbdD7/VPAEJ8/C5DbIKzYb6raQ4eaPXXyf2d7FnxfOo6H/9N2Df7jQNituPmw5ukT",
+	"kwFhWZG51sM5rQwDrHJZHqhPL8Igox9KsBcXI5PcTCjR/UTp46W6tDZGOFw78/vN7sYVelemPpxVxuzM",
+	"bW+o/JVLCTQObrrqsbCe1aCWWGcODlGU1dYMUppKXTrZTJK8tdFxo0d5awRyOiRszQVKNomoAjMQe4K4",
+	"VufkB6E3bfUib4wnb59ZPzE2DR4GA8evbOLJcMdEoWzzyprdASc0FXzdfGWOHo1i+lTY8Wz7Stym2yuu",
+	"QXJqd9gmxvzVt0+/f/I0JArway3QbqSpuDfqNqyT9TbZiGJGP1Sb8dcX4aHWwQ2qeGF9ezgsJaT2a6WN",
+	"V1WZXvPngFKTsfmqY6FvQqoiZ0b7DxnyAPDlQeQxJpdlTP/l7ZPvaDm9hWqrzCNt1CcW7/Y5K2XX2WSk",
+	"6zd0TU+4ZlkHEs3vVaAL2EiqUcXG59WyAFdsx2OKNziLtvtF57xuepXsPsFtFKdt+0VCqNlBzslrdguk",
+	"daQKCSUc7ttfMFX2mrbPRXhUMBtE2YPoPxsRgTnAypkheiNFsd6QhZlhYZ5vQzxcMdX0MWKZqOf41nRp",
+	"8hJ5sJUdZs2k4DFI4kpsAzgxtSDYhYj40SgSBa/2PqLA8NIspmlUXEvKtUUm6zQr+jalVuNlGe/7QcTb",
+	"k0m/N66w2+26krT7wjSwRdgxJbyXTMOA6VqgT241JgUNfRP2juOIL9GKtVY2RkMcOqchE8rn8LI1b7xd",
+	"G2tMSn139dWqtm3VTrFwRzm93JWeM3xoT2TeI+EXyvXXD+a5V22qhM6ZBFXWwh7FMgU8VuYEb+Habnuz",
+	"n5yTt9XnEeVcaOQp5gbomjLucLb+1lh3BdrH15dCRm7bLuhgLqH+ouTIS8dxd0iB0zA+IFulXKi2WX6w",
+	"XIUkRXfA1q49S1OfNFzBnbg1+8h1Nel/pOER0uCl47g04EfLmt1WHDaml+Hj4Jnip/L9hBTstFOcIAJa",
+	"IW1WaLYk12R2dx58PY2/2Ko1ntlPbFcNn4CollANSRdZQkfI+ktCp6SsU9P+RRAXyVXRVxR6L3Hx/bQL",
+	"dEuQ53CUzJKc5Z/RNB0jwbM0/QKpgKsyhMgSutBC58NkaNq4p6SDp919elo4S2sRYxHZC0qGieLcYDKR",
+	"+elWN8xsfnzXzEzPEJesbY7E9r6NYY44F3J8mRzx3TgyPUdcshqOVL7+IjHdyMMMaXcrT8QTf5f2zJwZ",
+	"6Ms+wZ7doWGbAb2DevckpGBq8nsLWmemvr/Y9ATEbxPQ0t6JZw9T3qmimYjuniqjmanuqxQ6Ac1d0lmK",
+	"20j4vsiBvSFK7Yn5hzY/4V70iCGlcpcHvLeCrTnmwzHs0Io5nGO4YY2vWF0k1r0gqnzQRuF/CU00SMxN",
+	"dIKUeSHX/hBl666riUTHe0PZ3FuZ926yGTazFn134WCO02XBRDTwXKJhCfDNxTfTTzJxMtUhcZ3B9CXS",
+	"phX1v0IuzV+TO1c+reaDY0oXYO4SGo7w/6OAAsxtwnZkHYfVG7SDG0hjQleisIbNRms75pZi5B+rDpv7",
+	"h4UklHxkOY59fv0rQVSUzQRg0QK7A7SN1eVFVUNAB2kTTm6qiNpXHZWJg+a6o3PySpONwA6Nsi8VQVXl",
+	"TsYcK001YF9ClBZx1cAqRTWKb6sUta3DskFoDKSQDVNayG1YLVqFVcuHea/CTuYDH5h8u5teVyHR9+Is",
+	"oZG51aC546DKotirD2JmdiWuTT9FVCb/MxpDVQdmuSDxr9I0Sc7JSxcZYlYLsWnHffWG0DiWoGxNFxea",
+	"aJO1FzWokCjzZ1uPsPT3b1utG7cm0mXvpWm7Uplbuvt0qjnnC0u06OnR3W6NTvd2LqsVpTtSKpdIXI22",
+	"7hBq3KpgqQ4J5eoeTEng04unVsPQXbId1K2SDNRYq29KU8zxVfUWtfZb2SHvrl5jfo+sgIgcyooPWist",
+	"SRm/9XpBvtsf58niDNScVe2fw6C7xWvHpoP6RfHu+I8sbw+vV7ZinBrMuwj1rph3DG/dl2XBVH1sEAe7",
+	"8KQKtf++0hMcH7wTtDXI7YzMC19or3UP9ETmzH8D+NwxPv/d3TOE+dokNvwxFyUMJwmv7OtJ4wjd+xgs",
+	"If7n4uvJ5zgyTmFIU1IRG5tBut5dl5DliKkiQv0W+NkDQp4W9ZPQuaScobTZExblBQH7yN3cizAZyfs3",
+	"XMxOcs8dFicheQPXrkmBvKs8gEKmZXvX5QLL/mi6QR7sbnb/HAAeBBnuGmYAAA==",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -1,16 +1,101 @@
 package handler
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/fenky-ng/swt-pro/constant"
+	"github.com/fenky-ng/swt-pro/export"
 	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/fenky-ng/swt-pro/logging"
+	"github.com/fenky-ng/swt-pro/phone"
 	"github.com/fenky-ng/swt-pro/repository"
+	"github.com/fenky-ng/swt-pro/totp"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/gommon/log"
 )
 
+// GetJwks
+// (GET /.well-known/jwks.json)
+func (s *Server) GetJwks(ctx echo.Context) error {
+	var response generated.JSONWebKeySet
+
+	response.Keys = []generated.JSONWebKey{}
+	for _, key := range s.KeyRing.JWKS() {
+		response.Keys = append(response.Keys, generated.JSONWebKey{
+			Kty: key.Kty,
+			Use: key.Use,
+			Alg: key.Alg,
+			Kid: key.Kid,
+			N:   key.N,
+			E:   key.E,
+		})
+	}
+
+	ctx.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// Healthz
+// (GET /healthz)
+func (s *Server) Healthz(ctx echo.Context) error {
+	var response generated.HealthResponse
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// Readyz
+// (GET /readyz)
+func (s *Server) Readyz(ctx echo.Context) error {
+	var (
+		funcName      = "Readyz"
+		response      generated.ReadinessResponse
+		errorMessages []i18n.Message
+	)
+
+	response.Data.Checks = []generated.ReadinessCheck{}
+	check := func(name string, ready bool, errorMessage i18n.Message) {
+		response.Data.Checks = append(response.Data.Checks, generated.ReadinessCheck{
+			Name:  name,
+			Ready: ready,
+		})
+		if !ready {
+			errorMessages = append(errorMessages, errorMessage)
+		}
+	}
+
+	// stop taking traffic as soon as shutdown begins
+	check("shutdown", !s.shuttingDown.Load(), i18n.M(i18n.ReadinessShuttingDown))
+
+	// ping the database
+	pingCtx, cancel := context.WithTimeout(ctx.Request().Context(), s.config.Server.ReadinessTimeout)
+	defer cancel()
+	err := s.Repository.Ping(pingCtx)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Ping error", "func", funcName, "error", err)
+	}
+	check("database", err == nil, i18n.M(i18n.ReadinessDatabase))
+
+	// make sure tokens can be signed
+	check("keys", s.KeyRing != nil && s.KeyRing.SigningKey().PrivateKey != nil, i18n.M(i18n.ReadinessSigningKey))
+
+	if len(errorMessages) != 0 {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnavailable, errorMessages, false)
+		return ctx.JSON(http.StatusServiceUnavailable, response)
+	}
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	return ctx.JSON(http.StatusOK, response)
+}
+
 // Register
 // (POST /register)
 func (s *Server) Register(ctx echo.Context) error {
@@ -23,35 +108,44 @@ func (s *Server) Register(ctx echo.Context) error {
 	// decode request body
 	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
 	if err != nil {
-		log.Errorf("[%s] Decode error: %s", funcName, err.Error())
-		response.Header = generateResponseHeader(constant.ErrorCodeUnmarshal, []string{"Bad request"}, false)
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
 		return ctx.JSON(http.StatusBadRequest, response)
 	}
 
 	// validate registration request
-	requestValidationErrors := validateRegistration(request)
+	requestValidationErrors := validateRegistration(s.config.Validation, &request)
 	if len(requestValidationErrors) != 0 {
-		response.Header = generateResponseHeader(constant.ErrorCodeValidation, requestValidationErrors, false)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, requestValidationErrors, false)
 		return ctx.JSON(http.StatusBadRequest, response)
 	}
 
+	// an unverified account only holds its phone number while its code can
+	// still be redeemed, past that the phone number is free again
+	err = s.Repository.ReleasePhoneNumber(ctx.Request().Context(), request.PhoneNumber)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "ReleasePhoneNumber error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
 	// check whether phone number is already registered or not
 	isNewPhoneNumber, err := checkNewPhoneNumber(ctx.Request().Context(), s, request.PhoneNumber)
 	if err != nil {
-		log.Errorf("[%s] checkNewPhoneNumber error: %s", funcName, err.Error())
-		response.Header = generateResponseHeader(constant.ErrorCodeDatabase, []string{"System error"}, false)
-		return ctx.JSON(http.StatusInternalServerError, response)
+		s.log().ErrorContext(ctx.Request().Context(), "checkNewPhoneNumber error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
 	}
 	if !isNewPhoneNumber {
-		response.Header = generateResponseHeader(constant.ErrorCodeValidation, []string{"Phone number is already registered"}, false)
-		return ctx.JSON(http.StatusBadRequest, response)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.PhoneNumberAlreadyRegistered)}, false)
+		return ctx.JSON(http.StatusConflict, response)
 	}
 
 	// hash and salt the password
-	salt, err := hashAndSalt(request.Password)
+	salt, err := hashAndSalt(ctx.Request().Context(), request.Password, s.config.Auth.BcryptCost)
 	if err != nil {
-		log.Errorf("[%s] hashAndSalt error: %s", funcName, err.Error())
-		response.Header = generateResponseHeader(constant.ErrorCodeHashAndSalt, []string{"There was an error when handling password"}, false)
+		s.log().ErrorContext(ctx.Request().Context(), "hashAndSalt error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeHashAndSalt, []i18n.Message{i18n.M(i18n.PasswordHashingErr)}, false)
 		return ctx.JSON(http.StatusInternalServerError, response)
 	}
 
@@ -61,13 +155,25 @@ func (s *Server) Register(ctx echo.Context) error {
 		Password:    salt,
 		FullName:    request.FullName,
 	})
+	if isPhoneNumberTaken(err) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.PhoneNumberAlreadyRegistered)}, false)
+		return ctx.JSON(http.StatusConflict, response)
+	}
 	if err != nil {
-		log.Errorf("[%s] InsertUser error: %s", funcName, err.Error())
-		response.Header = generateResponseHeader(constant.ErrorCodeDatabase, []string{"System error"}, false)
-		return ctx.JSON(http.StatusInternalServerError, response)
+		s.log().ErrorContext(ctx.Request().Context(), "InsertUser error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// ask the user to prove they own the phone number, a failed delivery is
+	// not fatal since logging in sends a new code
+	err = sendPhoneVerificationCode(ctx.Request().Context(), s, id, request.PhoneNumber)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "sendPhoneVerificationCode error", "func", funcName, "error", err)
 	}
 
-	response.Header = generateResponseHeader(0, nil, true)
+	s.metrics.registrations.Inc()
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
 	response.Data = &generated.RegistrationResponseData{
 		Id: id,
 	}
@@ -87,169 +193,1838 @@ func (s *Server) Login(ctx echo.Context) error {
 	// decode request body
 	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
 	if err != nil {
-		log.Errorf("[%s] Decode error: %s", funcName, err.Error())
-		response.Header = generateResponseHeader(constant.ErrorCodeUnmarshal, []string{"Bad request"}, false)
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
 		return ctx.JSON(http.StatusBadRequest, response)
 	}
 
-	// get user from db by phone number
-	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), request.PhoneNumber)
+	// refuse clients that failed too often
+	now := time.Now()
+	ipKey := loginAttemptIPKey(ctx.RealIP())
+	block, err := getLoginBlock(ctx.Request().Context(), s, ipKey, false, now)
 	if err != nil {
-		log.Errorf("[%s] GetUserByPhoneNumber error: %s", funcName, err.Error())
-		response.Header = generateResponseHeader(constant.ErrorCodeDatabase, []string{"System error"}, false)
-		return ctx.JSON(http.StatusInternalServerError, response)
+		s.log().ErrorContext(ctx.Request().Context(), "getLoginBlock error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if block != nil {
+		return respondWithLoginBlock(ctx, s, block)
 	}
 
-	// check whether user exists or not
-	if user.ID == 0 {
-		response.Header = generateResponseHeader(constant.ErrorCodeValidation, []string{"Phone number is not registered"}, false)
+	// get user from db by phone number. Accounts deleted within the grace
+	// period can still log in, which restores them once a session is
+	// started.
+	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), request.PhoneNumber)
+	if errors.Is(err, repository.ErrNotFound) {
+		user, err = s.Repository.GetDeletedUserByPhoneNumber(ctx.Request().Context(), request.PhoneNumber, now.Add(-s.config.AccountDeletion.GracePeriod))
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			s.log().ErrorContext(ctx.Request().Context(), "GetDeletedUserByPhoneNumber error", "func", funcName, "error", err)
+			response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+			return ctx.JSON(repositoryErrorStatus(err), response)
+		}
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		err = recordFailedLogin(ctx.Request().Context(), s, ipKey, false, now)
+		if err != nil {
+			s.log().ErrorContext(ctx.Request().Context(), "recordFailedLogin error", "func", funcName, "error", err)
+			response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+			return ctx.JSON(repositoryErrorStatus(err), response)
+		}
+		s.metrics.loginFailed(loginReasonUnknownPhoneNumber)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.PhoneNumberNotRegistered)}, false)
 		return ctx.JSON(http.StatusBadRequest, response)
 	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByPhoneNumber error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	logging.SetUserID(ctx.Request().Context(), user.ID)
+
+	// refuse accounts that failed too often
+	userKey := repository.LoginAttemptUserKey(user.ID)
+	block, err = getLoginBlock(ctx.Request().Context(), s, userKey, true, now)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "getLoginBlock error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if block != nil {
+		return respondWithLoginBlock(ctx, s, block)
+	}
 
 	// check password
-	if !comparePasswords(user.Password, request.Password) {
-		response.Header = generateResponseHeader(constant.ErrorCodeValidation, []string{"Wrong password"}, false)
+	if !comparePasswords(ctx.Request().Context(), user.Password, request.Password) {
+		for _, key := range []string{userKey, ipKey} {
+			err = recordFailedLogin(ctx.Request().Context(), s, key, key == userKey, now)
+			if err != nil {
+				s.log().ErrorContext(ctx.Request().Context(), "recordFailedLogin error", "func", funcName, "error", err)
+				response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+				return ctx.JSON(repositoryErrorStatus(err), response)
+			}
+		}
+		s.metrics.loginFailed(loginReasonWrongPassword)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.PasswordWrong)}, false)
 		return ctx.JSON(http.StatusBadRequest, response)
 	}
 
-	// generate jwt token
-	jwtToken, err := generateJwtToken(user)
+	// an account locked by support cannot log in until it is unlocked
+	if user.LockedAt != nil {
+		s.metrics.loginFailed(loginReasonLockedBySupport)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAccountLocked, []i18n.Message{i18n.M(i18n.LoginLockedBySupport)}, false)
+		return ctx.JSON(http.StatusLocked, response)
+	}
+
+	// the account cannot be used until its phone number is verified
+	if user.PhoneVerifiedAt == nil {
+		err = sendPhoneVerificationCode(ctx.Request().Context(), s, user.ID, user.PhoneNumber)
+		if err != nil {
+			s.log().ErrorContext(ctx.Request().Context(), "sendPhoneVerificationCode error", "func", funcName, "error", err)
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeGeneral, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+		s.metrics.loginFailed(loginReasonPhoneNotVerified)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePhoneNotVerified, []i18n.Message{i18n.M(i18n.PhoneNumberNotVerified)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// the password was expired by support, a new one is set with a reset code
+	if user.PasswordResetRequiredAt != nil {
+		err = sendPasswordResetCode(ctx.Request().Context(), s, user)
+		if err != nil {
+			s.log().ErrorContext(ctx.Request().Context(), "sendPasswordResetCode error", "func", funcName, "error", err)
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeGeneral, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+		s.metrics.loginFailed(loginReasonPasswordExpired)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePasswordExpired, []i18n.Message{i18n.M(i18n.LoginPasswordResetRequired)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// a second factor is required before a session is started
+	userTOTP, err := s.Repository.GetUserTOTP(ctx.Request().Context(), user.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserTOTP error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if err == nil && userTOTP.ConfirmedAt != nil {
+		mfaToken, err := generateMfaChallengeToken(ctx.Request().Context(), s, user)
+		if err != nil {
+			s.log().ErrorContext(ctx.Request().Context(), "generateMfaChallengeToken error", "func", funcName, "error", err)
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeJWT, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+		s.metrics.logins.Inc(loginResultMfaRequired, "")
+		response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+		response.Challenge = &generated.LoginChallenge{
+			Type:      generated.MfaRequired,
+			MfaToken:  mfaToken,
+			ExpiresIn: int64(s.config.Auth.MfaChallengeTTL.Seconds()),
+		}
+		return ctx.JSON(http.StatusOK, response)
+	}
+
+	return respondWithNewSession(ctx, s, funcName, user)
+}
+
+// LoginMfa
+// (POST /login/mfa)
+func (s *Server) LoginMfa(ctx echo.Context) error {
+	var (
+		funcName = "LoginMfa"
+		request  generated.LoginMfaRequest
+		response generated.LoginResponse
+	)
+
+	// decode request body
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// check challenge token
+	challengeClaims, err := parseJwtToken(ctx.Request().Context(), s, request.MfaToken)
+	if err != nil {
+		s.metrics.loginFailed(loginReasonInvalidMfaToken)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusUnauthorized, response)
+	}
+	if challengeClaims.Purpose != constant.TokenPurposeMfaChallenge {
+		s.metrics.loginFailed(loginReasonInvalidMfaToken)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.MfaInvalidToken)}, false)
+		return ctx.JSON(http.StatusUnauthorized, response)
+	}
+	revoked, err := isSessionRevoked(ctx.Request().Context(), s, challengeClaims)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "isSessionRevoked error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if revoked {
+		s.metrics.loginFailed(loginReasonInvalidMfaToken)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.MfaTokenUsed)}, false)
+		return ctx.JSON(http.StatusUnauthorized, response)
+	}
+
+	// get user from db by id, or the account deleted within the grace
+	// period that logging in restores
+	now := time.Now()
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), challengeClaims.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		user, err = s.Repository.GetDeletedUserByPhoneNumber(ctx.Request().Context(), challengeClaims.PhoneNumber, now.Add(-s.config.AccountDeletion.GracePeriod))
+		if err == nil && user.ID != challengeClaims.UserID {
+			err = repository.ErrNotFound
+		}
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		s.metrics.loginFailed(loginReasonInvalidMfaToken)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.MfaInvalidToken)}, false)
+		return ctx.JSON(http.StatusUnauthorized, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	logging.SetUserID(ctx.Request().Context(), user.ID)
+
+	// the account may have been locked since the password was checked
+	if user.LockedAt != nil {
+		s.metrics.loginFailed(loginReasonLockedBySupport)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAccountLocked, []i18n.Message{i18n.M(i18n.LoginLockedBySupport)}, false)
+		return ctx.JSON(http.StatusLocked, response)
+	}
+
+	// get totp enrollment
+	userTOTP, err := s.Repository.GetUserTOTP(ctx.Request().Context(), user.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserTOTP error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if errors.Is(err, repository.ErrNotFound) || userTOTP.ConfirmedAt == nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.MfaNotEnabled)}, false)
+		return ctx.JSON(http.StatusUnauthorized, response)
+	}
+
+	// codes are guessed against the same counters as passwords
+	userKey := repository.LoginAttemptUserKey(user.ID)
+	ipKey := loginAttemptIPKey(ctx.RealIP())
+	for _, key := range []string{ipKey, userKey} {
+		block, err := getLoginBlock(ctx.Request().Context(), s, key, key == userKey, now)
+		if err != nil {
+			s.log().ErrorContext(ctx.Request().Context(), "getLoginBlock error", "func", funcName, "error", err)
+			response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+			return ctx.JSON(repositoryErrorStatus(err), response)
+		}
+		if block != nil {
+			return respondWithLoginBlock(ctx, s, block)
+		}
+	}
+
+	// check code
+	verified, err := verifySecondFactor(ctx.Request().Context(), s, userTOTP, request.Code)
 	if err != nil {
-		log.Errorf("[%s] generateJwtToken error: %s", funcName, err.Error())
-		response.Header = generateResponseHeader(constant.ErrorCodeJWT, []string{"System error"}, false)
+		s.log().ErrorContext(ctx.Request().Context(), "verifySecondFactor error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.SystemError)}, false)
 		return ctx.JSON(http.StatusInternalServerError, response)
 	}
+	if !verified {
+		for _, key := range []string{userKey, ipKey} {
+			err = recordFailedLogin(ctx.Request().Context(), s, key, key == userKey, now)
+			if err != nil {
+				s.log().ErrorContext(ctx.Request().Context(), "recordFailedLogin error", "func", funcName, "error", err)
+				response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+				return ctx.JSON(repositoryErrorStatus(err), response)
+			}
+		}
+		s.metrics.loginFailed(loginReasonInvalidCode)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.MfaInvalidCode)}, false)
+		return ctx.JSON(http.StatusUnauthorized, response)
+	}
+
+	// the challenge token can only be exchanged once
+	expiresAt := time.Unix(challengeClaims.ExpiresAt, 0)
+	err = s.Repository.InsertRevokedToken(ctx.Request().Context(), repository.RevokedToken{
+		JTI:       challengeClaims.Id,
+		UserID:    user.ID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "InsertRevokedToken error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	s.revocationCache.setTokenRevoked(challengeClaims.Id, true, expiresAt, now)
+
+	return respondWithNewSession(ctx, s, funcName, user)
+}
+
+// RefreshToken
+// (POST /token/refresh)
+func (s *Server) RefreshToken(ctx echo.Context) error {
+	var (
+		funcName = "RefreshToken"
+		request  generated.RefreshTokenRequest
+		response generated.RefreshTokenResponse
+	)
+
+	// decode request body
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// get refresh token from db by its hash
+	storedToken, err := s.Repository.GetRefreshTokenByHash(ctx.Request().Context(), hashToken(request.RefreshToken))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		s.log().ErrorContext(ctx.Request().Context(), "GetRefreshTokenByHash error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if errors.Is(err, repository.ErrNotFound) || storedToken.RevokedAt != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeRefreshToken, []i18n.Message{i18n.M(i18n.RefreshTokenInvalid)}, false)
+		return ctx.JSON(http.StatusUnauthorized, response)
+	}
+
+	// a refresh token that has already been rotated is being replayed,
+	// so the whole family is treated as compromised
+	if storedToken.UsedAt != nil {
+		return rejectReusedRefreshToken(ctx, s, funcName, storedToken.FamilyID)
+	}
+
+	if time.Now().After(storedToken.ExpiresAt) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeRefreshToken, []i18n.Message{i18n.M(i18n.RefreshTokenExpired)}, false)
+		return ctx.JSON(http.StatusUnauthorized, response)
+	}
+
+	// mark the refresh token as used, losing this race also means reuse
+	marked, err := s.Repository.MarkRefreshTokenUsed(ctx.Request().Context(), storedToken.ID)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "MarkRefreshTokenUsed error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if !marked {
+		return rejectReusedRefreshToken(ctx, s, funcName, storedToken.FamilyID)
+	}
+
+	// get user from db by id
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), storedToken.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeRefreshToken, []i18n.Message{i18n.M(i18n.RefreshTokenInvalid)}, false)
+		return ctx.JSON(http.StatusUnauthorized, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
 
-	// increase login count
-	err = s.Repository.IncreaseLoginCount(ctx.Request().Context(), user.ID)
+	// generate jwt token
+	jwtToken, err := generateJwtToken(ctx.Request().Context(), s, user, storedToken.FamilyID)
 	if err != nil {
-		log.Errorf("[%s] IncreaseLoginCount error: %s", funcName, err.Error())
-		response.Header = generateResponseHeader(constant.ErrorCodeDatabase, []string{"System error"}, false)
+		s.log().ErrorContext(ctx.Request().Context(), "generateJwtToken error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeJWT, []i18n.Message{i18n.M(i18n.SystemError)}, false)
 		return ctx.JSON(http.StatusInternalServerError, response)
 	}
 
-	response.Header = generateResponseHeader(0, nil, true)
-	response.Data = &generated.LoginResponseData{
-		Id:  user.ID,
-		Jwt: jwtToken,
+	// rotate refresh token within the same family
+	refreshToken, err := issueRefreshToken(ctx.Request().Context(), s, user.ID, storedToken.FamilyID)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "issueRefreshToken error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	response.Data = &generated.RefreshTokenResponseData{
+		Id:           user.ID,
+		Jwt:          jwtToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.config.Auth.AccessTokenTTL.Seconds()),
 	}
 
 	return ctx.JSON(http.StatusOK, response)
 }
 
-// GetProfile
-// (GET /profile)
-func (s *Server) GetProfile(ctx echo.Context) error {
+// Logout
+// (POST /logout)
+func (s *Server) Logout(ctx echo.Context) error {
 	var (
-		funcName = "GetProfile"
-		response generated.GetProfileResponse
+		funcName = "Logout"
+		response generated.LogoutResponse
 	)
 
 	// get session claims
-	sessionClaims, err := getSessionClaims(ctx)
+	sessionClaims, err := getSessionClaims(ctx, s)
 	if err != nil {
-		response.Header = generateResponseHeader(constant.ErrorCodeAuthorization, []string{err.Error()}, false)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
 		return ctx.JSON(http.StatusForbidden, response)
 	}
 
-	// get user from db by id
-	user, err := s.Repository.GetUserByID(ctx.Request().Context(), sessionClaims.UserID)
-	if err != nil {
-		log.Errorf("[%s] GetUserByID error: %s", funcName, err.Error())
-		response.Header = generateResponseHeader(constant.ErrorCodeDatabase, []string{"System error"}, false)
-		return ctx.JSON(http.StatusInternalServerError, response)
+	// revoke the access token until it would have expired anyway
+	if sessionClaims.Id != "" {
+		expiresAt := time.Unix(sessionClaims.ExpiresAt, 0)
+		err = s.Repository.InsertRevokedToken(ctx.Request().Context(), repository.RevokedToken{
+			JTI:       sessionClaims.Id,
+			UserID:    sessionClaims.UserID,
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			s.log().ErrorContext(ctx.Request().Context(), "InsertRevokedToken error", "func", funcName, "error", err)
+			response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+			return ctx.JSON(repositoryErrorStatus(err), response)
+		}
+		s.revocationCache.setTokenRevoked(sessionClaims.Id, true, expiresAt, time.Now())
 	}
 
-	response.Header = generateResponseHeader(0, nil, true)
-	response.Data = &generated.GetProfileResponseData{
-		FullName:    user.FullName,
-		PhoneNumber: user.PhoneNumber,
+	// revoke the refresh tokens of this session
+	if sessionClaims.SessionID != "" {
+		err = s.Repository.RevokeRefreshTokenFamily(ctx.Request().Context(), sessionClaims.SessionID)
+		if err != nil {
+			s.log().ErrorContext(ctx.Request().Context(), "RevokeRefreshTokenFamily error", "func", funcName, "error", err)
+			response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+			return ctx.JSON(repositoryErrorStatus(err), response)
+		}
 	}
 
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+
 	return ctx.JSON(http.StatusOK, response)
 }
 
-// UpdateProfile
-// (PATCH /profile)
-func (s *Server) UpdateProfile(ctx echo.Context) error {
+// LogoutAll
+// (POST /logout-all)
+func (s *Server) LogoutAll(ctx echo.Context) error {
 	var (
-		funcName = "UpdateProfile"
-		request  generated.UpdateProfileRequest
-		response generated.UpdateProfileResponse
+		funcName = "LogoutAll"
+		response generated.LogoutResponse
 	)
 
 	// get session claims
-	sessionClaims, err := getSessionClaims(ctx)
+	sessionClaims, err := getSessionClaims(ctx, s)
 	if err != nil {
-		response.Header = generateResponseHeader(constant.ErrorCodeAuthorization, []string{err.Error()}, false)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
 		return ctx.JSON(http.StatusForbidden, response)
 	}
 
-	// decode request body
-	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	// invalidate every access token issued up to now
+	now := tokensCutOff(time.Now())
+	err = s.Repository.UpdateTokensValidAfter(ctx.Request().Context(), sessionClaims.UserID, now)
 	if err != nil {
-		log.Errorf("[%s] Decode error: %s", funcName, err.Error())
-		response.Header = generateResponseHeader(constant.ErrorCodeUnmarshal, []string{"Bad request"}, false)
-		return ctx.JSON(http.StatusBadRequest, response)
+		s.log().ErrorContext(ctx.Request().Context(), "UpdateTokensValidAfter error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	s.revocationCache.setTokensValidAfter(sessionClaims.UserID, now, now)
+
+	// revoke every refresh token of the user
+	err = s.Repository.RevokeRefreshTokensByUserID(ctx.Request().Context(), sessionClaims.UserID)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "RevokeRefreshTokensByUserID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
 	}
 
+	recordAuditEvent(ctx.Request().Context(), s, funcName, repository.AuditEvent{UserID: sessionClaims.UserID, Action: repository.AuditSessionsRevoked})
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// VerifyPhone
+// (POST /phone/verify)
+func (s *Server) VerifyPhone(ctx echo.Context) error {
 	var (
-		changesCount int
-		phoneNumber  string
-		fullName     string
+		funcName = "VerifyPhone"
+		request  generated.VerifyPhoneRequest
+		response generated.VerifyPhoneResponse
 	)
 
-	if request.PhoneNumber != nil && *request.PhoneNumber != "" {
-		phoneNumber = *request.PhoneNumber
+	// decode request body
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
 
-		// validate phone number
-		if errorMessages := validatePhoneNumber(phoneNumber); len(errorMessages) != 0 {
-			response.Header = generateResponseHeader(constant.ErrorCodeValidation, errorMessages, false)
-			return ctx.JSON(http.StatusBadRequest, response)
-		}
+	// get the latest pending code of the phone number
+	phoneVerification, err := s.Repository.GetActivePhoneVerification(ctx.Request().Context(), request.PhoneNumber)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePhoneVerification, []i18n.Message{i18n.M(i18n.CodeInvalid)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetActivePhoneVerification error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
 
-		// check whether phone number is already registered or not
-		user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), phoneNumber)
-		if err != nil {
-			log.Errorf("[%s] GetUserByPhoneNumber error: %s", funcName, err.Error())
-			response.Header = generateResponseHeader(constant.ErrorCodeDatabase, []string{"System error"}, false)
-			return ctx.JSON(http.StatusInternalServerError, response)
-		}
-		if user.ID != 0 && user.ID != sessionClaims.UserID {
-			response.Header = generateResponseHeader(constant.ErrorCodeValidation, []string{"Phone number is already registered"}, false)
-			return ctx.JSON(http.StatusConflict, response)
-		}
+	// count the attempt before checking the code so concurrent guesses are
+	// limited as well
+	increased, err := s.Repository.IncreasePhoneVerificationAttempts(ctx.Request().Context(), phoneVerification.ID, s.config.Auth.PhoneVerification.MaxAttempts)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "IncreasePhoneVerificationAttempts error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if !increased {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePhoneVerification, []i18n.Message{i18n.M(i18n.CodeTooManyAttempts)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
 
-		changesCount++
+	// check code
+	if !comparePasswords(ctx.Request().Context(), phoneVerification.CodeHash, request.Code) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePhoneVerification, []i18n.Message{i18n.M(i18n.CodeInvalid)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
 	}
-	if request.FullName != nil && *request.FullName != "" {
-		fullName = *request.FullName
 
-		// validate full name
-		if errorMessages := validateFullName(fullName); len(errorMessages) != 0 {
-			response.Header = generateResponseHeader(constant.ErrorCodeValidation, errorMessages, false)
-			return ctx.JSON(http.StatusBadRequest, response)
-		}
+	// the phone number may have been claimed since the code was sent, only
+	// verified accounts keep it from being taken
+	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), phoneVerification.PhoneNumber)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+	case err != nil:
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByPhoneNumber error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	case user.ID != phoneVerification.UserID && user.PhoneVerifiedAt != nil:
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.PhoneNumberAlreadyRegistered)}, false)
+		return ctx.JSON(http.StatusConflict, response)
+	}
 
-		changesCount++
+	// apply the phone number and mark it as verified
+	verified, err := s.Repository.VerifyPhoneNumber(ctx.Request().Context(), phoneVerification.ID, repository.User{
+		ID:          phoneVerification.UserID,
+		PhoneNumber: phoneVerification.PhoneNumber,
+	})
+	if isPhoneNumberTaken(err) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.PhoneNumberAlreadyRegistered)}, false)
+		return ctx.JSON(http.StatusConflict, response)
 	}
-	if changesCount == 0 {
-		response.Header = generateResponseHeader(constant.ErrorCodeValidation, []string{"No changes"}, false)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "VerifyPhoneNumber error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if !verified {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePhoneVerification, []i18n.Message{i18n.M(i18n.CodeInvalid)}, false)
 		return ctx.JSON(http.StatusBadRequest, response)
 	}
 
-	err = s.Repository.UpdateUser(ctx.Request().Context(), repository.User{
-		ID:          sessionClaims.UserID,
-		PhoneNumber: phoneNumber,
-		FullName:    fullName,
-	})
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// ForgotPassword
+// (POST /password/forgot)
+func (s *Server) ForgotPassword(ctx echo.Context) error {
+	var (
+		funcName = "ForgotPassword"
+		request  generated.ForgotPasswordRequest
+		response generated.ForgotPasswordResponse
+	)
+
+	// decode request body
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// the same response is returned whether the phone number is registered
+	// or not, so this endpoint cannot be used to enumerate users
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+
+	// get user from db by phone number
+	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), request.PhoneNumber)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ctx.JSON(http.StatusOK, response)
+	}
 	if err != nil {
-		log.Errorf("[%s] UpdateUser error: %s", funcName, err.Error())
-		response.Header = generateResponseHeader(constant.ErrorCodeDatabase, []string{"System error"}, false)
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByPhoneNumber error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// send code, unless the previous one is still fresh
+	err = sendPasswordResetCode(ctx.Request().Context(), s, user)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "sendPasswordResetCode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeGeneral, []i18n.Message{i18n.M(i18n.SystemError)}, false)
 		return ctx.JSON(http.StatusInternalServerError, response)
 	}
 
-	response.Header = generateResponseHeader(0, nil, true)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// ResetPassword
+// (POST /password/reset)
+func (s *Server) ResetPassword(ctx echo.Context) error {
+	var (
+		funcName = "ResetPassword"
+		request  generated.ResetPasswordRequest
+		response generated.ResetPasswordResponse
+	)
+
+	// decode request body
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// validate reset password request
+	requestValidationErrors := validateResetPassword(s.config.Validation, request)
+	if len(requestValidationErrors) != 0 {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, requestValidationErrors, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// get user from db by phone number
+	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), request.PhoneNumber)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePasswordReset, []i18n.Message{i18n.M(i18n.CodeInvalid)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByPhoneNumber error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// get the latest pending code of the user
+	passwordReset, err := s.Repository.GetActivePasswordReset(ctx.Request().Context(), user.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePasswordReset, []i18n.Message{i18n.M(i18n.CodeInvalid)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetActivePasswordReset error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// count the attempt before checking the code so concurrent guesses are
+	// limited as well
+	increased, err := s.Repository.IncreasePasswordResetAttempts(ctx.Request().Context(), passwordReset.ID, s.config.Auth.PasswordReset.MaxAttempts)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "IncreasePasswordResetAttempts error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if !increased {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePasswordReset, []i18n.Message{i18n.M(i18n.CodeTooManyAttempts)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// check code
+	if !comparePasswords(ctx.Request().Context(), passwordReset.CodeHash, request.Code) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePasswordReset, []i18n.Message{i18n.M(i18n.CodeInvalid)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// hash and salt the password
+	salt, err := hashAndSalt(ctx.Request().Context(), request.Password, s.config.Auth.BcryptCost)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "hashAndSalt error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeHashAndSalt, []i18n.Message{i18n.M(i18n.PasswordHashingErr)}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// update password and sign out every existing session
+	now := tokensCutOff(time.Now())
+	reset, err := s.Repository.ResetPassword(ctx.Request().Context(), passwordReset.ID, repository.User{
+		ID:       user.ID,
+		Password: salt,
+	}, now)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "ResetPassword error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if !reset {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePasswordReset, []i18n.Message{i18n.M(i18n.CodeInvalid)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+	s.revocationCache.setTokensValidAfter(user.ID, now, now)
+
+	recordAuditEvent(ctx.Request().Context(), s, funcName, repository.AuditEvent{UserID: user.ID, Action: repository.AuditPasswordReset})
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// GetProfile
+// (GET /profile)
+func (s *Server) GetProfile(ctx echo.Context) error {
+	var (
+		funcName = "GetProfile"
+		response generated.GetProfileResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// get user from db by id
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), sessionClaims.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeNotFound, []i18n.Message{i18n.M(i18n.UserNotFound)}, false)
+		return ctx.JSON(http.StatusNotFound, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	response.Data = &generated.GetProfileResponseData{
+		FullName:    user.FullName,
+		PhoneNumber: user.PhoneNumber,
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// UpdateProfile
+// (PATCH /profile)
+func (s *Server) UpdateProfile(ctx echo.Context) error {
+	var (
+		funcName = "UpdateProfile"
+		request  generated.UpdateProfileRequest
+		response generated.UpdateProfileResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// decode request body
+	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	var (
+		changesCount       int
+		pendingPhoneNumber string
+		fullName           string
+	)
+
+	if request.PhoneNumber != nil && *request.PhoneNumber != "" {
+		// validate phone number
+		phoneNumber, errorMessages := normalizePhoneNumber(s.config.Validation, *request.PhoneNumber)
+		if len(errorMessages) != 0 {
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, errorMessages, false)
+			return ctx.JSON(http.StatusBadRequest, response)
+		}
+
+		// check whether phone number is already registered or not
+		user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), phoneNumber)
+		switch {
+		case err != nil && !errors.Is(err, repository.ErrNotFound):
+			s.log().ErrorContext(ctx.Request().Context(), "GetUserByPhoneNumber error", "func", funcName, "error", err)
+			response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+			return ctx.JSON(repositoryErrorStatus(err), response)
+		case err != nil, user.ID != sessionClaims.UserID && user.PhoneVerifiedAt == nil:
+			// the new phone number only takes effect once it is verified, an
+			// unverified account holding it loses it then
+			pendingPhoneNumber = phoneNumber
+		case user.ID != sessionClaims.UserID:
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.PhoneNumberAlreadyRegistered)}, false)
+			return ctx.JSON(http.StatusConflict, response)
+		}
+
+		changesCount++
+	}
+	if request.FullName != nil && *request.FullName != "" {
+		fullName = *request.FullName
+
+		// validate full name
+		if errorMessages := validateFullName(s.config.Validation, fullName); len(errorMessages) != 0 {
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, errorMessages, false)
+			return ctx.JSON(http.StatusBadRequest, response)
+		}
+
+		changesCount++
+	}
+	if changesCount == 0 {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.ProfileNoChanges)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	if fullName != "" {
+		err = s.Repository.UpdateUser(ctx.Request().Context(), repository.User{
+			ID:       sessionClaims.UserID,
+			FullName: fullName,
+		})
+		if err != nil {
+			s.log().ErrorContext(ctx.Request().Context(), "UpdateUser error", "func", funcName, "error", err)
+			response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+			return ctx.JSON(repositoryErrorStatus(err), response)
+		}
+	}
+
+	if pendingPhoneNumber != "" {
+		err = sendPhoneVerificationCode(ctx.Request().Context(), s, sessionClaims.UserID, pendingPhoneNumber)
+		if err != nil {
+			s.log().ErrorContext(ctx.Request().Context(), "sendPhoneVerificationCode error", "func", funcName, "error", err)
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeGeneral, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+		response.Data = &generated.UpdateProfileResponseData{
+			PendingPhoneNumber: &pendingPhoneNumber,
+		}
+	}
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// DeleteProfile
+// (DELETE /profile)
+func (s *Server) DeleteProfile(ctx echo.Context) error {
+	var (
+		funcName = "DeleteProfile"
+		request  generated.DeleteProfileRequest
+		response generated.DeleteProfileResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// decode request body
+	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// get user from db by id
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), sessionClaims.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{i18n.M(i18n.UserNotFound)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// a stolen session alone must not be enough to delete the account
+	if !comparePasswords(ctx.Request().Context(), user.Password, request.Password) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.PasswordWrong)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// mark the account deleted, signing out every session
+	now := tokensCutOff(time.Now())
+	err = s.Repository.DeleteUser(ctx.Request().Context(), user.ID, now)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "DeleteUser error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	s.revocationCache.setTokensValidAfter(user.ID, now, now)
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	response.Data = &generated.DeleteProfileResponseData{
+		RestorableUntil: now.Add(s.config.AccountDeletion.GracePeriod),
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// ChangePassword
+// (PUT /profile/password)
+func (s *Server) ChangePassword(ctx echo.Context) error {
+	var (
+		funcName = "ChangePassword"
+		request  generated.ChangePasswordRequest
+		response generated.ChangePasswordResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// decode request body
+	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// validate change password request
+	requestValidationErrors := validateChangePassword(s.config.Validation, request)
+	if len(requestValidationErrors) != 0 {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, requestValidationErrors, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// get user from db by id
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), sessionClaims.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{i18n.M(i18n.UserNotFound)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// check current password
+	if !comparePasswords(ctx.Request().Context(), user.Password, request.CurrentPassword) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.PasswordWrong)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// hash and salt the new password
+	salt, err := hashAndSalt(ctx.Request().Context(), request.NewPassword, s.config.Auth.BcryptCost)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "hashAndSalt error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeHashAndSalt, []i18n.Message{i18n.M(i18n.PasswordHashingErr)}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// every access token issued up to now is invalidated, so the current
+	// session is handed a fresh one, issued after the cut-off, to keep it
+	// signed in
+	now := tokensCutOff(time.Now())
+	jwtToken, err := generateJwtToken(ctx.Request().Context(), s, user, sessionClaims.SessionID)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "generateJwtToken error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeJWT, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// update password and revoke the refresh tokens of every other session
+	err = s.Repository.UpdatePassword(ctx.Request().Context(), repository.User{
+		ID:       user.ID,
+		Password: salt,
+	}, now, sessionClaims.SessionID)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "UpdatePassword error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	s.revocationCache.setTokensValidAfter(user.ID, now, now)
+
+	recordAuditEvent(ctx.Request().Context(), s, funcName, repository.AuditEvent{UserID: user.ID, Action: repository.AuditPasswordChanged})
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	response.Data = &generated.ChangePasswordResponseData{
+		Jwt:       jwtToken,
+		ExpiresIn: int64(s.config.Auth.AccessTokenTTL.Seconds()),
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// ExportProfile
+// (POST /profile/export)
+func (s *Server) ExportProfile(ctx echo.Context) error {
+	var (
+		funcName = "ExportProfile"
+		request  generated.ExportProfileRequest
+		response generated.ExportProfileResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// decode request body, which may be left out
+	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+	format := generated.Json
+	if request.Format != nil {
+		format = *request.Format
+	}
+
+	// the token stands for the session when downloading
+	downloadToken, err := generateRandomToken(32)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "generateRandomToken error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeGeneral, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// queue the export, built by the export worker
+	expiresAt := time.Now().Add(s.config.DataExport.DownloadTTL)
+	dataExportID, err := s.Repository.InsertDataExport(ctx.Request().Context(), repository.DataExport{
+		UserID:    sessionClaims.UserID,
+		Format:    string(format),
+		TokenHash: hashToken(downloadToken),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "InsertDataExport error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	s.DataExportTrigger.Notify()
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	response.Data = &generated.ExportProfileResponseData{
+		Id:            dataExportID,
+		Status:        generated.Pending,
+		DownloadToken: downloadToken,
+		ExpiresAt:     expiresAt,
+	}
+
+	return ctx.JSON(http.StatusAccepted, response)
+}
+
+// DownloadProfileExport
+// (GET /profile/export/{id})
+func (s *Server) DownloadProfileExport(ctx echo.Context, id int64, params generated.DownloadProfileExportParams) error {
+	var (
+		funcName = "DownloadProfileExport"
+		response generated.DownloadProfileExportResponse
+	)
+
+	// get data export from db by id
+	dataExport, err := s.Repository.GetDataExport(ctx.Request().Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeNotFound, []i18n.Message{i18n.M(i18n.DataExportNotFound)}, false)
+		return ctx.JSON(http.StatusNotFound, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetDataExport error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// a wrong token must not tell the export exists
+	if subtle.ConstantTimeCompare([]byte(hashToken(params.Token)), []byte(dataExport.TokenHash)) != 1 {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeNotFound, []i18n.Message{i18n.M(i18n.DataExportNotFound)}, false)
+		return ctx.JSON(http.StatusNotFound, response)
+	}
+	if !time.Now().Before(dataExport.ExpiresAt) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeDataExport, []i18n.Message{i18n.M(i18n.DataExportExpired)}, false)
+		return ctx.JSON(http.StatusGone, response)
+	}
+
+	switch dataExport.Status {
+	case repository.DataExportPending, repository.DataExportProcessing:
+		response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+		response.Data = &generated.DownloadProfileExportResponseData{
+			Id:        dataExport.ID,
+			Status:    generated.DataExportStatus(dataExport.Status),
+			ExpiresAt: dataExport.ExpiresAt,
+		}
+		return ctx.JSON(http.StatusAccepted, response)
+	case repository.DataExportReady:
+		format, ok := export.LookupFormat(dataExport.Format)
+		if ok {
+			ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="profile-export-%d.%s"`, dataExport.ID, format.Extension()))
+			ctx.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+			return ctx.Blob(http.StatusOK, format.ContentType(), dataExport.Archive)
+		}
+		s.log().ErrorContext(ctx.Request().Context(), "Unknown data export format", "func", funcName, "format", dataExport.Format)
+	}
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeDataExport, []i18n.Message{i18n.M(i18n.DataExportFailed)}, false)
+	return ctx.JSON(http.StatusInternalServerError, response)
+}
+
+// EnrollTotp
+// (POST /mfa/totp)
+func (s *Server) EnrollTotp(ctx echo.Context) error {
+	var (
+		funcName = "EnrollTotp"
+		response generated.EnrollTotpResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// get user from db by id
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), sessionClaims.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{i18n.M(i18n.UserNotFound)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// an enabled second factor has to be disabled before enrolling again
+	userTOTP, err := s.Repository.GetUserTOTP(ctx.Request().Context(), user.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserTOTP error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if err == nil && userTOTP.ConfirmedAt != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.MfaAlreadyEnabled)}, false)
+		return ctx.JSON(http.StatusConflict, response)
+	}
+
+	// generate secret
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GenerateSecret error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeGeneral, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// store the pending enrollment, it is enabled once a first code is confirmed
+	err = s.Repository.UpsertUserTOTP(ctx.Request().Context(), repository.UserTOTP{
+		UserID: user.ID,
+		Secret: secret,
+	})
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "UpsertUserTOTP error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	response.Data = &generated.EnrollTotpResponseData{
+		Secret:     secret,
+		OtpauthUri: s.totp.URI(secret, constant.ApplicationName, user.PhoneNumber),
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// ConfirmTotp
+// (POST /mfa/totp/confirm)
+func (s *Server) ConfirmTotp(ctx echo.Context) error {
+	var (
+		funcName = "ConfirmTotp"
+		request  generated.TotpCodeRequest
+		response generated.ConfirmTotpResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// decode request body
+	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// get pending enrollment
+	userTOTP, err := s.Repository.GetUserTOTP(ctx.Request().Context(), sessionClaims.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.MfaEnrollmentNotFound)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserTOTP error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if userTOTP.ConfirmedAt != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.MfaAlreadyEnabled)}, false)
+		return ctx.JSON(http.StatusConflict, response)
+	}
+
+	// check code
+	step, ok, err := s.totp.Validate(userTOTP.Secret, strings.TrimSpace(request.Code))
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Validate error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+	if !ok {
+		s.metrics.mfaRejections.Inc(mfaActionConfirm)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.MfaInvalidCode)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// generate recovery codes
+	recoveryCodes, recoveryCodeHashes, err := generateRecoveryCodes(s.config.Auth.RecoveryCodeCount)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "generateRecoveryCodes error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeGeneral, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// enable the second factor
+	confirmed, err := s.Repository.ConfirmUserTOTP(ctx.Request().Context(), sessionClaims.UserID, step, recoveryCodeHashes)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "ConfirmUserTOTP error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if !confirmed {
+		s.metrics.mfaRejections.Inc(mfaActionConfirm)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.MfaInvalidCode)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	recordAuditEvent(ctx.Request().Context(), s, funcName, repository.AuditEvent{UserID: sessionClaims.UserID, Action: repository.AuditTOTPEnabled})
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	response.Data = &generated.ConfirmTotpResponseData{
+		RecoveryCodes: recoveryCodes,
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// DisableTotp
+// (POST /mfa/totp/disable)
+func (s *Server) DisableTotp(ctx echo.Context) error {
+	var (
+		funcName = "DisableTotp"
+		request  generated.TotpCodeRequest
+		response generated.DisableTotpResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// decode request body
+	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// get enrollment
+	userTOTP, err := s.Repository.GetUserTOTP(ctx.Request().Context(), sessionClaims.UserID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserTOTP error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if errors.Is(err, repository.ErrNotFound) || userTOTP.ConfirmedAt == nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.MfaNotEnabled)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// a stolen session alone must not be enough to turn the second factor off
+	verified, err := verifySecondFactor(ctx.Request().Context(), s, userTOTP, request.Code)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "verifySecondFactor error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+	if !verified {
+		s.metrics.mfaRejections.Inc(mfaActionDisable)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeMfa, []i18n.Message{i18n.M(i18n.MfaInvalidCode)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// disable the second factor and drop its recovery codes
+	err = s.Repository.DeleteUserTOTP(ctx.Request().Context(), sessionClaims.UserID)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "DeleteUserTOTP error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	recordAuditEvent(ctx.Request().Context(), s, funcName, repository.AuditEvent{UserID: sessionClaims.UserID, Action: repository.AuditTOTPDisabled})
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// ListUsers
+// (GET /admin/users)
+func (s *Server) ListUsers(ctx echo.Context, params generated.ListUsersParams) error {
+	var (
+		funcName = "ListUsers"
+		response generated.ListUsersResponse
+	)
+
+	// get session claims
+	_, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// the defaults of api.yml, bounds are checked against the spec
+	page, pageSize := 1, 20
+	if params.Page != nil {
+		page = *params.Page
+	}
+	if params.PageSize != nil {
+		pageSize = *params.PageSize
+	}
+	filter := repository.UserFilter{
+		Locked:        params.Locked,
+		PhoneVerified: params.PhoneVerified,
+		Offset:        (page - 1) * pageSize,
+		Limit:         pageSize,
+	}
+	if params.Role != nil {
+		filter.Role = *params.Role
+	}
+
+	// get users from db
+	users, total, err := s.Repository.ListUsers(ctx.Request().Context(), filter)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "ListUsers error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	response.Data = &generated.ListUsersResponseData{
+		Users:    make([]generated.AdminUser, 0, len(users)),
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	}
+	for _, user := range users {
+		response.Data.Users = append(response.Data.Users, newAdminUser(user))
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// GetUser
+// (GET /admin/users/{id})
+func (s *Server) GetUser(ctx echo.Context, id int64) error {
+	var (
+		funcName = "GetUser"
+		response generated.AdminUserResponse
+	)
+
+	// get session claims
+	_, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// get user from db by id
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeNotFound, []i18n.Message{i18n.M(i18n.UserNotFound)}, false)
+		return ctx.JSON(http.StatusNotFound, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	adminUser := newAdminUser(user)
+	response.Data = &adminUser
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// UpdateUser
+// (PATCH /admin/users/{id})
+func (s *Server) UpdateUser(ctx echo.Context, id int64) error {
+	var (
+		funcName = "UpdateUser"
+		request  generated.UpdateUserJSONRequestBody
+		response generated.AdminUserResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// decode request body
+	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "Decode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeUnmarshal, []i18n.Message{i18n.M(i18n.BadRequest)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// validate changes
+	var changes repository.User
+	if request.PhoneNumber != nil && *request.PhoneNumber != "" {
+		phoneNumber, errorMessages := normalizePhoneNumber(s.config.Validation, *request.PhoneNumber)
+		if len(errorMessages) != 0 {
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, errorMessages, false)
+			return ctx.JSON(http.StatusBadRequest, response)
+		}
+		changes.PhoneNumber = phoneNumber
+	}
+	if request.FullName != nil && *request.FullName != "" {
+		if errorMessages := validateFullName(s.config.Validation, *request.FullName); len(errorMessages) != 0 {
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, errorMessages, false)
+			return ctx.JSON(http.StatusBadRequest, response)
+		}
+		changes.FullName = *request.FullName
+	}
+	if changes.PhoneNumber == "" && changes.FullName == "" {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.ProfileNoChanges)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// get user from db by id
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeNotFound, []i18n.Message{i18n.M(i18n.UserNotFound)}, false)
+		return ctx.JSON(http.StatusNotFound, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// staff only manage the accounts of users granted less than them
+	refusal, allowed, err := checkAdminTarget(ctx.Request().Context(), s, sessionClaims.UserID, user)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "checkAdminTarget error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if !allowed {
+		s.log().WarnContext(ctx.Request().Context(), "Admin action refused", "func", funcName, "actor_id", sessionClaims.UserID, "target_id", user.ID)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePermission, []i18n.Message{refusal}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// like UpdateProfile, a new phone number only takes effect once the user
+	// verifies it, the account keeps its current one until then
+	var pendingPhoneNumber string
+	if changes.PhoneNumber != "" && changes.PhoneNumber != user.PhoneNumber {
+		holder, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), changes.PhoneNumber)
+		switch {
+		case err != nil && !errors.Is(err, repository.ErrNotFound):
+			s.log().ErrorContext(ctx.Request().Context(), "GetUserByPhoneNumber error", "func", funcName, "error", err)
+			response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+			return ctx.JSON(repositoryErrorStatus(err), response)
+		case err == nil && holder.PhoneVerifiedAt != nil:
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.PhoneNumberAlreadyRegistered)}, false)
+			return ctx.JSON(http.StatusConflict, response)
+		}
+		pendingPhoneNumber = changes.PhoneNumber
+	}
+
+	if changes.FullName != "" {
+		err = s.Repository.UpdateUser(ctx.Request().Context(), repository.User{
+			ID:       user.ID,
+			FullName: changes.FullName,
+		})
+		if err != nil {
+			s.log().ErrorContext(ctx.Request().Context(), "UpdateUser error", "func", funcName, "error", err)
+			response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+			return ctx.JSON(repositoryErrorStatus(err), response)
+		}
+		user.FullName = changes.FullName
+	}
+
+	if pendingPhoneNumber != "" {
+		err = sendPhoneVerificationCode(ctx.Request().Context(), s, user.ID, pendingPhoneNumber)
+		if err != nil {
+			s.log().ErrorContext(ctx.Request().Context(), "sendPhoneVerificationCode error", "func", funcName, "error", err)
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeGeneral, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+	}
+
+	s.log().InfoContext(ctx.Request().Context(), "Admin action", "func", funcName, "actor_id", sessionClaims.UserID, "target_id", user.ID)
+	recordAuditEvent(ctx.Request().Context(), s, funcName, repository.AuditEvent{UserID: user.ID, ActorID: &sessionClaims.UserID, Action: repository.AuditUserUpdated})
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	adminUser := newAdminUser(user)
+	if pendingPhoneNumber != "" {
+		adminUser.PendingPhoneNumber = &pendingPhoneNumber
+	}
+	response.Data = &adminUser
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// ForcePasswordReset
+// (POST /admin/users/{id}/password-reset)
+func (s *Server) ForcePasswordReset(ctx echo.Context, id int64) error {
+	var (
+		funcName = "ForcePasswordReset"
+		response generated.AdminUserResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// get user from db by id
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeNotFound, []i18n.Message{i18n.M(i18n.UserNotFound)}, false)
+		return ctx.JSON(http.StatusNotFound, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// staff only manage the accounts of users granted less than them
+	refusal, allowed, err := checkAdminTarget(ctx.Request().Context(), s, sessionClaims.UserID, user)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "checkAdminTarget error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if !allowed {
+		s.log().WarnContext(ctx.Request().Context(), "Admin action refused", "func", funcName, "actor_id", sessionClaims.UserID, "target_id", user.ID)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePermission, []i18n.Message{refusal}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// expire the password, signing out every session
+	now := tokensCutOff(time.Now())
+	err = s.Repository.RequirePasswordReset(ctx.Request().Context(), user.ID, now)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "RequirePasswordReset error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	s.revocationCache.setTokensValidAfter(user.ID, now, now)
+	user.PasswordResetRequiredAt = &now
+
+	// send code, login sends another one once it expires
+	err = sendPasswordResetCode(ctx.Request().Context(), s, user)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "sendPasswordResetCode error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeGeneral, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	s.log().InfoContext(ctx.Request().Context(), "Admin action", "func", funcName, "actor_id", sessionClaims.UserID, "target_id", user.ID)
+	recordAuditEvent(ctx.Request().Context(), s, funcName, repository.AuditEvent{UserID: user.ID, ActorID: &sessionClaims.UserID, Action: repository.AuditPasswordResetForced})
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	adminUser := newAdminUser(user)
+	response.Data = &adminUser
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// LockUser
+// (POST /admin/users/{id}/lock)
+func (s *Server) LockUser(ctx echo.Context, id int64) error {
+	var (
+		funcName = "LockUser"
+		response generated.AdminUserResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// get user from db by id
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeNotFound, []i18n.Message{i18n.M(i18n.UserNotFound)}, false)
+		return ctx.JSON(http.StatusNotFound, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// staff only manage the accounts of users granted less than them
+	refusal, allowed, err := checkAdminTarget(ctx.Request().Context(), s, sessionClaims.UserID, user)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "checkAdminTarget error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if !allowed {
+		s.log().WarnContext(ctx.Request().Context(), "Admin action refused", "func", funcName, "actor_id", sessionClaims.UserID, "target_id", user.ID)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePermission, []i18n.Message{refusal}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// lock the account, signing out every session. Locking it again keeps
+	// the time it was first locked.
+	now := tokensCutOff(time.Now())
+	err = s.Repository.LockUser(ctx.Request().Context(), user.ID, now)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "LockUser error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	s.revocationCache.setTokensValidAfter(user.ID, now, now)
+	if user.LockedAt == nil {
+		user.LockedAt = &now
+	}
+
+	s.log().InfoContext(ctx.Request().Context(), "Admin action", "func", funcName, "actor_id", sessionClaims.UserID, "target_id", user.ID)
+	recordAuditEvent(ctx.Request().Context(), s, funcName, repository.AuditEvent{UserID: user.ID, ActorID: &sessionClaims.UserID, Action: repository.AuditUserLocked})
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	adminUser := newAdminUser(user)
+	response.Data = &adminUser
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// UnlockUser
+// (DELETE /admin/users/{id}/lock)
+func (s *Server) UnlockUser(ctx echo.Context, id int64) error {
+	var (
+		funcName = "UnlockUser"
+		response generated.AdminUserResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// get user from db by id
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeNotFound, []i18n.Message{i18n.M(i18n.UserNotFound)}, false)
+		return ctx.JSON(http.StatusNotFound, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// staff only manage the accounts of users granted less than them
+	refusal, allowed, err := checkAdminTarget(ctx.Request().Context(), s, sessionClaims.UserID, user)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "checkAdminTarget error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if !allowed {
+		s.log().WarnContext(ctx.Request().Context(), "Admin action refused", "func", funcName, "actor_id", sessionClaims.UserID, "target_id", user.ID)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePermission, []i18n.Message{refusal}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	err = s.Repository.UnlockUser(ctx.Request().Context(), user.ID)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "UnlockUser error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	user.LockedAt = nil
+
+	// lift the lockout of failed logins as well
+	err = s.LoginAttempts.ResetLoginAttempts(ctx.Request().Context(), repository.LoginAttemptUserKey(user.ID))
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "ResetLoginAttempts error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	s.log().InfoContext(ctx.Request().Context(), "Admin action", "func", funcName, "actor_id", sessionClaims.UserID, "target_id", user.ID)
+	recordAuditEvent(ctx.Request().Context(), s, funcName, repository.AuditEvent{UserID: user.ID, ActorID: &sessionClaims.UserID, Action: repository.AuditUserUnlocked})
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	adminUser := newAdminUser(user)
+	response.Data = &adminUser
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// RevokeUserSessions
+// (DELETE /admin/users/{id}/sessions)
+func (s *Server) RevokeUserSessions(ctx echo.Context, id int64) error {
+	var (
+		funcName = "RevokeUserSessions"
+		response generated.AdminUserResponse
+	)
+
+	// get session claims
+	sessionClaims, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// get user from db by id
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeNotFound, []i18n.Message{i18n.M(i18n.UserNotFound)}, false)
+		return ctx.JSON(http.StatusNotFound, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "GetUserByID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// staff only manage the accounts of users granted less than them
+	refusal, allowed, err := checkAdminTarget(ctx.Request().Context(), s, sessionClaims.UserID, user)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "checkAdminTarget error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	if !allowed {
+		s.log().WarnContext(ctx.Request().Context(), "Admin action refused", "func", funcName, "actor_id", sessionClaims.UserID, "target_id", user.ID)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodePermission, []i18n.Message{refusal}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// invalidate every access token issued up to now
+	now := tokensCutOff(time.Now())
+	err = s.Repository.UpdateTokensValidAfter(ctx.Request().Context(), user.ID, now)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "UpdateTokensValidAfter error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+	s.revocationCache.setTokensValidAfter(user.ID, now, now)
+
+	// revoke every refresh token of the user
+	err = s.Repository.RevokeRefreshTokensByUserID(ctx.Request().Context(), user.ID)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "RevokeRefreshTokensByUserID error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	s.log().InfoContext(ctx.Request().Context(), "Admin action", "func", funcName, "actor_id", sessionClaims.UserID, "target_id", user.ID)
+	recordAuditEvent(ctx.Request().Context(), s, funcName, repository.AuditEvent{UserID: user.ID, ActorID: &sessionClaims.UserID, Action: repository.AuditSessionsRevoked})
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	adminUser := newAdminUser(user)
+	response.Data = &adminUser
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// SearchUsers
+// (GET /admin/users/search)
+func (s *Server) SearchUsers(ctx echo.Context, params generated.SearchUsersParams) error {
+	var (
+		funcName = "SearchUsers"
+		response generated.SearchUsersResponse
+	)
+
+	// get session claims
+	_, err := getSessionClaims(ctx, s)
+	if err != nil {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAuthorization, []i18n.Message{sessionErrorMessage(err)}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// the default limit of api.yml, bounds are checked against the spec
+	search := repository.UserSearch{
+		Limit: 20,
+	}
+	if params.PhoneNumber != nil && *params.PhoneNumber != "" {
+		prefix, ok := phone.NormalizePrefix(*params.PhoneNumber)
+		if !ok {
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.PhoneNumberFormat)}, false)
+			return ctx.JSON(http.StatusBadRequest, response)
+		}
+		search.PhoneNumberPrefix = prefix
+	}
+	if params.FullName != nil {
+		search.FullName = strings.TrimSpace(*params.FullName)
+	}
+	if params.Sort != nil {
+		search.Sort = string(*params.Sort)
+	}
+	if params.Order != nil {
+		search.Descending = *params.Order == generated.Desc
+	}
+	if params.Cursor != nil {
+		search.Cursor = *params.Cursor
+	}
+	if params.Limit != nil {
+		search.Limit = *params.Limit
+	}
+
+	// search users in db
+	users, nextCursor, err := s.Repository.SearchUsers(ctx.Request().Context(), search)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.SearchCursorInvalid)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "SearchUsers error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	response.Data = &generated.SearchUsersResponseData{
+		Users: make([]generated.AdminUser, 0, len(users)),
+	}
+	for _, user := range users {
+		response.Data.Users = append(response.Data.Users, newAdminUser(user))
+	}
+	if nextCursor != "" {
+		response.Data.NextCursor = &nextCursor
+	}
 
 	return ctx.JSON(http.StatusOK, response)
 }
@@ -3,25 +3,8355 @@ package handler
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/fenky-ng/swt-pro/export"
+	"github.com/fenky-ng/swt-pro/generated"
 	errorHelper "github.com/fenky-ng/swt-pro/helper/error"
+	"github.com/fenky-ng/swt-pro/keyring"
+	"github.com/fenky-ng/swt-pro/notifier"
 	"github.com/fenky-ng/swt-pro/repository"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
 )
 
+func Test_Server_GetJwks(t *testing.T) {
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name             string
+		args             args
+		wantStatusCode   int
+		wantCacheControl string
+		wantErr          error
+	}{
+		{
+			name: "passed",
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			wantStatusCode:   http.StatusOK,
+			wantCacheControl: "public, max-age=300",
+			wantErr:          nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:  testConfig,
+				KeyRing: testKeyRing,
+			}
+			gotErr := s.GetJwks(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.GetJwks() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.GetJwks() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+				if got := tt.args.ctx.Response().Header().Get(echo.HeaderCacheControl); got != tt.wantCacheControl {
+					t.Errorf("Server.GetJwks() gotCacheControl = %s, wantCacheControl = %s", got, tt.wantCacheControl)
+				}
+			}
+		})
+	}
+}
+
+func Test_Server_Healthz(t *testing.T) {
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "passed",
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config: testConfig,
+			}
+			s.BeginShutdown()
+			gotErr := s.Healthz(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.Healthz() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.Healthz() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+		})
+	}
+}
+
+func Test_Server_Readyz(t *testing.T) {
+	type fields struct {
+		mockCtrl     *gomock.Controller
+		Repository   *repository.MockRepositoryInterface
+		KeyRing      *keyring.KeyRing
+		shuttingDown bool
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantBody       string
+		wantErr        error
+	}{
+		{
+			name: "error Ping",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					KeyRing:    testKeyRing,
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().Ping(gomock.Any()).
+					Return(errors.New("expected error"))
+			},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantBody:       `{"data":{"checks":[{"name":"shutdown","ready":true},{"name":"database","ready":false},{"name":"keys","ready":true}]},"header":{"error_code":1015,"error_message_ids":["readiness.database_unavailable"],"error_messages":["Database unavailable"]}}`,
+			wantErr:        nil,
+		},
+		{
+			name: "no signing key",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().Ping(gomock.Any()).
+					Return(nil)
+			},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantBody:       `{"data":{"checks":[{"name":"shutdown","ready":true},{"name":"database","ready":true},{"name":"keys","ready":false}]},"header":{"error_code":1015,"error_message_ids":["readiness.no_signing_key"],"error_messages":["No signing key"]}}`,
+			wantErr:        nil,
+		},
+		{
+			name: "shutting down",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:     mockCtrl,
+					Repository:   repository.NewMockRepositoryInterface(mockCtrl),
+					KeyRing:      testKeyRing,
+					shuttingDown: true,
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().Ping(gomock.Any()).
+					Return(nil)
+			},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantBody:       `{"data":{"checks":[{"name":"shutdown","ready":false},{"name":"database","ready":true},{"name":"keys","ready":true}]},"header":{"error_code":1015,"error_message_ids":["readiness.shutting_down"],"error_messages":["Shutting down"]}}`,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					KeyRing:    testKeyRing,
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().Ping(gomock.Any()).
+					Return(nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"data":{"checks":[{"name":"shutdown","ready":true},{"name":"database","ready":true},{"name":"keys","ready":true}]},"header":{"successful":true}}`,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    tt.fields.KeyRing,
+			}
+			if tt.fields.shuttingDown {
+				s.BeginShutdown()
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.Readyz(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.Readyz() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.Readyz() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+				gotBody := strings.TrimSpace(tt.args.ctx.Response().Writer.(*httptest.ResponseRecorder).Body.String())
+				if gotBody != tt.wantBody {
+					t.Errorf("Server.Readyz() gotBody = %s, wantBody = %s", gotBody, tt.wantBody)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
 func Test_Server_Register(t *testing.T) {
 	type fields struct {
 		mockCtrl   *gomock.Controller
 		Repository *repository.MockRepositoryInterface
+		Notifier   *notifier.MockNotifier
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "invalid request",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "",
+						"full_name": "",
+						"password": ""
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error ReleasePhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().ReleasePhoneNumber(context.Background(), "+628223344551").
+					Return(errors.New("expected ReleasePhoneNumber error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "error checkNewPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().ReleasePhoneNumber(context.Background(), "+628223344551").
+					Return(nil).
+					Times(1)
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "phone number already registered",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().ReleasePhoneNumber(context.Background(), "+628223344551").
+					Return(nil).
+					Times(1)
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusConflict,
+			wantErr:        nil,
+		},
+		{
+			name: "error InsertUser",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().ReleasePhoneNumber(context.Background(), "+628223344551").
+					Return(nil).
+					Times(1)
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertUser(context.Background(), gomock.AssignableToTypeOf(repository.User{})).
+					Return(int64(0), errors.New("expected InsertUser error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "phone number registered concurrently",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().ReleasePhoneNumber(context.Background(), "+628223344551").
+					Return(nil).
+					Times(1)
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertUser(context.Background(), gomock.AssignableToTypeOf(repository.User{})).
+					Return(int64(0), &repository.UniqueViolationError{Constraint: "user_phone_number_unique", Err: errors.New("expected InsertUser error")}).
+					Times(1)
+			},
+			wantStatusCode: http.StatusConflict,
+			wantErr:        nil,
+		},
+		{
+			name: "error sendPhoneVerificationCode",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().ReleasePhoneNumber(context.Background(), "+628223344551").
+					Return(nil).
+					Times(1)
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertUser(context.Background(), gomock.AssignableToTypeOf(repository.User{})).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628223344551").
+					Return(repository.PhoneVerification{}, errors.New("expected GetActivePhoneVerification error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().ReleasePhoneNumber(context.Background(), "+628223344551").
+					Return(nil).
+					Times(1)
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertUser(context.Background(), gomock.AssignableToTypeOf(repository.User{})).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628223344551").
+					Return(repository.PhoneVerification{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPhoneVerification(context.Background(), gomock.AssignableToTypeOf(repository.PhoneVerification{})).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.Notifier.EXPECT().SendSMS(context.Background(), "+628223344551", gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+				Notifier:   tt.fields.Notifier,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.Register(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.Register() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.Register() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+// Test_Server_Register_uniqueViolation checks registrations passing
+// checkNewPhoneNumber together are answered 409 once InsertUser reports a
+// *repository.UniqueViolationError. The mocked repository stands in for the
+// unique index, see Test_Repository_InsertUser_uniqueViolation for the
+// mapping of the Postgres error.
+func Test_Server_Register_uniqueViolation(t *testing.T) {
+	const registrations = 5
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockRepository := repository.NewMockRepositoryInterface(mockCtrl)
+	mockNotifier := notifier.NewMockNotifier(mockCtrl)
+
+	// every registration sees the phone number as new before any inserts it
+	var checked sync.WaitGroup
+	checked.Add(registrations)
+	mockRepository.EXPECT().ReleasePhoneNumber(gomock.Any(), "+628223344551").
+		Return(nil).
+		Times(registrations)
+	mockRepository.EXPECT().GetUserByPhoneNumber(gomock.Any(), "+628223344551").
+		DoAndReturn(func(ctx context.Context, phoneNumber string) (repository.User, error) {
+			checked.Done()
+			checked.Wait()
+			return repository.User{}, repository.ErrNotFound
+		}).
+		Times(registrations)
+
+	// the unique index only lets the first insert through
+	var (
+		mu    sync.Mutex
+		users []repository.User
+	)
+	mockRepository.EXPECT().InsertUser(gomock.Any(), gomock.AssignableToTypeOf(repository.User{})).
+		DoAndReturn(func(ctx context.Context, data repository.User) (int64, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, user := range users {
+				if user.PhoneNumber == data.PhoneNumber {
+					return 0, &repository.UniqueViolationError{
+						Constraint: "user_phone_number_unique",
+						Err:        errors.New("duplicate key value violates unique constraint"),
+					}
+				}
+			}
+			data.ID = int64(len(users) + 1)
+			users = append(users, data)
+			return data.ID, nil
+		}).
+		Times(registrations)
+
+	mockRepository.EXPECT().GetActivePhoneVerification(gomock.Any(), "+628223344551").
+		Return(repository.PhoneVerification{}, repository.ErrNotFound).
+		Times(1)
+	mockRepository.EXPECT().InsertPhoneVerification(gomock.Any(), gomock.AssignableToTypeOf(repository.PhoneVerification{})).
+		Return(int64(1), nil).
+		Times(1)
+	mockNotifier.EXPECT().SendSMS(gomock.Any(), "+628223344551", gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	s := &Server{
+		config:     testConfig,
+		Repository: mockRepository,
+		KeyRing:    testKeyRing,
+		Notifier:   mockNotifier,
+	}
+
+	statusCodes := make(chan int, registrations)
+	var wg sync.WaitGroup
+	for i := 0; i < registrations; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodPost, "url", strings.NewReader(fmt.Sprintf(`{
+				"phone_number": "+628223344551",
+				"full_name": "Sawit Pro %d",
+				"password": "Sawit@123"
+			}`, i)))
+			res := httptest.NewRecorder()
+			c := echo.New().NewContext(req, res)
+			err := s.Register(c)
+			if err != nil {
+				t.Errorf("Server.Register() gotErr = %s", err.Error())
+			}
+			statusCodes <- res.Code
+		}(i)
+	}
+	wg.Wait()
+	close(statusCodes)
+
+	gotStatusCodes := map[int]int{}
+	for statusCode := range statusCodes {
+		gotStatusCodes[statusCode]++
+	}
+	if gotStatusCodes[http.StatusOK] != 1 || gotStatusCodes[http.StatusConflict] != registrations-1 {
+		t.Errorf("Server.Register() gotStatusCodes = %v, want one %d and %d %d", gotStatusCodes, http.StatusOK, registrations-1, http.StatusConflict)
+	}
+}
+
+func Test_Server_Login(t *testing.T) {
+	phoneVerifiedAt := time.Now()
+	deletedAt := time.Now().Add(-time.Hour)
+	type fields struct {
+		mockCtrl      *gomock.Controller
+		Repository    *repository.MockRepositoryInterface
+		LoginAttempts *repository.MemoryLoginAttemptRepository
+		Notifier      *notifier.MockNotifier
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "client ip backed off",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Random@123"
+					}`)))
+					req.Header.Set("X-Real-IP", "10.0.0.1")
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				for i := 0; i < 20; i++ {
+					fields.LoginAttempts.IncreaseFailedLoginCount(context.Background(), "ip:10.0.0.1", time.Time{}, time.Now())
+				}
+				fields.LoginAttempts.BlockLogin(context.Background(), "ip:10.0.0.1", time.Now().Add(time.Minute))
+			},
+			wantStatusCode: http.StatusTooManyRequests,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserByPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "user not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().GetDeletedUserByPhoneNumber(context.Background(), "+628223344551", gomock.Any()).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetDeletedUserByPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().GetDeletedUserByPhoneNumber(context.Background(), "+628223344551", gomock.Any()).
+					Return(repository.User{}, errors.New("expected GetDeletedUserByPhoneNumber error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "error RestoreUser",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().GetDeletedUserByPhoneNumber(context.Background(), "+628223344551", gomock.Any()).
+					Return(repository.User{
+						ID:              1,
+						Password:        "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						PhoneVerifiedAt: &phoneVerifiedAt,
+						DeletedAt:       &deletedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().RestoreUser(context.Background(), int64(1), gomock.Any()).
+					Return(false, errors.New("expected RestoreUser error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "deleted user purged meanwhile",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().GetDeletedUserByPhoneNumber(context.Background(), "+628223344551", gomock.Any()).
+					Return(repository.User{
+						ID:              1,
+						Password:        "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						PhoneVerifiedAt: &phoneVerifiedAt,
+						DeletedAt:       &deletedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().RestoreUser(context.Background(), int64(1), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "deleted user restored",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().GetDeletedUserByPhoneNumber(context.Background(), "+628223344551", gomock.Any()).
+					Return(repository.User{
+						ID:              1,
+						Password:        "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						PhoneVerifiedAt: &phoneVerifiedAt,
+						DeletedAt:       &deletedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RestoreUser(context.Background(), int64(1), gomock.Any()).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreaseLoginCount(context.Background(), int64(1)).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.AssignableToTypeOf(repository.RefreshToken{})).
+					Return(int64(1), nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "deleted user with mfa not restored before the code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().GetDeletedUserByPhoneNumber(context.Background(), "+628223344551", gomock.Any()).
+					Return(repository.User{
+						ID:              1,
+						Password:        "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						PhoneVerifiedAt: &phoneVerifiedAt,
+						DeletedAt:       &deletedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						ConfirmedAt: &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "wrong password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Random@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:       1,
+						Password: "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "account backed off",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Random@123"
+					}`)))
+					req.Header.Set("X-Real-IP", "10.0.0.1")
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				for i := 0; i < 3; i++ {
+					fields.LoginAttempts.IncreaseFailedLoginCount(context.Background(), "user:1", time.Time{}, time.Now())
+				}
+				fields.LoginAttempts.BlockLogin(context.Background(), "user:1", time.Now().Add(time.Minute))
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:       1,
+						Password: "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusTooManyRequests,
+			wantErr:        nil,
+		},
+		{
+			name: "account locked",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Random@123"
+					}`)))
+					req.Header.Set("X-Real-IP", "10.0.0.1")
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				for i := 0; i < 10; i++ {
+					fields.LoginAttempts.IncreaseFailedLoginCount(context.Background(), "user:1", time.Time{}, time.Now())
+				}
+				fields.LoginAttempts.BlockLogin(context.Background(), "user:1", time.Now().Add(time.Minute))
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:       1,
+						Password: "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusLocked,
+			wantErr:        nil,
+		},
+		{
+			name: "error sendPhoneVerificationCode",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628223344551",
+						Password:    "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628223344551").
+					Return(repository.PhoneVerification{}, errors.New("expected GetActivePhoneVerification error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "phone number is not verified",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628223344551",
+						Password:    "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628223344551").
+					Return(repository.PhoneVerification{
+						ID:          2,
+						UserID:      1,
+						PhoneNumber: "+628223344551",
+						CreatedAt:   time.Now(),
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "locked by support",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+628223344551",
+						Password:        "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						PhoneVerifiedAt: &phoneVerifiedAt,
+						LockedAt:        &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusLocked,
+			wantErr:        nil,
+		},
+		{
+			name: "error sendPasswordResetCode",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:                      1,
+						PhoneNumber:             "+628223344551",
+						Password:                "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						PhoneVerifiedAt:         &phoneVerifiedAt,
+						PasswordResetRequiredAt: &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPasswordReset(context.Background(), gomock.AssignableToTypeOf(repository.PasswordReset{})).
+					Return(int64(2), nil).
+					Times(1)
+
+				fields.Notifier.EXPECT().SendSMS(context.Background(), "+628223344551", gomock.Any()).
+					Return(errors.New("expected SendSMS error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "password reset required",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:                      1,
+						PhoneNumber:             "+628223344551",
+						Password:                "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						PhoneVerifiedAt:         &phoneVerifiedAt,
+						PasswordResetRequiredAt: &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPasswordReset(context.Background(), gomock.AssignableToTypeOf(repository.PasswordReset{})).
+					Return(int64(2), nil).
+					Times(1)
+
+				fields.Notifier.EXPECT().SendSMS(context.Background(), "+628223344551", gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserTOTP",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:              1,
+						Password:        "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						PhoneVerifiedAt: &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, errors.New("expected GetUserTOTP error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "mfa required",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:              1,
+						Password:        "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						PhoneVerifiedAt: &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						ConfirmedAt: &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "error IncreaseLoginCount",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:              1,
+						Password:        "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						PhoneVerifiedAt: &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreaseLoginCount(context.Background(), int64(1)).
+					Return(errors.New("expected IncreaseLoginCount error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "error InsertRefreshToken",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628223344551",
+							"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:              1,
+						Password:        "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						PhoneVerifiedAt: &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreaseLoginCount(context.Background(), int64(1)).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.AssignableToTypeOf(repository.RefreshToken{})).
+					Return(int64(0), errors.New("expected InsertRefreshToken error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+					Notifier:      notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628223344551",
+							"password": "Sawit@123"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:              1,
+						Password:        "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						PhoneVerifiedAt: &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreaseLoginCount(context.Background(), int64(1)).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.AssignableToTypeOf(repository.RefreshToken{})).
+					Return(int64(1), nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:        testConfig,
+				Repository:    tt.fields.Repository,
+				KeyRing:       testKeyRing,
+				LoginAttempts: tt.fields.LoginAttempts,
+				Notifier:      tt.fields.Notifier,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.Login(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.Login() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.Register() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_LoginMfa(t *testing.T) {
+	mfaToken, _ := generateMfaChallengeToken(context.Background(), testServer, repository.User{ID: 1})
+	deletedMfaToken, _ := generateMfaChallengeToken(context.Background(), testServer, repository.User{ID: 1, PhoneNumber: "+628223344551"})
+	sessionToken, _ := generateJwtToken(context.Background(), testServer, repository.User{ID: 1}, "session")
+	totpCode, _ := testTOTP.GenerateCode(testTOTPSecret)
+	confirmedAt := time.Now()
+	type fields struct {
+		mockCtrl      *gomock.Controller
+		Repository    *repository.MockRepositoryInterface
+		LoginAttempts *repository.MemoryLoginAttemptRepository
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "invalid mfa token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, "invalid", totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "session token as mfa token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, sessionToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "error isSessionRevoked",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, errors.New("expected GetTokensValidAfter error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "used mfa token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(true, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserByID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected GetUserByID error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "user not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().GetDeletedUserByPhoneNumber(context.Background(), "", gomock.Any()).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "locked by support",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID:       1,
+						LockedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusLocked,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserTOTP",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, errors.New("expected GetUserTOTP error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "totp not enabled",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "error UpdateTOTPLastUsedStep",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateTOTPLastUsedStep(context.Background(), int64(1), testTOTP.Step(testTOTP.Clock())).
+					Return(false, errors.New("expected UpdateTOTPLastUsedStep error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "reused totp code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateTOTPLastUsedStep(context.Background(), int64(1), testTOTP.Step(testTOTP.Clock())).
+					Return(false, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "invalid recovery code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, "abcd-efgh-ijkl-mnop"))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UseRecoveryCode(context.Background(), int64(1), hashToken("abcdefghijklmnop")).
+					Return(false, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "error InsertRevokedToken",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateTOTPLastUsedStep(context.Background(), int64(1), testTOTP.Step(testTOTP.Clock())).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRevokedToken(context.Background(), gomock.AssignableToTypeOf(repository.RevokedToken{})).
+					Return(errors.New("expected InsertRevokedToken error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "passed with totp code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateTOTPLastUsedStep(context.Background(), int64(1), testTOTP.Step(testTOTP.Clock())).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRevokedToken(context.Background(), gomock.AssignableToTypeOf(repository.RevokedToken{})).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreaseLoginCount(context.Background(), int64(1)).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.AssignableToTypeOf(repository.RefreshToken{})).
+					Return(int64(1), nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "deleted user restored",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, deletedMfaToken, totpCode))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().GetDeletedUserByPhoneNumber(context.Background(), "+628223344551", gomock.Any()).
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628223344551",
+						DeletedAt:   &confirmedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateTOTPLastUsedStep(context.Background(), int64(1), testTOTP.Step(testTOTP.Clock())).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRevokedToken(context.Background(), gomock.AssignableToTypeOf(repository.RevokedToken{})).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RestoreUser(context.Background(), int64(1), gomock.Any()).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreaseLoginCount(context.Background(), int64(1)).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.AssignableToTypeOf(repository.RefreshToken{})).
+					Return(int64(1), nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "passed with recovery code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, "ABCD-EFGH-IJKL-MNOP"))))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UseRecoveryCode(context.Background(), int64(1), hashToken("abcdefghijklmnop")).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRevokedToken(context.Background(), gomock.AssignableToTypeOf(repository.RevokedToken{})).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreaseLoginCount(context.Background(), int64(1)).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.AssignableToTypeOf(repository.RefreshToken{})).
+					Return(int64(1), nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:        testConfig,
+				Repository:    tt.fields.Repository,
+				KeyRing:       testKeyRing,
+				LoginAttempts: tt.fields.LoginAttempts,
+				totp:          testTOTP,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.LoginMfa(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.LoginMfa() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.LoginMfa() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_LoginMfa_failuresOutliveLogin(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockRepository := repository.NewMockRepositoryInterface(mockCtrl)
+
+	// the password is known, the second factor is guessed
+	confirmedAt := time.Now()
+	user := repository.User{
+		ID:              1,
+		PhoneNumber:     "+628223344551",
+		Password:        "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+		PhoneVerifiedAt: &confirmedAt,
+	}
+	mockRepository.EXPECT().GetUserByPhoneNumber(gomock.Any(), "+628223344551").
+		Return(user, nil).
+		AnyTimes()
+	mockRepository.EXPECT().GetUserByID(gomock.Any(), int64(1)).
+		Return(user, nil).
+		AnyTimes()
+	mockRepository.EXPECT().GetUserTOTP(gomock.Any(), int64(1)).
+		Return(repository.UserTOTP{
+			UserID:      1,
+			Secret:      testTOTPSecret,
+			ConfirmedAt: &confirmedAt,
+		}, nil).
+		AnyTimes()
+	mockRepository.EXPECT().GetTokensValidAfter(gomock.Any(), int64(1)).
+		Return(time.Time{}, nil).
+		AnyTimes()
+	mockRepository.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).
+		Return(false, nil).
+		AnyTimes()
+
+	s := &Server{
+		config:        testConfig,
+		Repository:    mockRepository,
+		KeyRing:       testKeyRing,
+		LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+		totp:          testTOTP,
+	}
+
+	wrongCode := ""
+	for i := 0; wrongCode == ""; i++ {
+		code := fmt.Sprintf("%06d", i)
+		if _, ok, _ := testTOTP.Validate(testTOTPSecret, code); !ok {
+			wrongCode = code
+		}
+	}
+
+	login := func() string {
+		req, _ := http.NewRequest(http.MethodPost, "url", strings.NewReader(`{
+			"phone_number": "+628223344551",
+			"password": "Sawit@123"
+		}`))
+		res := httptest.NewRecorder()
+		err := s.Login(echo.New().NewContext(req, res))
+		var response generated.LoginResponse
+		if err == nil {
+			err = json.Unmarshal(res.Body.Bytes(), &response)
+		}
+		if err != nil || response.Challenge == nil {
+			t.Fatalf("Server.Login() gotBody = %s", res.Body.String())
+		}
+		return response.Challenge.MfaToken
+	}
+	guess := func(mfaToken string) int {
+		req, _ := http.NewRequest(http.MethodPost, "url", strings.NewReader(fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, wrongCode)))
+		res := httptest.NewRecorder()
+		err := s.LoginMfa(echo.New().NewContext(req, res))
+		if err != nil {
+			t.Fatalf("Server.LoginMfa() gotErr = %s", err.Error())
+		}
+		return res.Code
+	}
+
+	// logging in again in between must not clear the failed codes
+	mfaToken := login()
+	for i := 1; i < testConfig.LoginThrottle.BackoffThreshold; i++ {
+		if gotStatusCode := guess(mfaToken); gotStatusCode != http.StatusUnauthorized {
+			t.Fatalf("Server.LoginMfa() gotStatusCode = %d, wantStatusCode = %d", gotStatusCode, http.StatusUnauthorized)
+		}
+	}
+	mfaToken = login()
+	if gotStatusCode := guess(mfaToken); gotStatusCode != http.StatusUnauthorized {
+		t.Fatalf("Server.LoginMfa() gotStatusCode = %d, wantStatusCode = %d", gotStatusCode, http.StatusUnauthorized)
+	}
+	if gotStatusCode := guess(mfaToken); gotStatusCode != http.StatusTooManyRequests {
+		t.Errorf("Server.LoginMfa() gotStatusCode = %d, wantStatusCode = %d", gotStatusCode, http.StatusTooManyRequests)
+	}
+
+	// the failures were counted against the client ip as well
+	loginAttempt, err := s.LoginAttempts.GetLoginAttempt(context.Background(), loginAttemptIPKey(""))
+	if err != nil || loginAttempt.FailedCount != testConfig.LoginThrottle.BackoffThreshold {
+		t.Errorf("LoginAttempts.GetLoginAttempt() gotFailedCount = %d, wantFailedCount = %d", loginAttempt.FailedCount, testConfig.LoginThrottle.BackoffThreshold)
+	}
+}
+
+func Test_Server_RefreshToken(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetRefreshTokenByHash",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"refresh_token": "refresh-token"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), hashToken("refresh-token")).
+					Return(repository.RefreshToken{}, errors.New("expected GetRefreshTokenByHash error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "refresh token not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"refresh_token": "refresh-token"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), hashToken("refresh-token")).
+					Return(repository.RefreshToken{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "refresh token is revoked",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"refresh_token": "refresh-token"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), hashToken("refresh-token")).
+					Return(repository.RefreshToken{
+						ID:        1,
+						UserID:    1,
+						FamilyID:  "family",
+						ExpiresAt: time.Now().Add(time.Hour),
+						RevokedAt: func() *time.Time {
+							res := time.Now()
+							return &res
+						}(),
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "reused refresh token error RevokeRefreshTokenFamily",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"refresh_token": "refresh-token"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), hashToken("refresh-token")).
+					Return(repository.RefreshToken{
+						ID:        1,
+						UserID:    1,
+						FamilyID:  "family",
+						ExpiresAt: time.Now().Add(time.Hour),
+						UsedAt: func() *time.Time {
+							res := time.Now()
+							return &res
+						}(),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeRefreshTokenFamily(context.Background(), "family").
+					Return(errors.New("expected RevokeRefreshTokenFamily error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "reused refresh token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"refresh_token": "refresh-token"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), hashToken("refresh-token")).
+					Return(repository.RefreshToken{
+						ID:        1,
+						UserID:    1,
+						FamilyID:  "family",
+						ExpiresAt: time.Now().Add(time.Hour),
+						UsedAt: func() *time.Time {
+							res := time.Now()
+							return &res
+						}(),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeRefreshTokenFamily(context.Background(), "family").
+					Return(nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "refresh token is expired",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"refresh_token": "refresh-token"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), hashToken("refresh-token")).
+					Return(repository.RefreshToken{
+						ID:        1,
+						UserID:    1,
+						FamilyID:  "family",
+						ExpiresAt: time.Now().Add(-time.Hour),
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "error MarkRefreshTokenUsed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"refresh_token": "refresh-token"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), hashToken("refresh-token")).
+					Return(repository.RefreshToken{
+						ID:        1,
+						UserID:    1,
+						FamilyID:  "family",
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().MarkRefreshTokenUsed(context.Background(), int64(1)).
+					Return(false, errors.New("expected MarkRefreshTokenUsed error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "refresh token rotated concurrently",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"refresh_token": "refresh-token"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), hashToken("refresh-token")).
+					Return(repository.RefreshToken{
+						ID:        1,
+						UserID:    1,
+						FamilyID:  "family",
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().MarkRefreshTokenUsed(context.Background(), int64(1)).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeRefreshTokenFamily(context.Background(), "family").
+					Return(nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserByID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"refresh_token": "refresh-token"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), hashToken("refresh-token")).
+					Return(repository.RefreshToken{
+						ID:        1,
+						UserID:    1,
+						FamilyID:  "family",
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().MarkRefreshTokenUsed(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected GetUserByID error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "user not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"refresh_token": "refresh-token"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), hashToken("refresh-token")).
+					Return(repository.RefreshToken{
+						ID:        1,
+						UserID:    1,
+						FamilyID:  "family",
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().MarkRefreshTokenUsed(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			wantErr:        nil,
+		},
+		{
+			name: "error InsertRefreshToken",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"refresh_token": "refresh-token"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), hashToken("refresh-token")).
+					Return(repository.RefreshToken{
+						ID:        1,
+						UserID:    1,
+						FamilyID:  "family",
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().MarkRefreshTokenUsed(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.AssignableToTypeOf(repository.RefreshToken{})).
+					Return(int64(0), errors.New("expected InsertRefreshToken error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"refresh_token": "refresh-token"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), hashToken("refresh-token")).
+					Return(repository.RefreshToken{
+						ID:        1,
+						UserID:    1,
+						FamilyID:  "family",
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().MarkRefreshTokenUsed(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.AssignableToTypeOf(repository.RefreshToken{})).
+					Return(int64(2), nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.RefreshToken(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.RefreshToken() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.RefreshToken() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_Logout(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "error InsertRevokedToken",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().InsertRevokedToken(context.Background(), gomock.AssignableToTypeOf(repository.RevokedToken{})).
+					Return(errors.New("expected InsertRevokedToken error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "error RevokeRefreshTokenFamily",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().InsertRevokedToken(context.Background(), gomock.AssignableToTypeOf(repository.RevokedToken{})).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeRefreshTokenFamily(context.Background(), "session").
+					Return(errors.New("expected RevokeRefreshTokenFamily error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().InsertRevokedToken(context.Background(), gomock.AssignableToTypeOf(repository.RevokedToken{})).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeRefreshTokenFamily(context.Background(), "session").
+					Return(nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.Logout(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.Logout() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.Logout() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_LogoutAll(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "error UpdateTokensValidAfter",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().UpdateTokensValidAfter(context.Background(), int64(1), gomock.AssignableToTypeOf(time.Time{})).
+					Return(errors.New("expected UpdateTokensValidAfter error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "error RevokeRefreshTokensByUserID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().UpdateTokensValidAfter(context.Background(), int64(1), gomock.AssignableToTypeOf(time.Time{})).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeRefreshTokensByUserID(context.Background(), int64(1)).
+					Return(errors.New("expected RevokeRefreshTokensByUserID error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().UpdateTokensValidAfter(context.Background(), int64(1), gomock.AssignableToTypeOf(time.Time{})).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeRefreshTokensByUserID(context.Background(), int64(1)).
+					Return(nil).
+					Times(1)
+
+				mockAuditEvent(fields.Repository, 1, 0, repository.AuditSessionsRevoked)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.LogoutAll(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.LogoutAll() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.LogoutAll() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_VerifyPhone(t *testing.T) {
+	codeHash, _ := hashAndSalt(context.Background(), "123456", testConfig.Auth.BcryptCost)
+	phoneVerifiedAt := time.Now()
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetActivePhoneVerification",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628123456789").
+					Return(repository.PhoneVerification{}, errors.New("expected GetActivePhoneVerification error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "no active phone verification",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628123456789").
+					Return(repository.PhoneVerification{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error IncreasePhoneVerificationAttempts",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628123456789").
+					Return(repository.PhoneVerification{
+						ID:          2,
+						UserID:      1,
+						PhoneNumber: "+628123456789",
+						CodeHash:    codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePhoneVerificationAttempts(context.Background(), int64(2), testConfig.Auth.PhoneVerification.MaxAttempts).
+					Return(false, errors.New("expected IncreasePhoneVerificationAttempts error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "too many attempts",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628123456789").
+					Return(repository.PhoneVerification{
+						ID:          2,
+						UserID:      1,
+						PhoneNumber: "+628123456789",
+						CodeHash:    codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePhoneVerificationAttempts(context.Background(), int64(2), testConfig.Auth.PhoneVerification.MaxAttempts).
+					Return(false, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "wrong code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "654321"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628123456789").
+					Return(repository.PhoneVerification{
+						ID:          2,
+						UserID:      1,
+						PhoneNumber: "+628123456789",
+						CodeHash:    codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePhoneVerificationAttempts(context.Background(), int64(2), testConfig.Auth.PhoneVerification.MaxAttempts).
+					Return(true, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserByPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628123456789").
+					Return(repository.PhoneVerification{
+						ID:          2,
+						UserID:      1,
+						PhoneNumber: "+628123456789",
+						CodeHash:    codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePhoneVerificationAttempts(context.Background(), int64(2), testConfig.Auth.PhoneVerification.MaxAttempts).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "phone number is already registered",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628123456789").
+					Return(repository.PhoneVerification{
+						ID:          2,
+						UserID:      1,
+						PhoneNumber: "+628123456789",
+						CodeHash:    codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePhoneVerificationAttempts(context.Background(), int64(2), testConfig.Auth.PhoneVerification.MaxAttempts).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:              3,
+						PhoneVerifiedAt: &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusConflict,
+			wantErr:        nil,
+		},
+		{
+			name: "error VerifyPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628123456789").
+					Return(repository.PhoneVerification{
+						ID:          2,
+						UserID:      1,
+						PhoneNumber: "+628123456789",
+						CodeHash:    codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePhoneVerificationAttempts(context.Background(), int64(2), testConfig.Auth.PhoneVerification.MaxAttempts).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().VerifyPhoneNumber(context.Background(), int64(2), repository.User{
+					ID:          1,
+					PhoneNumber: "+628123456789",
+				}).
+					Return(false, errors.New("expected VerifyPhoneNumber error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "phone number claimed while verifying",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628123456789").
+					Return(repository.PhoneVerification{
+						ID:          2,
+						UserID:      1,
+						PhoneNumber: "+628123456789",
+						CodeHash:    codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePhoneVerificationAttempts(context.Background(), int64(2), testConfig.Auth.PhoneVerification.MaxAttempts).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().VerifyPhoneNumber(context.Background(), int64(2), repository.User{
+					ID:          1,
+					PhoneNumber: "+628123456789",
+				}).
+					Return(false, &repository.UniqueViolationError{Constraint: "user_phone_number_unique", Err: errors.New("expected VerifyPhoneNumber error")}).
+					Times(1)
+			},
+			wantStatusCode: http.StatusConflict,
+			wantErr:        nil,
+		},
+		{
+			name: "code was already used",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628123456789").
+					Return(repository.PhoneVerification{
+						ID:          2,
+						UserID:      1,
+						PhoneNumber: "+628123456789",
+						CodeHash:    codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePhoneVerificationAttempts(context.Background(), int64(2), testConfig.Auth.PhoneVerification.MaxAttempts).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().VerifyPhoneNumber(context.Background(), int64(2), repository.User{
+					ID:          1,
+					PhoneNumber: "+628123456789",
+				}).
+					Return(false, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "phone number held by an unverified account",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628123456789").
+					Return(repository.PhoneVerification{
+						ID:          2,
+						UserID:      1,
+						PhoneNumber: "+628123456789",
+						CodeHash:    codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePhoneVerificationAttempts(context.Background(), int64(2), testConfig.Auth.PhoneVerification.MaxAttempts).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID: 3,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().VerifyPhoneNumber(context.Background(), int64(2), repository.User{
+					ID:          1,
+					PhoneNumber: "+628123456789",
+				}).
+					Return(true, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628123456789").
+					Return(repository.PhoneVerification{
+						ID:          2,
+						UserID:      1,
+						PhoneNumber: "+628123456789",
+						CodeHash:    codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePhoneVerificationAttempts(context.Background(), int64(2), testConfig.Auth.PhoneVerification.MaxAttempts).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().VerifyPhoneNumber(context.Background(), int64(2), repository.User{
+					ID:          1,
+					PhoneNumber: "+628123456789",
+				}).
+					Return(true, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.VerifyPhone(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.VerifyPhone() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.VerifyPhone() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_ForgotPassword(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+		Notifier   *notifier.MockNotifier
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "get user error",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{}, errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "phone number is not registered",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "get active password reset error",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{}, errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "code was sent recently",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{
+						ID:        2,
+						CreatedAt: time.Now(),
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "insert password reset error",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPasswordReset(context.Background(), gomock.AssignableToTypeOf(repository.PasswordReset{})).
+					Return(int64(0), errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "send sms error",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPasswordReset(context.Background(), gomock.AssignableToTypeOf(repository.PasswordReset{})).
+					Return(int64(2), nil).
+					Times(1)
+
+				fields.Notifier.EXPECT().SendSMS(context.Background(), "+628123456789", gomock.Any()).
+					Return(errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{
+						ID:        2,
+						CreatedAt: time.Now().Add(-time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPasswordReset(context.Background(), gomock.AssignableToTypeOf(repository.PasswordReset{})).
+					Return(int64(2), nil).
+					Times(1)
+
+				fields.Notifier.EXPECT().SendSMS(context.Background(), "+628123456789", gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+				Notifier:   tt.fields.Notifier,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.ForgotPassword(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.ForgotPassword() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.ForgotPassword() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_ResetPassword(t *testing.T) {
+	codeHash, _ := hashAndSalt(context.Background(), "123456", testConfig.Auth.BcryptCost)
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "invalid request",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "",
+							"password": "password"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "get user error",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456",
+							"password": "Password1!"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{}, errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "phone number is not registered",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456",
+							"password": "Password1!"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "get active password reset error",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456",
+							"password": "Password1!"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{}, errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "no active password reset",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456",
+							"password": "Password1!"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "increase attempts error",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456",
+							"password": "Password1!"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{
+						ID:       2,
+						UserID:   1,
+						CodeHash: codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePasswordResetAttempts(context.Background(), int64(2), testConfig.Auth.PasswordReset.MaxAttempts).
+					Return(false, errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "too many attempts",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456",
+							"password": "Password1!"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{
+						ID:       2,
+						UserID:   1,
+						CodeHash: codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePasswordResetAttempts(context.Background(), int64(2), testConfig.Auth.PasswordReset.MaxAttempts).
+					Return(false, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "wrong code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "654321",
+							"password": "Password1!"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{
+						ID:       2,
+						UserID:   1,
+						CodeHash: codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePasswordResetAttempts(context.Background(), int64(2), testConfig.Auth.PasswordReset.MaxAttempts).
+					Return(true, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "reset password error",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456",
+							"password": "Password1!"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{
+						ID:       2,
+						UserID:   1,
+						CodeHash: codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePasswordResetAttempts(context.Background(), int64(2), testConfig.Auth.PasswordReset.MaxAttempts).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().ResetPassword(context.Background(), int64(2), gomock.AssignableToTypeOf(repository.User{}), gomock.AssignableToTypeOf(time.Time{})).
+					Return(false, errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "code was already used",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456",
+							"password": "Password1!"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{
+						ID:       2,
+						UserID:   1,
+						CodeHash: codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePasswordResetAttempts(context.Background(), int64(2), testConfig.Auth.PasswordReset.MaxAttempts).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().ResetPassword(context.Background(), int64(2), gomock.AssignableToTypeOf(repository.User{}), gomock.AssignableToTypeOf(time.Time{})).
+					Return(false, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+							"phone_number": "+628123456789",
+							"code": "123456",
+							"password": "Password1!"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628123456789").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(1)).
+					Return(repository.PasswordReset{
+						ID:       2,
+						UserID:   1,
+						CodeHash: codeHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncreasePasswordResetAttempts(context.Background(), int64(2), testConfig.Auth.PasswordReset.MaxAttempts).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().ResetPassword(context.Background(), int64(2), gomock.AssignableToTypeOf(repository.User{}), gomock.AssignableToTypeOf(time.Time{})).
+					Return(true, nil).
+					Times(1)
+
+				mockAuditEvent(fields.Repository, 1, 0, repository.AuditPasswordReset)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.ResetPassword(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.ResetPassword() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.ResetPassword() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_GetProfile(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserByID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected GetUserByID error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "user not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.GetProfile(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.GetProfile() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.Register() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_UpdateProfile(t *testing.T) {
+	phoneVerifiedAt := time.Now()
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+		Notifier   *notifier.MockNotifier
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(``)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "no params at all",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "all params have empty value",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "",
+						"full_name": ""
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "all params have empty value",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "",
+						"full_name": ""
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "invalid phone number",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "123",
+						"full_name": "Sawit Pro 1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserByPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "phone number is already registered",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:              2,
+						PhoneVerifiedAt: &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusConflict,
+			wantErr:        nil,
+		},
+		{
+			name: "invalid full name",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "SP"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error UpdateUser",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(),
+					repository.User{
+						ID:       1,
+						FullName: "Sawit Pro 1",
+					}).
+					Return(errors.New("expected UpdateUser error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "unchanged phone number",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(),
+					repository.User{
+						ID:       1,
+						FullName: "Sawit Pro 1",
+					}).
+					Return(nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "error sendPhoneVerificationCode",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(),
+					repository.User{
+						ID:       1,
+						FullName: "Sawit Pro 1",
+					}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628223344551").
+					Return(repository.PhoneVerification{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPhoneVerification(context.Background(), gomock.AssignableToTypeOf(repository.PhoneVerification{})).
+					Return(int64(0), errors.New("expected InsertPhoneVerification error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "phone number held by an unverified account",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID: 2,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(),
+					repository.User{
+						ID:       1,
+						FullName: "Sawit Pro 1",
+					}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628223344551").
+					Return(repository.PhoneVerification{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPhoneVerification(context.Background(), gomock.AssignableToTypeOf(repository.PhoneVerification{})).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.Notifier.EXPECT().SendSMS(context.Background(), "+628223344551", gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(),
+					repository.User{
+						ID:       1,
+						FullName: "Sawit Pro 1",
+					}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628223344551").
+					Return(repository.PhoneVerification{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPhoneVerification(context.Background(), gomock.AssignableToTypeOf(repository.PhoneVerification{})).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.Notifier.EXPECT().SendSMS(context.Background(), "+628223344551", gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+				Notifier:   tt.fields.Notifier,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.UpdateProfile(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.UpdateProfile() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.Register() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_DeleteProfile(t *testing.T) {
+	passwordHash, _ := hashAndSalt(context.Background(), "Sawit@Pr0", testConfig.Auth.BcryptCost)
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodDelete, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodDelete, "url", bytes.NewBuffer([]byte(``)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "get user error",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodDelete, "url", bytes.NewBuffer([]byte(`{
+							"password": "Sawit@Pr0"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "user not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodDelete, "url", bytes.NewBuffer([]byte(`{
+							"password": "Sawit@Pr0"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "wrong password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodDelete, "url", bytes.NewBuffer([]byte(`{
+							"password": "Sawit@Pr1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID:       1,
+						Password: passwordHash,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "delete user error",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodDelete, "url", bytes.NewBuffer([]byte(`{
+							"password": "Sawit@Pr0"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID:       1,
+						Password: passwordHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().DeleteUser(context.Background(), int64(1), gomock.AssignableToTypeOf(time.Time{})).
+					Return(errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodDelete, "url", bytes.NewBuffer([]byte(`{
+							"password": "Sawit@Pr0"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID:       1,
+						Password: passwordHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().DeleteUser(context.Background(), int64(1), gomock.AssignableToTypeOf(time.Time{})).
+					Return(nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.DeleteProfile(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.DeleteProfile() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.DeleteProfile() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_ChangePassword(t *testing.T) {
+	passwordHash, _ := hashAndSalt(context.Background(), "Sawit@Pr0", testConfig.Auth.BcryptCost)
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPut, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPut, "url", bytes.NewBuffer([]byte(``)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "invalid request",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPut, "url", bytes.NewBuffer([]byte(`{
+							"current_password": "Sawit@Pr0",
+							"new_password": "Sawit@Pr0"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "get user error",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPut, "url", bytes.NewBuffer([]byte(`{
+							"current_password": "Sawit@Pr0",
+							"new_password": "Sawit@Pr1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "user not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPut, "url", bytes.NewBuffer([]byte(`{
+							"current_password": "Sawit@Pr0",
+							"new_password": "Sawit@Pr1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "wrong password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPut, "url", bytes.NewBuffer([]byte(`{
+							"current_password": "Sawit@Pr9",
+							"new_password": "Sawit@Pr1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID:       1,
+						Password: passwordHash,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "update password error",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPut, "url", bytes.NewBuffer([]byte(`{
+							"current_password": "Sawit@Pr0",
+							"new_password": "Sawit@Pr1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID:       1,
+						Password: passwordHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdatePassword(context.Background(), gomock.AssignableToTypeOf(repository.User{}), gomock.AssignableToTypeOf(time.Time{}), "session").
+					Return(errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPut, "url", bytes.NewBuffer([]byte(`{
+							"current_password": "Sawit@Pr0",
+							"new_password": "Sawit@Pr1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID:       1,
+						Password: passwordHash,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdatePassword(context.Background(), gomock.AssignableToTypeOf(repository.User{}), gomock.AssignableToTypeOf(time.Time{}), "session").
+					Return(nil).
+					Times(1)
+
+				mockAuditEvent(fields.Repository, 1, 0, repository.AuditPasswordChanged)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.ChangePassword(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.ChangePassword() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.ChangePassword() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_ExportProfile(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantNotified   bool
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantNotified:   false,
+			wantErr:        nil,
+		},
+		{
+			name: "invalid request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantNotified:   false,
+			wantErr:        nil,
+		},
+		{
+			name: "error InsertDataExport",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().InsertDataExport(context.Background(), gomock.AssignableToTypeOf(repository.DataExport{})).
+					Return(int64(0), errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantNotified:   false,
+			wantErr:        nil,
+		},
+		{
+			name: "passed without request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().InsertDataExport(context.Background(), gomock.AssignableToTypeOf(repository.DataExport{})).
+					DoAndReturn(func(_ context.Context, data repository.DataExport) (int64, error) {
+						if data.UserID != 1 || data.Format != "json" || data.TokenHash == "" {
+							t.Errorf("Server.ExportProfile() inserted %+v", data)
+						}
+						return 1, nil
+					}).
+					Times(1)
+			},
+			wantStatusCode: http.StatusAccepted,
+			wantNotified:   true,
+			wantErr:        nil,
+		},
+		{
+			name: "passed csv",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"format": "csv"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().InsertDataExport(context.Background(), gomock.AssignableToTypeOf(repository.DataExport{})).
+					DoAndReturn(func(_ context.Context, data repository.DataExport) (int64, error) {
+						if data.UserID != 1 || data.Format != "csv" || data.TokenHash == "" {
+							t.Errorf("Server.ExportProfile() inserted %+v", data)
+						}
+						return 1, nil
+					}).
+					Times(1)
+			},
+			wantStatusCode: http.StatusAccepted,
+			wantNotified:   true,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:            testConfig,
+				Repository:        tt.fields.Repository,
+				KeyRing:           testKeyRing,
+				DataExportTrigger: export.NewTrigger(),
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.ExportProfile(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.ExportProfile() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.ExportProfile() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			if gotNotified := len(s.DataExportTrigger) == 1; gotNotified != tt.wantNotified {
+				t.Errorf("Server.ExportProfile() gotNotified = %t, wantNotified = %t", gotNotified, tt.wantNotified)
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_DownloadProfileExport(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx    echo.Context
+		id     int64
+		params generated.DownloadProfileExportParams
+	}
+	tests := []struct {
+		name            string
+		fields          fields
+		args            args
+		mock            func(fields *fields)
+		wantStatusCode  int
+		wantContentType string
+		wantErr         error
+	}{
+		{
+			name: "error GetDataExport",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 1,
+				params: generated.DownloadProfileExportParams{
+					Token: "token",
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetDataExport(context.Background(), int64(1)).
+					Return(repository.DataExport{}, errors.New("expected error")).
+					Times(1)
+			},
+			wantStatusCode:  http.StatusInternalServerError,
+			wantContentType: "",
+			wantErr:         nil,
+		},
+		{
+			name: "not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 1,
+				params: generated.DownloadProfileExportParams{
+					Token: "token",
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetDataExport(context.Background(), int64(1)).
+					Return(repository.DataExport{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode:  http.StatusNotFound,
+			wantContentType: "",
+			wantErr:         nil,
+		},
+		{
+			name: "wrong token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 1,
+				params: generated.DownloadProfileExportParams{
+					Token: "token",
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetDataExport(context.Background(), int64(1)).
+					Return(repository.DataExport{
+						ID:        1,
+						UserID:    1,
+						Format:    "json",
+						Status:    repository.DataExportReady,
+						TokenHash: hashToken("other"),
+						ExpiresAt: expiresAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode:  http.StatusNotFound,
+			wantContentType: "",
+			wantErr:         nil,
+		},
+		{
+			name: "expired",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 1,
+				params: generated.DownloadProfileExportParams{
+					Token: "token",
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetDataExport(context.Background(), int64(1)).
+					Return(repository.DataExport{
+						ID:        1,
+						UserID:    1,
+						Format:    "json",
+						Status:    repository.DataExportReady,
+						TokenHash: hashToken("token"),
+						ExpiresAt: time.Now().Add(-time.Minute),
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode:  http.StatusGone,
+			wantContentType: "",
+			wantErr:         nil,
+		},
+		{
+			name: "pending",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 1,
+				params: generated.DownloadProfileExportParams{
+					Token: "token",
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetDataExport(context.Background(), int64(1)).
+					Return(repository.DataExport{
+						ID:        1,
+						UserID:    1,
+						Format:    "json",
+						Status:    repository.DataExportPending,
+						TokenHash: hashToken("token"),
+						ExpiresAt: expiresAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode:  http.StatusAccepted,
+			wantContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			wantErr:         nil,
+		},
+		{
+			name: "failed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 1,
+				params: generated.DownloadProfileExportParams{
+					Token: "token",
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetDataExport(context.Background(), int64(1)).
+					Return(repository.DataExport{
+						ID:        1,
+						UserID:    1,
+						Format:    "json",
+						Status:    repository.DataExportFailed,
+						TokenHash: hashToken("token"),
+						ExpiresAt: expiresAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode:  http.StatusInternalServerError,
+			wantContentType: "",
+			wantErr:         nil,
+		},
+		{
+			name: "ready",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 1,
+				params: generated.DownloadProfileExportParams{
+					Token: "token",
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetDataExport(context.Background(), int64(1)).
+					Return(repository.DataExport{
+						ID:        1,
+						UserID:    1,
+						Format:    "json",
+						Status:    repository.DataExportReady,
+						TokenHash: hashToken("token"),
+						Archive:   []byte(`{"profile":{}}`),
+						ExpiresAt: expiresAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "application/json",
+			wantErr:         nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.DownloadProfileExport(tt.args.ctx, tt.args.id, tt.args.params)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.DownloadProfileExport() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.DownloadProfileExport() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			if gotContentType := tt.args.ctx.Response().Header().Get(echo.HeaderContentType); tt.wantContentType != "" && gotContentType != tt.wantContentType {
+				t.Errorf("Server.DownloadProfileExport() gotContentType = %s, wantContentType = %s", gotContentType, tt.wantContentType)
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_EnrollTotp(t *testing.T) {
+	confirmedAt := time.Now()
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserByID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected GetUserByID error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "user not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserTOTP",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, errors.New("expected GetUserTOTP error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "already enabled",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusConflict,
+			wantErr:        nil,
+		},
+		{
+			name: "error UpsertUserTOTP",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().UpsertUserTOTP(context.Background(), gomock.AssignableToTypeOf(repository.UserTOTP{})).
+					Return(errors.New("expected UpsertUserTOTP error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().UpsertUserTOTP(context.Background(), gomock.AssignableToTypeOf(repository.UserTOTP{})).
+					Return(nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+				totp:       testTOTP,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.EnrollTotp(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.EnrollTotp() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.EnrollTotp() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_ConfirmTotp(t *testing.T) {
+	totpCode, _ := testTOTP.GenerateCode(testTOTPSecret)
+	confirmedAt := time.Now()
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserTOTP",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, totpCode))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, errors.New("expected GetUserTOTP error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "not enrolled",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, totpCode))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "already enabled",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, totpCode))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusConflict,
+			wantErr:        nil,
+		},
+		{
+			name: "invalid code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, "12345"))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID: 1,
+						Secret: testTOTPSecret,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error ConfirmUserTOTP",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, totpCode))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID: 1,
+						Secret: testTOTPSecret,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().ConfirmUserTOTP(context.Background(), int64(1), testTOTP.Step(testTOTP.Clock()), gomock.Len(testConfig.Auth.RecoveryCodeCount)).
+					Return(false, errors.New("expected ConfirmUserTOTP error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "reused code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, totpCode))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID: 1,
+						Secret: testTOTPSecret,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().ConfirmUserTOTP(context.Background(), int64(1), testTOTP.Step(testTOTP.Clock()), gomock.Len(testConfig.Auth.RecoveryCodeCount)).
+					Return(false, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, totpCode))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID: 1,
+						Secret: testTOTPSecret,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().ConfirmUserTOTP(context.Background(), int64(1), testTOTP.Step(testTOTP.Clock()), gomock.Len(testConfig.Auth.RecoveryCodeCount)).
+					Return(true, nil).
+					Times(1)
+
+				mockAuditEvent(fields.Repository, 1, 0, repository.AuditTOTPEnabled)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+				totp:       testTOTP,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.ConfirmTotp(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.ConfirmTotp() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.ConfirmTotp() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_DisableTotp(t *testing.T) {
+	totpCode, _ := testTOTP.GenerateCode(testTOTPSecret)
+	confirmedAt := time.Now()
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserTOTP",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, totpCode))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{}, errors.New("expected GetUserTOTP error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "not enabled",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, totpCode))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID: 1,
+						Secret: testTOTPSecret,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error verifySecondFactor",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, totpCode))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateTOTPLastUsedStep(context.Background(), int64(1), testTOTP.Step(testTOTP.Clock())).
+					Return(false, errors.New("expected UpdateTOTPLastUsedStep error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "invalid code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, totpCode))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateTOTPLastUsedStep(context.Background(), int64(1), testTOTP.Step(testTOTP.Clock())).
+					Return(false, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "error DeleteUserTOTP",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, totpCode))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateTOTPLastUsedStep(context.Background(), int64(1), testTOTP.Step(testTOTP.Clock())).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().DeleteUserTOTP(context.Background(), int64(1)).
+					Return(errors.New("expected DeleteUserTOTP error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(fmt.Sprintf(`{"code": "%s"}`, totpCode))))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserTOTP(context.Background(), int64(1)).
+					Return(repository.UserTOTP{
+						UserID:      1,
+						Secret:      testTOTPSecret,
+						ConfirmedAt: &confirmedAt,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateTOTPLastUsedStep(context.Background(), int64(1), testTOTP.Step(testTOTP.Clock())).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().DeleteUserTOTP(context.Background(), int64(1)).
+					Return(nil).
+					Times(1)
+
+				mockAuditEvent(fields.Repository, 1, 0, repository.AuditTOTPDisabled)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+				totp:       testTOTP,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.DisableTotp(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.DisableTotp() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.DisableTotp() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func mockActiveSession(repo *repository.MockRepositoryInterface, userID int64) {
+	repo.EXPECT().GetTokensValidAfter(context.Background(), userID).
+		Return(time.Time{}, nil).
+		Times(1)
+
+	repo.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+		Return(false, nil).
+		Times(1)
+}
+
+// mockAdminActor expects checkAdminTarget to look up an acting user with
+// the admin role, managing a user without any.
+func mockAdminActor(repo *repository.MockRepositoryInterface, actorID int64) {
+	repo.EXPECT().GetUserByID(context.Background(), actorID).
+		Return(repository.User{
+			ID:   actorID,
+			Role: "admin",
+		}, nil).
+		Times(1)
+
+	repo.EXPECT().GetRolePermissions(context.Background(), "admin").
+		Return([]string{"users:read", "users:write", "users:lock", "users:reset_password", "users:revoke_sessions"}, nil).
+		Times(1)
+}
+
+func mockAuditEvent(repo *repository.MockRepositoryInterface, userID, actorID int64, action string) {
+	event := repository.AuditEvent{
+		UserID: userID,
+		Action: action,
+	}
+	if actorID != 0 {
+		event.ActorID = &actorID
+	}
+
+	repo.EXPECT().InsertAuditEvent(context.Background(), event).
+		Return(nil).
+		Times(1)
+}
+
+func Test_Server_ListUsers(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx    echo.Context
+		params generated.ListUsersParams
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				params: generated.ListUsersParams{},
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "error ListUsers",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				params: generated.ListUsersParams{},
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().ListUsers(context.Background(), repository.UserFilter{
+					Offset: 0,
+					Limit:  20,
+				}).
+					Return(nil, int64(0), errors.New("expected ListUsers error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				params: generated.ListUsersParams{
+					Role:     func() *string { role := "support"; return &role }(),
+					Page:     func() *int { page := 2; return &page }(),
+					PageSize: func() *int { pageSize := 10; return &pageSize }(),
+				},
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().ListUsers(context.Background(), repository.UserFilter{
+					Role:   "support",
+					Offset: 10,
+					Limit:  10,
+				}).
+					Return([]repository.User{
+						{
+							ID:          2,
+							PhoneNumber: "+628123456789",
+							FullName:    "Sawit Pro",
+							Role:        "support",
+						},
+					}, int64(11), nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.ListUsers(tt.args.ctx, tt.args.params)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.ListUsers() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.ListUsers() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_GetUser(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+		id  int64
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserByID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{}, errors.New("expected GetUserByID error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "user not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.GetUser(tt.args.ctx, tt.args.id)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.GetUser() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.GetUser() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_UpdateUser(t *testing.T) {
+	phoneVerifiedAt := time.Now()
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+		Notifier   *notifier.MockNotifier
+	}
+	type args struct {
+		ctx echo.Context
+		id  int64
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(``)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "invalid phone number",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+6212"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "no changes",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+			},
+			wantStatusCode: http.StatusBadRequest,
+			wantErr:        nil,
+		},
+		{
+			name: "user not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantErr:        nil,
+		},
+		{
+			name: "phone number taken",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID:              3,
+						PhoneVerifiedAt: &phoneVerifiedAt,
+					}, nil).
+					Times(1)
+			},
+			wantStatusCode: http.StatusConflict,
+			wantErr:        nil,
+		},
+		{
+			name: "error GetUserByPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "error UpdateUser",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(), repository.User{
+					ID:       2,
+					FullName: "Sawit Pro 1",
+				}).
+					Return(errors.New("expected UpdateUser error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "error sendPhoneVerificationCode",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628223344551").
+					Return(repository.PhoneVerification{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPhoneVerification(context.Background(), gomock.AssignableToTypeOf(repository.PhoneVerification{})).
+					Return(int64(0), errors.New("expected InsertPhoneVerification error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			wantErr:        nil,
+		},
+		{
+			name: "unchanged phone number",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628123456789"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				mockAuditEvent(fields.Repository, 2, 1, repository.AuditUserUpdated)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "phone number held by an unverified account",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{
+						ID: 3,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628223344551").
+					Return(repository.PhoneVerification{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPhoneVerification(context.Background(), gomock.AssignableToTypeOf(repository.PhoneVerification{})).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.Notifier.EXPECT().SendSMS(context.Background(), "+628223344551", gomock.Any()).
+					Return(nil).
+					Times(1)
+
+				mockAuditEvent(fields.Repository, 2, 1, repository.AuditUserUpdated)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+628223344551",
+						"full_name": "Sawit Pro 1"
+					}`)))
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(), repository.User{
+					ID:       2,
+					FullName: "Sawit Pro 1",
+				}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePhoneVerification(context.Background(), "+628223344551").
+					Return(repository.PhoneVerification{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPhoneVerification(context.Background(), gomock.AssignableToTypeOf(repository.PhoneVerification{})).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.Notifier.EXPECT().SendSMS(context.Background(), "+628223344551", gomock.Any()).
+					Return(nil).
+					Times(1)
+
+				mockAuditEvent(fields.Repository, 2, 1, repository.AuditUserUpdated)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+				Notifier:   tt.fields.Notifier,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.UpdateUser(tt.args.ctx, tt.args.id)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.UpdateUser() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.UpdateUser() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_ForcePasswordReset(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+		Notifier   *notifier.MockNotifier
 	}
 	type args struct {
 		ctx echo.Context
+		id  int64
 	}
 	tests := []struct {
 		name           string
@@ -32,141 +8362,154 @@ func Test_Server_Register(t *testing.T) {
 		wantErr        error
 	}{
 		{
-			name: "no request body",
-			fields: func() fields {
-				mockCtrl := gomock.NewController(t)
-				return fields{
-					mockCtrl:   mockCtrl,
-					Repository: repository.NewMockRepositoryInterface(mockCtrl),
-				}
-			}(),
-			args: args{
-				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
-					res := httptest.NewRecorder()
-					c := echo.New().NewContext(req, res)
-					return c
-				}(),
-			},
-			mock:           func(fields *fields) {},
-			wantStatusCode: http.StatusBadRequest,
-			wantErr:        nil,
-		},
-		{
-			name: "invalid request",
+			name: "invalid authorization",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
 					mockCtrl:   mockCtrl,
 					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
 				}
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "",
-						"full_name": "",
-						"password": ""
-					}`)))
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock:           func(fields *fields) {},
-			wantStatusCode: http.StatusBadRequest,
+			wantStatusCode: http.StatusForbidden,
 			wantErr:        nil,
 		},
 		{
-			name: "error checkNewPhoneNumber",
+			name: "user not found",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
 					mockCtrl:   mockCtrl,
 					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
 				}
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"full_name": "Sawit Pro 1",
-						"password": "Sawit@123"
-					}`)))
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
-					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{}, repository.ErrNotFound).
 					Times(1)
 			},
-			wantStatusCode: http.StatusInternalServerError,
+			wantStatusCode: http.StatusNotFound,
 			wantErr:        nil,
 		},
 		{
-			name: "phone number already registered",
+			name: "error RequirePasswordReset",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
 					mockCtrl:   mockCtrl,
 					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
 				}
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"full_name": "Sawit Pro 1",
-						"password": "Sawit@123"
-					}`)))
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
 					Return(repository.User{
-						ID: 1,
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
 					}, nil).
 					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().RequirePasswordReset(context.Background(), int64(2), gomock.AssignableToTypeOf(time.Time{})).
+					Return(errors.New("expected RequirePasswordReset error")).
+					Times(1)
 			},
-			wantStatusCode: http.StatusBadRequest,
+			wantStatusCode: http.StatusInternalServerError,
 			wantErr:        nil,
 		},
 		{
-			name: "error InsertUser",
+			name: "error sendPasswordResetCode",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
 					mockCtrl:   mockCtrl,
 					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
 				}
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"full_name": "Sawit Pro 1",
-						"password": "Sawit@123"
-					}`)))
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
 					Return(repository.User{
-						ID: 0,
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
 					}, nil).
 					Times(1)
 
-				fields.Repository.EXPECT().InsertUser(context.Background(), gomock.AssignableToTypeOf(repository.User{})).
-					Return(int64(0), errors.New("expected InsertUser error")).
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().RequirePasswordReset(context.Background(), int64(2), gomock.AssignableToTypeOf(time.Time{})).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(2)).
+					Return(repository.PasswordReset{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPasswordReset(context.Background(), gomock.AssignableToTypeOf(repository.PasswordReset{})).
+					Return(int64(3), nil).
+					Times(1)
+
+				fields.Notifier.EXPECT().SendSMS(context.Background(), "+628123456789", gomock.Any()).
+					Return(errors.New("expected SendSMS error")).
 					Times(1)
 			},
 			wantStatusCode: http.StatusInternalServerError,
@@ -179,30 +8522,52 @@ func Test_Server_Register(t *testing.T) {
 				return fields{
 					mockCtrl:   mockCtrl,
 					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					Notifier:   notifier.NewMockNotifier(mockCtrl),
 				}
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"full_name": "Sawit Pro 1",
-						"password": "Sawit@123"
-					}`)))
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
 					Return(repository.User{
-						ID: 0,
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
 					}, nil).
 					Times(1)
 
-				fields.Repository.EXPECT().InsertUser(context.Background(), gomock.AssignableToTypeOf(repository.User{})).
-					Return(int64(1), nil).
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().RequirePasswordReset(context.Background(), int64(2), gomock.AssignableToTypeOf(time.Time{})).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActivePasswordReset(context.Background(), int64(2)).
+					Return(repository.PasswordReset{}, repository.ErrNotFound).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertPasswordReset(context.Background(), gomock.AssignableToTypeOf(repository.PasswordReset{})).
+					Return(int64(3), nil).
+					Times(1)
+
+				fields.Notifier.EXPECT().SendSMS(context.Background(), "+628123456789", gomock.Any()).
+					Return(nil).
 					Times(1)
+
+				mockAuditEvent(fields.Repository, 2, 1, repository.AuditPasswordResetForced)
 			},
 			wantStatusCode: http.StatusOK,
 			wantErr:        nil,
@@ -211,16 +8576,19 @@ func Test_Server_Register(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Server{
+				config:     testConfig,
 				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+				Notifier:   tt.fields.Notifier,
 			}
 			tt.mock(&tt.fields)
-			gotErr := s.Register(tt.args.ctx)
+			gotErr := s.ForcePasswordReset(tt.args.ctx, tt.args.id)
 			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
-				t.Errorf("Server.Register() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+				t.Errorf("Server.ForcePasswordReset() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
 			}
 			if gotErr == nil {
 				if tt.args.ctx.Response().Status != tt.wantStatusCode {
-					t.Errorf("Server.Register() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+					t.Errorf("Server.ForcePasswordReset() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
 				}
 			}
 			tt.fields.mockCtrl.Finish()
@@ -228,13 +8596,14 @@ func Test_Server_Register(t *testing.T) {
 	}
 }
 
-func Test_Server_Login(t *testing.T) {
+func Test_Server_LockUser(t *testing.T) {
 	type fields struct {
 		mockCtrl   *gomock.Controller
 		Repository *repository.MockRepositoryInterface
 	}
 	type args struct {
 		ctx echo.Context
+		id  int64
 	}
 	tests := []struct {
 		name           string
@@ -245,7 +8614,7 @@ func Test_Server_Login(t *testing.T) {
 		wantErr        error
 	}{
 		{
-			name: "no request body",
+			name: "invalid authorization",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -255,18 +8624,19 @@ func Test_Server_Login(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock:           func(fields *fields) {},
-			wantStatusCode: http.StatusBadRequest,
+			wantStatusCode: http.StatusForbidden,
 			wantErr:        nil,
 		},
 		{
-			name: "error GetUserByPhoneNumber",
+			name: "user not found",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -276,25 +8646,29 @@ func Test_Server_Login(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"password": "Sawit@123"
-					}`)))
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
-					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{}, repository.ErrNotFound).
 					Times(1)
 			},
-			wantStatusCode: http.StatusInternalServerError,
+			wantStatusCode: http.StatusNotFound,
 			wantErr:        nil,
 		},
 		{
-			name: "user not found",
+			name: "own account",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -304,27 +8678,33 @@ func Test_Server_Login(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"password": "Sawit@123"
-					}`)))
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 1,
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
 					Return(repository.User{
-						ID: 0,
+						ID:          1,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
 					}, nil).
 					Times(1)
 			},
-			wantStatusCode: http.StatusBadRequest,
+			wantStatusCode: http.StatusForbidden,
 			wantErr:        nil,
 		},
 		{
-			name: "wrong password",
+			name: "error checkAdminTarget",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -334,28 +8714,37 @@ func Test_Server_Login(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"password": "Random@123"
-					}`)))
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
 					Return(repository.User{
-						ID:       1,
-						Password: "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
 					}, nil).
 					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected GetUserByID error")).
+					Times(1)
 			},
-			wantStatusCode: http.StatusBadRequest,
+			wantStatusCode: http.StatusInternalServerError,
 			wantErr:        nil,
 		},
 		{
-			name: "error IncreaseLoginCount",
+			name: "user granted as much",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -365,30 +8754,136 @@ func Test_Server_Login(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"password": "Sawit@123"
-					}`)))
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
 					Return(repository.User{
-						ID:       1,
-						Password: "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+						Role:        "support",
 					}, nil).
 					Times(1)
 
-				fields.Repository.EXPECT().IncreaseLoginCount(context.Background(), int64(1)).
-					Return(errors.New("expected IncreaseLoginCount error")).
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID:   1,
+						Role: "support",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetRolePermissions(context.Background(), "support").
+					Return([]string{"users:read", "users:lock"}, nil).
+					Times(2)
+			},
+			wantStatusCode: http.StatusForbidden,
+			wantErr:        nil,
+		},
+		{
+			name: "error LockUser",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().LockUser(context.Background(), int64(2), gomock.AssignableToTypeOf(time.Time{})).
+					Return(errors.New("expected LockUser error")).
 					Times(1)
 			},
 			wantStatusCode: http.StatusInternalServerError,
 			wantErr:        nil,
 		},
+		{
+			name: "error InsertAuditEvent",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().LockUser(context.Background(), int64(2), gomock.AssignableToTypeOf(time.Time{})).
+					Return(nil).
+					Times(1)
+
+				actorID := int64(1)
+				fields.Repository.EXPECT().InsertAuditEvent(context.Background(), repository.AuditEvent{
+					UserID:  2,
+					ActorID: &actorID,
+					Action:  repository.AuditUserLocked,
+				}).
+					Return(errors.New("expected InsertAuditEvent error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusOK,
+			wantErr:        nil,
+		},
 		{
 			name: "passed",
 			fields: func() fields {
@@ -400,26 +8895,35 @@ func Test_Server_Login(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"password": "Sawit@123"
-					}`)))
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
 					Return(repository.User{
-						ID:       1,
-						Password: "$2a$04$DcEZFEpGx1t/cpN1jHBjrO2wRLM317fSp.aU4uQtw3GUhbDMvXODe",
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
 					}, nil).
 					Times(1)
 
-				fields.Repository.EXPECT().IncreaseLoginCount(context.Background(), int64(1)).
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().LockUser(context.Background(), int64(2), gomock.AssignableToTypeOf(time.Time{})).
 					Return(nil).
 					Times(1)
+
+				mockAuditEvent(fields.Repository, 2, 1, repository.AuditUserLocked)
 			},
 			wantStatusCode: http.StatusOK,
 			wantErr:        nil,
@@ -428,16 +8932,18 @@ func Test_Server_Login(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Server{
+				config:     testConfig,
 				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
 			}
 			tt.mock(&tt.fields)
-			gotErr := s.Login(tt.args.ctx)
+			gotErr := s.LockUser(tt.args.ctx, tt.args.id)
 			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
-				t.Errorf("Server.Login() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+				t.Errorf("Server.LockUser() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
 			}
 			if gotErr == nil {
 				if tt.args.ctx.Response().Status != tt.wantStatusCode {
-					t.Errorf("Server.Register() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+					t.Errorf("Server.LockUser() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
 				}
 			}
 			tt.fields.mockCtrl.Finish()
@@ -445,13 +8951,15 @@ func Test_Server_Login(t *testing.T) {
 	}
 }
 
-func Test_Server_GetProfile(t *testing.T) {
+func Test_Server_UnlockUser(t *testing.T) {
 	type fields struct {
-		mockCtrl   *gomock.Controller
-		Repository *repository.MockRepositoryInterface
+		mockCtrl      *gomock.Controller
+		Repository    *repository.MockRepositoryInterface
+		LoginAttempts *repository.MemoryLoginAttemptRepository
 	}
 	type args struct {
 		ctx echo.Context
+		id  int64
 	}
 	tests := []struct {
 		name           string
@@ -466,46 +8974,95 @@ func Test_Server_GetProfile(t *testing.T) {
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
-					mockCtrl:   mockCtrl,
-					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
 				}
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					req, _ := http.NewRequest(http.MethodDelete, "url", nil)
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock:           func(fields *fields) {},
 			wantStatusCode: http.StatusForbidden,
 			wantErr:        nil,
 		},
 		{
-			name: "error GetUserByID",
+			name: "user not found",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
-					mockCtrl:   mockCtrl,
-					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
 				}
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodGet, "url", nil)
-					jwt, _ := generateJwtToken(repository.User{
+					req, _ := http.NewRequest(http.MethodDelete, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
 						ID: 1,
-					})
+					}, "session")
 					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
-					Return(repository.User{}, errors.New("expected GetUserByID error")).
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantErr:        nil,
+		},
+		{
+			name: "error UnlockUser",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodDelete, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID: 1,
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+				id: 2,
+			},
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().UnlockUser(context.Background(), int64(2)).
+					Return(errors.New("expected UnlockUser error")).
 					Times(1)
 			},
 			wantStatusCode: http.StatusInternalServerError,
@@ -516,26 +9073,44 @@ func Test_Server_GetProfile(t *testing.T) {
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
-					mockCtrl:   mockCtrl,
-					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					mockCtrl:      mockCtrl,
+					Repository:    repository.NewMockRepositoryInterface(mockCtrl),
+					LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
 				}
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodGet, "url", nil)
-					jwt, _ := generateJwtToken(repository.User{
+					req, _ := http.NewRequest(http.MethodDelete, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
 						ID: 1,
-					})
+					}, "session")
 					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
-					Return(repository.User{}, nil).
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().UnlockUser(context.Background(), int64(2)).
+					Return(nil).
 					Times(1)
+
+				fields.LoginAttempts.BlockLogin(context.Background(), "user:2", time.Now().Add(time.Minute))
+
+				mockAuditEvent(fields.Repository, 2, 1, repository.AuditUserUnlocked)
 			},
 			wantStatusCode: http.StatusOK,
 			wantErr:        nil,
@@ -544,16 +9119,19 @@ func Test_Server_GetProfile(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Server{
-				Repository: tt.fields.Repository,
+				config:        testConfig,
+				Repository:    tt.fields.Repository,
+				KeyRing:       testKeyRing,
+				LoginAttempts: tt.fields.LoginAttempts,
 			}
 			tt.mock(&tt.fields)
-			gotErr := s.GetProfile(tt.args.ctx)
+			gotErr := s.UnlockUser(tt.args.ctx, tt.args.id)
 			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
-				t.Errorf("Server.GetProfile() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+				t.Errorf("Server.UnlockUser() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
 			}
 			if gotErr == nil {
 				if tt.args.ctx.Response().Status != tt.wantStatusCode {
-					t.Errorf("Server.Register() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+					t.Errorf("Server.UnlockUser() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
 				}
 			}
 			tt.fields.mockCtrl.Finish()
@@ -561,13 +9139,14 @@ func Test_Server_GetProfile(t *testing.T) {
 	}
 }
 
-func Test_Server_UpdateProfile(t *testing.T) {
+func Test_Server_RevokeUserSessions(t *testing.T) {
 	type fields struct {
 		mockCtrl   *gomock.Controller
 		Repository *repository.MockRepositoryInterface
 	}
 	type args struct {
 		ctx echo.Context
+		id  int64
 	}
 	tests := []struct {
 		name           string
@@ -588,18 +9167,19 @@ func Test_Server_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", nil)
+					req, _ := http.NewRequest(http.MethodDelete, "url", nil)
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
 			mock:           func(fields *fields) {},
 			wantStatusCode: http.StatusForbidden,
 			wantErr:        nil,
 		},
 		{
-			name: "no request body",
+			name: "user not found",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -609,47 +9189,29 @@ func Test_Server_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(``)))
-					jwt, _ := generateJwtToken(repository.User{
+					req, _ := http.NewRequest(http.MethodDelete, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
 						ID: 1,
-					})
+					}, "session")
 					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
-			mock:           func(fields *fields) {},
-			wantStatusCode: http.StatusBadRequest,
-			wantErr:        nil,
-		},
-		{
-			name: "no params at all",
-			fields: func() fields {
-				mockCtrl := gomock.NewController(t)
-				return fields{
-					mockCtrl:   mockCtrl,
-					Repository: repository.NewMockRepositoryInterface(mockCtrl),
-				}
-			}(),
-			args: args{
-				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{}`)))
-					jwt, _ := generateJwtToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
-					res := httptest.NewRecorder()
-					c := echo.New().NewContext(req, res)
-					return c
-				}(),
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
 			},
-			mock:           func(fields *fields) {},
-			wantStatusCode: http.StatusBadRequest,
+			wantStatusCode: http.StatusNotFound,
 			wantErr:        nil,
 		},
 		{
-			name: "all params have empty value",
+			name: "error UpdateTokensValidAfter",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -659,25 +9221,39 @@ func Test_Server_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "",
-						"full_name": ""
-					}`)))
-					jwt, _ := generateJwtToken(repository.User{
+					req, _ := http.NewRequest(http.MethodDelete, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
 						ID: 1,
-					})
+					}, "session")
 					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
-			mock:           func(fields *fields) {},
-			wantStatusCode: http.StatusBadRequest,
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().UpdateTokensValidAfter(context.Background(), int64(2), gomock.AssignableToTypeOf(time.Time{})).
+					Return(errors.New("expected UpdateTokensValidAfter error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
 			wantErr:        nil,
 		},
 		{
-			name: "all params have empty value",
+			name: "error RevokeRefreshTokensByUserID",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -687,25 +9263,43 @@ func Test_Server_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "",
-						"full_name": ""
-					}`)))
-					jwt, _ := generateJwtToken(repository.User{
+					req, _ := http.NewRequest(http.MethodDelete, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
 						ID: 1,
-					})
+					}, "session")
 					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
-			mock:           func(fields *fields) {},
-			wantStatusCode: http.StatusBadRequest,
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().UpdateTokensValidAfter(context.Background(), int64(2), gomock.AssignableToTypeOf(time.Time{})).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeRefreshTokensByUserID(context.Background(), int64(2)).
+					Return(errors.New("expected RevokeRefreshTokensByUserID error")).
+					Times(1)
+			},
+			wantStatusCode: http.StatusInternalServerError,
 			wantErr:        nil,
 		},
 		{
-			name: "invalid phone number",
+			name: "passed",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -715,25 +9309,85 @@ func Test_Server_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "123",
-						"full_name": "Sawit Pro 1"
-					}`)))
-					jwt, _ := generateJwtToken(repository.User{
+					req, _ := http.NewRequest(http.MethodDelete, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
 						ID: 1,
-					})
+					}, "session")
 					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				id: 2,
 			},
-			mock:           func(fields *fields) {},
-			wantStatusCode: http.StatusBadRequest,
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(2)).
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+628123456789",
+						FullName:    "Sawit Pro",
+					}, nil).
+					Times(1)
+
+				mockAdminActor(fields.Repository, 1)
+
+				fields.Repository.EXPECT().UpdateTokensValidAfter(context.Background(), int64(2), gomock.AssignableToTypeOf(time.Time{})).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeRefreshTokensByUserID(context.Background(), int64(2)).
+					Return(nil).
+					Times(1)
+
+				mockAuditEvent(fields.Repository, 2, 1, repository.AuditSessionsRevoked)
+			},
+			wantStatusCode: http.StatusOK,
 			wantErr:        nil,
 		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotErr := s.RevokeUserSessions(tt.args.ctx, tt.args.id)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.RevokeUserSessions() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotErr == nil {
+				if tt.args.ctx.Response().Status != tt.wantStatusCode {
+					t.Errorf("Server.RevokeUserSessions() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Server_SearchUsers(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx    echo.Context
+		params generated.SearchUsersParams
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantStatusCode int
+		wantErr        error
+	}{
 		{
-			name: "error GetUserByPhoneNumber",
+			name: "invalid authorization",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -743,29 +9397,19 @@ func Test_Server_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"full_name": "Sawit Pro 1"
-					}`)))
-					jwt, _ := generateJwtToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				params: generated.SearchUsersParams{},
 			},
-			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
-					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
-					Times(1)
-			},
-			wantStatusCode: http.StatusInternalServerError,
+			mock:           func(fields *fields) {},
+			wantStatusCode: http.StatusForbidden,
 			wantErr:        nil,
 		},
 		{
-			name: "phone number is already registered",
+			name: "invalid phone number",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -775,31 +9419,27 @@ func Test_Server_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"full_name": "Sawit Pro 1"
-					}`)))
-					jwt, _ := generateJwtToken(repository.User{
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
 						ID: 1,
-					})
+					}, "session")
 					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				params: generated.SearchUsersParams{
+					PhoneNumber: func() *string { phoneNumber := "0812"; return &phoneNumber }(),
+				},
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
-					Return(repository.User{
-						ID: 2,
-					}, nil).
-					Times(1)
+				mockActiveSession(fields.Repository, 1)
 			},
-			wantStatusCode: http.StatusConflict,
+			wantStatusCode: http.StatusBadRequest,
 			wantErr:        nil,
 		},
 		{
-			name: "invalid full name",
+			name: "invalid cursor",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -809,29 +9449,34 @@ func Test_Server_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"full_name": "SP"
-					}`)))
-					jwt, _ := generateJwtToken(repository.User{
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
 						ID: 1,
-					})
+					}, "session")
 					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				params: generated.SearchUsersParams{
+					Cursor: func() *string { cursor := "not a cursor"; return &cursor }(),
+				},
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
-					Return(repository.User{}, nil).
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().SearchUsers(context.Background(), repository.UserSearch{
+					Cursor: "not a cursor",
+					Limit:  20,
+				}).
+					Return(nil, "", repository.ErrInvalidCursor).
 					Times(1)
 			},
 			wantStatusCode: http.StatusBadRequest,
 			wantErr:        nil,
 		},
 		{
-			name: "error UpdateUser",
+			name: "error SearchUsers",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -841,31 +9486,24 @@ func Test_Server_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"full_name": "Sawit Pro 1"
-					}`)))
-					jwt, _ := generateJwtToken(repository.User{
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
 						ID: 1,
-					})
+					}, "session")
 					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				params: generated.SearchUsersParams{},
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
-					Return(repository.User{}, nil).
-					Times(1)
+				mockActiveSession(fields.Repository, 1)
 
-				fields.Repository.EXPECT().UpdateUser(context.Background(),
-					repository.User{
-						ID:          1,
-						PhoneNumber: "+628223344551",
-						FullName:    "Sawit Pro 1",
-					}).
-					Return(errors.New("expected UpdateUser error")).
+				fields.Repository.EXPECT().SearchUsers(context.Background(), repository.UserSearch{
+					Limit: 20,
+				}).
+					Return(nil, "", errors.New("expected SearchUsers error")).
 					Times(1)
 			},
 			wantStatusCode: http.StatusInternalServerError,
@@ -882,31 +9520,40 @@ func Test_Server_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+628223344551",
-						"full_name": "Sawit Pro 1"
-					}`)))
-					jwt, _ := generateJwtToken(repository.User{
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
 						ID: 1,
-					})
+					}, "session")
 					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
+				params: generated.SearchUsersParams{
+					PhoneNumber: func() *string { phoneNumber := "+62 0822"; return &phoneNumber }(),
+					FullName:    func() *string { fullName := " sawit "; return &fullName }(),
+					Sort:        func() *generated.SearchUsersParamsSort { sort := generated.FullName; return &sort }(),
+					Order:       func() *generated.SearchUsersParamsOrder { order := generated.Desc; return &order }(),
+					Limit:       func() *int { limit := 1; return &limit }(),
+				},
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344551").
-					Return(repository.User{}, nil).
-					Times(1)
+				mockActiveSession(fields.Repository, 1)
 
-				fields.Repository.EXPECT().UpdateUser(context.Background(),
-					repository.User{
-						ID:          1,
-						PhoneNumber: "+628223344551",
-						FullName:    "Sawit Pro 1",
-					}).
-					Return(nil).
+				fields.Repository.EXPECT().SearchUsers(context.Background(), repository.UserSearch{
+					PhoneNumberPrefix: "+62822",
+					FullName:          "sawit",
+					Sort:              repository.UserSortFullName,
+					Descending:        true,
+					Limit:             1,
+				}).
+					Return([]repository.User{
+						{
+							ID:          2,
+							PhoneNumber: "+628223344551",
+							FullName:    "Sawit Pro",
+						},
+					}, "next", nil).
 					Times(1)
 			},
 			wantStatusCode: http.StatusOK,
@@ -916,16 +9563,18 @@ func Test_Server_UpdateProfile(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Server{
+				config:     testConfig,
 				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
 			}
 			tt.mock(&tt.fields)
-			gotErr := s.UpdateProfile(tt.args.ctx)
+			gotErr := s.SearchUsers(tt.args.ctx, tt.args.params)
 			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
-				t.Errorf("Server.UpdateProfile() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+				t.Errorf("Server.SearchUsers() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
 			}
 			if gotErr == nil {
 				if tt.args.ctx.Response().Status != tt.wantStatusCode {
-					t.Errorf("Server.Register() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
+					t.Errorf("Server.SearchUsers() gotStatusCode = %d, wantStatusCode = %d", tt.args.ctx.Response().Status, tt.wantStatusCode)
 				}
 			}
 			tt.fields.mockCtrl.Finish()
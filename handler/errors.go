@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/fenky-ng/swt-pro/constant"
+	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/fenky-ng/swt-pro/repository"
+)
+
+// repositoryErrors maps the repository errors a handler does not act on
+// itself to the response they are answered with. Any other error is a
+// system error.
+var repositoryErrors = []struct {
+	err        error
+	statusCode int
+	errorCode  int
+	messageID  string
+}{
+	{
+		err:        repository.ErrNotFound,
+		statusCode: http.StatusNotFound,
+		errorCode:  constant.ErrorCodeNotFound,
+		messageID:  i18n.NotFound,
+	},
+	{
+		err:        repository.ErrConflict,
+		statusCode: http.StatusConflict,
+		errorCode:  constant.ErrorCodeConflict,
+		messageID:  i18n.Conflict,
+	},
+	{
+		err:        repository.ErrUnavailable,
+		statusCode: http.StatusServiceUnavailable,
+		errorCode:  constant.ErrorCodeUnavailable,
+		messageID:  i18n.Unavailable,
+	},
+}
+
+// repositoryErrorStatus returns the status code answering a repository
+// error.
+func repositoryErrorStatus(err error) int {
+	for _, repositoryError := range repositoryErrors {
+		if errors.Is(err, repositoryError.err) {
+			return repositoryError.statusCode
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// repositoryErrorHeader returns the response header answering a repository
+// error.
+func repositoryErrorHeader(ctx context.Context, err error) generated.ResponseHeader {
+	for _, repositoryError := range repositoryErrors {
+		if errors.Is(err, repositoryError.err) {
+			return generateResponseHeader(ctx, repositoryError.errorCode, []i18n.Message{i18n.M(repositoryError.messageID)}, false)
+		}
+	}
+	return generateResponseHeader(ctx, constant.ErrorCodeDatabase, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/fenky-ng/swt-pro/constant"
+	"github.com/fenky-ng/swt-pro/repository"
+)
+
+func Test_repositoryError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatusCode int
+		wantErrorCode  int
+	}{
+		{
+			name:           "not found",
+			err:            repository.ErrNotFound,
+			wantStatusCode: http.StatusNotFound,
+			wantErrorCode:  constant.ErrorCodeNotFound,
+		},
+		{
+			name: "unique violation",
+			err: &repository.UniqueViolationError{
+				Constraint: "user_phone_number_unique",
+				Err:        errors.New("expected error"),
+			},
+			wantStatusCode: http.StatusConflict,
+			wantErrorCode:  constant.ErrorCodeConflict,
+		},
+		{
+			name:           "unavailable",
+			err:            fmt.Errorf("%w: %w", repository.ErrUnavailable, errors.New("expected error")),
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantErrorCode:  constant.ErrorCodeUnavailable,
+		},
+		{
+			name:           "other error",
+			err:            errors.New("expected error"),
+			wantStatusCode: http.StatusInternalServerError,
+			wantErrorCode:  constant.ErrorCodeDatabase,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := repositoryErrorStatus(tt.err); got != tt.wantStatusCode {
+				t.Errorf("repositoryErrorStatus() got = %d, want = %d", got, tt.wantStatusCode)
+			}
+			header := repositoryErrorHeader(context.Background(), tt.err)
+			if header.ErrorCode == nil || *header.ErrorCode != tt.wantErrorCode || header.Successful != nil {
+				t.Errorf("repositoryErrorHeader() got = %+v, wantErrorCode = %d", header, tt.wantErrorCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fenky-ng/swt-pro/config"
+	"github.com/fenky-ng/swt-pro/constant"
+	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/labstack/echo/v4"
+)
+
+// loginBlock describes why and for how long login attempts are refused.
+type loginBlock struct {
+	locked     bool
+	retryAfter time.Duration
+}
+
+func loginAttemptIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// backoffDelay returns how long to block after failedCount failures, zero
+// when failedCount is below threshold.
+func backoffDelay(cfg config.LoginThrottleConfig, failedCount int, threshold int) time.Duration {
+	if failedCount < threshold {
+		return 0
+	}
+	delay := cfg.BackoffBaseDelay
+	for i := threshold; i < failedCount && delay < cfg.BackoffMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > cfg.BackoffMaxDelay {
+		delay = cfg.BackoffMaxDelay
+	}
+	return delay
+}
+
+// getLoginBlock returns the block in force for key, if any. A block is a
+// lockout when the failures behind it reached the lockout threshold.
+func getLoginBlock(ctx context.Context, s *Server, key string, lockout bool, now time.Time) (block *loginBlock, err error) {
+	loginAttempt, err := s.LoginAttempts.GetLoginAttempt(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if loginAttempt.BlockedUntil == nil || !loginAttempt.BlockedUntil.After(now) {
+		return nil, nil
+	}
+	return &loginBlock{
+		locked:     lockout && loginAttempt.FailedCount >= s.config.LoginThrottle.LockoutThreshold,
+		retryAfter: loginAttempt.BlockedUntil.Sub(now),
+	}, nil
+}
+
+// recordFailedLogin counts a failure for key and blocks further attempts once
+// a threshold is reached. lockout tells whether key may be locked out or only
+// backed off.
+func recordFailedLogin(ctx context.Context, s *Server, key string, lockout bool, now time.Time) error {
+	cfg := s.config.LoginThrottle
+
+	failedCount, err := s.LoginAttempts.IncreaseFailedLoginCount(ctx, key, now.Add(-cfg.Window), now)
+	if err != nil {
+		return err
+	}
+
+	var delay time.Duration
+	if lockout && failedCount >= cfg.LockoutThreshold {
+		delay = cfg.LockoutDuration
+	} else if lockout {
+		delay = backoffDelay(cfg, failedCount, cfg.BackoffThreshold)
+	} else {
+		delay = backoffDelay(cfg, failedCount, cfg.IPBackoffThreshold)
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	return s.LoginAttempts.BlockLogin(ctx, key, now.Add(delay))
+}
+
+// respondWithLoginBlock writes the response for a refused login attempt along
+// with a Retry-After header.
+func respondWithLoginBlock(ctx echo.Context, s *Server, block *loginBlock) error {
+	var response generated.LoginResponse
+
+	retryAfter := int(math.Ceil(block.retryAfter.Seconds()))
+	ctx.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+	if block.locked {
+		s.metrics.loginFailed(loginReasonLocked)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeAccountLocked, []i18n.Message{i18n.M(i18n.LoginAccountLocked)}, false)
+		return ctx.JSON(http.StatusLocked, response)
+	}
+
+	s.metrics.loginFailed(loginReasonThrottled)
+	response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeLoginThrottled, []i18n.Message{i18n.M(i18n.LoginThrottled)}, false)
+	return ctx.JSON(http.StatusTooManyRequests, response)
+}
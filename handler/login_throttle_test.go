@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fenky-ng/swt-pro/config"
+	"github.com/fenky-ng/swt-pro/repository"
+)
+
+func Test_backoffDelay(t *testing.T) {
+	cfg := config.LoginThrottleConfig{
+		BackoffBaseDelay: time.Second,
+		BackoffMaxDelay:  10 * time.Second,
+	}
+	tests := []struct {
+		name        string
+		failedCount int
+		threshold   int
+		wantRes     time.Duration
+	}{
+		{
+			name:        "below threshold",
+			failedCount: 2,
+			threshold:   3,
+			wantRes:     0,
+		},
+		{
+			name:        "at threshold",
+			failedCount: 3,
+			threshold:   3,
+			wantRes:     time.Second,
+		},
+		{
+			name:        "doubled per extra failure",
+			failedCount: 5,
+			threshold:   3,
+			wantRes:     4 * time.Second,
+		},
+		{
+			name:        "capped",
+			failedCount: 100,
+			threshold:   3,
+			wantRes:     10 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRes := backoffDelay(cfg, tt.failedCount, tt.threshold)
+			if gotRes != tt.wantRes {
+				t.Errorf("backoffDelay() gotRes = %s, wantRes = %s", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_recordFailedLogin(t *testing.T) {
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	cfg := config.LoginThrottleConfig{
+		Window:             time.Hour,
+		BackoffThreshold:   2,
+		BackoffBaseDelay:   time.Second,
+		BackoffMaxDelay:    time.Minute,
+		LockoutThreshold:   4,
+		LockoutDuration:    15 * time.Minute,
+		IPBackoffThreshold: 3,
+	}
+	type args struct {
+		key      string
+		lockout  bool
+		failures int
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantBlock *loginBlock
+	}{
+		{
+			name: "user below threshold",
+			args: args{
+				key:      "user:1",
+				lockout:  true,
+				failures: 1,
+			},
+			wantBlock: nil,
+		},
+		{
+			name: "user backed off",
+			args: args{
+				key:      "user:1",
+				lockout:  true,
+				failures: 3,
+			},
+			wantBlock: &loginBlock{
+				retryAfter: 2 * time.Second,
+			},
+		},
+		{
+			name: "user locked",
+			args: args{
+				key:      "user:1",
+				lockout:  true,
+				failures: 4,
+			},
+			wantBlock: &loginBlock{
+				locked:     true,
+				retryAfter: 15 * time.Minute,
+			},
+		},
+		{
+			name: "ip below threshold",
+			args: args{
+				key:      "ip:10.0.0.1",
+				failures: 2,
+			},
+			wantBlock: nil,
+		},
+		{
+			name: "ip is never locked",
+			args: args{
+				key:      "ip:10.0.0.1",
+				failures: 10,
+			},
+			wantBlock: &loginBlock{
+				retryAfter: time.Minute,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+				config: config.Config{
+					LoginThrottle: cfg,
+				},
+			}
+			for i := 0; i < tt.args.failures; i++ {
+				err := recordFailedLogin(context.Background(), s, tt.args.key, tt.args.lockout, now)
+				if err != nil {
+					t.Fatalf("recordFailedLogin() gotErr = %s", err.Error())
+				}
+			}
+			gotBlock, gotErr := getLoginBlock(context.Background(), s, tt.args.key, tt.args.lockout, now)
+			if gotErr != nil {
+				t.Fatalf("getLoginBlock() gotErr = %s", gotErr.Error())
+			}
+			if !reflect.DeepEqual(gotBlock, tt.wantBlock) {
+				t.Errorf("getLoginBlock() gotBlock = %+v, wantBlock = %+v", gotBlock, tt.wantBlock)
+			}
+
+			// blocks are lifted once they run out
+			gotBlock, _ = getLoginBlock(context.Background(), s, tt.args.key, tt.args.lockout, now.Add(cfg.LockoutDuration))
+			if gotBlock != nil {
+				t.Errorf("getLoginBlock() gotBlock = %+v after it expired", gotBlock)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"github.com/fenky-ng/swt-pro/metrics"
+)
+
+const (
+	loginResultSuccess     = "success"
+	loginResultMfaRequired = "mfa_required"
+	loginResultFailure     = "failure"
+
+	loginReasonUnknownPhoneNumber = "unknown_phone_number"
+	loginReasonWrongPassword      = "wrong_password"
+	loginReasonPhoneNotVerified   = "phone_not_verified"
+	loginReasonThrottled          = "throttled"
+	loginReasonLocked             = "locked"
+	loginReasonLockedBySupport    = "locked_by_support"
+	loginReasonPasswordExpired    = "password_expired"
+	loginReasonInvalidMfaToken    = "invalid_mfa_token"
+	loginReasonInvalidCode        = "invalid_code"
+
+	sessionRejectionMissing     = "missing"
+	sessionRejectionInvalid     = "invalid"
+	sessionRejectionExpired     = "expired"
+	sessionRejectionPurpose     = "not_a_session"
+	sessionRejectionRevoked     = "revoked"
+	sessionRejectionSystemError = "system_error"
+
+	mfaActionConfirm = "confirm"
+	mfaActionDisable = "disable"
+)
+
+// serverMetrics holds the business counters of the handlers. Its counters
+// are nil, and ignore every call, when the server has no registry.
+type serverMetrics struct {
+	registrations     *metrics.Counter
+	logins            *metrics.Counter
+	sessionRejections *metrics.Counter
+	mfaRejections     *metrics.Counter
+}
+
+func newServerMetrics(registry *metrics.Registry) serverMetrics {
+	if registry == nil {
+		return serverMetrics{}
+	}
+	return serverMetrics{
+		registrations: registry.NewCounter(
+			"auth_registrations_total",
+			"Number of users registered.",
+		),
+		logins: registry.NewCounter(
+			"auth_logins_total",
+			"Number of login attempts by result, success, mfa_required or failure, and reason of failure.",
+			"result", "reason",
+		),
+		sessionRejections: registry.NewCounter(
+			"auth_session_rejections_total",
+			"Number of requests refused for lack of a valid session, by reason.",
+			"reason",
+		),
+		mfaRejections: registry.NewCounter(
+			"auth_mfa_rejections_total",
+			"Number of two-factor codes refused outside of login, by action, confirm or disable.",
+			"action",
+		),
+	}
+}
+
+func (m serverMetrics) loginFailed(reason string) {
+	m.logins.Inc(loginResultFailure, reason)
+}
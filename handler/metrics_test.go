@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fenky-ng/swt-pro/metrics"
+)
+
+func writeTestMetrics(t *testing.T, registry *metrics.Registry) string {
+	var buf bytes.Buffer
+	err := registry.Write(&buf)
+	if err != nil {
+		t.Fatalf("Registry.Write() error = %s", err.Error())
+	}
+	return buf.String()
+}
+
+func Test_newServerMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := newServerMetrics(registry)
+	m.registrations.Inc()
+	m.logins.Inc(loginResultSuccess, "")
+	m.loginFailed(loginReasonWrongPassword)
+	m.loginFailed(loginReasonWrongPassword)
+	m.mfaRejections.Inc(mfaActionDisable)
+
+	want := `# HELP auth_logins_total Number of login attempts by result, success, mfa_required or failure, and reason of failure.
+# TYPE auth_logins_total counter
+auth_logins_total{result="failure",reason="wrong_password"} 2
+auth_logins_total{result="success",reason=""} 1
+# HELP auth_mfa_rejections_total Number of two-factor codes refused outside of login, by action, confirm or disable.
+# TYPE auth_mfa_rejections_total counter
+auth_mfa_rejections_total{action="disable"} 1
+# HELP auth_registrations_total Number of users registered.
+# TYPE auth_registrations_total counter
+auth_registrations_total 1
+# HELP auth_session_rejections_total Number of requests refused for lack of a valid session, by reason.
+# TYPE auth_session_rejections_total counter
+`
+	if got := writeTestMetrics(t, registry); got != want {
+		t.Errorf("newServerMetrics() got =\n%s\nwant =\n%s", got, want)
+	}
+
+	// without a registry every counter is a no-op
+	m = newServerMetrics(nil)
+	m.registrations.Inc()
+	m.loginFailed(loginReasonWrongPassword)
+	m.mfaRejections.Inc(mfaActionConfirm)
+}
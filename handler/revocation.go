@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"sync"
+	"time"
+)
+
+// revocationCache keeps recently looked up revocation state in process so
+// getSessionClaims does not have to query the database on every request.
+// Revoked tokens stay cached until they expire since a revocation is never
+// undone; everything else is only trusted for the configured ttl so other
+// instances pick up logouts quickly.
+type revocationCache struct {
+	mu          sync.RWMutex
+	ttl         time.Duration
+	tokens      map[string]revokedTokenEntry
+	validAfters map[int64]tokensValidAfterEntry
+	lastEvicted time.Time
+}
+
+type revokedTokenEntry struct {
+	revoked     bool
+	cachedUntil time.Time
+}
+
+type tokensValidAfterEntry struct {
+	validAfter  time.Time
+	cachedUntil time.Time
+}
+
+func newRevocationCache(ttl time.Duration) *revocationCache {
+	return &revocationCache{
+		ttl:         ttl,
+		tokens:      make(map[string]revokedTokenEntry),
+		validAfters: make(map[int64]tokensValidAfterEntry),
+	}
+}
+
+func (c *revocationCache) getTokenRevoked(jti string, now time.Time) (revoked bool, found bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.tokens[jti]
+	if !ok || now.After(entry.cachedUntil) {
+		return false, false
+	}
+	return entry.revoked, true
+}
+
+func (c *revocationCache) setTokenRevoked(jti string, revoked bool, expiresAt time.Time, now time.Time) {
+	if c == nil {
+		return
+	}
+	cachedUntil := now.Add(c.ttl)
+	if revoked {
+		cachedUntil = expiresAt
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictExpired(now)
+	c.tokens[jti] = revokedTokenEntry{
+		revoked:     revoked,
+		cachedUntil: cachedUntil,
+	}
+}
+
+func (c *revocationCache) getTokensValidAfter(userID int64, now time.Time) (validAfter time.Time, found bool) {
+	if c == nil {
+		return validAfter, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.validAfters[userID]
+	if !ok || now.After(entry.cachedUntil) {
+		return validAfter, false
+	}
+	return entry.validAfter, true
+}
+
+func (c *revocationCache) setTokensValidAfter(userID int64, validAfter time.Time, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictExpired(now)
+	c.validAfters[userID] = tokensValidAfterEntry{
+		validAfter:  validAfter,
+		cachedUntil: now.Add(c.ttl),
+	}
+}
+
+// evictExpired sweeps stale entries at most once per ttl. It must be called
+// with the write lock held.
+func (c *revocationCache) evictExpired(now time.Time) {
+	if now.Sub(c.lastEvicted) < c.ttl {
+		return
+	}
+	c.lastEvicted = now
+	for jti, entry := range c.tokens {
+		if now.After(entry.cachedUntil) {
+			delete(c.tokens, jti)
+		}
+	}
+	for userID, entry := range c.validAfters {
+		if now.After(entry.cachedUntil) {
+			delete(c.validAfters, userID)
+		}
+	}
+}
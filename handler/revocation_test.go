@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_revocationCache_tokenRevoked(t *testing.T) {
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type args struct {
+		jti       string
+		revoked   bool
+		expiresAt time.Time
+		lookupAt  time.Time
+	}
+	tests := []struct {
+		name        string
+		cache       *revocationCache
+		args        args
+		wantRevoked bool
+		wantFound   bool
+	}{
+		{
+			name:  "nil cache",
+			cache: nil,
+			args: args{
+				jti:       "jti",
+				revoked:   true,
+				expiresAt: now.Add(time.Hour),
+				lookupAt:  now,
+			},
+			wantRevoked: false,
+			wantFound:   false,
+		},
+		{
+			name:  "revoked token is cached until it expires",
+			cache: newRevocationCache(time.Minute),
+			args: args{
+				jti:       "jti",
+				revoked:   true,
+				expiresAt: now.Add(time.Hour),
+				lookupAt:  now.Add(30 * time.Minute),
+			},
+			wantRevoked: true,
+			wantFound:   true,
+		},
+		{
+			name:  "active token is cached for ttl",
+			cache: newRevocationCache(time.Minute),
+			args: args{
+				jti:       "jti",
+				revoked:   false,
+				expiresAt: now.Add(time.Hour),
+				lookupAt:  now.Add(30 * time.Second),
+			},
+			wantRevoked: false,
+			wantFound:   true,
+		},
+		{
+			name:  "active token is stale after ttl",
+			cache: newRevocationCache(time.Minute),
+			args: args{
+				jti:       "jti",
+				revoked:   false,
+				expiresAt: now.Add(time.Hour),
+				lookupAt:  now.Add(2 * time.Minute),
+			},
+			wantRevoked: false,
+			wantFound:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cache.setTokenRevoked(tt.args.jti, tt.args.revoked, tt.args.expiresAt, now)
+			gotRevoked, gotFound := tt.cache.getTokenRevoked(tt.args.jti, tt.args.lookupAt)
+			if gotRevoked != tt.wantRevoked || gotFound != tt.wantFound {
+				t.Errorf("revocationCache.getTokenRevoked() gotRevoked = %t, gotFound = %t, wantRevoked = %t, wantFound = %t", gotRevoked, gotFound, tt.wantRevoked, tt.wantFound)
+			}
+		})
+	}
+}
+
+func Test_revocationCache_tokensValidAfter(t *testing.T) {
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type args struct {
+		userID     int64
+		validAfter time.Time
+		lookupAt   time.Time
+	}
+	tests := []struct {
+		name           string
+		cache          *revocationCache
+		args           args
+		wantValidAfter time.Time
+		wantFound      bool
+	}{
+		{
+			name:  "nil cache",
+			cache: nil,
+			args: args{
+				userID:     1,
+				validAfter: now,
+				lookupAt:   now,
+			},
+			wantValidAfter: time.Time{},
+			wantFound:      false,
+		},
+		{
+			name:  "cached for ttl",
+			cache: newRevocationCache(time.Minute),
+			args: args{
+				userID:     1,
+				validAfter: now,
+				lookupAt:   now.Add(30 * time.Second),
+			},
+			wantValidAfter: now,
+			wantFound:      true,
+		},
+		{
+			name:  "stale after ttl",
+			cache: newRevocationCache(time.Minute),
+			args: args{
+				userID:     1,
+				validAfter: now,
+				lookupAt:   now.Add(2 * time.Minute),
+			},
+			wantValidAfter: time.Time{},
+			wantFound:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cache.setTokensValidAfter(tt.args.userID, tt.args.validAfter, now)
+			gotValidAfter, gotFound := tt.cache.getTokensValidAfter(tt.args.userID, tt.args.lookupAt)
+			if !gotValidAfter.Equal(tt.wantValidAfter) || gotFound != tt.wantFound {
+				t.Errorf("revocationCache.getTokensValidAfter() gotValidAfter = %s, gotFound = %t, wantValidAfter = %s, wantFound = %t", gotValidAfter, gotFound, tt.wantValidAfter, tt.wantFound)
+			}
+		})
+	}
+}
@@ -1,21 +1,73 @@
 package handler
 
 import (
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/fenky-ng/swt-pro/config"
+	"github.com/fenky-ng/swt-pro/export"
+	"github.com/fenky-ng/swt-pro/keyring"
+	"github.com/fenky-ng/swt-pro/metrics"
+	"github.com/fenky-ng/swt-pro/notifier"
 	"github.com/fenky-ng/swt-pro/repository"
+	"github.com/fenky-ng/swt-pro/totp"
 )
 
 type Server struct {
-	Repository repository.RepositoryInterface
+	Repository        repository.RepositoryInterface
+	KeyRing           *keyring.KeyRing
+	Notifier          notifier.Notifier
+	LoginAttempts     repository.LoginAttemptRepositoryInterface
+	Logger            *slog.Logger
+	DataExportTrigger export.Trigger
+	config            config.Config
+	revocationCache   *revocationCache
+	totp              *totp.TOTP
+	metrics           serverMetrics
+	shuttingDown      atomic.Bool
 }
 
 type NewServerOptions struct {
-	Repository repository.RepositoryInterface
+	Repository    repository.RepositoryInterface
+	KeyRing       *keyring.KeyRing
+	Notifier      notifier.Notifier
+	LoginAttempts repository.LoginAttemptRepositoryInterface
+	Logger        *slog.Logger
+	Config        config.Config
+	// Metrics receives the business counters, none are kept when nil
+	Metrics *metrics.Registry
+	// DataExportTrigger is notified of every export queued, it may be nil
+	// when the exports are built elsewhere
+	DataExportTrigger export.Trigger
 }
 
 func NewServer(
 	opts NewServerOptions,
 ) *Server {
 	return &Server{
-		Repository: opts.Repository,
+		Repository:        opts.Repository,
+		KeyRing:           opts.KeyRing,
+		Notifier:          opts.Notifier,
+		LoginAttempts:     opts.LoginAttempts,
+		Logger:            opts.Logger,
+		DataExportTrigger: opts.DataExportTrigger,
+		config:            opts.Config,
+		revocationCache:   newRevocationCache(opts.Config.Auth.RevocationCacheTTL),
+		totp:              totp.New(),
+		metrics:           newServerMetrics(opts.Metrics),
+	}
+}
+
+// BeginShutdown makes Readyz fail from now on, so load balancers stop
+// routing new requests here while the ones in flight drain.
+func (s *Server) BeginShutdown() {
+	s.shuttingDown.Store(true)
+}
+
+// log returns the logger of the server, the default one when none is set.
+func (s *Server) log() *slog.Logger {
+	if s.Logger == nil {
+		return slog.Default()
 	}
+	return s.Logger
 }
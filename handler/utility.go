@@ -1,56 +1,49 @@
 package handler
 
 import (
-	"crypto/rsa"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/fenky-ng/swt-pro/constant"
 	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/fenky-ng/swt-pro/keyring"
+	"github.com/fenky-ng/swt-pro/logging"
 	"github.com/fenky-ng/swt-pro/model"
 	"github.com/fenky-ng/swt-pro/repository"
+	"github.com/fenky-ng/swt-pro/tracing"
 	jwt "github.com/golang-jwt/jwt/v4"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/gommon/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var (
-	signKey   *rsa.PrivateKey
-	verifyKey *rsa.PublicKey
-)
-
-func getSignKey() *rsa.PrivateKey {
-	if signKey == nil {
-		key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(constant.PrivateRSA))
-		if err != nil {
-			log.Errorf("ParseRSAPrivateKeyFromPEM error: %s", err.Error())
-			panic(err)
-		}
-		signKey = key
-	}
-	return signKey
-}
+func hashAndSalt(ctx context.Context, input string, cost int) (salt string, err error) {
+	_, span := tracing.Tracer().Start(ctx, "bcrypt.GenerateFromPassword", trace.WithAttributes(
+		attribute.Int("bcrypt.cost", cost),
+	))
+	defer span.End()
 
-func getVerifyKey() *rsa.PublicKey {
-	if verifyKey == nil {
-		key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(constant.PublicRSA))
-		if err != nil {
-			log.Errorf("ParseRSAPublicKeyFromPEM error: %s", err.Error())
-			panic(err)
-		}
-		verifyKey = key
-	}
-	return verifyKey
-}
-
-func hashAndSalt(input string) (salt string, err error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(input), bcrypt.MinCost)
+	hash, err := bcrypt.GenerateFromPassword([]byte(input), cost)
 	return string(hash), err
 }
 
-func comparePasswords(hashedPassword string, plainPassword string) bool {
+func comparePasswords(ctx context.Context, hashedPassword string, plainPassword string) bool {
+	_, span := tracing.Tracer().Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+
 	hashedPasswordBytes := []byte(hashedPassword)
 	plainPasswordBytes := []byte(plainPassword)
 
@@ -62,59 +55,630 @@ func comparePasswords(hashedPassword string, plainPassword string) bool {
 	return true
 }
 
-func generateJwtToken(user repository.User) (signedToken string, err error) {
-	t := jwt.New(jwt.GetSigningMethod("RS256"))
+func generateJwtToken(ctx context.Context, s *Server, user repository.User, sessionID string) (signedToken string, err error) {
+	now := time.Now()
+	return signJwtToken(ctx, s.KeyRing, model.SessionClaims{
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(s.config.Auth.AccessTokenTTL).Unix(),
+		},
+		IssuedAtNano: now.UnixNano(),
+		UserID:       user.ID,
+		PhoneNumber:  user.PhoneNumber,
+		SessionID:    sessionID,
+		Role:         user.Role,
+	})
+}
 
-	t.Claims = model.SessionClaims{
+// generateMfaChallengeToken returns a short-lived token proving the password
+// was checked. It is only accepted by LoginMfa, never as a session.
+func generateMfaChallengeToken(ctx context.Context, s *Server, user repository.User) (signedToken string, err error) {
+	now := time.Now()
+	return signJwtToken(ctx, s.KeyRing, model.SessionClaims{
 		StandardClaims: jwt.StandardClaims{
-			Issuer:    constant.ApplicationName,
-			ExpiresAt: time.Now().Add(constant.LoginExpirationDuration).Unix(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(s.config.Auth.MfaChallengeTTL).Unix(),
 		},
 		UserID:      user.ID,
 		PhoneNumber: user.PhoneNumber,
+		Purpose:     constant.TokenPurposeMfaChallenge,
+	})
+}
+
+// signJwtToken stamps the issuer, a random jti and the kid of the active key
+// on the claims before signing them.
+func signJwtToken(ctx context.Context, kr *keyring.KeyRing, claims model.SessionClaims) (signedToken string, err error) {
+	_, span := tracing.Tracer().Start(ctx, "jwt.Sign")
+	defer span.End()
+
+	jti, err := generateRandomToken(16)
+	if err != nil {
+		return signedToken, err
+	}
+	claims.Id = jti
+	claims.Issuer = constant.ApplicationName
+
+	signingKey := kr.SigningKey()
+
+	t := jwt.New(jwt.GetSigningMethod("RS256"))
+	t.Header["kid"] = signingKey.ID
+	span.SetAttributes(attribute.String("jwt.kid", signingKey.ID))
+	t.Claims = claims
+
+	return t.SignedString(signingKey.PrivateKey)
+}
+
+func generateRandomToken(size int) (token string, err error) {
+	b := make([]byte, size)
+	_, err = rand.Read(b)
+	if err != nil {
+		return token, err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// generateNumericCode returns a uniformly random code made of the given
+// number of decimal digits, suitable for typing in from an SMS.
+func generateNumericCode(length int) (code string, err error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return code, err
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken creates a new opaque refresh token within the given token
+// family and stores its hash. Only the plain token is returned to the client.
+func issueRefreshToken(
+	ctx context.Context,
+	s *Server,
+	userID int64,
+	familyID string,
+) (string, error) {
+	refreshToken, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.Repository.InsertRefreshToken(ctx, repository.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.config.Auth.RefreshTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+// sendPhoneVerificationCode issues a new one-time code for the given phone
+// number and delivers it by SMS, unless one was sent to it moments ago.
+func sendPhoneVerificationCode(
+	ctx context.Context,
+	s *Server,
+	userID int64,
+	phoneNumber string,
+) error {
+	phoneVerification, err := s.Repository.GetActivePhoneVerification(ctx, phoneNumber)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	if err == nil &&
+		phoneVerification.UserID == userID &&
+		time.Since(phoneVerification.CreatedAt) < s.config.Auth.PhoneVerification.ResendInterval {
+		return nil
+	}
+
+	code, err := generateNumericCode(s.config.Auth.PhoneVerification.CodeLength)
+	if err != nil {
+		return err
+	}
+
+	codeHash, err := hashAndSalt(ctx, code, s.config.Auth.BcryptCost)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Repository.InsertPhoneVerification(ctx, repository.PhoneVerification{
+		UserID:      userID,
+		PhoneNumber: phoneNumber,
+		CodeHash:    codeHash,
+		ExpiresAt:   time.Now().Add(s.config.Auth.PhoneVerification.CodeTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.Notifier.SendSMS(ctx, phoneNumber, fmt.Sprintf(
+		"Your %s verification code is %s. It expires in %d minutes.",
+		constant.ApplicationName, code, int(s.config.Auth.PhoneVerification.CodeTTL.Minutes()),
+	))
+}
+
+// sendPasswordResetCode issues a new password reset code for the user and
+// delivers it by SMS, unless one was sent moments ago.
+func sendPasswordResetCode(ctx context.Context, s *Server, user repository.User) error {
+	passwordReset, err := s.Repository.GetActivePasswordReset(ctx, user.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	if err == nil && time.Since(passwordReset.CreatedAt) < s.config.Auth.PasswordReset.ResendInterval {
+		return nil
+	}
+
+	code, err := generateNumericCode(s.config.Auth.PasswordReset.CodeLength)
+	if err != nil {
+		return err
+	}
+
+	codeHash, err := hashAndSalt(ctx, code, s.config.Auth.BcryptCost)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Repository.InsertPasswordReset(ctx, repository.PasswordReset{
+		UserID:    user.ID,
+		CodeHash:  codeHash,
+		ExpiresAt: time.Now().Add(s.config.Auth.PasswordReset.CodeTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.Notifier.SendSMS(ctx, user.PhoneNumber, fmt.Sprintf(
+		"Your %s password reset code is %s. It expires in %d minutes.",
+		constant.ApplicationName, code, int(s.config.Auth.PasswordReset.CodeTTL.Minutes()),
+	))
+}
+
+// newAdminUser describes a user to support staff, secrets left out.
+func newAdminUser(user repository.User) generated.AdminUser {
+	adminUser := generated.AdminUser{
+		Id:                      user.ID,
+		PhoneNumber:             user.PhoneNumber,
+		FullName:                user.FullName,
+		PhoneVerifiedAt:         user.PhoneVerifiedAt,
+		LockedAt:                user.LockedAt,
+		PasswordResetRequiredAt: user.PasswordResetRequiredAt,
+	}
+	if user.Role != "" {
+		adminUser.Role = &user.Role
+	}
+	return adminUser
+}
+
+// checkAdminTarget tells whether the acting user may manage the target user,
+// with the message telling why not. Nobody manages their own account through
+// the admin API, nor the account of a user whose role grants every
+// permission of theirs: only accounts granted strictly fewer permissions.
+func checkAdminTarget(
+	ctx context.Context,
+	s *Server,
+	actorID int64,
+	target repository.User,
+) (refusal i18n.Message, allowed bool, err error) {
+	if target.ID == actorID {
+		return i18n.M(i18n.PermissionOwnAccount), false, nil
+	}
+
+	actor, err := s.Repository.GetUserByID(ctx, actorID)
+	if err != nil {
+		return refusal, false, err
+	}
+	actorPermissions, err := getRolePermissions(ctx, s, actor.Role)
+	if err != nil {
+		return refusal, false, err
+	}
+	targetPermissions, err := getRolePermissions(ctx, s, target.Role)
+	if err != nil {
+		return refusal, false, err
+	}
+
+	for _, permission := range targetPermissions {
+		if !slices.Contains(actorPermissions, permission) {
+			return i18n.M(i18n.PermissionTargetRole), false, nil
+		}
+	}
+	if len(targetPermissions) >= len(actorPermissions) {
+		return i18n.M(i18n.PermissionTargetRole), false, nil
+	}
+
+	return refusal, true, nil
+}
+
+// getRolePermissions returns no permissions for users without a role.
+func getRolePermissions(ctx context.Context, s *Server, role string) ([]string, error) {
+	if role == "" {
+		return nil, nil
+	}
+	return s.Repository.GetRolePermissions(ctx, role)
+}
+
+// generateRecoveryCodes returns the plain codes to show the user once along
+// with the hashes to store. Codes are formatted as four groups of four
+// characters to make them easier to copy.
+func generateRecoveryCodes(count int) (codes []string, codeHashes []string, err error) {
+	for i := 0; i < count; i++ {
+		b := make([]byte, 10)
+		_, err = rand.Read(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		code = strings.Join([]string{code[0:4], code[4:8], code[8:12], code[12:16]}, "-")
+
+		codes = append(codes, code)
+		codeHashes = append(codeHashes, hashToken(normalizeRecoveryCode(code)))
+	}
+	return codes, codeHashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return code
+}
+
+// verifySecondFactor accepts either the current TOTP code or an unused
+// recovery code. A TOTP code is only accepted once, and a recovery code is
+// consumed when it is accepted.
+func verifySecondFactor(
+	ctx context.Context,
+	s *Server,
+	userTOTP repository.UserTOTP,
+	code string,
+) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == s.totp.Digits && strings.Trim(code, "0123456789") == "" {
+		step, ok, err := s.totp.Validate(userTOTP.Secret, code)
+		if err != nil || !ok {
+			return false, err
+		}
+		return s.Repository.UpdateTOTPLastUsedStep(ctx, userTOTP.UserID, step)
+	}
+
+	return s.Repository.UseRecoveryCode(ctx, userTOTP.UserID, hashToken(normalizeRecoveryCode(code)))
+}
+
+// respondWithNewSession starts a session for a user who has been fully
+// authenticated and writes the login response.
+func respondWithNewSession(
+	ctx echo.Context,
+	s *Server,
+	funcName string,
+	user repository.User,
+) error {
+	var response generated.LoginResponse
+
+	// every factor has passed, restore a deleted account unless it was
+	// purged in the meantime
+	if user.DeletedAt != nil {
+		restored, err := s.Repository.RestoreUser(ctx.Request().Context(), user.ID, time.Now().Add(-s.config.AccountDeletion.GracePeriod))
+		if err != nil {
+			s.log().ErrorContext(ctx.Request().Context(), "RestoreUser error", "func", funcName, "error", err)
+			response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+			return ctx.JSON(repositoryErrorStatus(err), response)
+		}
+		if !restored {
+			s.metrics.loginFailed(loginReasonUnknownPhoneNumber)
+			response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeValidation, []i18n.Message{i18n.M(i18n.PhoneNumberNotRegistered)}, false)
+			return ctx.JSON(http.StatusBadRequest, response)
+		}
+	}
+
+	// forget earlier failures of the account only now, a right password
+	// alone must not clear the failed codes of its second factor. The
+	// client ip keeps its count so one known account cannot be used to
+	// clear it while guessing others.
+	err := s.LoginAttempts.ResetLoginAttempts(ctx.Request().Context(), repository.LoginAttemptUserKey(user.ID))
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "ResetLoginAttempts error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// start a new session, its id is shared by the refresh token family
+	sessionID, err := generateRandomToken(16)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "generateRandomToken error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeGeneral, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// generate jwt token
+	jwtToken, err := generateJwtToken(ctx.Request().Context(), s, user, sessionID)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "generateJwtToken error", "func", funcName, "error", err)
+		response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeJWT, []i18n.Message{i18n.M(i18n.SystemError)}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// increase login count
+	err = s.Repository.IncreaseLoginCount(ctx.Request().Context(), user.ID)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "IncreaseLoginCount error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	// issue the first refresh token of the session
+	refreshToken, err := issueRefreshToken(ctx.Request().Context(), s, user.ID, sessionID)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "issueRefreshToken error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
+	}
+
+	s.metrics.logins.Inc(loginResultSuccess, "")
+	response.Header = generateResponseHeader(ctx.Request().Context(), 0, nil, true)
+	response.Data = &generated.LoginResponseData{
+		Id:           user.ID,
+		Jwt:          jwtToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.config.Auth.AccessTokenTTL.Seconds()),
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func rejectReusedRefreshToken(
+	ctx echo.Context,
+	s *Server,
+	funcName string,
+	familyID string,
+) error {
+	var response generated.RefreshTokenResponse
+
+	err := s.Repository.RevokeRefreshTokenFamily(ctx.Request().Context(), familyID)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "RevokeRefreshTokenFamily error", "func", funcName, "error", err)
+		response.Header = repositoryErrorHeader(ctx.Request().Context(), err)
+		return ctx.JSON(repositoryErrorStatus(err), response)
 	}
 
-	return t.SignedString(getSignKey())
+	response.Header = generateResponseHeader(ctx.Request().Context(), constant.ErrorCodeRefreshToken, []i18n.Message{i18n.M(i18n.RefreshTokenUsed)}, false)
+	return ctx.JSON(http.StatusUnauthorized, response)
 }
 
-func getSessionClaims(ctx echo.Context) (sc model.SessionClaims, err error) {
+// sessionClaimsKey holds the claims of the session checked by
+// getSessionClaims in the echo context of the request.
+const sessionClaimsKey = "session_claims"
+
+func getSessionClaims(ctx echo.Context, s *Server) (sc model.SessionClaims, err error) {
+	// the session may have been checked for authz.Middleware already
+	sc, ok := ctx.Get(sessionClaimsKey).(model.SessionClaims)
+	if ok {
+		return sc, nil
+	}
+
 	tokenString := ctx.Request().Header.Get("Authorization")
 	tokenString = strings.ReplaceAll(tokenString, "Bearer ", "")
 	if tokenString == "" {
-		err = errors.New("JWT token not found")
+		s.metrics.sessionRejections.Inc(sessionRejectionMissing)
+		err = errSessionTokenMissing
 		return sc, err
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &model.SessionClaims{}, func(*jwt.Token) (interface{}, error) {
-		return getVerifyKey(), nil
+	claims, err := parseJwtToken(ctx.Request().Context(), s, tokenString)
+	if err != nil {
+		if errors.Is(err, errSessionExpired) {
+			s.metrics.sessionRejections.Inc(sessionRejectionExpired)
+		} else {
+			s.metrics.sessionRejections.Inc(sessionRejectionInvalid)
+		}
+		return sc, err
+	}
+
+	// tokens issued for another purpose, such as an MFA challenge, must not
+	// grant a session
+	if claims.Purpose != "" {
+		s.metrics.sessionRejections.Inc(sessionRejectionPurpose)
+		err = errSessionNone
+		return sc, err
+	}
+
+	// reject tokens revoked by logout or logout-all
+	revoked, err := isSessionRevoked(ctx.Request().Context(), s, claims)
+	if err != nil {
+		s.log().ErrorContext(ctx.Request().Context(), "isSessionRevoked error", "error", err)
+		s.metrics.sessionRejections.Inc(sessionRejectionSystemError)
+		err = errSessionCheck
+		return sc, err
+	}
+	if revoked {
+		s.metrics.sessionRejections.Inc(sessionRejectionRevoked)
+		err = errSessionRevoked
+		return sc, err
+	}
+
+	sc = claims
+	logging.SetUserID(ctx.Request().Context(), sc.UserID)
+	ctx.Set(sessionClaimsKey, sc)
+
+	return sc, nil
+}
+
+// SessionUserID returns the user of a request carrying a validly signed
+// session token. Revocation is not checked, so it is only meant for cheap
+// lookups such as rate limiting.
+func (s *Server) SessionUserID(ctx echo.Context) (userID int64, ok bool) {
+	tokenString := ctx.Request().Header.Get("Authorization")
+	tokenString = strings.ReplaceAll(tokenString, "Bearer ", "")
+	if tokenString == "" {
+		return userID, false
+	}
+
+	claims, err := parseJwtToken(ctx.Request().Context(), s, tokenString)
+	if err != nil || claims.Purpose != "" {
+		return userID, false
+	}
+
+	return claims.UserID, true
+}
+
+// SessionRole returns the role of the user of a request carrying a valid
+// session. The session is checked as getSessionClaims does and the role is
+// read from the database rather than the token, so a role taken away applies
+// right away.
+func (s *Server) SessionRole(ctx echo.Context) (role string, ok bool, err error) {
+	sc, err := getSessionClaims(ctx, s)
+	if err != nil {
+		return role, false, nil
+	}
+
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), sc.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return role, false, nil
+	}
+	if err != nil {
+		return role, false, err
+	}
+
+	return user.Role, true, nil
+}
+
+// sessionError rejects the session token of a request, told to the client
+// by its message.
+type sessionError struct {
+	messageID string
+}
+
+func (e *sessionError) Error() string {
+	return i18n.Localize(i18n.DefaultLanguage, i18n.M(e.messageID))
+}
+
+var (
+	errSessionTokenMissing = &sessionError{messageID: i18n.SessionTokenMissing}
+	errSessionNone         = &sessionError{messageID: i18n.SessionNone}
+	errSessionCheck        = &sessionError{messageID: i18n.SessionCheckError}
+	errSessionRevoked      = &sessionError{messageID: i18n.SessionRevoked}
+	errSessionExpired      = &sessionError{messageID: i18n.SessionExpired}
+	errSessionParse        = &sessionError{messageID: i18n.SessionParseError}
+)
+
+// sessionErrorMessage returns the message answering an error of
+// getSessionClaims or parseJwtToken.
+func sessionErrorMessage(err error) i18n.Message {
+	var sessionErr *sessionError
+	if errors.As(err, &sessionErr) {
+		return i18n.M(sessionErr.messageID)
+	}
+	return i18n.M(i18n.SystemError)
+}
+
+// parseJwtToken verifies the signature and expiry of a token issued by
+// signJwtToken and returns its claims.
+func parseJwtToken(ctx context.Context, s *Server, tokenString string) (sc model.SessionClaims, err error) {
+	token, err := jwt.ParseWithClaims(tokenString, &model.SessionClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return s.KeyRing.VerificationKey(kid)
 	})
 	if err != nil {
 		if strings.HasPrefix(err.Error(), jwt.ErrTokenExpired.Error()) {
-			err = errors.New("Session is expired")
+			err = errSessionExpired
 		} else {
-			log.Errorf("ParseWithClaims error: %s", err.Error())
-			err = errors.New("There was an error when parsing JWT")
+			s.log().ErrorContext(ctx, "ParseWithClaims error", "error", err)
+			err = errSessionParse
 		}
 		return sc, err
 	}
 
 	if token.Claims.(*model.SessionClaims) == nil {
-		err = errors.New("No session")
+		err = errSessionNone
 		return sc, err
 	}
 
-	sc = *token.Claims.(*model.SessionClaims)
+	return *token.Claims.(*model.SessionClaims), nil
+}
 
-	return sc, nil
+// recordAuditEvent records a change already made, so failing to record it
+// is logged rather than failing the request.
+func recordAuditEvent(ctx context.Context, s *Server, funcName string, event repository.AuditEvent) {
+	err := s.Repository.InsertAuditEvent(ctx, event)
+	if err != nil {
+		s.log().ErrorContext(ctx, "InsertAuditEvent error", "func", funcName, "error", err)
+	}
+}
+
+// tokensCutOff returns the tokens_valid_after revoking the access tokens
+// issued up to now. It is truncated to the microseconds Postgres keeps, so
+// the cached cut-off is the one stored.
+func tokensCutOff(now time.Time) time.Time {
+	return now.Truncate(time.Microsecond)
+}
+
+// issuedAfter tells whether the token was issued after t. Tokens issued
+// before they told the nanosecond are only known to the second, and are
+// not issued after t within its second.
+func issuedAfter(sc model.SessionClaims, t time.Time) bool {
+	if sc.IssuedAtNano != 0 {
+		return time.Unix(0, sc.IssuedAtNano).After(t)
+	}
+	return sc.IssuedAt > t.Unix()
+}
+
+// isSessionRevoked checks the per-user "tokens valid after" timestamp set by
+// logout-all and the revocation list filled by logout. Both are served from
+// the in-process cache when possible.
+func isSessionRevoked(
+	ctx context.Context,
+	s *Server,
+	sc model.SessionClaims,
+) (bool, error) {
+	now := time.Now()
+
+	validAfter, found := s.revocationCache.getTokensValidAfter(sc.UserID, now)
+	if !found {
+		var err error
+		validAfter, err = s.Repository.GetTokensValidAfter(ctx, sc.UserID)
+		if err != nil {
+			return false, err
+		}
+		s.revocationCache.setTokensValidAfter(sc.UserID, validAfter, now)
+	}
+	if !validAfter.IsZero() && !issuedAfter(sc, validAfter) {
+		return true, nil
+	}
+
+	if sc.Id == "" {
+		return false, nil
+	}
+	revoked, found := s.revocationCache.getTokenRevoked(sc.Id, now)
+	if !found {
+		var err error
+		revoked, err = s.Repository.IsTokenRevoked(ctx, sc.Id)
+		if err != nil {
+			return false, err
+		}
+		s.revocationCache.setTokenRevoked(sc.Id, revoked, time.Unix(sc.ExpiresAt, 0), now)
+	}
+
+	return revoked, nil
 }
 
-func generateResponseHeader(errorCode int, errorMessages []string, successful bool) generated.ResponseHeader {
+// generateResponseHeader localizes errorMessages into the language of the
+// request, see i18n.Middleware, keeping their IDs alongside.
+func generateResponseHeader(ctx context.Context, errorCode int, errorMessages []i18n.Message, successful bool) generated.ResponseHeader {
 	var res generated.ResponseHeader
 	if errorCode != 0 {
 		res.ErrorCode = &errorCode
 	}
 	if len(errorMessages) != 0 {
-		res.ErrorMessages = &errorMessages
+		ids, texts := i18n.LocalizeAll(i18n.FromContext(ctx), errorMessages)
+		res.ErrorMessageIds = &ids
+		res.ErrorMessages = &texts
 	}
 	if successful != false {
 		res.Successful = &successful
@@ -1,20 +1,33 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fenky-ng/swt-pro/config"
 	"github.com/fenky-ng/swt-pro/constant"
 	"github.com/fenky-ng/swt-pro/generated"
 	errorHelper "github.com/fenky-ng/swt-pro/helper/error"
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/fenky-ng/swt-pro/keyring"
+	"github.com/fenky-ng/swt-pro/metrics"
 	"github.com/fenky-ng/swt-pro/model"
 	"github.com/fenky-ng/swt-pro/repository"
+	"github.com/fenky-ng/swt-pro/totp"
+	"github.com/fenky-ng/swt-pro/tracing"
 	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func Test_hashAndSalt(t *testing.T) {
@@ -53,12 +66,12 @@ func Test_hashAndSalt(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes, gotErr := hashAndSalt(tt.args.input)
+			gotRes, gotErr := hashAndSalt(context.Background(), tt.args.input, testConfig.Auth.BcryptCost)
 			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
-				t.Errorf("hashAndSalt() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+				t.Errorf("hashAndSalt(context.Background(), ) gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
 			}
 			if (len(gotRes) != 0) != (len(tt.wantRes) != 0) {
-				t.Errorf("hashAndSalt() gotRes = %s, wantRes = %s", gotRes, tt.wantRes)
+				t.Errorf("hashAndSalt(context.Background(), ) gotRes = %s, wantRes = %s", gotRes, tt.wantRes)
 			}
 		})
 	}
@@ -93,7 +106,7 @@ func Test_comparePasswords(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := comparePasswords(tt.args.hashedPassword, tt.args.plainPassword)
+			gotRes := comparePasswords(context.Background(), tt.args.hashedPassword, tt.args.plainPassword)
 			if gotRes != tt.wantRes {
 				t.Errorf("comparePasswords() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
 			}
@@ -125,7 +138,7 @@ func Test_generateJwtToken(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes, gotErr := generateJwtToken(tt.args.user)
+			gotRes, gotErr := generateJwtToken(context.Background(), testServer, tt.args.user, "session")
 			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
 				t.Errorf("generateJwtToken() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
 			}
@@ -136,18 +149,60 @@ func Test_generateJwtToken(t *testing.T) {
 	}
 }
 
+func Test_utilitySpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(tracing.NewProvider(tracing.ProviderOptions{
+		Exporter:    exporter,
+		Sync:        true,
+		ServiceName: "test",
+		SampleRatio: 1,
+	}))
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "Login")
+	hash, _ := hashAndSalt(ctx, "Sawit@Pr0", testConfig.Auth.BcryptCost)
+	comparePasswords(ctx, hash, "Sawit@Pr0")
+	generateJwtToken(ctx, testServer, repository.User{ID: 1}, "session")
+	parent.End()
+
+	var gotNames []string
+	for _, span := range exporter.GetSpans() {
+		if span.Parent.SpanID() == parent.SpanContext().SpanID() {
+			gotNames = append(gotNames, span.Name)
+		}
+	}
+	wantNames := []string{"bcrypt.GenerateFromPassword", "bcrypt.CompareHashAndPassword", "jwt.Sign"}
+	if !reflect.DeepEqual(gotNames, wantNames) {
+		t.Errorf("child spans = %q, want = %q", gotNames, wantNames)
+	}
+}
+
 func Test_getSessionClaims(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
 	type args struct {
 		ctx echo.Context
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantRes model.SessionClaims
-		wantErr error
+		name          string
+		fields        fields
+		args          args
+		mock          func(fields *fields)
+		wantRes       model.SessionClaims
+		wantErr       error
+		wantRejection string
 	}{
 		{
 			name: "no jwt token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
 			args: args{
 				ctx: func() echo.Context {
 					req, _ := http.NewRequest(http.MethodGet, "url", nil)
@@ -155,11 +210,20 @@ func Test_getSessionClaims(t *testing.T) {
 					return echo.New().NewContext(req, res)
 				}(),
 			},
-			wantRes: model.SessionClaims{},
-			wantErr: errors.New("JWT token not found"),
+			mock:          func(fields *fields) {},
+			wantRes:       model.SessionClaims{},
+			wantErr:       errSessionTokenMissing,
+			wantRejection: "missing",
 		},
 		{
 			name: "token is expired",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
 			args: args{
 				ctx: func() echo.Context {
 					req, _ := http.NewRequest(http.MethodGet, "url", nil)
@@ -168,15 +232,24 @@ func Test_getSessionClaims(t *testing.T) {
 					return echo.New().NewContext(req, res)
 				}(),
 			},
-			wantRes: model.SessionClaims{},
-			wantErr: errors.New("Session is expired"),
+			mock:          func(fields *fields) {},
+			wantRes:       model.SessionClaims{},
+			wantErr:       errSessionExpired,
+			wantRejection: "expired",
 		},
 		{
-			name: "passed",
+			name: "mfa challenge token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
 			args: args{
 				ctx: func() echo.Context {
 					req, _ := http.NewRequest(http.MethodGet, "url", nil)
-					jwt, _ := generateJwtToken(repository.User{
+					jwt, _ := generateMfaChallengeToken(context.Background(), testServer, repository.User{
 						ID:          1,
 						PhoneNumber: "+628223344556",
 					})
@@ -185,34 +258,719 @@ func Test_getSessionClaims(t *testing.T) {
 					return echo.New().NewContext(req, res)
 				}(),
 			},
+			mock:          func(fields *fields) {},
+			wantRes:       model.SessionClaims{},
+			wantErr:       errSessionNone,
+			wantRejection: "not_a_session",
+		},
+		{
+			name: "error GetTokensValidAfter",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID:          1,
+						PhoneNumber: "+628223344556",
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					return echo.New().NewContext(req, res)
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, errors.New("expected GetTokensValidAfter error")).
+					Times(1)
+			},
+			wantRes:       model.SessionClaims{},
+			wantErr:       errSessionCheck,
+			wantRejection: "system_error",
+		},
+		{
+			name: "session is revoked",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID:          1,
+						PhoneNumber: "+628223344556",
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					return echo.New().NewContext(req, res)
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(true, nil).
+					Times(1)
+			},
+			wantRes:       model.SessionClaims{},
+			wantErr:       errSessionRevoked,
+			wantRejection: "revoked",
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := generateJwtToken(context.Background(), testServer, repository.User{
+						ID:          1,
+						PhoneNumber: "+628223344556",
+					}, "session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					return echo.New().NewContext(req, res)
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+			},
 			wantRes: model.SessionClaims{
 				StandardClaims: jwt.StandardClaims{
 					Issuer: constant.ApplicationName,
 				},
 				UserID:      1,
 				PhoneNumber: "+628223344556",
+				SessionID:   "session",
 			},
 			wantErr: nil,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes, gotErr := getSessionClaims(tt.args.ctx)
+			registry := metrics.NewRegistry()
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+				metrics:    newServerMetrics(registry),
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := getSessionClaims(tt.args.ctx, s)
+			gotMetrics := writeTestMetrics(t, registry)
+			if tt.wantRejection != "" && !strings.Contains(gotMetrics, `auth_session_rejections_total{reason="`+tt.wantRejection+`"} 1`) {
+				t.Errorf("getSessionClaims() rejection %s not counted, gotMetrics =\n%s", tt.wantRejection, gotMetrics)
+			}
+			if tt.wantRejection == "" && strings.Contains(gotMetrics, "auth_session_rejections_total{") {
+				t.Errorf("getSessionClaims() counted a rejection, gotMetrics =\n%s", gotMetrics)
+			}
 			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
 				t.Errorf("getSessionClaims() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
 			}
+			gotRes.Id = ""
+			gotRes.IssuedAt = 0
 			gotRes.ExpiresAt = 0
+			gotRes.IssuedAtNano = 0
 			if gotRes != tt.wantRes {
 				t.Errorf("getSessionClaims() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
 			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_checkAdminTarget(t *testing.T) {
+	permissions := map[string][]string{
+		"admin":   {"users:read", "users:write", "users:lock"},
+		"support": {"users:read", "users:lock"},
+		"auditor": {"users:read", "users:write"},
+	}
+	tests := []struct {
+		name        string
+		actorRole   string
+		targetID    int64
+		targetRole  string
+		wantRefusal i18n.Message
+		wantAllowed bool
+	}{
+		{
+			name:        "own account",
+			actorRole:   "admin",
+			targetID:    1,
+			wantRefusal: i18n.M(i18n.PermissionOwnAccount),
+		},
+		{
+			name:        "user without role",
+			actorRole:   "support",
+			targetID:    2,
+			wantAllowed: true,
+		},
+		{
+			name:        "user granted less",
+			actorRole:   "admin",
+			targetID:    2,
+			targetRole:  "support",
+			wantAllowed: true,
+		},
+		{
+			name:        "user granted as much",
+			actorRole:   "admin",
+			targetID:    2,
+			targetRole:  "admin",
+			wantRefusal: i18n.M(i18n.PermissionTargetRole),
+		},
+		{
+			name:        "user granted more",
+			actorRole:   "support",
+			targetID:    2,
+			targetRole:  "admin",
+			wantRefusal: i18n.M(i18n.PermissionTargetRole),
+		},
+		{
+			name:        "user granted other permissions",
+			actorRole:   "support",
+			targetID:    2,
+			targetRole:  "auditor",
+			wantRefusal: i18n.M(i18n.PermissionTargetRole),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockRepository := repository.NewMockRepositoryInterface(mockCtrl)
+			mockRepository.EXPECT().GetUserByID(context.Background(), int64(1)).
+				Return(repository.User{ID: 1, Role: tt.actorRole}, nil).
+				AnyTimes()
+			mockRepository.EXPECT().GetRolePermissions(context.Background(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, role string) ([]string, error) {
+					return permissions[role], nil
+				}).
+				AnyTimes()
+			s := &Server{
+				config:     testConfig,
+				Repository: mockRepository,
+			}
+			gotRefusal, gotAllowed, gotErr := checkAdminTarget(context.Background(), s, 1, repository.User{ID: tt.targetID, Role: tt.targetRole})
+			if gotErr != nil {
+				t.Fatalf("checkAdminTarget() gotErr = %s", gotErr.Error())
+			}
+			if !reflect.DeepEqual(gotRefusal, tt.wantRefusal) || gotAllowed != tt.wantAllowed {
+				t.Errorf("checkAdminTarget() gotRefusal = %+v, gotAllowed = %t, wantRefusal = %+v, wantAllowed = %t", gotRefusal, gotAllowed, tt.wantRefusal, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func Test_generateRecoveryCodes(t *testing.T) {
+	gotCodes, gotHashes, gotErr := generateRecoveryCodes(testConfig.Auth.RecoveryCodeCount)
+	if gotErr != nil {
+		t.Fatalf("generateRecoveryCodes() gotErr = %s", gotErr.Error())
+	}
+	if len(gotCodes) != testConfig.Auth.RecoveryCodeCount || len(gotHashes) != testConfig.Auth.RecoveryCodeCount {
+		t.Fatalf("generateRecoveryCodes() got %d codes and %d hashes, want %d", len(gotCodes), len(gotHashes), testConfig.Auth.RecoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for i, code := range gotCodes {
+		if !regexp.MustCompile(`^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`).MatchString(code) {
+			t.Errorf("generateRecoveryCodes() got code = %s", code)
+		}
+		if gotHashes[i] != hashToken(normalizeRecoveryCode(strings.ToUpper(code))) {
+			t.Errorf("generateRecoveryCodes() got hash = %s for code = %s", gotHashes[i], code)
+		}
+		if seen[code] {
+			t.Errorf("generateRecoveryCodes() got duplicate code = %s", code)
+		}
+		seen[code] = true
+	}
+}
+
+func Test_Server_SessionUserID(t *testing.T) {
+	sessionToken, _ := generateJwtToken(context.Background(), testServer, repository.User{ID: 1}, "session")
+	mfaToken, _ := generateMfaChallengeToken(context.Background(), testServer, repository.User{ID: 1})
+	tests := []struct {
+		name          string
+		authorization string
+		wantRes       int64
+		wantOk        bool
+	}{
+		{
+			name:          "no jwt token",
+			authorization: "",
+		},
+		{
+			name:          "invalid jwt token",
+			authorization: "Bearer invalid",
+		},
+		{
+			name:          "mfa challenge token",
+			authorization: "Bearer " + mfaToken,
+		},
+		{
+			name:          "passed",
+			authorization: "Bearer " + sessionToken,
+			wantRes:       1,
+			wantOk:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:  testConfig,
+				KeyRing: testKeyRing,
+			}
+			req, _ := http.NewRequest(http.MethodGet, "url", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			ctx := echo.New().NewContext(req, httptest.NewRecorder())
+			gotRes, gotOk := s.SessionUserID(ctx)
+			if gotRes != tt.wantRes || gotOk != tt.wantOk {
+				t.Errorf("Server.SessionUserID() gotRes = %d, gotOk = %t, wantRes = %d, wantOk = %t", gotRes, gotOk, tt.wantRes, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_Server_SessionRole(t *testing.T) {
+	sessionToken, _ := generateJwtToken(context.Background(), testServer, repository.User{ID: 1, Role: "admin"}, "session")
+	mfaToken, _ := generateMfaChallengeToken(context.Background(), testServer, repository.User{ID: 1})
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		authorization string
+		mock          func(fields *fields)
+		wantRes       string
+		wantOk        bool
+		wantErr       error
+	}{
+		{
+			name: "no jwt token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			authorization: "",
+			mock:          func(fields *fields) {},
+		},
+		{
+			name: "mfa challenge token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			authorization: "Bearer " + mfaToken,
+			mock:          func(fields *fields) {},
+		},
+		{
+			name: "revoked session",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			authorization: "Bearer " + sessionToken,
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Now().Add(time.Hour), nil).
+					Times(1)
+			},
+		},
+		{
+			name: "user not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			authorization: "Bearer " + sessionToken,
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, repository.ErrNotFound).
+					Times(1)
+			},
+		},
+		{
+			name: "error GetUserByID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			authorization: "Bearer " + sessionToken,
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected error")).
+					Times(1)
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "role taken away since the token was issued",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			authorization: "Bearer " + sessionToken,
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{ID: 1}, nil).
+					Times(1)
+			},
+			wantRes: "",
+			wantOk:  true,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			authorization: "Bearer " + sessionToken,
+			mock: func(fields *fields) {
+				mockActiveSession(fields.Repository, 1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{ID: 1, Role: "support"}, nil).
+					Times(1)
+			},
+			wantRes: "support",
+			wantOk:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			req, _ := http.NewRequest(http.MethodGet, "url", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			ctx := echo.New().NewContext(req, httptest.NewRecorder())
+			gotRes, gotOk, gotErr := s.SessionRole(ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Server.SessionRole() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes || gotOk != tt.wantOk {
+				t.Errorf("Server.SessionRole() gotRes = %s, gotOk = %t, wantRes = %s, wantOk = %t", gotRes, gotOk, tt.wantRes, tt.wantOk)
+			}
+			// the handler behind the middleware reuses the checked session
+			if gotOk {
+				_, err := getSessionClaims(ctx, s)
+				if err != nil {
+					t.Errorf("getSessionClaims() gotErr = %s after Server.SessionRole()", err.Error())
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_isSessionRevoked(t *testing.T) {
+	now := time.Now()
+	type fields struct {
+		mockCtrl        *gomock.Controller
+		Repository      *repository.MockRepositoryInterface
+		revocationCache *revocationCache
+	}
+	type args struct {
+		ctx context.Context
+		sc  model.SessionClaims
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "issued before tokens valid after",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:        mockCtrl,
+					Repository:      repository.NewMockRepositoryInterface(mockCtrl),
+					revocationCache: newRevocationCache(time.Minute),
+				}
+			}(),
+			args: args{
+				ctx: context.Background(),
+				sc: model.SessionClaims{
+					StandardClaims: jwt.StandardClaims{
+						Id:       "jti",
+						IssuedAt: now.Add(-time.Hour).Unix(),
+					},
+					UserID: 1,
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(now, nil).
+					Times(1)
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+		{
+			name: "issued at tokens valid after",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:        mockCtrl,
+					Repository:      repository.NewMockRepositoryInterface(mockCtrl),
+					revocationCache: newRevocationCache(time.Minute),
+				}
+			}(),
+			args: args{
+				ctx: context.Background(),
+				sc: model.SessionClaims{
+					StandardClaims: jwt.StandardClaims{
+						Id:       "jti",
+						IssuedAt: now.Unix(),
+					},
+					UserID:       1,
+					IssuedAtNano: now.UnixNano(),
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(now, nil).
+					Times(1)
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+		{
+			name: "issued right after tokens valid after",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:        mockCtrl,
+					Repository:      repository.NewMockRepositoryInterface(mockCtrl),
+					revocationCache: newRevocationCache(time.Minute),
+				}
+			}(),
+			args: args{
+				ctx: context.Background(),
+				sc: model.SessionClaims{
+					StandardClaims: jwt.StandardClaims{
+						Id:       "jti",
+						IssuedAt: now.Unix(),
+					},
+					UserID:       1,
+					IssuedAtNano: now.Add(time.Microsecond).UnixNano(),
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(now, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), "jti").
+					Return(false, nil).
+					Times(1)
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+		{
+			name: "issued in the second of tokens valid after without nanoseconds",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:        mockCtrl,
+					Repository:      repository.NewMockRepositoryInterface(mockCtrl),
+					revocationCache: newRevocationCache(time.Minute),
+				}
+			}(),
+			args: args{
+				ctx: context.Background(),
+				sc: model.SessionClaims{
+					StandardClaims: jwt.StandardClaims{
+						Id:       "jti",
+						IssuedAt: now.Unix(),
+					},
+					UserID: 1,
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(now, nil).
+					Times(1)
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+		{
+			name: "error IsTokenRevoked",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:        mockCtrl,
+					Repository:      repository.NewMockRepositoryInterface(mockCtrl),
+					revocationCache: newRevocationCache(time.Minute),
+				}
+			}(),
+			args: args{
+				ctx: context.Background(),
+				sc: model.SessionClaims{
+					StandardClaims: jwt.StandardClaims{
+						Id:       "jti",
+						IssuedAt: now.Unix(),
+					},
+					UserID: 1,
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(time.Time{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), "jti").
+					Return(false, errors.New("expected IsTokenRevoked error")).
+					Times(1)
+			},
+			wantRes: false,
+			wantErr: errors.New("expected IsTokenRevoked error"),
+		},
+		{
+			name: "served from cache",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				cache := newRevocationCache(time.Minute)
+				cache.setTokensValidAfter(1, time.Time{}, now)
+				cache.setTokenRevoked("jti", true, now.Add(time.Hour), now)
+				return fields{
+					mockCtrl:        mockCtrl,
+					Repository:      repository.NewMockRepositoryInterface(mockCtrl),
+					revocationCache: cache,
+				}
+			}(),
+			args: args{
+				ctx: context.Background(),
+				sc: model.SessionClaims{
+					StandardClaims: jwt.StandardClaims{
+						Id:       "jti",
+						IssuedAt: now.Unix(),
+					},
+					UserID: 1,
+				},
+			},
+			mock:    func(fields *fields) {},
+			wantRes: true,
+			wantErr: nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:        mockCtrl,
+					Repository:      repository.NewMockRepositoryInterface(mockCtrl),
+					revocationCache: newRevocationCache(time.Minute),
+				}
+			}(),
+			args: args{
+				ctx: context.Background(),
+				sc: model.SessionClaims{
+					StandardClaims: jwt.StandardClaims{
+						Id:       "jti",
+						IssuedAt: now.Unix(),
+					},
+					UserID: 1,
+				},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetTokensValidAfter(context.Background(), int64(1)).
+					Return(now.Add(-time.Hour), nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IsTokenRevoked(context.Background(), "jti").
+					Return(false, nil).
+					Times(1)
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:          testConfig,
+				Repository:      tt.fields.Repository,
+				KeyRing:         testKeyRing,
+				revocationCache: tt.fields.revocationCache,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := isSessionRevoked(tt.args.ctx, s, tt.args.sc)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("isSessionRevoked() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("isSessionRevoked() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
+			}
+			tt.fields.mockCtrl.Finish()
 		})
 	}
 }
 
 func Test_generateResponseHeader(t *testing.T) {
 	type args struct {
+		ctx           context.Context
 		errorCode     int
-		errorMessages []string
+		errorMessages []i18n.Message
 		successful    bool
 	}
 	tests := []struct {
@@ -223,6 +981,7 @@ func Test_generateResponseHeader(t *testing.T) {
 		{
 			name: "error code",
 			args: args{
+				ctx:       context.Background(),
 				errorCode: constant.ErrorCodeGeneral,
 			},
 			wantRes: generated.ResponseHeader{
@@ -235,11 +994,33 @@ func Test_generateResponseHeader(t *testing.T) {
 		{
 			name: "error message",
 			args: args{
-				errorMessages: []string{"expected error"},
+				ctx:           context.Background(),
+				errorMessages: []i18n.Message{i18n.M(i18n.FullNameLength, 3, 60)},
 			},
 			wantRes: generated.ResponseHeader{
+				ErrorMessageIds: func() *[]string {
+					res := []string{i18n.FullNameLength}
+					return &res
+				}(),
 				ErrorMessages: func() *[]string {
-					res := []string{"expected error"}
+					res := []string{"Full name must be at minimum 3 characters and maximum 60 characters"}
+					return &res
+				}(),
+			},
+		},
+		{
+			name: "localized error message",
+			args: args{
+				ctx:           i18n.NewContext(context.Background(), i18n.Indonesian),
+				errorMessages: []i18n.Message{i18n.M(i18n.SystemError)},
+			},
+			wantRes: generated.ResponseHeader{
+				ErrorMessageIds: func() *[]string {
+					res := []string{i18n.SystemError}
+					return &res
+				}(),
+				ErrorMessages: func() *[]string {
+					res := []string{"Terjadi kesalahan sistem"}
 					return &res
 				}(),
 			},
@@ -247,6 +1028,7 @@ func Test_generateResponseHeader(t *testing.T) {
 		{
 			name: "successful",
 			args: args{
+				ctx:        context.Background(),
 				successful: true,
 			},
 			wantRes: generated.ResponseHeader{
@@ -259,10 +1041,213 @@ func Test_generateResponseHeader(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := generateResponseHeader(tt.args.errorCode, tt.args.errorMessages, tt.args.successful)
+			gotRes := generateResponseHeader(tt.args.ctx, tt.args.errorCode, tt.args.errorMessages, tt.args.successful)
 			if !reflect.DeepEqual(gotRes, tt.wantRes) {
 				t.Errorf("generateResponseHeader() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
 			}
 		})
 	}
 }
+
+func Test_generateRandomToken(t *testing.T) {
+	type args struct {
+		size int
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantLength int
+		wantErr    error
+	}{
+		{
+			name: "passed",
+			args: args{
+				size: 32,
+			},
+			wantLength: 43,
+			wantErr:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRes, gotErr := generateRandomToken(tt.args.size)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("generateRandomToken() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if len(gotRes) != tt.wantLength {
+				t.Errorf("generateRandomToken() gotLength = %d, wantLength = %d", len(gotRes), tt.wantLength)
+			}
+		})
+	}
+}
+
+func Test_generateNumericCode(t *testing.T) {
+	type args struct {
+		length int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{
+			name: "passed",
+			args: args{
+				length: 6,
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRes, gotErr := generateNumericCode(tt.args.length)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("generateNumericCode() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if match, _ := regexp.MatchString(fmt.Sprintf("^[0-9]{%d}$", tt.args.length), gotRes); !match {
+				t.Errorf("generateNumericCode() gotRes = %s, want %d digits", gotRes, tt.args.length)
+			}
+		})
+	}
+}
+
+func Test_hashToken(t *testing.T) {
+	type args struct {
+		token string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantRes string
+	}{
+		{
+			name: "passed",
+			args: args{
+				token: "refresh-token",
+			},
+			wantRes: "0eb17643d4e9261163783a420859c92c7d212fa9624106a12b510afbec266120",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRes := hashToken(tt.args.token)
+			if gotRes != tt.wantRes {
+				t.Errorf("hashToken() gotRes = %s, wantRes = %s", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_issueRefreshToken(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx      context.Context
+		userID   int64
+		familyID string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "error InsertRefreshToken",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				familyID: "family",
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.AssignableToTypeOf(repository.RefreshToken{})).
+					Return(int64(0), errors.New("expected InsertRefreshToken error")).
+					Times(1)
+			},
+			wantRes: false,
+			wantErr: errors.New("expected InsertRefreshToken error"),
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				familyID: "family",
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.AssignableToTypeOf(repository.RefreshToken{})).
+					DoAndReturn(func(ctx context.Context, data repository.RefreshToken) (int64, error) {
+						if data.UserID != 1 || data.FamilyID != "family" || data.TokenHash == "" {
+							t.Errorf("issueRefreshToken() unexpected refresh token data = %+v", data)
+						}
+						return int64(1), nil
+					}).
+					Times(1)
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:     testConfig,
+				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := issueRefreshToken(tt.args.ctx, s, tt.args.userID, tt.args.familyID)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("issueRefreshToken() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if (len(gotRes) != 0) != tt.wantRes {
+				t.Errorf("issueRefreshToken() gotRes = %s, wantRes = %t", gotRes, tt.wantRes)
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+var testKeyRing = func() *keyring.KeyRing {
+	kr, err := keyring.Default()
+	if err != nil {
+		panic(err)
+	}
+	return kr
+}()
+
+var testConfig = config.Default()
+
+// testServer holds what the token helpers need to sign tokens in tests
+var testServer = &Server{
+	KeyRing: testKeyRing,
+	config:  testConfig,
+}
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+var testTOTP = func() *totp.TOTP {
+	t := totp.New()
+	t.Clock = func() time.Time {
+		return time.Unix(1700000000, 0)
+	}
+	return t
+}()
@@ -2,55 +2,117 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"regexp"
-	"strings"
+	"slices"
+	"unicode/utf8"
 
+	"github.com/fenky-ng/swt-pro/config"
 	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/fenky-ng/swt-pro/phone"
+	"github.com/fenky-ng/swt-pro/repository"
 )
 
-func validateRegistration(request generated.RegistrationRequest) []string {
-	var errorMessages []string
+func passwordValidationMessage(cfg config.ValidationConfig) i18n.Message {
+	return i18n.M(i18n.PasswordRules, cfg.PasswordMinLength, cfg.PasswordMaxLength)
+}
+
+// validateRegistration checks the request and normalizes its phone number.
+func validateRegistration(cfg config.ValidationConfig, request *generated.RegistrationRequest) []i18n.Message {
+	var errorMessages []i18n.Message
 
 	// validate phone number
-	errorMessages = append(errorMessages, validatePhoneNumber(request.PhoneNumber)...)
+	phoneNumber, phoneNumberErrors := normalizePhoneNumber(cfg, request.PhoneNumber)
+	request.PhoneNumber = phoneNumber
+	errorMessages = append(errorMessages, phoneNumberErrors...)
 
 	// validate full name
-	errorMessages = append(errorMessages, validateFullName(request.FullName)...)
+	errorMessages = append(errorMessages, validateFullName(cfg, request.FullName)...)
+
+	// validate password
+	if !validatePassword(cfg, request.Password) {
+		errorMessages = append(errorMessages, passwordValidationMessage(cfg))
+	}
+
+	return errorMessages
+}
+
+func validateResetPassword(cfg config.ValidationConfig, request generated.ResetPasswordRequest) []i18n.Message {
+	var errorMessages []i18n.Message
 
 	// validate password
-	if !validatePassword(request.Password) {
-		errorMessages = append(errorMessages, "Passwords must be minimum 6 characters and maximum 64 characters, containing at least 1 capital characters AND 1 number AND 1 special (non alpha-numeric) characters")
+	if !validatePassword(cfg, request.Password) {
+		errorMessages = append(errorMessages, passwordValidationMessage(cfg))
 	}
 
 	return errorMessages
 }
 
-func validatePhoneNumber(input string) []string {
-	var errorMessages []string
+func validateChangePassword(cfg config.ValidationConfig, request generated.ChangePasswordRequest) []i18n.Message {
+	var errorMessages []i18n.Message
 
-	if len(input) < 10 || len(input) > 13 {
-		errorMessages = append(errorMessages, "Phone numbers must be at minimum 10 characters and maximum 13 characters")
+	// validate new password
+	if !validatePassword(cfg, request.NewPassword) {
+		errorMessages = append(errorMessages, passwordValidationMessage(cfg))
 	}
-	if !strings.HasPrefix(input, "+62") {
-		errorMessages = append(errorMessages, "Phone numbers must start with the Indonesia country code “+62”")
+	if request.NewPassword == request.CurrentPassword {
+		errorMessages = append(errorMessages, i18n.M(i18n.PasswordUnchanged))
 	}
 
 	return errorMessages
 }
 
-func validateFullName(input string) []string {
-	var errorMessages []string
+// normalizePhoneNumber parses input into E.164, checking it is a valid
+// number of an accepted country.
+func normalizePhoneNumber(cfg config.ValidationConfig, input string) (phoneNumber string, errorMessages []i18n.Message) {
+	number, err := phone.Parse(input)
+	switch {
+	case errors.Is(err, phone.ErrFormat):
+		errorMessages = append(errorMessages, i18n.M(i18n.PhoneNumberFormat))
+	case errors.Is(err, phone.ErrCountry) || !slices.Contains(cfg.PhoneCountries, number.Country.Code):
+		errorMessages = append(errorMessages, i18n.M(i18n.PhoneNumberCountry, acceptedCountries(cfg)))
+	case errors.Is(err, phone.ErrLength):
+		errorMessages = append(errorMessages, i18n.M(i18n.PhoneNumberLength, i18n.Country(number.Country.Code), number.Country.MinLength, number.Country.MaxLength, number.Country.CallingCode))
+	case errors.Is(err, phone.ErrPrefix):
+		errorMessages = append(errorMessages, i18n.M(i18n.PhoneNumberPrefix, i18n.Country(number.Country.Code)))
+	}
+	if len(errorMessages) != 0 {
+		return phoneNumber, errorMessages
+	}
 
-	if len(input) < 3 || len(input) > 60 {
-		errorMessages = append(errorMessages, "Full name must be at minimum 3 characters and maximum 60 characters")
+	return number.E164(), errorMessages
+}
+
+// acceptedCountries lists the accepted countries, written as "Indonesia
+// (+62) or Malaysia (+60)".
+func acceptedCountries(cfg config.ValidationConfig) i18n.List {
+	names := make(i18n.List, 0, len(cfg.PhoneCountries))
+	for _, code := range cfg.PhoneCountries {
+		country, _ := phone.LookupCountry(code)
+		names = append(names, i18n.M(i18n.PhoneNumberCountryCode, i18n.Country(country.Code), country.CallingCode))
+	}
+	return names
+}
+
+func validateFullName(cfg config.ValidationConfig, input string) []i18n.Message {
+	var errorMessages []i18n.Message
+
+	if len(input) < cfg.FullNameMinLength || len(input) > cfg.FullNameMaxLength {
+		errorMessages = append(errorMessages, i18n.M(i18n.FullNameLength, cfg.FullNameMinLength, cfg.FullNameMaxLength))
 	}
 
 	return errorMessages
 }
 
-func validatePassword(input string) bool {
+func validatePassword(cfg config.ValidationConfig, input string) bool {
+	// length of password
+	length := utf8.RuneCountInString(input)
+	if length < cfg.PasswordMinLength || length > cfg.PasswordMaxLength {
+		return false
+	}
+
 	validations := []string{
-		".{6,64}",   // length of password
 		"[A-Z]",     // at least capital character
 		"[0-9]",     // at least one numeric character
 		"[^\\d\\w]", // at least one non alpha-numeric character
@@ -70,19 +132,21 @@ func checkNewPhoneNumber(
 	s *Server,
 	phoneNumber string,
 ) (bool, error) {
-	var (
-		res bool
-		err error
-	)
-
-	user, err := s.Repository.GetUserByPhoneNumber(ctx, phoneNumber)
+	_, err := s.Repository.GetUserByPhoneNumber(ctx, phoneNumber)
+	if errors.Is(err, repository.ErrNotFound) {
+		return true, nil
+	}
 	if err != nil {
-		return res, err
+		return false, err
 	}
 
-	if user.ID == 0 {
-		res = true
-	}
+	return false, nil
+}
 
-	return res, err
+// isPhoneNumberTaken tells whether err reports the phone number belonging to
+// another user, caught by the unique index on it when concurrent requests all
+// pass checkNewPhoneNumber.
+func isPhoneNumberTaken(err error) bool {
+	var uniqueErr *repository.UniqueViolationError
+	return errors.As(err, &uniqueErr)
 }
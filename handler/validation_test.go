@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/fenky-ng/swt-pro/config"
 	"github.com/fenky-ng/swt-pro/generated"
 	errorHelper "github.com/fenky-ng/swt-pro/helper/error"
+	"github.com/fenky-ng/swt-pro/i18n"
 	"github.com/fenky-ng/swt-pro/repository"
 	"github.com/golang/mock/gomock"
 )
@@ -17,9 +20,10 @@ func Test_validateRegistration(t *testing.T) {
 		request generated.RegistrationRequest
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantRes []string
+		name            string
+		args            args
+		wantPhoneNumber string
+		wantRes         []i18n.Message
 	}{
 		{
 			name: "invalid phone number",
@@ -30,8 +34,8 @@ func Test_validateRegistration(t *testing.T) {
 					Password:    "Sawit@Pr0",
 				},
 			},
-			wantRes: []string{
-				"Phone numbers must be at minimum 10 characters and maximum 13 characters",
+			wantRes: []i18n.Message{
+				i18n.M(i18n.PhoneNumberLength, i18n.Country("ID"), 7, 12, "62"),
 			},
 		},
 		{
@@ -43,8 +47,9 @@ func Test_validateRegistration(t *testing.T) {
 					Password:    "Sawit@Pr0",
 				},
 			},
-			wantRes: []string{
-				"Full name must be at minimum 3 characters and maximum 60 characters",
+			wantPhoneNumber: "+628123456",
+			wantRes: []i18n.Message{
+				i18n.M(i18n.FullNameLength, 3, 60),
 			},
 		},
 		{
@@ -56,70 +61,242 @@ func Test_validateRegistration(t *testing.T) {
 					Password:    "sawitpro",
 				},
 			},
-			wantRes: []string{
-				"Passwords must be minimum 6 characters and maximum 64 characters, containing at least 1 capital characters AND 1 number AND 1 special (non alpha-numeric) characters",
+			wantPhoneNumber: "+628123456",
+			wantRes: []i18n.Message{
+				i18n.M(i18n.PasswordRules, 6, 64),
 			},
 		},
+		{
+			name: "passed with formatted phone number",
+			args: args{
+				request: generated.RegistrationRequest{
+					PhoneNumber: "+62 812-3456",
+					FullName:    "Sawit Pro",
+					Password:    "Sawit@Pr0",
+				},
+			},
+			wantPhoneNumber: "+628123456",
+			wantRes:         nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := validateRegistration(tt.args.request)
+			gotRes := validateRegistration(testConfig.Validation, &tt.args.request)
 			if !reflect.DeepEqual(gotRes, tt.wantRes) {
 				t.Errorf("validateRegistration() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
 			}
+			if tt.args.request.PhoneNumber != tt.wantPhoneNumber {
+				t.Errorf("validateRegistration() gotPhoneNumber = %s, wantPhoneNumber = %s", tt.args.request.PhoneNumber, tt.wantPhoneNumber)
+			}
 		})
 	}
 }
 
-func Test_validatePhoneNumber(t *testing.T) {
+func Test_validateResetPassword(t *testing.T) {
 	type args struct {
-		input string
+		request generated.ResetPasswordRequest
 	}
 	tests := []struct {
 		name    string
 		args    args
-		wantRes []string
+		wantRes []i18n.Message
+	}{
+		{
+			name: "invalid password",
+			args: args{
+				request: generated.ResetPasswordRequest{
+					PhoneNumber: "+628123456",
+					Code:        "123456",
+					Password:    "sawitpro",
+				},
+			},
+			wantRes: []i18n.Message{
+				i18n.M(i18n.PasswordRules, 6, 64),
+			},
+		},
+		{
+			name: "passed",
+			args: args{
+				request: generated.ResetPasswordRequest{
+					PhoneNumber: "+628123456",
+					Code:        "123456",
+					Password:    "Sawit@Pr0",
+				},
+			},
+			wantRes: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRes := validateResetPassword(testConfig.Validation, tt.args.request)
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("validateResetPassword() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_validateChangePassword(t *testing.T) {
+	type args struct {
+		request generated.ChangePasswordRequest
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantRes []i18n.Message
+	}{
+		{
+			name: "invalid new password",
+			args: args{
+				request: generated.ChangePasswordRequest{
+					CurrentPassword: "Sawit@Pr0",
+					NewPassword:     "sawitpro",
+				},
+			},
+			wantRes: []i18n.Message{
+				i18n.M(i18n.PasswordRules, 6, 64),
+			},
+		},
+		{
+			name: "same password",
+			args: args{
+				request: generated.ChangePasswordRequest{
+					CurrentPassword: "Sawit@Pr0",
+					NewPassword:     "Sawit@Pr0",
+				},
+			},
+			wantRes: []i18n.Message{
+				i18n.M(i18n.PasswordUnchanged),
+			},
+		},
+		{
+			name: "passed",
+			args: args{
+				request: generated.ChangePasswordRequest{
+					CurrentPassword: "Sawit@Pr0",
+					NewPassword:     "Sawit@Pr1",
+				},
+			},
+			wantRes: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRes := validateChangePassword(testConfig.Validation, tt.args.request)
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("validateChangePassword() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_normalizePhoneNumber(t *testing.T) {
+	type args struct {
+		cfg   config.ValidationConfig
+		input string
+	}
+	tests := []struct {
+		name            string
+		args            args
+		wantPhoneNumber string
+		wantRes         []i18n.Message
 	}{
 		{
 			name: "too short",
 			args: args{
+				cfg:   testConfig.Validation,
 				input: "+62822334",
 			},
-			wantRes: []string{
-				"Phone numbers must be at minimum 10 characters and maximum 13 characters",
+			wantRes: []i18n.Message{
+				i18n.M(i18n.PhoneNumberLength, i18n.Country("ID"), 7, 12, "62"),
 			},
 		},
 		{
 			name: "too long",
 			args: args{
-				input: "+6282233445566",
+				cfg:   testConfig.Validation,
+				input: "+628223344556677",
 			},
-			wantRes: []string{
-				"Phone numbers must be at minimum 10 characters and maximum 13 characters",
+			wantRes: []i18n.Message{
+				i18n.M(i18n.PhoneNumberLength, i18n.Country("ID"), 7, 12, "62"),
 			},
 		},
 		{
-			name: "invalid prefix",
+			name: "without country code",
 			args: args{
+				cfg:   testConfig.Validation,
 				input: "1234567890",
 			},
-			wantRes: []string{
-				"Phone numbers must start with the Indonesia country code “+62”",
+			wantRes: []i18n.Message{
+				i18n.M(i18n.PhoneNumberFormat),
+			},
+		},
+		{
+			name: "country not accepted",
+			args: args{
+				cfg:   testConfig.Validation,
+				input: "+60123456789",
+			},
+			wantRes: []i18n.Message{
+				i18n.M(i18n.PhoneNumberCountry, i18n.List{
+					i18n.M(i18n.PhoneNumberCountryCode, i18n.Country("ID"), "62"),
+				}),
+			},
+		},
+		{
+			name: "unsupported country",
+			args: args{
+				cfg: config.ValidationConfig{
+					PhoneCountries: []string{"ID", "SG"},
+				},
+				input: "+14155552671",
+			},
+			wantRes: []i18n.Message{
+				i18n.M(i18n.PhoneNumberCountry, i18n.List{
+					i18n.M(i18n.PhoneNumberCountryCode, i18n.Country("ID"), "62"),
+					i18n.M(i18n.PhoneNumberCountryCode, i18n.Country("SG"), "65"),
+				}),
+			},
+		},
+		{
+			name: "invalid prefix",
+			args: args{
+				cfg: config.ValidationConfig{
+					PhoneCountries: []string{"SG"},
+				},
+				input: "+6512345678",
+			},
+			wantRes: []i18n.Message{
+				i18n.M(i18n.PhoneNumberPrefix, i18n.Country("SG")),
 			},
 		},
 		{
 			name: "passed",
 			args: args{
+				cfg:   testConfig.Validation,
 				input: "+628223344556",
 			},
-			wantRes: nil,
+			wantPhoneNumber: "+628223344556",
+			wantRes:         nil,
+		},
+		{
+			name: "passed with formatting",
+			args: args{
+				cfg:   testConfig.Validation,
+				input: "+62 822-3344-556",
+			},
+			wantPhoneNumber: "+628223344556",
+			wantRes:         nil,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := validatePhoneNumber(tt.args.input)
+			gotPhoneNumber, gotRes := normalizePhoneNumber(tt.args.cfg, tt.args.input)
+			if gotPhoneNumber != tt.wantPhoneNumber {
+				t.Errorf("normalizePhoneNumber() gotPhoneNumber = %s, wantPhoneNumber = %s", gotPhoneNumber, tt.wantPhoneNumber)
+			}
 			if !reflect.DeepEqual(gotRes, tt.wantRes) {
-				t.Errorf("validatePhoneNumber() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+				t.Errorf("normalizePhoneNumber() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
 			}
 		})
 	}
@@ -132,15 +309,15 @@ func Test_validateFullName(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		wantRes []string
+		wantRes []i18n.Message
 	}{
 		{
 			name: "too short",
 			args: args{
 				input: "12",
 			},
-			wantRes: []string{
-				"Full name must be at minimum 3 characters and maximum 60 characters",
+			wantRes: []i18n.Message{
+				i18n.M(i18n.FullNameLength, 3, 60),
 			},
 		},
 		{
@@ -154,8 +331,8 @@ func Test_validateFullName(t *testing.T) {
 					"1234567890" +
 					"1",
 			},
-			wantRes: []string{
-				"Full name must be at minimum 3 characters and maximum 60 characters",
+			wantRes: []i18n.Message{
+				i18n.M(i18n.FullNameLength, 3, 60),
 			},
 		},
 		{
@@ -168,7 +345,7 @@ func Test_validateFullName(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := validateFullName(tt.args.input)
+			gotRes := validateFullName(testConfig.Validation, tt.args.input)
 			if !reflect.DeepEqual(gotRes, tt.wantRes) {
 				t.Errorf("validateFullName() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
 			}
@@ -195,10 +372,24 @@ func Test_validatePassword(t *testing.T) {
 		{
 			name: "too long",
 			args: args{
-				input: "12",
+				input: "Sawit@Pr0" + strings.Repeat("a", testConfig.Validation.PasswordMaxLength-8),
 			},
 			wantRes: false,
 		},
+		{
+			name: "longest",
+			args: args{
+				input: "Sawit@Pr0" + strings.Repeat("a", testConfig.Validation.PasswordMaxLength-9),
+			},
+			wantRes: true,
+		},
+		{
+			name: "longest counted in characters",
+			args: args{
+				input: "Sawit@Pr0" + strings.Repeat("é", testConfig.Validation.PasswordMaxLength-9),
+			},
+			wantRes: true,
+		},
 		{
 			name: "no capital",
 			args: args{
@@ -230,7 +421,7 @@ func Test_validatePassword(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := validatePassword(tt.args.input)
+			gotRes := validatePassword(testConfig.Validation, tt.args.input)
 			if !reflect.DeepEqual(gotRes, tt.wantRes) {
 				t.Errorf("validatePassword() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
 			}
@@ -314,7 +505,7 @@ func Test_checkNewPhoneNumber(t *testing.T) {
 			},
 			mock: func(fields *fields) {
 				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+628223344556").
-					Return(repository.User{}, nil).
+					Return(repository.User{}, repository.ErrNotFound).
 					Times(1)
 			},
 			wantRes: true,
@@ -324,7 +515,9 @@ func Test_checkNewPhoneNumber(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Server{
+				config:     testConfig,
 				Repository: tt.fields.Repository,
+				KeyRing:    testKeyRing,
 			}
 			tt.mock(&tt.fields)
 			gotRes, gotErr := checkNewPhoneNumber(tt.args.ctx, s, tt.args.phoneNumber)
@@ -0,0 +1,140 @@
+// Package i18n translates the messages of responses into the language a
+// request accepts. Messages are looked up by stable IDs, see messages.go, in
+// the catalog of each supported language, falling back to English.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	English    = "en"
+	Indonesian = "id"
+)
+
+// DefaultLanguage is the language of messages missing from a catalog.
+const DefaultLanguage = English
+
+var catalogs = map[string]map[string]string{
+	English:    messagesEnglish,
+	Indonesian: messagesIndonesian,
+}
+
+// Supported tells whether lang, such as "id", has a catalog.
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Message is a message of the catalogs with the arguments of its verbs.
+// Arguments that are a Message or a List are translated as well.
+type Message struct {
+	ID   string
+	Args []any
+}
+
+// M returns the message id with args.
+func M(id string, args ...any) Message {
+	return Message{ID: id, Args: args}
+}
+
+// List is an argument listing alternatives, written as "a or b or c".
+type List []Message
+
+// Localize writes message in lang. Messages missing from the catalog of
+// lang are written in DefaultLanguage, and unknown messages as their ID.
+func Localize(lang string, message Message) string {
+	format, ok := catalogs[lang][message.ID]
+	if !ok {
+		format, ok = catalogs[DefaultLanguage][message.ID]
+	}
+	if !ok {
+		return message.ID
+	}
+	if len(message.Args) == 0 {
+		return format
+	}
+
+	args := make([]any, 0, len(message.Args))
+	for _, arg := range message.Args {
+		switch arg := arg.(type) {
+		case Message:
+			args = append(args, Localize(lang, arg))
+		case List:
+			items := make([]string, 0, len(arg))
+			for _, item := range arg {
+				items = append(items, Localize(lang, item))
+			}
+			args = append(args, strings.Join(items, Localize(lang, M(ListOr))))
+		default:
+			args = append(args, arg)
+		}
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// LocalizeAll writes messages in lang, returning their IDs alongside.
+func LocalizeAll(lang string, messages []Message) (ids []string, texts []string) {
+	ids = make([]string, 0, len(messages))
+	texts = make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+		texts = append(texts, Localize(lang, message))
+	}
+	return ids, texts
+}
+
+// Negotiate picks the supported language an Accept-Language header, such as
+// "id-ID,id;q=0.9,en;q=0.8", prefers, matching regional variants by their
+// primary language. It returns fallback when none is accepted.
+func Negotiate(acceptLanguage string, fallback string) string {
+	var (
+		best        = fallback
+		bestQuality float64
+	)
+	for _, languageRange := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(languageRange, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if name == "q" {
+				q, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					q = 0
+				}
+				quality = q
+			}
+		}
+
+		primary, _, _ := strings.Cut(tag, "-")
+		if primary == "*" {
+			primary = fallback
+		}
+		if quality > bestQuality && Supported(primary) {
+			best = primary
+			bestQuality = quality
+		}
+	}
+	return best
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the language of the request.
+func NewContext(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// FromContext returns the language of the request, DefaultLanguage when ctx
+// carries none.
+func FromContext(ctx context.Context) string {
+	lang, ok := ctx.Value(contextKey{}).(string)
+	if !ok {
+		return DefaultLanguage
+	}
+	return lang
+}
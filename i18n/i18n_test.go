@@ -0,0 +1,173 @@
+package i18n
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+func Test_catalogs(t *testing.T) {
+	verbs := regexp.MustCompile(`%\[\d+\][a-z]`)
+	for lang, catalog := range catalogs {
+		if lang == DefaultLanguage {
+			continue
+		}
+		for id, format := range catalogs[DefaultLanguage] {
+			translation, ok := catalog[id]
+			if !ok {
+				t.Errorf("catalog %s is missing %s", lang, id)
+				continue
+			}
+			wantVerbs := verbs.FindAllString(format, -1)
+			gotVerbs := verbs.FindAllString(translation, -1)
+			sort.Strings(wantVerbs)
+			sort.Strings(gotVerbs)
+			if !reflect.DeepEqual(gotVerbs, wantVerbs) {
+				t.Errorf("catalog %s gotVerbs of %s = %v, wantVerbs = %v", lang, id, gotVerbs, wantVerbs)
+			}
+		}
+		for id := range catalog {
+			if _, ok := catalogs[DefaultLanguage][id]; !ok {
+				t.Errorf("catalog %s has %s, missing from %s", lang, id, DefaultLanguage)
+			}
+		}
+	}
+}
+
+func Test_Localize(t *testing.T) {
+	tests := []struct {
+		name    string
+		lang    string
+		message Message
+		want    string
+	}{
+		{
+			name:    "english",
+			lang:    English,
+			message: M(UserNotFound),
+			want:    "User not found",
+		},
+		{
+			name:    "indonesian",
+			lang:    Indonesian,
+			message: M(UserNotFound),
+			want:    "Pengguna tidak ditemukan",
+		},
+		{
+			name:    "arguments",
+			lang:    Indonesian,
+			message: M(FullNameLength, 3, 60),
+			want:    "Nama lengkap harus terdiri dari minimal 3 karakter dan maksimal 60 karakter",
+		},
+		{
+			name:    "message argument",
+			lang:    Indonesian,
+			message: M(PhoneNumberPrefix, Country("SG")),
+			want:    "Nomor telepon bukan nomor Singapura yang valid",
+		},
+		{
+			name: "list argument",
+			lang: English,
+			message: M(PhoneNumberCountry, List{
+				M(PhoneNumberCountryCode, Country("ID"), "62"),
+				M(PhoneNumberCountryCode, Country("PH"), "63"),
+			}),
+			want: "Phone numbers must be from Indonesia (+62) or Philippines (+63)",
+		},
+		{
+			name:    "unsupported language",
+			lang:    "fr",
+			message: M(UserNotFound),
+			want:    "User not found",
+		},
+		{
+			name:    "unknown message",
+			lang:    English,
+			message: M("unknown"),
+			want:    "unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Localize(tt.lang, tt.message); got != tt.want {
+				t.Errorf("Localize() got = %q, want = %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Negotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		fallback       string
+		want           string
+	}{
+		{
+			name:           "exact",
+			acceptLanguage: "id",
+			fallback:       English,
+			want:           Indonesian,
+		},
+		{
+			name:           "regional variant",
+			acceptLanguage: "id-ID",
+			fallback:       English,
+			want:           Indonesian,
+		},
+		{
+			name:           "quality",
+			acceptLanguage: "en;q=0.5, id-ID;q=0.8",
+			fallback:       English,
+			want:           Indonesian,
+		},
+		{
+			name:           "unsupported preferred",
+			acceptLanguage: "fr-FR, fr;q=0.9, en;q=0.8",
+			fallback:       Indonesian,
+			want:           English,
+		},
+		{
+			name:           "refused",
+			acceptLanguage: "id;q=0",
+			fallback:       English,
+			want:           English,
+		},
+		{
+			name:           "anything",
+			acceptLanguage: "*",
+			fallback:       Indonesian,
+			want:           Indonesian,
+		},
+		{
+			name:           "none supported",
+			acceptLanguage: "ja, zh-CN;q=0.9",
+			fallback:       English,
+			want:           English,
+		},
+		{
+			name:           "missing",
+			acceptLanguage: "",
+			fallback:       Indonesian,
+			want:           Indonesian,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.acceptLanguage, tt.fallback); got != tt.want {
+				t.Errorf("Negotiate() got = %s, want = %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_FromContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != DefaultLanguage {
+		t.Errorf("FromContext() got = %s, want = %s", got, DefaultLanguage)
+	}
+	if got := FromContext(NewContext(context.Background(), Indonesian)); got != Indonesian {
+		t.Errorf("FromContext() got = %s, want = %s", got, Indonesian)
+	}
+}
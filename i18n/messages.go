@@ -0,0 +1,185 @@
+package i18n
+
+// The IDs of messages are part of the API, clients may rely on them rather
+// than on the text, so they are never renamed. Verbs are indexed, such as
+// %[1]s, so translations may order the arguments differently.
+const (
+	// general
+	BadRequest         = "bad_request"
+	SystemError        = "system_error"
+	NotFound           = "not_found"
+	Conflict           = "conflict"
+	Unavailable        = "unavailable"
+	TooManyRequests    = "too_many_requests"
+	PasswordHashingErr = "password.hashing_error"
+	ListOr             = "list.or"
+
+	// readiness
+	ReadinessShuttingDown = "readiness.shutting_down"
+	ReadinessDatabase     = "readiness.database_unavailable"
+	ReadinessSigningKey   = "readiness.no_signing_key"
+
+	// request fields, checked against the spec
+	FieldRequired     = "field.required"
+	FieldUnknown      = "field.unknown"
+	FieldType         = "field.type"
+	FieldEmpty        = "field.empty"
+	FieldMinLength    = "field.min_length"
+	FieldMaxLength    = "field.max_length"
+	FieldEnum         = "field.enum"
+	FieldFormat       = "field.format"
+	FieldInvalid      = "field.invalid"
+	FieldInvalidValue = "field.invalid_value"
+
+	// phone number
+	PhoneNumberFormat            = "phone_number.format"
+	PhoneNumberCountry           = "phone_number.country"
+	PhoneNumberLength            = "phone_number.length"
+	PhoneNumberPrefix            = "phone_number.prefix"
+	PhoneNumberCountryCode       = "phone_number.country_code"
+	PhoneNumberAlreadyRegistered = "phone_number.already_registered"
+	PhoneNumberNotRegistered     = "phone_number.not_registered"
+	PhoneNumberNotVerified       = "phone_number.not_verified"
+
+	// user
+	FullNameLength    = "full_name.length"
+	PasswordRules     = "password.rules"
+	PasswordUnchanged = "password.unchanged"
+	PasswordWrong     = "password.wrong"
+	ProfileNoChanges  = "profile.no_changes"
+	UserNotFound      = "user.not_found"
+
+	// verification codes
+	CodeInvalid         = "code.invalid"
+	CodeTooManyAttempts = "code.too_many_attempts"
+
+	// login
+	LoginAccountLocked         = "login.account_locked"
+	LoginThrottled             = "login.throttled"
+	LoginLockedBySupport       = "login.locked_by_support"
+	LoginPasswordResetRequired = "login.password_reset_required"
+
+	// session
+	SessionTokenMissing = "session.token_missing"
+	SessionNone         = "session.none"
+	SessionCheckError   = "session.check_error"
+	SessionRevoked      = "session.revoked"
+	SessionExpired      = "session.expired"
+	SessionParseError   = "session.parse_error"
+
+	// permissions
+	PermissionDenied     = "permission.denied"
+	PermissionOwnAccount = "permission.own_account"
+	PermissionTargetRole = "permission.target_role"
+
+	// refresh token
+	RefreshTokenInvalid = "refresh_token.invalid"
+	RefreshTokenExpired = "refresh_token.expired"
+	RefreshTokenUsed    = "refresh_token.used"
+
+	// two-factor authentication
+	MfaInvalidCode        = "mfa.invalid_code"
+	MfaInvalidToken       = "mfa.invalid_token"
+	MfaTokenUsed          = "mfa.token_used"
+	MfaNotEnabled         = "mfa.not_enabled"
+	MfaAlreadyEnabled     = "mfa.already_enabled"
+	MfaEnrollmentNotFound = "mfa.enrollment_not_found"
+
+	// data export
+	DataExportNotFound = "data_export.not_found"
+	DataExportExpired  = "data_export.expired"
+	DataExportFailed   = "data_export.failed"
+
+	// user search
+	SearchCursorInvalid = "search.cursor_invalid"
+)
+
+// Country returns the name of a country by its ISO 3166-1 alpha-2 code.
+func Country(code string) Message {
+	return M("country." + code)
+}
+
+var messagesEnglish = map[string]string{
+	BadRequest:         "Bad request",
+	SystemError:        "System error",
+	NotFound:           "Not found",
+	Conflict:           "Conflict with the current state, please try again",
+	Unavailable:        "Service unavailable, please try again later",
+	TooManyRequests:    "Too many requests, try again later",
+	PasswordHashingErr: "There was an error when handling password",
+	ListOr:             " or ",
+
+	ReadinessShuttingDown: "Shutting down",
+	ReadinessDatabase:     "Database unavailable",
+	ReadinessSigningKey:   "No signing key",
+
+	FieldRequired:     "%[1]s is required",
+	FieldUnknown:      "%[1]s is not a known field",
+	FieldType:         "%[1]s must be of type %[2]s",
+	FieldEmpty:        "%[1]s must not be empty",
+	FieldMinLength:    "%[1]s must be at least %[2]d characters",
+	FieldMaxLength:    "%[1]s must be at most %[2]d characters",
+	FieldEnum:         "%[1]s must be one of %[2]s",
+	FieldFormat:       "%[1]s has an invalid format",
+	FieldInvalid:      "%[1]s is invalid: %[2]s",
+	FieldInvalidValue: "%[1]s is invalid",
+
+	PhoneNumberFormat:            "Phone numbers must start with “+” and the country code",
+	PhoneNumberCountry:           "Phone numbers must be from %[1]s",
+	PhoneNumberLength:            "Phone numbers of %[1]s must have %[2]d to %[3]d digits after the country code “+%[4]s”",
+	PhoneNumberPrefix:            "Phone number is not a valid number of %[1]s",
+	PhoneNumberCountryCode:       "%[1]s (+%[2]s)",
+	PhoneNumberAlreadyRegistered: "Phone number is already registered",
+	PhoneNumberNotRegistered:     "Phone number is not registered",
+	PhoneNumberNotVerified:       "Phone number is not verified, a verification code has been sent",
+
+	FullNameLength:    "Full name must be at minimum %[1]d characters and maximum %[2]d characters",
+	PasswordRules:     "Passwords must be minimum %[1]d characters and maximum %[2]d characters, containing at least 1 capital characters AND 1 number AND 1 special (non alpha-numeric) characters",
+	PasswordUnchanged: "New password must be different from the current password",
+	PasswordWrong:     "Wrong password",
+	ProfileNoChanges:  "No changes",
+	UserNotFound:      "User not found",
+
+	CodeInvalid:         "Invalid or expired code",
+	CodeTooManyAttempts: "Too many attempts, please request a new code",
+
+	LoginAccountLocked:         "Account is temporarily locked, try again later",
+	LoginThrottled:             "Too many failed login attempts, try again later",
+	LoginLockedBySupport:       "Account is locked, please contact support",
+	LoginPasswordResetRequired: "Password must be reset before logging in, a reset code has been sent",
+
+	SessionTokenMissing: "JWT token not found",
+	SessionNone:         "No session",
+	SessionCheckError:   "There was an error when checking session",
+	SessionRevoked:      "Session is revoked",
+	SessionExpired:      "Session is expired",
+	SessionParseError:   "There was an error when parsing JWT",
+
+	PermissionDenied:     "The %[1]s permission is required",
+	PermissionOwnAccount: "Your own account cannot be managed here",
+	PermissionTargetRole: "Accounts with a role granting as much as yours cannot be managed",
+
+	RefreshTokenInvalid: "Invalid refresh token",
+	RefreshTokenExpired: "Refresh token is expired",
+	RefreshTokenUsed:    "Refresh token has already been used",
+
+	MfaInvalidCode:        "Invalid code",
+	MfaInvalidToken:       "Invalid MFA token",
+	MfaTokenUsed:          "MFA token has already been used",
+	MfaNotEnabled:         "Two-factor authentication is not enabled",
+	MfaAlreadyEnabled:     "Two-factor authentication is already enabled",
+	MfaEnrollmentNotFound: "Two-factor authentication enrollment not found",
+
+	DataExportNotFound: "Data export not found",
+	DataExportExpired:  "Data export has expired, please request a new one",
+	DataExportFailed:   "Data export could not be created, please request a new one",
+
+	SearchCursorInvalid: "The cursor does not continue this search, please start it over",
+
+	"country.ID": "Indonesia",
+	"country.MY": "Malaysia",
+	"country.SG": "Singapore",
+	"country.TH": "Thailand",
+	"country.VN": "Vietnam",
+	"country.PH": "Philippines",
+}
@@ -0,0 +1,86 @@
+package i18n
+
+var messagesIndonesian = map[string]string{
+	BadRequest:         "Permintaan tidak valid",
+	SystemError:        "Terjadi kesalahan sistem",
+	NotFound:           "Tidak ditemukan",
+	Conflict:           "Terjadi konflik dengan data saat ini, silakan coba lagi",
+	Unavailable:        "Layanan tidak tersedia, silakan coba lagi nanti",
+	TooManyRequests:    "Terlalu banyak permintaan, coba lagi nanti",
+	PasswordHashingErr: "Terjadi kesalahan saat memproses kata sandi",
+	ListOr:             " atau ",
+
+	ReadinessShuttingDown: "Sedang dimatikan",
+	ReadinessDatabase:     "Basis data tidak tersedia",
+	ReadinessSigningKey:   "Tidak ada kunci penandatanganan",
+
+	FieldRequired:     "%[1]s wajib diisi",
+	FieldUnknown:      "%[1]s bukan field yang dikenal",
+	FieldType:         "%[1]s harus bertipe %[2]s",
+	FieldEmpty:        "%[1]s tidak boleh kosong",
+	FieldMinLength:    "%[1]s minimal %[2]d karakter",
+	FieldMaxLength:    "%[1]s maksimal %[2]d karakter",
+	FieldEnum:         "%[1]s harus salah satu dari %[2]s",
+	FieldFormat:       "Format %[1]s tidak valid",
+	FieldInvalid:      "%[1]s tidak valid: %[2]s",
+	FieldInvalidValue: "%[1]s tidak valid",
+
+	PhoneNumberFormat:            "Nomor telepon harus diawali “+” dan kode negara",
+	PhoneNumberCountry:           "Nomor telepon harus dari %[1]s",
+	PhoneNumberLength:            "Nomor telepon %[1]s harus terdiri dari %[2]d sampai %[3]d digit setelah kode negara “+%[4]s”",
+	PhoneNumberPrefix:            "Nomor telepon bukan nomor %[1]s yang valid",
+	PhoneNumberCountryCode:       "%[1]s (+%[2]s)",
+	PhoneNumberAlreadyRegistered: "Nomor telepon sudah terdaftar",
+	PhoneNumberNotRegistered:     "Nomor telepon belum terdaftar",
+	PhoneNumberNotVerified:       "Nomor telepon belum diverifikasi, kode verifikasi telah dikirim",
+
+	FullNameLength:    "Nama lengkap harus terdiri dari minimal %[1]d karakter dan maksimal %[2]d karakter",
+	PasswordRules:     "Kata sandi harus terdiri dari minimal %[1]d karakter dan maksimal %[2]d karakter, mengandung setidaknya 1 huruf kapital, 1 angka, dan 1 karakter khusus (non alfanumerik)",
+	PasswordUnchanged: "Kata sandi baru harus berbeda dari kata sandi saat ini",
+	PasswordWrong:     "Kata sandi salah",
+	ProfileNoChanges:  "Tidak ada perubahan",
+	UserNotFound:      "Pengguna tidak ditemukan",
+
+	CodeInvalid:         "Kode tidak valid atau sudah kedaluwarsa",
+	CodeTooManyAttempts: "Terlalu banyak percobaan, silakan minta kode baru",
+
+	LoginAccountLocked:         "Akun dikunci sementara, coba lagi nanti",
+	LoginThrottled:             "Terlalu banyak percobaan masuk yang gagal, coba lagi nanti",
+	LoginLockedBySupport:       "Akun dikunci, silakan hubungi dukungan",
+	LoginPasswordResetRequired: "Kata sandi harus diatur ulang sebelum masuk, kode atur ulang telah dikirim",
+
+	SessionTokenMissing: "Token JWT tidak ditemukan",
+	SessionNone:         "Tidak ada sesi",
+	SessionCheckError:   "Terjadi kesalahan saat memeriksa sesi",
+	SessionRevoked:      "Sesi telah dicabut",
+	SessionExpired:      "Sesi telah kedaluwarsa",
+	SessionParseError:   "Terjadi kesalahan saat membaca JWT",
+
+	PermissionDenied:     "Izin %[1]s diperlukan",
+	PermissionOwnAccount: "Akun Anda sendiri tidak dapat dikelola di sini",
+	PermissionTargetRole: "Akun dengan peran yang memberi izin sebanyak peran Anda tidak dapat dikelola",
+
+	RefreshTokenInvalid: "Refresh token tidak valid",
+	RefreshTokenExpired: "Refresh token sudah kedaluwarsa",
+	RefreshTokenUsed:    "Refresh token sudah pernah digunakan",
+
+	MfaInvalidCode:        "Kode tidak valid",
+	MfaInvalidToken:       "Token MFA tidak valid",
+	MfaTokenUsed:          "Token MFA sudah pernah digunakan",
+	MfaNotEnabled:         "Autentikasi dua faktor belum diaktifkan",
+	MfaAlreadyEnabled:     "Autentikasi dua faktor sudah diaktifkan",
+	MfaEnrollmentNotFound: "Pendaftaran autentikasi dua faktor tidak ditemukan",
+
+	DataExportNotFound: "Ekspor data tidak ditemukan",
+	DataExportExpired:  "Ekspor data sudah kedaluwarsa, silakan minta ekspor baru",
+	DataExportFailed:   "Ekspor data gagal dibuat, silakan minta ekspor baru",
+
+	SearchCursorInvalid: "Kursor tidak melanjutkan pencarian ini, silakan mulai ulang pencarian",
+
+	"country.ID": "Indonesia",
+	"country.MY": "Malaysia",
+	"country.SG": "Singapura",
+	"country.TH": "Thailand",
+	"country.VN": "Vietnam",
+	"country.PH": "Filipina",
+}
@@ -0,0 +1,37 @@
+package i18n
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerAcceptLanguage  = "Accept-Language"
+	headerContentLanguage = "Content-Language"
+)
+
+type MiddlewareOptions struct {
+	// DefaultLanguage answers requests accepting no supported language
+	DefaultLanguage string
+}
+
+// Middleware negotiates the language of a request from its Accept-Language
+// header and carries it in the request context, see FromContext, telling it
+// in the Content-Language header of the response.
+func Middleware(opts MiddlewareOptions) echo.MiddlewareFunc {
+	fallback := opts.DefaultLanguage
+	if !Supported(fallback) {
+		fallback = DefaultLanguage
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			lang := Negotiate(ctx.Request().Header.Get(headerAcceptLanguage), fallback)
+			ctx.SetRequest(ctx.Request().WithContext(NewContext(ctx.Request().Context(), lang)))
+
+			header := ctx.Response().Header()
+			header.Set(headerContentLanguage, lang)
+			// caches have to tell the languages of responses apart
+			header.Add(echo.HeaderVary, headerAcceptLanguage)
+			return next(ctx)
+		}
+	}
+}
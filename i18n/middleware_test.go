@@ -0,0 +1,66 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func Test_Middleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           MiddlewareOptions
+		acceptLanguage string
+		wantLanguage   string
+		wantBody       string
+	}{
+		{
+			name:           "accepted language",
+			opts:           MiddlewareOptions{DefaultLanguage: English},
+			acceptLanguage: "id-ID,id;q=0.9,en;q=0.8",
+			wantLanguage:   Indonesian,
+			wantBody:       "Pengguna tidak ditemukan",
+		},
+		{
+			name:         "default language",
+			opts:         MiddlewareOptions{DefaultLanguage: Indonesian},
+			wantLanguage: Indonesian,
+			wantBody:     "Pengguna tidak ditemukan",
+		},
+		{
+			name:           "unsupported default language",
+			opts:           MiddlewareOptions{DefaultLanguage: "fr"},
+			acceptLanguage: "fr",
+			wantLanguage:   English,
+			wantBody:       "User not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(Middleware(tt.opts))
+			e.GET("/", func(ctx echo.Context) error {
+				return ctx.String(http.StatusNotFound, Localize(FromContext(ctx.Request().Context()), M(UserNotFound)))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set(headerAcceptLanguage, tt.acceptLanguage)
+			}
+			res := httptest.NewRecorder()
+			e.ServeHTTP(res, req)
+
+			if got := res.Header().Get(headerContentLanguage); got != tt.wantLanguage {
+				t.Errorf("Middleware() gotContentLanguage = %s, wantContentLanguage = %s", got, tt.wantLanguage)
+			}
+			if got := res.Header().Get(echo.HeaderVary); got != headerAcceptLanguage {
+				t.Errorf("Middleware() gotVary = %s, wantVary = %s", got, headerAcceptLanguage)
+			}
+			if res.Body.String() != tt.wantBody {
+				t.Errorf("Middleware() gotBody = %s, wantBody = %s", res.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
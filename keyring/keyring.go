@@ -0,0 +1,150 @@
+// Package keyring holds the RSA keys used to sign and verify session JWTs.
+//
+// Exactly one key is used for signing at a time. Other keys stay available
+// for verification so tokens signed before a rotation remain valid until they
+// expire, and retired keys are rejected entirely.
+package keyring
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+type KeyStatus string
+
+const (
+	// KeyStatusActive keys sign new tokens and verify existing ones.
+	KeyStatusActive KeyStatus = "active"
+	// KeyStatusVerify keys only verify tokens signed before a rotation.
+	KeyStatusVerify KeyStatus = "verify"
+	// KeyStatusRetired keys are loaded but never used.
+	KeyStatusRetired KeyStatus = "retired"
+)
+
+var (
+	ErrNoSigningKey = errors.New("keyring: no signing key")
+	ErrUnknownKey   = errors.New("keyring: unknown key id")
+	ErrRetiredKey   = errors.New("keyring: key is retired")
+)
+
+type Key struct {
+	ID         string
+	PrivateKey *rsa.PrivateKey
+	PublicKey  *rsa.PublicKey
+	Status     KeyStatus
+}
+
+type KeyRing struct {
+	keys         map[string]Key
+	signingKeyID string
+}
+
+// JSONWebKey is the RFC 7517 representation of an RSA public key.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// New builds a key ring out of the given keys. The key identified by
+// signingKeyID becomes the active key; every other key that is not retired
+// is kept for verification only.
+func New(keys []Key, signingKeyID string) (*KeyRing, error) {
+	kr := &KeyRing{
+		keys: make(map[string]Key, len(keys)),
+	}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("keyring: key id must not be empty")
+		}
+		if _, ok := kr.keys[key.ID]; ok {
+			return nil, fmt.Errorf("keyring: duplicate key id %q", key.ID)
+		}
+		if key.PublicKey == nil && key.PrivateKey != nil {
+			key.PublicKey = &key.PrivateKey.PublicKey
+		}
+		if key.PublicKey == nil {
+			return nil, fmt.Errorf("keyring: key %q has no public key", key.ID)
+		}
+		if key.Status != KeyStatusRetired {
+			key.Status = KeyStatusVerify
+		}
+		kr.keys[key.ID] = key
+	}
+
+	signingKey, ok := kr.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNoSigningKey, signingKeyID)
+	}
+	if signingKey.PrivateKey == nil {
+		return nil, fmt.Errorf("%w: key %q has no private key", ErrNoSigningKey, signingKeyID)
+	}
+	if signingKey.Status == KeyStatusRetired {
+		return nil, fmt.Errorf("%w: key %q is retired", ErrNoSigningKey, signingKeyID)
+	}
+	signingKey.Status = KeyStatusActive
+	kr.keys[signingKeyID] = signingKey
+	kr.signingKeyID = signingKeyID
+
+	return kr, nil
+}
+
+// SigningKey returns the active key used to sign new tokens.
+func (kr *KeyRing) SigningKey() Key {
+	return kr.keys[kr.signingKeyID]
+}
+
+// VerificationKey returns the public key for the given kid header. Tokens
+// issued before key ids were stamped carry no kid and are checked against
+// the signing key.
+func (kr *KeyRing) VerificationKey(kid string) (*rsa.PublicKey, error) {
+	if kid == "" {
+		return kr.SigningKey().PublicKey, nil
+	}
+	key, ok := kr.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if key.Status == KeyStatusRetired {
+		return nil, fmt.Errorf("%w: %q", ErrRetiredKey, kid)
+	}
+	return key.PublicKey, nil
+}
+
+// Keys returns every key in the ring ordered by id.
+func (kr *KeyRing) Keys() []Key {
+	keys := make([]Key, 0, len(kr.keys))
+	for _, key := range kr.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// JWKS returns the public part of every non-retired key.
+func (kr *KeyRing) JWKS() []JSONWebKey {
+	var res []JSONWebKey
+	for _, key := range kr.Keys() {
+		if key.Status == KeyStatusRetired {
+			continue
+		}
+		res = append(res, JSONWebKey{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: key.ID,
+			N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		})
+	}
+	return res
+}
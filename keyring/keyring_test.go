@@ -0,0 +1,277 @@
+package keyring
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func generateTestKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %s", err.Error())
+	}
+	return key
+}
+
+func encodePrivateKey(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
+
+func encodePublicKey(t *testing.T, key *rsa.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKIXPublicKey() error = %s", err.Error())
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: der,
+	})
+}
+
+func Test_New(t *testing.T) {
+	keyA := generateTestKey(t)
+	keyB := generateTestKey(t)
+	type args struct {
+		keys         []Key
+		signingKeyID string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantStatuses map[string]KeyStatus
+		wantErr      error
+	}{
+		{
+			name: "unknown signing key",
+			args: args{
+				keys: []Key{
+					{ID: "a", PrivateKey: keyA},
+				},
+				signingKeyID: "b",
+			},
+			wantErr: ErrNoSigningKey,
+		},
+		{
+			name: "signing key without private key",
+			args: args{
+				keys: []Key{
+					{ID: "a", PublicKey: &keyA.PublicKey},
+				},
+				signingKeyID: "a",
+			},
+			wantErr: ErrNoSigningKey,
+		},
+		{
+			name: "retired signing key",
+			args: args{
+				keys: []Key{
+					{ID: "a", PrivateKey: keyA, Status: KeyStatusRetired},
+				},
+				signingKeyID: "a",
+			},
+			wantErr: ErrNoSigningKey,
+		},
+		{
+			name: "passed",
+			args: args{
+				keys: []Key{
+					{ID: "a", PrivateKey: keyA},
+					{ID: "b", PrivateKey: keyB},
+					{ID: "c", PublicKey: &keyB.PublicKey, Status: KeyStatusRetired},
+				},
+				signingKeyID: "b",
+			},
+			wantStatuses: map[string]KeyStatus{
+				"a": KeyStatusVerify,
+				"b": KeyStatusActive,
+				"c": KeyStatusRetired,
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRes, gotErr := New(tt.args.keys, tt.args.signingKeyID)
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Errorf("New() gotErr = %v, wantErr = %v", gotErr, tt.wantErr)
+			}
+			if gotErr != nil {
+				return
+			}
+			gotStatuses := map[string]KeyStatus{}
+			for _, key := range gotRes.Keys() {
+				gotStatuses[key.ID] = key.Status
+			}
+			if !reflect.DeepEqual(gotStatuses, tt.wantStatuses) {
+				t.Errorf("New() gotStatuses = %v, wantStatuses = %v", gotStatuses, tt.wantStatuses)
+			}
+		})
+	}
+}
+
+func Test_KeyRing_VerificationKey(t *testing.T) {
+	keyA := generateTestKey(t)
+	keyB := generateTestKey(t)
+	kr, err := New([]Key{
+		{ID: "a", PrivateKey: keyA},
+		{ID: "b", PrivateKey: keyB},
+		{ID: "old", PublicKey: &keyB.PublicKey, Status: KeyStatusRetired},
+	}, "b")
+	if err != nil {
+		t.Fatalf("New() error = %s", err.Error())
+	}
+	tests := []struct {
+		name    string
+		kid     string
+		wantRes *rsa.PublicKey
+		wantErr error
+	}{
+		{
+			name:    "no kid falls back to signing key",
+			kid:     "",
+			wantRes: &keyB.PublicKey,
+		},
+		{
+			name:    "verify only key",
+			kid:     "a",
+			wantRes: &keyA.PublicKey,
+		},
+		{
+			name:    "unknown key",
+			kid:     "x",
+			wantErr: ErrUnknownKey,
+		},
+		{
+			name:    "retired key",
+			kid:     "old",
+			wantErr: ErrRetiredKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRes, gotErr := kr.VerificationKey(tt.kid)
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Errorf("KeyRing.VerificationKey() gotErr = %v, wantErr = %v", gotErr, tt.wantErr)
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("KeyRing.VerificationKey() gotRes = %v, wantRes = %v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_KeyRing_JWKS(t *testing.T) {
+	key := generateTestKey(t)
+	kr, err := New([]Key{
+		{ID: "a", PrivateKey: key},
+		{ID: "old", PublicKey: &key.PublicKey, Status: KeyStatusRetired},
+	}, "a")
+	if err != nil {
+		t.Fatalf("New() error = %s", err.Error())
+	}
+
+	got := kr.JWKS()
+	if len(got) != 1 {
+		t.Fatalf("KeyRing.JWKS() got %d keys, want 1", len(got))
+	}
+	if got[0].Kid != "a" || got[0].Kty != "RSA" || got[0].Alg != "RS256" || got[0].Use != "sig" {
+		t.Errorf("KeyRing.JWKS() got = %+v", got[0])
+	}
+	if got[0].E != "AQAB" {
+		t.Errorf("KeyRing.JWKS() got e = %s, want AQAB", got[0].E)
+	}
+}
+
+func Test_Load(t *testing.T) {
+	keyA := generateTestKey(t)
+	keyB := generateTestKey(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "2023-01.pem"), encodePrivateKey(keyA), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "2022-01.pub.pem"), encodePublicKey(t, &keyB.PublicKey), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		opts          LoadOptions
+		wantSigningID string
+		wantKeyIDs    []string
+		wantIsDefault bool
+		wantErr       error
+	}{
+		{
+			name:          "nothing configured",
+			opts:          LoadOptions{},
+			wantSigningID: DefaultKeyID,
+			wantKeyIDs:    []string{DefaultKeyID},
+			wantIsDefault: true,
+		},
+		{
+			name: "single private key in dir",
+			opts: LoadOptions{
+				Dir: dir,
+			},
+			wantSigningID: "2023-01",
+			wantKeyIDs:    []string{"2022-01", "2023-01"},
+		},
+		{
+			name: "ambiguous active key",
+			opts: LoadOptions{
+				Dir: dir,
+				Environ: []string{
+					"JWT_PRIVATE_KEY_2024_01=" + string(encodePrivateKey(keyB)),
+				},
+			},
+			wantErr: ErrNoSigningKey,
+		},
+		{
+			name: "active key from environment",
+			opts: LoadOptions{
+				Dir: dir,
+				Environ: []string{
+					"PATH=/usr/bin",
+					"JWT_PRIVATE_KEY_2024_01=" + string(encodePrivateKey(keyB)),
+				},
+				ActiveKeyID:   "2024_01",
+				RetiredKeyIDs: []string{"2022-01"},
+			},
+			wantSigningID: "2024_01",
+			wantKeyIDs:    []string{"2022-01", "2023-01", "2024_01"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRes, gotIsDefault, gotErr := Load(tt.opts)
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Fatalf("Load() gotErr = %v, wantErr = %v", gotErr, tt.wantErr)
+			}
+			if gotErr != nil {
+				return
+			}
+			if gotIsDefault != tt.wantIsDefault {
+				t.Errorf("Load() gotIsDefault = %t, wantIsDefault = %t", gotIsDefault, tt.wantIsDefault)
+			}
+			if gotRes.SigningKey().ID != tt.wantSigningID {
+				t.Errorf("Load() gotSigningID = %s, wantSigningID = %s", gotRes.SigningKey().ID, tt.wantSigningID)
+			}
+			var gotKeyIDs []string
+			for _, key := range gotRes.Keys() {
+				gotKeyIDs = append(gotKeyIDs, key.ID)
+			}
+			if !reflect.DeepEqual(gotKeyIDs, tt.wantKeyIDs) {
+				t.Errorf("Load() gotKeyIDs = %v, wantKeyIDs = %v", gotKeyIDs, tt.wantKeyIDs)
+			}
+		})
+	}
+}
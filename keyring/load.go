@@ -0,0 +1,167 @@
+package keyring
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fenky-ng/swt-pro/constant"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// DefaultKeyID identifies the built-in development key.
+	DefaultKeyID = "default"
+
+	privateKeyFileSuffix = ".pem"
+	publicKeyFileSuffix  = ".pub.pem"
+	privateKeyEnvPrefix  = "JWT_PRIVATE_KEY_"
+)
+
+type LoadOptions struct {
+	// Dir holds <kid>.pem private keys and <kid>.pub.pem public keys.
+	Dir string
+	// Environ is searched for JWT_PRIVATE_KEY_<KID>=<PEM> entries, the kid
+	// being the lower-cased suffix.
+	Environ []string
+	// ActiveKeyID selects the signing key. It may be left empty when only one
+	// private key is loaded.
+	ActiveKeyID   string
+	RetiredKeyIDs []string
+}
+
+// Load reads keys from the configured directory and environment. When no key
+// is configured at all it falls back to the development key compiled into
+// the constant package, which must never be relied upon in production.
+func Load(opts LoadOptions) (kr *KeyRing, isDefault bool, err error) {
+	var keys []Key
+
+	if opts.Dir != "" {
+		dirKeys, err := loadDir(opts.Dir)
+		if err != nil {
+			return nil, false, err
+		}
+		keys = append(keys, dirKeys...)
+	}
+
+	envKeys, err := loadEnviron(opts.Environ)
+	if err != nil {
+		return nil, false, err
+	}
+	keys = append(keys, envKeys...)
+
+	if len(keys) == 0 {
+		kr, err = Default()
+		return kr, true, err
+	}
+
+	retired := make(map[string]bool, len(opts.RetiredKeyIDs))
+	for _, kid := range opts.RetiredKeyIDs {
+		retired[kid] = true
+	}
+	for i := range keys {
+		if retired[keys[i].ID] {
+			keys[i].Status = KeyStatusRetired
+		}
+	}
+
+	activeKeyID := opts.ActiveKeyID
+	if activeKeyID == "" {
+		var candidates []string
+		for _, key := range keys {
+			if key.PrivateKey != nil && key.Status != KeyStatusRetired {
+				candidates = append(candidates, key.ID)
+			}
+		}
+		if len(candidates) != 1 {
+			return nil, false, fmt.Errorf("%w: active key id must be set when %d private keys are loaded", ErrNoSigningKey, len(candidates))
+		}
+		activeKeyID = candidates[0]
+	}
+
+	kr, err = New(keys, activeKeyID)
+	return kr, false, err
+}
+
+// Default returns a key ring holding only the built-in development key.
+func Default() (*KeyRing, error) {
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(constant.PrivateRSA))
+	if err != nil {
+		return nil, err
+	}
+	return New([]Key{
+		{
+			ID:         DefaultKeyID,
+			PrivateKey: privateKey,
+		},
+	}, DefaultKeyID)
+}
+
+func loadDir(dir string) ([]Key, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []Key
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		switch {
+		case strings.HasSuffix(name, publicKeyFileSuffix):
+			if err != nil {
+				return nil, err
+			}
+			publicKey, err := jwt.ParseRSAPublicKeyFromPEM(content)
+			if err != nil {
+				return nil, fmt.Errorf("keyring: parse %s: %w", name, err)
+			}
+			keys = append(keys, Key{
+				ID:        strings.TrimSuffix(name, publicKeyFileSuffix),
+				PublicKey: publicKey,
+			})
+		case strings.HasSuffix(name, privateKeyFileSuffix):
+			if err != nil {
+				return nil, err
+			}
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(content)
+			if err != nil {
+				return nil, fmt.Errorf("keyring: parse %s: %w", name, err)
+			}
+			keys = append(keys, Key{
+				ID:         strings.TrimSuffix(name, privateKeyFileSuffix),
+				PrivateKey: privateKey,
+			})
+		}
+	}
+
+	return keys, nil
+}
+
+func loadEnviron(environ []string) ([]Key, error) {
+	var keys []Key
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, privateKeyEnvPrefix) || value == "" {
+			continue
+		}
+		kid := strings.ToLower(strings.TrimPrefix(name, privateKeyEnvPrefix))
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(value))
+		if err != nil {
+			return nil, fmt.Errorf("keyring: parse %s: %w", name, err)
+		}
+		keys = append(keys, Key{
+			ID:         kid,
+			PrivateKey: privateKey,
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
@@ -0,0 +1,99 @@
+// Package logging builds the structured JSON logger of the service. Lines
+// logged with the context of a request carry its id, operation, user,
+// latency and trace, and sensitive values are redacted before they are
+// written.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New returns a logger writing JSON lines to w from level on.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(&contextHandler{
+		next: slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level:       level,
+			ReplaceAttr: redactAttr,
+		}),
+	})
+}
+
+type requestInfoKey struct{}
+
+type requestInfo struct {
+	id        string
+	operation string
+	start     time.Time
+	userID    atomic.Int64
+}
+
+// NewContext returns ctx carrying the request fields added to every line
+// logged with it.
+func NewContext(ctx context.Context, requestID string, operation string, start time.Time) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, &requestInfo{
+		id:        requestID,
+		operation: operation,
+		start:     start,
+	})
+}
+
+// SetUserID records the user making the request once it is known, for the
+// lines logged from then on.
+func SetUserID(ctx context.Context, userID int64) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.userID.Store(userID)
+	}
+}
+
+// RequestID returns the id of the request ctx belongs to, if any.
+func RequestID(ctx context.Context) string {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		return info.id
+	}
+	return ""
+}
+
+// contextHandler adds the request fields of the context to every record.
+type contextHandler struct {
+	next slog.Handler
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		record = record.Clone()
+		record.AddAttrs(
+			slog.String("request_id", info.id),
+			slog.String("operation", info.operation),
+		)
+		if userID := info.userID.Load(); userID != 0 {
+			record.AddAttrs(slog.Int64("user_id", userID))
+		}
+		record.AddAttrs(slog.Duration("latency", time.Since(info.start)))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsSampled() {
+		record = record.Clone()
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type MiddlewareOptions struct {
+	Logger *slog.Logger
+	// Operations maps "<METHOD> <path>" of every route to its operationId,
+	// see ratelimit.OperationIDs. Routes outside the spec are labelled with
+	// their path.
+	Operations map[string]string
+}
+
+// requestIDPattern restricts ids taken from clients to what is safe to log
+// and echo back.
+var requestIDPattern = regexp.MustCompile(`^[\w.:-]{1,128}$`)
+
+// Middleware gives every request an id, taken from its X-Request-ID header
+// when valid or generated otherwise, returns it in the response and logs one
+// line per request once handled.
+func Middleware(opts MiddlewareOptions) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			start := time.Now()
+			req := ctx.Request()
+
+			requestID := req.Header.Get(echo.HeaderXRequestID)
+			if !requestIDPattern.MatchString(requestID) {
+				requestID = newRequestID()
+			}
+			ctx.Response().Header().Set(echo.HeaderXRequestID, requestID)
+
+			operation := opts.Operations[req.Method+" "+ctx.Path()]
+			if operation == "" {
+				operation = ctx.Path()
+			}
+			reqCtx := NewContext(req.Context(), requestID, operation, start)
+			ctx.SetRequest(req.WithContext(reqCtx))
+
+			err := next(ctx)
+
+			status := ctx.Response().Status
+			if err != nil {
+				status = http.StatusInternalServerError
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Code
+				}
+			}
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			opts.Logger.Log(reqCtx, level, "request",
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.Int("status", status),
+				slog.String("remote_ip", ctx.RealIP()),
+			)
+			return err
+		}
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
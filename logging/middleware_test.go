@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func Test_Middleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		requestID     string
+		path          string
+		wantRequestID string
+		wantStatus    float64
+		wantOperation string
+		wantLevel     string
+	}{
+		{
+			name:          "propagated request id",
+			requestID:     "abc-123",
+			path:          "/profile",
+			wantRequestID: "abc-123",
+			wantStatus:    http.StatusOK,
+			wantOperation: "GetProfile",
+			wantLevel:     "INFO",
+		},
+		{
+			name:          "invalid request id",
+			requestID:     "bad id\n",
+			path:          "/profile",
+			wantStatus:    http.StatusOK,
+			wantOperation: "GetProfile",
+			wantLevel:     "INFO",
+		},
+		{
+			name:          "generated request id",
+			path:          "/failing",
+			wantStatus:    http.StatusBadGateway,
+			wantOperation: "/failing",
+			wantLevel:     "ERROR",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(&buf, nil)
+			e := echo.New()
+			e.Use(Middleware(MiddlewareOptions{
+				Logger: logger,
+				Operations: map[string]string{
+					"GET /profile": "GetProfile",
+				},
+			}))
+			var handlerRequestID string
+			e.GET("/profile", func(ctx echo.Context) error {
+				handlerRequestID = RequestID(ctx.Request().Context())
+				SetUserID(ctx.Request().Context(), 7)
+				return ctx.NoContent(http.StatusOK)
+			})
+			e.GET("/failing", func(ctx echo.Context) error {
+				handlerRequestID = RequestID(ctx.Request().Context())
+				return echo.NewHTTPError(http.StatusBadGateway)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.requestID != "" {
+				req.Header.Set(echo.HeaderXRequestID, tt.requestID)
+			}
+			res := httptest.NewRecorder()
+			e.ServeHTTP(res, req)
+
+			gotRequestID := res.Header().Get(echo.HeaderXRequestID)
+			if tt.wantRequestID != "" && gotRequestID != tt.wantRequestID {
+				t.Errorf("Middleware() gotRequestID = %s, wantRequestID = %s", gotRequestID, tt.wantRequestID)
+			}
+			if len(gotRequestID) != 32 && tt.wantRequestID == "" {
+				t.Errorf("Middleware() generated request id = %q", gotRequestID)
+			}
+			if handlerRequestID != gotRequestID {
+				t.Errorf("Middleware() handler saw request id %s, response has %s", handlerRequestID, gotRequestID)
+			}
+
+			lines := decodeLines(t, &buf)
+			if len(lines) != 1 {
+				t.Fatalf("Middleware() got %d lines, want 1", len(lines))
+			}
+			line := lines[0]
+			if line["request_id"] != gotRequestID || line["status"] != tt.wantStatus || line["operation"] != tt.wantOperation || line["level"] != tt.wantLevel {
+				t.Errorf("Middleware() line = %v", line)
+			}
+			if tt.path == "/profile" && line["user_id"] != float64(7) {
+				t.Errorf("Middleware() line user_id = %v, want 7", line["user_id"])
+			}
+		})
+	}
+}
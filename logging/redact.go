@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveKeys are attribute keys whose values are never written.
+var sensitiveKeys = map[string]bool{
+	"password":         true,
+	"current_password": true,
+	"new_password":     true,
+	"jwt":              true,
+	"token":            true,
+	"access_token":     true,
+	"refresh_token":    true,
+	"mfa_token":        true,
+	"authorization":    true,
+	"secret":           true,
+	"code":             true,
+	"recovery_codes":   true,
+	"otpauth_uri":      true,
+}
+
+var (
+	// jwtPattern matches the header and payload of a JWT, both base64url
+	// encoded JSON objects starting with "eyJ"
+	jwtPattern    = regexp.MustCompile(`eyJ[\w-]*\.eyJ[\w-]*\.[\w-]*`)
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)\S+`)
+)
+
+// redactAttr hides the values of sensitive keys, and tokens found in any
+// string or error, before they reach the output.
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, redactString(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, redactString(err.Error()))
+		}
+	}
+	return a
+}
+
+func redactString(value string) string {
+	value = jwtPattern.ReplaceAllString(value, redacted)
+	value = bearerPattern.ReplaceAllString(value, "${1}"+redacted)
+	return value
+}
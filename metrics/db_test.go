@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func Test_RegisterDBStats(t *testing.T) {
+	dbMock, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("[Test_RegisterDBStats] %s", err.Error())
+	}
+	defer dbMock.Close()
+	dbMock.SetMaxOpenConns(7)
+
+	r := NewRegistry()
+	RegisterDBStats(r, dbMock)
+
+	var buf bytes.Buffer
+	err = r.Write(&buf)
+	if err != nil {
+		t.Fatalf("Registry.Write() gotErr = %s", err.Error())
+	}
+	for _, want := range []string{
+		"db_max_open_connections 7",
+		"db_in_use_connections 0",
+		"db_wait_count_total 0",
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("RegisterDBStats() output lacks %s, got =\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Registry_Write(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("requests_total", "Requests.\nBy path.", "path", "code")
+	duration := r.NewHistogram("duration_seconds", "Duration.", []float64{0.1, 1}, "path")
+	r.NewGaugeFunc("connections", "Connections.", func() float64 { return 3 })
+	r.NewCounterFunc("waits_total", "Waits.", func() float64 { return math.Inf(1) })
+	r.NewCounter("unused_total", "Unused.")
+
+	requests.Inc("/b", "200")
+	requests.Add(2, "/a", "500")
+	requests.Inc(`/"quoted"\`, "200")
+	duration.Observe(0.05, "/a")
+	duration.Observe(0.5, "/a")
+	duration.Observe(5, "/a")
+
+	var buf bytes.Buffer
+	err := r.Write(&buf)
+	if err != nil {
+		t.Fatalf("Registry.Write() gotErr = %s", err.Error())
+	}
+	want := `# HELP connections Connections.
+# TYPE connections gauge
+connections 3
+# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{path="/a",le="0.1"} 1
+duration_seconds_bucket{path="/a",le="1"} 2
+duration_seconds_bucket{path="/a",le="+Inf"} 3
+duration_seconds_sum{path="/a"} 5.55
+duration_seconds_count{path="/a"} 3
+# HELP requests_total Requests.\nBy path.
+# TYPE requests_total counter
+requests_total{path="/\"quoted\"\\",code="200"} 1
+requests_total{path="/a",code="500"} 2
+requests_total{path="/b",code="200"} 1
+# HELP unused_total Unused.
+# TYPE unused_total counter
+# HELP waits_total Waits.
+# TYPE waits_total counter
+waits_total +Inf
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Registry.Write() got =\n%s\nwant =\n%s", got, want)
+	}
+}
+
+func Test_Registry_ServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("requests_total", "Requests.").Inc()
+
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got := res.Header().Get("Content-Type"); got != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Registry.ServeHTTP() gotContentType = %s", got)
+	}
+	if got := res.Body.String(); got != "# HELP requests_total Requests.\n# TYPE requests_total counter\nrequests_total 1\n" {
+		t.Errorf("Registry.ServeHTTP() gotBody = %s", got)
+	}
+}
+
+func Test_Counter_nil(t *testing.T) {
+	var c *Counter
+	c.Inc("ignored")
+	var h *Histogram
+	h.Observe(1, "ignored")
+}
+
+func Test_Registry_panics(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(r *Registry)
+	}{
+		{
+			name: "duplicate name",
+			fn: func(r *Registry) {
+				r.NewCounter("requests_total", "Requests.")
+				r.NewGaugeFunc("requests_total", "Requests.", func() float64 { return 0 })
+			},
+		},
+		{
+			name: "wrong number of label values",
+			fn: func(r *Registry) {
+				r.NewCounter("requests_total", "Requests.", "path").Inc()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", tt.name)
+				}
+			}()
+			tt.fn(NewRegistry())
+		})
+	}
+}
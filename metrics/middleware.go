@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type MiddlewareOptions struct {
+	Registry *Registry
+	// Operations maps "<METHOD> <path>" of every route to its operationId,
+	// see ratelimit.OperationIDs. Routes outside the spec are labelled with
+	// their path.
+	Operations map[string]string
+}
+
+// Middleware counts requests and observes their latency per operation.
+func Middleware(opts MiddlewareOptions) echo.MiddlewareFunc {
+	requests := opts.Registry.NewCounter(
+		"http_requests_total",
+		"Number of HTTP requests handled, by operation, method and status code.",
+		"operation", "method", "code",
+	)
+	duration := opts.Registry.NewHistogram(
+		"http_request_duration_seconds",
+		"Latency of HTTP requests, by operation and method.",
+		DefaultBuckets,
+		"operation", "method",
+	)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			start := time.Now()
+			err := next(ctx)
+
+			method := ctx.Request().Method
+			operation := opts.Operations[method+" "+ctx.Path()]
+			if operation == "" {
+				operation = ctx.Path()
+			}
+
+			code := ctx.Response().Status
+			if err != nil {
+				code = http.StatusInternalServerError
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					code = httpErr.Code
+				}
+			}
+
+			requests.Inc(operation, method, strconv.Itoa(code))
+			duration.Observe(time.Since(start).Seconds(), operation, method)
+			return err
+		}
+	}
+}
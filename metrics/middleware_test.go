@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func Test_Middleware(t *testing.T) {
+	r := NewRegistry()
+	e := echo.New()
+	e.Use(Middleware(MiddlewareOptions{
+		Registry: r,
+		Operations: map[string]string{
+			"GET /profile": "GetProfile",
+		},
+	}))
+	e.GET("/profile", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	})
+	e.GET("/failing", func(ctx echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadGateway)
+	})
+
+	for _, path := range []string{"/profile", "/profile", "/failing"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var buf bytes.Buffer
+	err := r.Write(&buf)
+	if err != nil {
+		t.Fatalf("Registry.Write() gotErr = %s", err.Error())
+	}
+	for _, want := range []string{
+		`http_requests_total{operation="GetProfile",method="GET",code="200"} 2`,
+		`http_requests_total{operation="/failing",method="GET",code="502"} 1`,
+		`http_request_duration_seconds_count{operation="GetProfile",method="GET"} 2`,
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("Middleware() output lacks %s, got =\n%s", want, buf.String())
+		}
+	}
+}
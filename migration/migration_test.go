@@ -0,0 +1,89 @@
+package migration
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func Test_Load(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []Migration
+		wantErr error
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"0002_create_b.up.sql":   {Data: []byte("CREATE b")},
+				"0002_create_b.down.sql": {Data: []byte("DROP b")},
+				"0001_create_a.up.sql":   {Data: []byte("CREATE a")},
+				"0001_create_a.down.sql": {Data: []byte("DROP a")},
+				"README.md":              {Data: []byte("ignored")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "create_a", Up: "CREATE a", Down: "DROP a"},
+				{Version: 2, Name: "create_b", Up: "CREATE b", Down: "DROP b"},
+			},
+		},
+		{
+			name: "invalid file name",
+			fsys: fstest.MapFS{
+				"create_a.up.sql": {Data: []byte("CREATE a")},
+			},
+			wantErr: ErrInvalidFileName,
+		},
+		{
+			name: "version zero",
+			fsys: fstest.MapFS{
+				"0000_create_a.up.sql": {Data: []byte("CREATE a")},
+			},
+			wantErr: ErrInvalidFileName,
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"0001_create_a.up.sql":   {Data: []byte("CREATE a")},
+				"0001_create_a.down.sql": {Data: []byte("DROP a")},
+				"0001_create_b.up.sql":   {Data: []byte("CREATE b")},
+				"0001_create_b.down.sql": {Data: []byte("DROP b")},
+			},
+			wantErr: ErrDuplicateVersion,
+		},
+		{
+			name: "missing down file",
+			fsys: fstest.MapFS{
+				"0001_create_a.up.sql": {Data: []byte("CREATE a")},
+			},
+			wantErr: ErrIncompleteVersion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.fsys)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Load() gotErr = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) && tt.wantErr == nil {
+				t.Errorf("Load() got = %+v, want = %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Embedded(t *testing.T) {
+	migrations, err := Embedded()
+	if err != nil {
+		t.Fatalf("Embedded() gotErr = %s", err.Error())
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 || migrations[0].Name != "create_user" {
+		t.Fatalf("Embedded() first migration = %+v, want 0001_create_user", migrations)
+	}
+	for i, migration := range migrations {
+		if migration.Version != int64(i+1) {
+			t.Errorf("Embedded() version %d found at position %d, versions must have no gaps", migration.Version, i+1)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// lockKey identifies the advisory lock that keeps instances from migrating
+// at the same time. Any number no other code locks on would do.
+const lockKey int64 = 5_713_209_844
+
+const (
+	queryLock = `
+		SELECT pg_advisory_lock($1)
+	`
+	queryUnlock = `
+		SELECT pg_advisory_unlock($1)
+	`
+	queryCreateTable = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`
+	queryGetApplied = `
+		SELECT version, name, applied_at
+		FROM schema_migrations
+		ORDER BY version
+	`
+	queryInsertVersion = `
+		INSERT INTO schema_migrations (version, name)
+		VALUES ($1, $2)
+	`
+	queryDeleteVersion = `
+		DELETE FROM schema_migrations
+		WHERE version = $1
+	`
+)
+
+// Migrator applies migrations to a Postgres database. Every operation holds
+// an advisory lock, so replicas starting together migrate one at a time and
+// the later ones find nothing left to do.
+type Migrator struct {
+	Db         *sql.DB
+	Migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{
+		Db:         db,
+		Migrations: migrations,
+	}
+}
+
+// Status is a migration along with when it was applied, nil when pending.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type appliedVersion struct {
+	version   int64
+	name      string
+	appliedAt time.Time
+}
+
+// Up applies every pending migration in version order, each in its own
+// transaction, and returns those applied.
+func (m *Migrator) Up(ctx context.Context) (applied []Migration, err error) {
+	err = m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		done := map[int64]bool{}
+		for _, version := range versions {
+			done[version.version] = true
+		}
+
+		for _, migration := range m.Migrations {
+			if done[migration.Version] {
+				continue
+			}
+			err = run(ctx, conn, migration.Up, queryInsertVersion, migration.Version, migration.Name)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s up: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, latest first, and returns
+// those reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (reverted []Migration, err error) {
+	err = m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(versions) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration, ok := m.find(versions[i].version)
+			if !ok {
+				return fmt.Errorf("%w: %d", ErrUnknownVersion, versions[i].version)
+			}
+			err = run(ctx, conn, migration.Down, queryDeleteVersion, migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s down: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration, followed by applied versions that
+// have no file.
+func (m *Migrator) Status(ctx context.Context) (statuses []Status, err error) {
+	err = m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		appliedAt := map[int64]time.Time{}
+		for _, version := range versions {
+			appliedAt[version.version] = version.appliedAt
+		}
+
+		for _, migration := range m.Migrations {
+			status := Status{Migration: migration}
+			if at, ok := appliedAt[migration.Version]; ok {
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+		for _, version := range versions {
+			if _, ok := m.find(version.version); !ok {
+				at := version.appliedAt
+				statuses = append(statuses, Status{
+					Migration: Migration{Version: version.version, Name: version.name},
+					AppliedAt: &at,
+				})
+			}
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.Migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// withLock runs fn on a single connection holding the advisory lock, after
+// making sure the schema_migrations table exists.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.Db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, queryLock, lockKey)
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), queryUnlock, lockKey)
+
+	_, err = conn.ExecContext(ctx, queryCreateTable)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func getApplied(ctx context.Context, conn *sql.Conn) (versions []appliedVersion, err error) {
+	rows, err := conn.QueryContext(ctx, queryGetApplied)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var version appliedVersion
+		err = rows.Scan(&version.version, &version.name, &version.appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+// run executes script and records it with query in one transaction.
+func run(ctx context.Context, conn *sql.Conn, script string, query string, args ...interface{}) (err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,222 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	errorHelper "github.com/fenky-ng/swt-pro/helper/error"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create_a", Up: "CREATE TABLE a ()", Down: "DROP TABLE a"},
+	{Version: 2, Name: "create_b", Up: "CREATE TABLE b ()", Down: "DROP TABLE b"},
+}
+
+func expectLock(sqlMock sqlmock.Sqlmock, versions ...int64) {
+	sqlMock.ExpectExec(regexp.QuoteMeta(queryLock)).
+		WithArgs(lockKey).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec(regexp.QuoteMeta(queryCreateTable)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{"version", "name", "applied_at"})
+	for _, version := range versions {
+		rows.AddRow(version, "applied", time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC))
+	}
+	sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetApplied)).
+		WillReturnRows(rows)
+}
+
+func expectUnlock(sqlMock sqlmock.Sqlmock) {
+	sqlMock.ExpectExec(regexp.QuoteMeta(queryUnlock)).
+		WithArgs(lockKey).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func Test_Migrator_Up(t *testing.T) {
+	tests := []struct {
+		name    string
+		mock    func(sqlMock sqlmock.Sqlmock)
+		wantRes []Migration
+		wantErr error
+	}{
+		{
+			name: "error lock",
+			mock: func(sqlMock sqlmock.Sqlmock) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLock)).
+					WithArgs(lockKey).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: nil,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "error migration",
+			mock: func(sqlMock sqlmock.Sqlmock) {
+				expectLock(sqlMock, 1)
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(testMigrations[1].Up)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+				expectUnlock(sqlMock)
+			},
+			wantRes: nil,
+			wantErr: errors.New("migration 0002_create_b up: expected error"),
+		},
+		{
+			name: "passed",
+			mock: func(sqlMock sqlmock.Sqlmock) {
+				expectLock(sqlMock, 1)
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(testMigrations[1].Up)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertVersion)).
+					WithArgs(int64(2), "create_b").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+				expectUnlock(sqlMock)
+			},
+			wantRes: testMigrations[1:],
+			wantErr: nil,
+		},
+		{
+			name: "up to date",
+			mock: func(sqlMock sqlmock.Sqlmock) {
+				expectLock(sqlMock, 1, 2)
+				expectUnlock(sqlMock)
+			},
+			wantRes: nil,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbMock, sqlMock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("[Test_Migrator_Up] %s", err.Error())
+			}
+			defer dbMock.Close()
+			m := NewMigrator(dbMock, testMigrations)
+			tt.mock(sqlMock)
+			gotRes, gotErr := m.Up(context.Background())
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Migrator.Up() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Migrator.Up() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Migrator.Up() %s", err.Error())
+			}
+		})
+	}
+}
+
+func Test_Migrator_Down(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   int
+		mock    func(sqlMock sqlmock.Sqlmock)
+		wantRes []Migration
+		wantErr error
+	}{
+		{
+			name:  "unknown version",
+			steps: 1,
+			mock: func(sqlMock sqlmock.Sqlmock) {
+				expectLock(sqlMock, 1, 2, 3)
+				expectUnlock(sqlMock)
+			},
+			wantRes: nil,
+			wantErr: errors.New("migration: applied version has no file: 3"),
+		},
+		{
+			name:  "passed",
+			steps: 1,
+			mock: func(sqlMock sqlmock.Sqlmock) {
+				expectLock(sqlMock, 1, 2)
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(testMigrations[1].Down)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteVersion)).
+					WithArgs(int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+				expectUnlock(sqlMock)
+			},
+			wantRes: testMigrations[1:],
+			wantErr: nil,
+		},
+		{
+			name:  "more steps than applied",
+			steps: 5,
+			mock: func(sqlMock sqlmock.Sqlmock) {
+				expectLock(sqlMock, 1)
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(testMigrations[0].Down)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteVersion)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+				expectUnlock(sqlMock)
+			},
+			wantRes: testMigrations[:1],
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbMock, sqlMock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("[Test_Migrator_Down] %s", err.Error())
+			}
+			defer dbMock.Close()
+			m := NewMigrator(dbMock, testMigrations)
+			tt.mock(sqlMock)
+			gotRes, gotErr := m.Down(context.Background(), tt.steps)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Migrator.Down() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Migrator.Down() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Migrator.Down() %s", err.Error())
+			}
+		})
+	}
+}
+
+func Test_Migrator_Status(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("[Test_Migrator_Status] %s", err.Error())
+	}
+	defer dbMock.Close()
+	appliedAt := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	expectLock(sqlMock, 1, 9)
+	expectUnlock(sqlMock)
+
+	m := NewMigrator(dbMock, testMigrations)
+	gotRes, gotErr := m.Status(context.Background())
+	if gotErr != nil {
+		t.Fatalf("Migrator.Status() gotErr = %s", gotErr.Error())
+	}
+	wantRes := []Status{
+		{Migration: testMigrations[0], AppliedAt: &appliedAt},
+		{Migration: testMigrations[1]},
+		{Migration: Migration{Version: 9, Name: "applied"}, AppliedAt: &appliedAt},
+	}
+	if !reflect.DeepEqual(gotRes, wantRes) {
+		t.Errorf("Migrator.Status() gotRes = %+v, wantRes = %+v", gotRes, wantRes)
+	}
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Migrator.Status() %s", err.Error())
+	}
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "user" (
+	id BIGSERIAL PRIMARY KEY,
+	phone_number VARCHAR NOT NULL,
+	"password" VARCHAR NOT NULL,
+	full_name VARCHAR NOT NULL,
+	login_count BIGINT
+);
+CREATE INDEX IF NOT EXISTS user_phone_number ON "user"(phone_number);
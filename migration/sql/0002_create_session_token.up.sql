@@ -0,0 +1,22 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS refresh_token (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	family_id VARCHAR NOT NULL,
+	token_hash VARCHAR NOT NULL UNIQUE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS refresh_token_family_id ON refresh_token(family_id);
+CREATE INDEX IF NOT EXISTS refresh_token_user_id ON refresh_token(user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_token (
+	jti VARCHAR PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	revoked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS revoked_token_expires_at ON revoked_token(expires_at);
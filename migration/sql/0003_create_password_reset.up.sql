@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS password_reset (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	code_hash VARCHAR NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	used_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS password_reset_user_id ON password_reset(user_id);
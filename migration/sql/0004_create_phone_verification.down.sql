@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS phone_verification;
+ALTER TABLE "user" DROP COLUMN IF EXISTS phone_verified_at;
@@ -0,0 +1,13 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS phone_verification (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	phone_number VARCHAR NOT NULL,
+	code_hash VARCHAR NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	verified_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS phone_verification_phone_number ON phone_verification(phone_number);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS user_totp (
+	user_id BIGINT PRIMARY KEY REFERENCES "user"(id) ON DELETE CASCADE,
+	secret VARCHAR NOT NULL,
+	last_used_step BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	confirmed_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS recovery_code (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	code_hash VARCHAR NOT NULL,
+	used_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS recovery_code_user_id ON recovery_code(user_id);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS login_attempt (
+	attempt_key VARCHAR PRIMARY KEY,
+	failed_count INT NOT NULL DEFAULT 0,
+	last_failed_at TIMESTAMPTZ NOT NULL,
+	blocked_until TIMESTAMPTZ
+);
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS rate_limit (
+	limit_key VARCHAR PRIMARY KEY,
+	tat TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,3 @@
+-- The original formatting of the phone numbers is not kept, so normalizing
+-- them cannot be undone.
+SELECT 1;
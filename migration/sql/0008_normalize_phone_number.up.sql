@@ -0,0 +1,27 @@
+-- Rewrites the phone numbers stored before normalization into E.164, the form
+-- phone.Parse gives: separators, a leading 00 and the trunk prefix written
+-- after the calling code are dropped. Numbers not in international format,
+-- and numbers that would then equal another user's number, are left as they
+-- are for manual review.
+CREATE FUNCTION pg_temp.normalize_phone_number(input VARCHAR) RETURNS VARCHAR AS $$
+	SELECT CASE
+		WHEN btrim(input) !~ '^(\+|00)[0-9 ().-]+$' THEN input
+		ELSE '+' || regexp_replace(
+			regexp_replace(regexp_replace(btrim(input), '^(\+|00)', ''), '[ ().-]', '', 'g'),
+			'^(60|62|63|66|84)0', '\1'
+		)
+	END
+$$ LANGUAGE SQL IMMUTABLE;
+
+UPDATE "user" AS u
+SET phone_number = pg_temp.normalize_phone_number(u.phone_number)
+WHERE u.phone_number <> pg_temp.normalize_phone_number(u.phone_number)
+	AND NOT EXISTS (
+		SELECT 1 FROM "user" AS other
+		WHERE other.id <> u.id
+			AND pg_temp.normalize_phone_number(other.phone_number) = pg_temp.normalize_phone_number(u.phone_number)
+	);
+
+UPDATE phone_verification
+SET phone_number = pg_temp.normalize_phone_number(phone_number)
+WHERE phone_number <> pg_temp.normalize_phone_number(phone_number);
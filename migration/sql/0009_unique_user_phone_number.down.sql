@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS user_phone_number ON "user"(phone_number);
+DROP INDEX IF EXISTS user_phone_number_unique;
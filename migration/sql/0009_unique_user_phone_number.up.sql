@@ -0,0 +1,4 @@
+-- Fails while numbers left duplicated by 0008_normalize_phone_number remain,
+-- they have to be merged first.
+CREATE UNIQUE INDEX IF NOT EXISTS user_phone_number_unique ON "user"(phone_number);
+DROP INDEX IF EXISTS user_phone_number;
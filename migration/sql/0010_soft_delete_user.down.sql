@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS user_deleted_at;
+ALTER TABLE "user" DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,2 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS user_deleted_at ON "user"(deleted_at) WHERE deleted_at IS NOT NULL;
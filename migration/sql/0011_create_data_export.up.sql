@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS data_export (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	format VARCHAR NOT NULL,
+	status VARCHAR NOT NULL DEFAULT 'pending',
+	token_hash VARCHAR NOT NULL,
+	archive BYTEA,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	started_at TIMESTAMPTZ,
+	completed_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS data_export_user_id ON data_export(user_id);
+CREATE INDEX IF NOT EXISTS data_export_queued ON data_export(created_at) WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS data_export_expires_at ON data_export(expires_at);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS user_role;
+ALTER TABLE "user" DROP COLUMN IF EXISTS password_reset_required_at;
+ALTER TABLE "user" DROP COLUMN IF EXISTS locked_at;
+ALTER TABLE "user" DROP COLUMN IF EXISTS role;
+DROP TABLE IF EXISTS role_permission;
+DROP TABLE IF EXISTS role;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS role (
+	name VARCHAR PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS role_permission (
+	role VARCHAR NOT NULL REFERENCES role(name) ON UPDATE CASCADE ON DELETE CASCADE,
+	permission VARCHAR NOT NULL,
+	PRIMARY KEY (role, permission)
+);
+
+INSERT INTO role (name) VALUES ('admin'), ('support')
+ON CONFLICT DO NOTHING;
+INSERT INTO role_permission (role, permission) VALUES
+	('admin', 'users:read'),
+	('admin', 'users:write'),
+	('admin', 'users:lock'),
+	('admin', 'users:reset_password'),
+	('admin', 'users:revoke_sessions'),
+	('support', 'users:read'),
+	('support', 'users:lock'),
+	('support', 'users:reset_password'),
+	('support', 'users:revoke_sessions')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS role VARCHAR REFERENCES role(name) ON UPDATE CASCADE ON DELETE SET NULL;
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS locked_at TIMESTAMPTZ;
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS password_reset_required_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS user_role ON "user"(role) WHERE role IS NOT NULL;
@@ -0,0 +1,4 @@
+-- The extensions are left in place, other schemas may use them.
+DROP INDEX IF EXISTS user_phone_number_prefix;
+DROP INDEX IF EXISTS user_full_name_search;
+DROP FUNCTION IF EXISTS immutable_unaccent(TEXT);
@@ -0,0 +1,14 @@
+-- pg_trgm backs the substring search on full names and unaccent lets it
+-- ignore diacritics. Creating them takes a role allowed to, on managed
+-- databases they may have to be created beforehand.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent is only STABLE, its dictionary could change, so the index and the
+-- queries using it go through this wrapper naming the dictionary.
+CREATE OR REPLACE FUNCTION immutable_unaccent(input TEXT) RETURNS TEXT
+	LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+	AS $$ SELECT public.unaccent('public.unaccent'::regdictionary, input) $$;
+
+CREATE INDEX IF NOT EXISTS user_full_name_search ON "user" USING gin (lower(immutable_unaccent(full_name)) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS user_phone_number_prefix ON "user"(phone_number text_pattern_ops);
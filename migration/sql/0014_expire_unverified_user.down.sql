@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS user_unverified_created_at;
+ALTER TABLE "user" DROP COLUMN IF EXISTS created_at;
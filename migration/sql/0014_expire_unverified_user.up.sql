@@ -0,0 +1,6 @@
+-- Accounts whose phone number is never verified are purged some time after
+-- they were created. Existing accounts are left without a creation time, so
+-- they are never purged.
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE "user" ALTER COLUMN created_at SET DEFAULT NOW();
+CREATE INDEX IF NOT EXISTS user_unverified_created_at ON "user"(created_at) WHERE phone_verified_at IS NULL;
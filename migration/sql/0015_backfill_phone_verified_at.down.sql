@@ -0,0 +1,2 @@
+-- The backfilled users cannot be told apart from the ones verified since, they
+-- are left verified.
@@ -0,0 +1,11 @@
+-- Users registered before 0004_create_phone_verification never had to verify
+-- their phone number and would now be refused at login. They are trusted as
+-- verified. Users registered since always got a code, the ones not verified
+-- yet still have to.
+UPDATE "user" SET phone_verified_at = NOW()
+WHERE phone_verified_at IS NULL
+	AND NOT EXISTS (
+		SELECT 1
+		FROM phone_verification
+		WHERE phone_verification.user_id = "user".id
+	);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS audit_event (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	actor_id BIGINT REFERENCES "user"(id) ON DELETE SET NULL,
+	action VARCHAR NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS audit_event_user_id ON audit_event(user_id);
@@ -8,4 +8,14 @@ type SessionClaims struct {
 	jwt.StandardClaims
 	UserID      int64  `json:"user_id"`
 	PhoneNumber string `json:"phone_number"`
+	SessionID   string `json:"sid,omitempty"`
+	// Role names the permissions of the user when the token was issued, see
+	// authz.Middleware. It is empty for users without any.
+	Role string `json:"role,omitempty"`
+	// Purpose is empty for session tokens. Any other value marks a token
+	// that may only be used for that purpose, such as an MFA challenge.
+	Purpose string `json:"purpose,omitempty"`
+	// IssuedAtNano is when the token was issued, in nanoseconds since the
+	// Unix epoch. The iat claim only tells the second.
+	IssuedAtNano int64 `json:"iat_ns,omitempty"`
 }
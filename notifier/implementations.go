@@ -0,0 +1,59 @@
+// This file contains stand-in notifier implementations that do not talk to a
+// real SMS gateway.
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogNotifier writes every message to the application log. Messages carry
+// one-time codes in clear, so it is only meant for development.
+type LogNotifier struct {
+	logger *slog.Logger
+}
+
+// NewLogNotifier logs to logger, or to the default logger when nil.
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &LogNotifier{
+		logger: logger,
+	}
+}
+
+func (n *LogNotifier) SendSMS(ctx context.Context, phoneNumber string, message string) (err error) {
+	n.logger.InfoContext(ctx, "SendSMS", "phone_number", phoneNumber, "message", message)
+	return nil
+}
+
+// FileNotifier appends every message to a file, one line per message.
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{
+		path: path,
+	}
+}
+
+func (n *FileNotifier) SendSMS(ctx context.Context, phoneNumber string, message string) (err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().UTC().Format(time.RFC3339), phoneNumber, message)
+	return err
+}
@@ -0,0 +1,77 @@
+package notifier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_LogNotifier_SendSMS(t *testing.T) {
+	n := NewLogNotifier(nil)
+	if err := n.SendSMS(context.Background(), "+628123456789", "message"); err != nil {
+		t.Errorf("LogNotifier.SendSMS() gotErr = %s, wantErr = nil", err.Error())
+	}
+}
+
+func Test_FileNotifier_SendSMS(t *testing.T) {
+	type args struct {
+		messages []string
+	}
+	tests := []struct {
+		name      string
+		path      string
+		args      args
+		wantLines []string
+		wantErr   bool
+	}{
+		{
+			name: "directory does not exist",
+			path: filepath.Join(t.TempDir(), "missing", "sms.log"),
+			args: args{
+				messages: []string{"message"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "passed",
+			path: filepath.Join(t.TempDir(), "sms.log"),
+			args: args{
+				messages: []string{"first", "second"},
+			},
+			wantLines: []string{
+				"+628123456789\tfirst",
+				"+628123456789\tsecond",
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewFileNotifier(tt.path)
+			for _, message := range tt.args.messages {
+				gotErr := n.SendSMS(context.Background(), "+628123456789", message)
+				if (gotErr != nil) != tt.wantErr {
+					t.Fatalf("FileNotifier.SendSMS() gotErr = %v, wantErr = %t", gotErr, tt.wantErr)
+				}
+			}
+			if tt.wantErr {
+				return
+			}
+			content, err := os.ReadFile(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotLines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+			if len(gotLines) != len(tt.wantLines) {
+				t.Fatalf("FileNotifier.SendSMS() gotLines = %d, wantLines = %d", len(gotLines), len(tt.wantLines))
+			}
+			for i := range gotLines {
+				if !strings.HasSuffix(gotLines[i], tt.wantLines[i]) {
+					t.Errorf("FileNotifier.SendSMS() gotLine = %q, wantLine suffix = %q", gotLines[i], tt.wantLines[i])
+				}
+			}
+		})
+	}
+}
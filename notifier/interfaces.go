@@ -0,0 +1,12 @@
+// This file contains the interfaces for the notifier layer.
+// The notifier layer is responsible for delivering messages to users.
+// For testing purpose we will generate mock implementations of these
+// interfaces using mockgen. See the Makefile for more information.
+package notifier
+
+import "context"
+
+//go:generate mockgen -source=interfaces.go -destination=interfaces.mock.gen.go -package=notifier
+type Notifier interface {
+	SendSMS(ctx context.Context, phoneNumber string, message string) (err error)
+}
@@ -0,0 +1,180 @@
+// Package phone parses phone numbers, written with or without spaces,
+// dashes, dots or parentheses, into their E.164 form, checking them against
+// the numbering rules of the supported countries.
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrFormat is returned for input that is not an international phone
+	// number, one starting with + or 00 and the calling code.
+	ErrFormat = errors.New("phone: not an international phone number")
+	// ErrCountry is returned for numbers of a country not supported.
+	ErrCountry = errors.New("phone: unsupported country")
+	// ErrLength is returned for numbers too short or too long for their
+	// country.
+	ErrLength = errors.New("phone: invalid length for the country")
+	// ErrPrefix is returned for numbers starting with digits their country
+	// does not use.
+	ErrPrefix = errors.New("phone: invalid prefix for the country")
+)
+
+// Country holds the numbering rules of a country. The lengths are those of
+// the national significant number, the digits after the calling code.
+type Country struct {
+	// Code is the ISO 3166-1 alpha-2 code, such as ID
+	Code        string
+	Name        string
+	CallingCode string
+	// TrunkPrefix is dialled before national numbers within the country,
+	// and dropped when written after the calling code
+	TrunkPrefix string
+	MinLength   int
+	MaxLength   int
+	// Prefixes lists the leading digits of valid numbers, any when empty
+	Prefixes []string
+}
+
+var countries = []Country{
+	{Code: "ID", Name: "Indonesia", CallingCode: "62", TrunkPrefix: "0", MinLength: 7, MaxLength: 12},
+	{Code: "MY", Name: "Malaysia", CallingCode: "60", TrunkPrefix: "0", MinLength: 8, MaxLength: 10},
+	{Code: "SG", Name: "Singapore", CallingCode: "65", MinLength: 8, MaxLength: 8, Prefixes: []string{"3", "6", "8", "9"}},
+	{Code: "TH", Name: "Thailand", CallingCode: "66", TrunkPrefix: "0", MinLength: 8, MaxLength: 9},
+	{Code: "VN", Name: "Vietnam", CallingCode: "84", TrunkPrefix: "0", MinLength: 9, MaxLength: 10},
+	{Code: "PH", Name: "Philippines", CallingCode: "63", TrunkPrefix: "0", MinLength: 8, MaxLength: 10},
+}
+
+// LookupCountry returns the rules of a supported country by its code.
+func LookupCountry(code string) (country Country, ok bool) {
+	for _, country := range countries {
+		if country.Code == strings.ToUpper(code) {
+			return country, true
+		}
+	}
+	return country, false
+}
+
+// Number is a parsed phone number.
+type Number struct {
+	Country Country
+	// National is the national significant number
+	National string
+}
+
+// E164 returns the number as "+<calling code><national number>".
+func (n Number) E164() string {
+	return "+" + n.Country.CallingCode + n.National
+}
+
+// Parse reads an international phone number such as "+62 812-3456-789" or
+// "0062 (0)812 3456 789".
+func Parse(input string) (number Number, err error) {
+	digits, ok := internationalDigits(input)
+	if !ok {
+		return number, ErrFormat
+	}
+
+	for _, country := range countries {
+		if !strings.HasPrefix(digits, country.CallingCode) {
+			continue
+		}
+		national := strings.TrimPrefix(digits, country.CallingCode)
+		if country.TrunkPrefix != "" {
+			national = strings.TrimPrefix(national, country.TrunkPrefix)
+		}
+		number = Number{
+			Country:  country,
+			National: national,
+		}
+
+		if len(national) < country.MinLength || len(national) > country.MaxLength {
+			return number, ErrLength
+		}
+		if !hasAnyPrefix(national, country.Prefixes) {
+			return number, ErrPrefix
+		}
+		return number, nil
+	}
+	return number, ErrCountry
+}
+
+// Normalize returns the E.164 form of input, or input without surrounding
+// spaces when it is not a valid number. It suits looking numbers up, where
+// an invalid number simply matches nothing.
+func Normalize(input string) string {
+	number, err := Parse(input)
+	if err != nil {
+		return strings.TrimSpace(input)
+	}
+	return number.E164()
+}
+
+// NormalizePrefix returns the E.164 form of the start of a phone number,
+// such as "+62812" for "+62 0812", to look numbers up by prefix. Like Parse,
+// it drops the trunk prefix written after a calling code. ok is false for
+// input not starting like an international phone number.
+func NormalizePrefix(input string) (prefix string, ok bool) {
+	digits, ok := internationalDigits(input)
+	if !ok {
+		return prefix, false
+	}
+
+	for _, country := range countries {
+		if !strings.HasPrefix(digits, country.CallingCode) {
+			continue
+		}
+		national := strings.TrimPrefix(digits, country.CallingCode)
+		if country.TrunkPrefix != "" {
+			national = strings.TrimPrefix(national, country.TrunkPrefix)
+		}
+		digits = country.CallingCode + national
+		break
+	}
+	return "+" + digits, true
+}
+
+// internationalDigits returns the digits of input after its + or 00,
+// skipping the separators people write numbers with.
+func internationalDigits(input string) (digits string, ok bool) {
+	input = strings.TrimSpace(input)
+	switch {
+	case strings.HasPrefix(input, "+"):
+		input = input[1:]
+	case strings.HasPrefix(input, "00"):
+		input = input[2:]
+	default:
+		return digits, false
+	}
+
+	var sb strings.Builder
+	for _, r := range input {
+		switch {
+		case r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return digits, false
+		}
+	}
+	digits = sb.String()
+	// E.164 numbers have at most 15 digits
+	if digits == "" || len(digits) > 15 {
+		return digits, false
+	}
+	return digits, true
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantE164    string
+		wantCountry string
+		wantErr     error
+	}{
+		{
+			name:        "e164",
+			input:       "+628123456789",
+			wantE164:    "+628123456789",
+			wantCountry: "ID",
+		},
+		{
+			name:        "spaces and dashes",
+			input:       " +62 812-3456-789 ",
+			wantE164:    "+628123456789",
+			wantCountry: "ID",
+		},
+		{
+			name:        "00 and trunk prefix",
+			input:       "0062 (0)812.3456.789",
+			wantE164:    "+628123456789",
+			wantCountry: "ID",
+		},
+		{
+			name:        "malaysia",
+			input:       "+60 12-345 6789",
+			wantE164:    "+60123456789",
+			wantCountry: "MY",
+		},
+		{
+			name:        "singapore",
+			input:       "+65 9123 4567",
+			wantE164:    "+6591234567",
+			wantCountry: "SG",
+		},
+		{
+			name:        "thailand",
+			input:       "+66 81 234 5678",
+			wantE164:    "+66812345678",
+			wantCountry: "TH",
+		},
+		{
+			name:        "vietnam",
+			input:       "+84 91 234 56 78",
+			wantE164:    "+84912345678",
+			wantCountry: "VN",
+		},
+		{
+			name:        "philippines",
+			input:       "+63 917 123 4567",
+			wantE164:    "+639171234567",
+			wantCountry: "PH",
+		},
+		{
+			name:    "national format",
+			input:   "08123456789",
+			wantErr: ErrFormat,
+		},
+		{
+			name:    "letters",
+			input:   "+62 812 CALL ME",
+			wantErr: ErrFormat,
+		},
+		{
+			name:    "more than 15 digits",
+			input:   "+6281234567890123",
+			wantErr: ErrFormat,
+		},
+		{
+			name:    "unsupported country",
+			input:   "+1 415 555 2671",
+			wantErr: ErrCountry,
+		},
+		{
+			name:    "too short",
+			input:   "+62 812 345",
+			wantErr: ErrLength,
+		},
+		{
+			name:    "too long",
+			input:   "+62 812 3456 789 012",
+			wantErr: ErrLength,
+		},
+		{
+			name:    "invalid prefix",
+			input:   "+65 1234 5678",
+			wantErr: ErrPrefix,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotNumber, gotErr := Parse(tt.input)
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Fatalf("Parse() gotErr = %v, wantErr = %v", gotErr, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if gotNumber.E164() != tt.wantE164 || gotNumber.Country.Code != tt.wantCountry {
+				t.Errorf("Parse() got = %s of %s, want = %s of %s", gotNumber.E164(), gotNumber.Country.Code, tt.wantE164, tt.wantCountry)
+			}
+		})
+	}
+}
+
+func Test_Normalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "valid",
+			input: "+62 812-3456-789",
+			want:  "+628123456789",
+		},
+		{
+			name:  "invalid",
+			input: " 0812 3456 789 ",
+			want:  "0812 3456 789",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.input); got != tt.want {
+				t.Errorf("Normalize() got = %s, want = %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_NormalizePrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		wantOk bool
+	}{
+		{
+			name:   "calling code only",
+			input:  "+6",
+			want:   "+6",
+			wantOk: true,
+		},
+		{
+			name:   "trunk prefix",
+			input:  "+62 (0)812-34",
+			want:   "+6281234",
+			wantOk: true,
+		},
+		{
+			name:   "unsupported country",
+			input:  "0044 20",
+			want:   "+4420",
+			wantOk: true,
+		},
+		{
+			name:   "national",
+			input:  "0812",
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NormalizePrefix(tt.input)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("NormalizePrefix() got = %s, %t, want = %s, %t", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_LookupCountry(t *testing.T) {
+	country, ok := LookupCountry("sg")
+	if !ok || country.CallingCode != "65" {
+		t.Errorf("LookupCountry() got = %+v, %t", country, ok)
+	}
+	_, ok = LookupCountry("US")
+	if ok {
+		t.Errorf("LookupCountry() found US")
+	}
+}
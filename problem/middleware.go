@@ -0,0 +1,98 @@
+package problem
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/labstack/echo/v4"
+)
+
+type MiddlewareOptions struct {
+	// TypeBaseURI prefixes the slug of problem types, "/problems/" making
+	// "/problems/validation".
+	TypeBaseURI string
+}
+
+type errorResponse struct {
+	Header *generated.ResponseHeader `json:"header"`
+}
+
+// Middleware rewrites the error responses, those with a status of 400 or
+// more in the response header envelope, of requests accepting
+// application/problem+json into problem documents. An *echo.HTTPError
+// returned instead of a response, such as for routes not found, is answered
+// with a problem document as well. Any other response is sent unchanged.
+func Middleware(opts MiddlewareOptions) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			// caches have to tell the two shapes of errors apart
+			ctx.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+			if !Accepted(ctx.Request().Header.Get(echo.HeaderAccept)) {
+				return next(ctx)
+			}
+
+			res := ctx.Response()
+			writer := &bufferedWriter{ResponseWriter: res.Writer, status: http.StatusOK}
+			res.Writer = writer
+			err := next(ctx)
+			res.Writer = writer.ResponseWriter
+
+			if !res.Committed {
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					return writeProblem(ctx, New(opts.TypeBaseURI, httpErr.Code, generated.ResponseHeader{
+						ErrorMessages: &[]string{fmt.Sprint(httpErr.Message)},
+					}))
+				}
+				return err
+			}
+
+			var body errorResponse
+			if writer.status >= http.StatusBadRequest &&
+				json.Unmarshal(writer.body.Bytes(), &body) == nil && body.Header != nil {
+				// the response is written again, as a problem document
+				res.Committed = false
+				writeErr := writeProblem(ctx, New(opts.TypeBaseURI, writer.status, *body.Header))
+				if err == nil {
+					err = writeErr
+				}
+				return err
+			}
+
+			res.Writer.WriteHeader(writer.status)
+			_, writeErr := res.Writer.Write(writer.body.Bytes())
+			if err == nil {
+				err = writeErr
+			}
+			return err
+		}
+	}
+}
+
+func writeProblem(ctx echo.Context, problem generated.Problem) error {
+	body, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+	ctx.Response().Header().Del(echo.HeaderContentLength)
+	ctx.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	return ctx.Blob(problem.Status, MIMEApplicationProblemJSON, body)
+}
+
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
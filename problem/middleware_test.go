@@ -0,0 +1,105 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fenky-ng/swt-pro/constant"
+	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/labstack/echo/v4"
+)
+
+func Test_Middleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		path            string
+		accept          string
+		wantStatusCode  int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "error accepting problem json",
+			path:            "/locked",
+			accept:          MIMEApplicationProblemJSON,
+			wantStatusCode:  http.StatusLocked,
+			wantContentType: MIMEApplicationProblemJSON,
+			wantBody:        `{"detail":"Account is locked","error_code":1012,"status":423,"title":"Account locked","type":"/problems/account-locked"}`,
+		},
+		{
+			name:            "error accepting json",
+			path:            "/locked",
+			accept:          echo.MIMEApplicationJSON,
+			wantStatusCode:  http.StatusLocked,
+			wantContentType: echo.MIMEApplicationJSON,
+			wantBody:        `{"header":{"error_code":1012,"error_messages":["Account is locked"]}}`,
+		},
+		{
+			name:            "success accepting problem json",
+			path:            "/ok",
+			accept:          MIMEApplicationProblemJSON,
+			wantStatusCode:  http.StatusOK,
+			wantContentType: echo.MIMEApplicationJSON,
+			wantBody:        `{"header":{"successful":true}}`,
+		},
+		{
+			name:            "route not found accepting problem json",
+			path:            "/missing",
+			accept:          MIMEApplicationProblemJSON,
+			wantStatusCode:  http.StatusNotFound,
+			wantContentType: MIMEApplicationProblemJSON,
+			wantBody:        `{"detail":"Not Found","status":404,"title":"Not Found","type":"about:blank"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(Middleware(MiddlewareOptions{TypeBaseURI: "/problems/"}))
+			e.GET("/locked", func(ctx echo.Context) error {
+				errorCode := constant.ErrorCodeAccountLocked
+				ctx.Response().Header().Set("Retry-After", "60")
+				return ctx.JSON(http.StatusLocked, map[string]generated.ResponseHeader{
+					"header": {
+						ErrorCode:     &errorCode,
+						ErrorMessages: &[]string{"Account is locked"},
+					},
+				})
+			})
+			e.GET("/ok", func(ctx echo.Context) error {
+				successful := true
+				return ctx.JSON(http.StatusOK, map[string]generated.ResponseHeader{
+					"header": {Successful: &successful},
+				})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set(echo.HeaderAccept, tt.accept)
+			res := httptest.NewRecorder()
+			e.ServeHTTP(res, req)
+
+			if res.Code != tt.wantStatusCode {
+				t.Errorf("Middleware() gotStatusCode = %d, wantStatusCode = %d", res.Code, tt.wantStatusCode)
+			}
+			if got := res.Header().Get(echo.HeaderContentType); !strings.HasPrefix(got, tt.wantContentType) {
+				t.Errorf("Middleware() gotContentType = %s, wantContentType = %s", got, tt.wantContentType)
+			}
+			if got := res.Header().Get(echo.HeaderVary); got != echo.HeaderAccept {
+				t.Errorf("Middleware() gotVary = %s, wantVary = %s", got, echo.HeaderAccept)
+			}
+			var gotBody, wantBody interface{}
+			json.Unmarshal(res.Body.Bytes(), &gotBody)
+			json.Unmarshal([]byte(tt.wantBody), &wantBody)
+			gotJSON, _ := json.Marshal(gotBody)
+			wantJSON, _ := json.Marshal(wantBody)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("Middleware() gotBody = %s, wantBody = %s", gotJSON, wantJSON)
+			}
+			if tt.path == "/locked" && res.Header().Get("Retry-After") != "60" {
+				t.Errorf("Middleware() lost the Retry-After header")
+			}
+		})
+	}
+}
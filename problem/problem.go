@@ -0,0 +1,95 @@
+// Package problem answers the errors of clients accepting
+// application/problem+json with RFC 7807 problem documents instead of the
+// response header envelope, leaving every other client as it is.
+package problem
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fenky-ng/swt-pro/constant"
+	"github.com/fenky-ng/swt-pro/generated"
+)
+
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Type names a problem type, the documents of an error code share it.
+type Type struct {
+	// Slug is appended to the base URI to make the type URI
+	Slug  string
+	Title string
+}
+
+var types = map[int]Type{
+	constant.ErrorCodeGeneral:           {Slug: "general", Title: "Unexpected error"},
+	constant.ErrorCodeUnmarshal:         {Slug: "malformed-request", Title: "Malformed request"},
+	constant.ErrorCodeValidation:        {Slug: "validation", Title: "Invalid request"},
+	constant.ErrorCodeHashAndSalt:       {Slug: "password-hashing", Title: "Password could not be processed"},
+	constant.ErrorCodeDatabase:          {Slug: "database", Title: "Database error"},
+	constant.ErrorCodeJWT:               {Slug: "token-signing", Title: "Token could not be signed"},
+	constant.ErrorCodeAuthorization:     {Slug: "unauthorized", Title: "Not authorized"},
+	constant.ErrorCodeRefreshToken:      {Slug: "invalid-refresh-token", Title: "Invalid refresh token"},
+	constant.ErrorCodePasswordReset:     {Slug: "invalid-password-reset", Title: "Invalid password reset"},
+	constant.ErrorCodePhoneNotVerified:  {Slug: "phone-not-verified", Title: "Phone number not verified"},
+	constant.ErrorCodePhoneVerification: {Slug: "invalid-phone-verification", Title: "Invalid phone verification"},
+	constant.ErrorCodeMfa:               {Slug: "mfa", Title: "Second factor required or invalid"},
+	constant.ErrorCodeAccountLocked:     {Slug: "account-locked", Title: "Account locked"},
+	constant.ErrorCodeLoginThrottled:    {Slug: "login-throttled", Title: "Too many failed logins"},
+	constant.ErrorCodeRateLimited:       {Slug: "rate-limited", Title: "Too many requests"},
+	constant.ErrorCodeUnavailable:       {Slug: "unavailable", Title: "Service unavailable"},
+	constant.ErrorCodeNotFound:          {Slug: "not-found", Title: "Not found"},
+	constant.ErrorCodeConflict:          {Slug: "conflict", Title: "Conflict"},
+	constant.ErrorCodeDataExport:        {Slug: "data-export", Title: "Data export unavailable"},
+	constant.ErrorCodePermission:        {Slug: "permission-denied", Title: "Permission denied"},
+	constant.ErrorCodePasswordExpired:   {Slug: "password-expired", Title: "Password must be reset"},
+}
+
+// LookupType returns the problem type of an error code.
+func LookupType(errorCode int) (problemType Type, ok bool) {
+	problemType, ok = types[errorCode]
+	return problemType, ok
+}
+
+// New turns the response header of an error answered with status into a
+// problem document. Error codes without a problem type, or no error code at
+// all, are reported as "about:blank", whose title is the status text.
+func New(baseURI string, status int, header generated.ResponseHeader) generated.Problem {
+	problem := generated.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+	}
+	if header.ErrorCode != nil {
+		problem.ErrorCode = header.ErrorCode
+		if problemType, ok := LookupType(*header.ErrorCode); ok {
+			problem.Type = baseURI + problemType.Slug
+			problem.Title = problemType.Title
+		}
+	}
+	if header.ErrorMessages != nil && len(*header.ErrorMessages) != 0 {
+		detail := strings.Join(*header.ErrorMessages, "; ")
+		problem.Detail = &detail
+	}
+	if header.ErrorFields != nil && len(*header.ErrorFields) != 0 {
+		problem.Errors = header.ErrorFields
+	}
+	return problem
+}
+
+// Accepted tells whether the Accept header of a request lists
+// application/problem+json, with a non-zero quality.
+func Accepted(accept string) bool {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil || mediaType != MIMEApplicationProblemJSON {
+			continue
+		}
+		quality, err := strconv.ParseFloat(params["q"], 64)
+		if err != nil || quality > 0 {
+			return true
+		}
+	}
+	return false
+}
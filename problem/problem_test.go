@@ -0,0 +1,115 @@
+package problem
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/fenky-ng/swt-pro/constant"
+	"github.com/fenky-ng/swt-pro/generated"
+)
+
+func Test_Accepted(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   bool
+	}{
+		{
+			name:   "problem json",
+			accept: "application/problem+json",
+			want:   true,
+		},
+		{
+			name:   "among others",
+			accept: "application/json;q=0.9, application/problem+json",
+			want:   true,
+		},
+		{
+			name:   "with parameters",
+			accept: "application/problem+json; charset=utf-8; q=0.5",
+			want:   true,
+		},
+		{
+			name:   "refused",
+			accept: "application/json, application/problem+json;q=0",
+			want:   false,
+		},
+		{
+			name:   "json only",
+			accept: "application/json",
+			want:   false,
+		},
+		{
+			name:   "anything",
+			accept: "*/*",
+			want:   false,
+		},
+		{
+			name:   "missing",
+			accept: "",
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Accepted(tt.accept); got != tt.want {
+				t.Errorf("Accepted() got = %t, want = %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_New(t *testing.T) {
+	errorCode := constant.ErrorCodeValidation
+	unknownErrorCode := 9999
+	detail := "password is required; remember_me is not a known field"
+	fieldErrors := []generated.FieldError{
+		{Field: "password", Message: "password is required"},
+		{Field: "remember_me", Message: "remember_me is not a known field"},
+	}
+	tests := []struct {
+		name   string
+		status int
+		header generated.ResponseHeader
+		want   generated.Problem
+	}{
+		{
+			name:   "known error code",
+			status: http.StatusBadRequest,
+			header: generated.ResponseHeader{
+				ErrorCode:     &errorCode,
+				ErrorMessages: &[]string{"password is required", "remember_me is not a known field"},
+				ErrorFields:   &fieldErrors,
+			},
+			want: generated.Problem{
+				Type:      "/problems/validation",
+				Title:     "Invalid request",
+				Status:    http.StatusBadRequest,
+				Detail:    &detail,
+				ErrorCode: &errorCode,
+				Errors:    &fieldErrors,
+			},
+		},
+		{
+			name:   "unknown error code",
+			status: http.StatusTeapot,
+			header: generated.ResponseHeader{
+				ErrorCode: &unknownErrorCode,
+			},
+			want: generated.Problem{
+				Type:      "about:blank",
+				Title:     "I'm a teapot",
+				Status:    http.StatusTeapot,
+				ErrorCode: &unknownErrorCode,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New("/problems/", tt.status, tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() got = %+v, want = %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Package purge removes the accounts deleted longer than the grace period
+// ago and the accounts whose phone number was never verified, along with
+// everything that belongs to them, so their phone numbers can be registered
+// again. Instances may run it concurrently, every batch
+// skips the accounts another one is purging.
+package purge
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/fenky-ng/swt-pro/repository"
+)
+
+type Options struct {
+	Repository repository.RepositoryInterface
+	// GracePeriod is how long deleted accounts can still be restored
+	GracePeriod time.Duration
+	// UnverifiedTTL is how long new accounts have to verify their phone
+	// number
+	UnverifiedTTL time.Duration
+	Interval      time.Duration
+	// BatchSize caps the accounts removed by one statement
+	BatchSize int
+	// Logger defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+// Run purges right away, then every Interval until ctx is done.
+func Run(ctx context.Context, opts Options) {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		purged, err := Purge(ctx, opts, time.Now())
+		if err != nil && ctx.Err() == nil {
+			opts.Logger.ErrorContext(ctx, "Purge error", "func", "Purge", "error", err)
+		}
+		if purged != 0 {
+			opts.Logger.InfoContext(ctx, "Purged users", "func", "Purge", "count", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge removes the accounts deleted at or before now minus the grace
+// period, then the accounts created at or before now minus UnverifiedTTL
+// and still unverified, batch by batch until none is left, and returns how
+// many were.
+func Purge(ctx context.Context, opts Options, now time.Time) (purged int64, err error) {
+	deletedBefore := now.Add(-opts.GracePeriod)
+	purged, err = purgeBatches(opts.BatchSize, func(limit int) (int64, error) {
+		return opts.Repository.PurgeDeletedUsers(ctx, deletedBefore, limit)
+	})
+	if err != nil {
+		return purged, err
+	}
+
+	createdBefore := now.Add(-opts.UnverifiedTTL)
+	count, err := purgeBatches(opts.BatchSize, func(limit int) (int64, error) {
+		return opts.Repository.PurgeUnverifiedUsers(ctx, createdBefore, limit)
+	})
+	return purged + count, err
+}
+
+func purgeBatches(batchSize int, purgeBatch func(limit int) (int64, error)) (purged int64, err error) {
+	for {
+		count, err := purgeBatch(batchSize)
+		purged += count
+		if err != nil {
+			return purged, err
+		}
+		if count < int64(batchSize) {
+			return purged, nil
+		}
+	}
+}
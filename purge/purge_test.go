@@ -0,0 +1,173 @@
+package purge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	errorHelper "github.com/fenky-ng/swt-pro/helper/error"
+	"github.com/fenky-ng/swt-pro/repository"
+	"github.com/golang/mock/gomock"
+)
+
+func Test_Purge(t *testing.T) {
+	now := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	deletedBefore := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	createdBefore := time.Date(2023, 12, 24, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		mockRepository *repository.MockRepositoryInterface
+	}
+	tests := []struct {
+		name       string
+		mock       func(fields *fields)
+		wantPurged int64
+		wantErr    error
+	}{
+		{
+			name: "nothing to purge",
+			mock: func(fields *fields) {
+				gomock.InOrder(
+					fields.mockRepository.EXPECT().
+						PurgeDeletedUsers(gomock.Any(), deletedBefore, 2).
+						Return(int64(0), nil),
+					fields.mockRepository.EXPECT().
+						PurgeUnverifiedUsers(gomock.Any(), createdBefore, 2).
+						Return(int64(0), nil),
+				)
+			},
+			wantPurged: 0,
+			wantErr:    nil,
+		},
+		{
+			name: "several batches",
+			mock: func(fields *fields) {
+				gomock.InOrder(
+					fields.mockRepository.EXPECT().
+						PurgeDeletedUsers(gomock.Any(), deletedBefore, 2).
+						Return(int64(2), nil),
+					fields.mockRepository.EXPECT().
+						PurgeDeletedUsers(gomock.Any(), deletedBefore, 2).
+						Return(int64(2), nil),
+					fields.mockRepository.EXPECT().
+						PurgeDeletedUsers(gomock.Any(), deletedBefore, 2).
+						Return(int64(1), nil),
+					fields.mockRepository.EXPECT().
+						PurgeUnverifiedUsers(gomock.Any(), createdBefore, 2).
+						Return(int64(2), nil),
+					fields.mockRepository.EXPECT().
+						PurgeUnverifiedUsers(gomock.Any(), createdBefore, 2).
+						Return(int64(0), nil),
+				)
+			},
+			wantPurged: 7,
+			wantErr:    nil,
+		},
+		{
+			name: "error after a batch",
+			mock: func(fields *fields) {
+				gomock.InOrder(
+					fields.mockRepository.EXPECT().
+						PurgeDeletedUsers(gomock.Any(), deletedBefore, 2).
+						Return(int64(2), nil),
+					fields.mockRepository.EXPECT().
+						PurgeDeletedUsers(gomock.Any(), deletedBefore, 2).
+						Return(int64(0), errors.New("expected error")),
+				)
+			},
+			wantPurged: 2,
+			wantErr:    errors.New("expected error"),
+		},
+		{
+			name: "error purging unverified users",
+			mock: func(fields *fields) {
+				gomock.InOrder(
+					fields.mockRepository.EXPECT().
+						PurgeDeletedUsers(gomock.Any(), deletedBefore, 2).
+						Return(int64(1), nil),
+					fields.mockRepository.EXPECT().
+						PurgeUnverifiedUsers(gomock.Any(), createdBefore, 2).
+						Return(int64(0), errors.New("expected error")),
+				)
+			},
+			wantPurged: 1,
+			wantErr:    errors.New("expected error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			fields := fields{
+				mockRepository: repository.NewMockRepositoryInterface(ctrl),
+			}
+			tt.mock(&fields)
+
+			gotPurged, gotErr := Purge(context.Background(), Options{
+				Repository:    fields.mockRepository,
+				GracePeriod:   time.Duration(30*24) * time.Hour,
+				UnverifiedTTL: time.Duration(7*24) * time.Hour,
+				BatchSize:     2,
+			}, now)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Purge() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotPurged != tt.wantPurged {
+				t.Errorf("Purge() gotPurged = %d, wantPurged = %d", gotPurged, tt.wantPurged)
+			}
+		})
+	}
+}
+
+func Test_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the first purge runs right away, the second stops the worker
+	gomock.InOrder(
+		mockRepository.EXPECT().
+			PurgeDeletedUsers(gomock.Any(), gomock.Any(), 100).
+			Return(int64(3), nil),
+		mockRepository.EXPECT().
+			PurgeUnverifiedUsers(gomock.Any(), gomock.Any(), 100).
+			Return(int64(0), nil),
+		mockRepository.EXPECT().
+			PurgeDeletedUsers(gomock.Any(), gomock.Any(), 100).
+			DoAndReturn(func(context.Context, time.Time, int) (int64, error) {
+				cancel()
+				return 0, context.Canceled
+			}),
+	)
+
+	var logs bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, Options{
+			Repository:    mockRepository,
+			GracePeriod:   time.Hour,
+			UnverifiedTTL: time.Hour,
+			Interval:      time.Millisecond,
+			BatchSize:     100,
+			Logger:        slog.New(slog.NewTextHandler(&logs, nil)),
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not stop once ctx was done")
+	}
+	if !strings.Contains(logs.String(), "count=3") {
+		t.Errorf("Run() purged count not logged, logs =\n%s", logs.String())
+	}
+	if strings.Contains(logs.String(), "error") {
+		t.Errorf("Run() logged the cancellation as an error, logs =\n%s", logs.String())
+	}
+}
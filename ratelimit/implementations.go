@@ -0,0 +1,152 @@
+// This file contains the token bucket stores.
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in process. Buckets are not shared between
+// instances, so a limit applies per instance.
+type MemoryStore struct {
+	mu          sync.Mutex
+	buckets     map[string]time.Time
+	lastEvicted time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (allowed bool, retryAfter time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictFull(now)
+
+	tat, allowed, retryAfter := take(s.buckets[key], limit, now)
+	if allowed {
+		s.buckets[key] = tat
+	}
+	return allowed, retryAfter, nil
+}
+
+// evictFull drops buckets that have refilled completely, they behave the
+// same as missing ones.
+func (s *MemoryStore) evictFull(now time.Time) {
+	if now.Sub(s.lastEvicted) < evictionInterval {
+		return
+	}
+	s.lastEvicted = now
+	for key, tat := range s.buckets {
+		if tat.Before(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// PostgresStore keeps buckets in the rate_limit table so every instance
+// shares them.
+type PostgresStore struct {
+	Db *sql.DB
+
+	mu          sync.Mutex
+	lastEvicted time.Time
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{
+		Db: db,
+	}
+}
+
+const (
+	evictionInterval = time.Duration(10) * time.Minute
+
+	queryTakeToken = `
+		INSERT INTO rate_limit (limit_key, tat)
+		VALUES ($1, $2::TIMESTAMPTZ + $3::BIGINT * INTERVAL '1 microsecond')
+		ON CONFLICT (limit_key) DO UPDATE
+		SET tat = GREATEST(rate_limit.tat, $2::TIMESTAMPTZ) + $3::BIGINT * INTERVAL '1 microsecond'
+		WHERE GREATEST(rate_limit.tat, $2::TIMESTAMPTZ) + $3::BIGINT * INTERVAL '1 microsecond'
+			<= $2::TIMESTAMPTZ + $4::BIGINT * INTERVAL '1 microsecond'
+		RETURNING tat;
+	`
+
+	queryGetTat = `
+		SELECT tat
+		FROM rate_limit
+		WHERE limit_key = $1;
+	`
+
+	queryDeleteFullBuckets = `
+		DELETE FROM rate_limit
+		WHERE tat < $1;
+	`
+)
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (allowed bool, retryAfter time.Duration, err error) {
+	err = s.evictFull(ctx, now)
+	if err != nil {
+		return allowed, retryAfter, err
+	}
+
+	// the bucket is only updated when a token is left, so a row comes back
+	// only when the request is allowed
+	rows, err := s.Db.QueryContext(ctx, queryTakeToken,
+		key,
+		now,
+		limit.emission().Microseconds(),
+		limit.Per.Microseconds())
+	if err != nil {
+		return allowed, retryAfter, err
+	}
+	allowed = rows.Next()
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return false, retryAfter, err
+	}
+	if allowed {
+		return allowed, retryAfter, nil
+	}
+
+	var tat time.Time
+	rows, err = s.Db.QueryContext(ctx, queryGetTat, key)
+	if err != nil {
+		return allowed, retryAfter, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&tat)
+		if err != nil {
+			return allowed, retryAfter, err
+		}
+	}
+
+	_, _, retryAfter = take(tat, limit, now)
+
+	return allowed, retryAfter, nil
+}
+
+// evictFull drops buckets that have refilled completely, at most once per
+// evictionInterval per instance.
+func (s *PostgresStore) evictFull(ctx context.Context, now time.Time) (err error) {
+	s.mu.Lock()
+	if now.Sub(s.lastEvicted) < evictionInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastEvicted = now
+	s.mu.Unlock()
+
+	_, err = s.Db.ExecContext(ctx, queryDeleteFullBuckets, now)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	errorHelper "github.com/fenky-ng/swt-pro/helper/error"
+)
+
+func Test_MemoryStore_Take(t *testing.T) {
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 2, Per: time.Minute}
+	s := NewMemoryStore()
+	tests := []struct {
+		name           string
+		key            string
+		now            time.Time
+		wantAllowed    bool
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:        "first token",
+			key:         "a",
+			now:         now,
+			wantAllowed: true,
+		},
+		{
+			name:        "second token",
+			key:         "a",
+			now:         now,
+			wantAllowed: true,
+		},
+		{
+			name:           "empty bucket",
+			key:            "a",
+			now:            now.Add(10 * time.Second),
+			wantAllowed:    false,
+			wantRetryAfter: 20 * time.Second,
+		},
+		{
+			name:        "other bucket",
+			key:         "b",
+			now:         now.Add(10 * time.Second),
+			wantAllowed: true,
+		},
+		{
+			name:        "refilled",
+			key:         "a",
+			now:         now.Add(30 * time.Second),
+			wantAllowed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAllowed, gotRetryAfter, gotErr := s.Take(context.Background(), tt.key, limit, tt.now)
+			if gotErr != nil {
+				t.Errorf("MemoryStore.Take() gotErr = %s", gotErr.Error())
+			}
+			if gotAllowed != tt.wantAllowed {
+				t.Errorf("MemoryStore.Take() gotAllowed = %t, wantAllowed = %t", gotAllowed, tt.wantAllowed)
+			}
+			if gotRetryAfter != tt.wantRetryAfter {
+				t.Errorf("MemoryStore.Take() gotRetryAfter = %s, wantRetryAfter = %s", gotRetryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func Test_PostgresStore_Take(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_PostgresStore_Take] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 2, Per: time.Minute}
+	tests := []struct {
+		name           string
+		mock           func()
+		wantAllowed    bool
+		wantRetryAfter time.Duration
+		wantErr        error
+	}{
+		{
+			name: "error evict",
+			mock: func() {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteFullBuckets)).
+					WithArgs(now).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "error take",
+			mock: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryTakeToken)).
+					WithArgs("a", now, int64(30000000), int64(60000000)).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "allowed",
+			mock: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryTakeToken)).
+					WithArgs("a", now, int64(30000000), int64(60000000)).
+					WillReturnRows(sqlmock.
+						NewRows([]string{"tat"}).
+						AddRow(now.Add(30 * time.Second)))
+			},
+			wantAllowed: true,
+		},
+		{
+			name: "error get tat",
+			mock: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryTakeToken)).
+					WithArgs("a", now, int64(30000000), int64(60000000)).
+					WillReturnRows(sqlmock.NewRows([]string{"tat"}))
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetTat)).
+					WithArgs("a").
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "denied",
+			mock: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryTakeToken)).
+					WithArgs("a", now, int64(30000000), int64(60000000)).
+					WillReturnRows(sqlmock.NewRows([]string{"tat"}))
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetTat)).
+					WithArgs("a").
+					WillReturnRows(sqlmock.
+						NewRows([]string{"tat"}).
+						AddRow(now.Add(45 * time.Second)))
+			},
+			wantAllowed:    false,
+			wantRetryAfter: 15 * time.Second,
+		},
+	}
+	s := NewPostgresStore(dbMock)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			gotAllowed, gotRetryAfter, gotErr := s.Take(context.Background(), "a", limit, now)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("PostgresStore.Take() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotAllowed != tt.wantAllowed {
+				t.Errorf("PostgresStore.Take() gotAllowed = %t, wantAllowed = %t", gotAllowed, tt.wantAllowed)
+			}
+			if gotRetryAfter != tt.wantRetryAfter {
+				t.Errorf("PostgresStore.Take() gotRetryAfter = %s, wantRetryAfter = %s", gotRetryAfter, tt.wantRetryAfter)
+			}
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("PostgresStore.Take() %s", err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+// This file contains the interfaces for the rate limit layer.
+// The rate limit layer is responsible for keeping token buckets.
+// For testing purpose we will generate mock implementations of these
+// interfaces using mockgen. See the Makefile for more information.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+//go:generate mockgen -source=interfaces.go -destination=interfaces.mock.gen.go -package=ratelimit
+type Store interface {
+	// Take removes a token from the bucket of key. When the bucket is empty
+	// it returns how long to wait until a token is available.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (allowed bool, retryAfter time.Duration, err error)
+}
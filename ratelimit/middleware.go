@@ -0,0 +1,209 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fenky-ng/swt-pro/constant"
+	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/fenky-ng/swt-pro/phone"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+)
+
+type MiddlewareOptions struct {
+	Store Store
+	Rules Rules
+	// Operations maps "<METHOD> <path>" of every route to its operationId,
+	// see OperationIDs.
+	Operations map[string]string
+	// UserID returns the id of the user making the request. Requests without
+	// a valid session are not limited by user.
+	UserID func(ctx echo.Context) (userID int64, ok bool)
+	// Clock defaults to time.Now.
+	Clock func() time.Time
+	// Logger defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+type errorResponse struct {
+	Header generated.ResponseHeader `json:"header"`
+}
+
+// Middleware refuses requests with 429 once a bucket of their operation is
+// empty. Store errors are logged and let the request through, since a
+// broken limiter should not take the whole service down.
+func Middleware(opts MiddlewareOptions) echo.MiddlewareFunc {
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	rulesByOperation := Rules{}
+	for operationID, rules := range opts.Rules {
+		rulesByOperation[normalizeOperationID(operationID)] = rules
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			operationID := normalizeOperationID(opts.Operations[ctx.Request().Method+" "+ctx.Path()])
+			rules := rulesByOperation[operationID]
+			if len(rules) == 0 {
+				return next(ctx)
+			}
+
+			now := opts.Clock()
+			for _, rule := range rules {
+				value, ok := keyValue(ctx, opts, rule.Key)
+				if !ok {
+					continue
+				}
+				key := operationID + ":" + rule.Key + ":" + value
+
+				allowed, retryAfter, err := opts.Store.Take(ctx.Request().Context(), key, rule.Limit, now)
+				if err != nil {
+					opts.Logger.ErrorContext(ctx.Request().Context(), "Take error", "func", "RateLimit", "error", err)
+					continue
+				}
+				if !allowed {
+					return respondRateLimited(ctx, retryAfter)
+				}
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+func respondRateLimited(ctx echo.Context, retryAfter time.Duration) error {
+	ctx.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+	errorCode := constant.ErrorCodeRateLimited
+	errorMessageIDs, errorMessages := i18n.LocalizeAll(i18n.FromContext(ctx.Request().Context()), []i18n.Message{i18n.M(i18n.TooManyRequests)})
+	return ctx.JSON(http.StatusTooManyRequests, errorResponse{
+		Header: generated.ResponseHeader{
+			ErrorCode:       &errorCode,
+			ErrorMessageIds: &errorMessageIDs,
+			ErrorMessages:   &errorMessages,
+		},
+	})
+}
+
+// IPExtractor returns how the client ip of KeyIP, and of login throttling,
+// is read. Without trusted proxies it is the address of the connection, so
+// headers a client may forge are ignored. Behind proxies, X-Forwarded-For is
+// followed back through the proxies in trustedProxies, given as CIDRs.
+func IPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q, expecting a CIDR", proxy)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+func keyValue(ctx echo.Context, opts MiddlewareOptions, key string) (value string, ok bool) {
+	switch key {
+	case KeyIP:
+		return ctx.RealIP(), true
+	case KeyPhoneNumber:
+		return requestPhoneNumber(ctx)
+	case KeyUser:
+		if opts.UserID == nil {
+			return value, false
+		}
+		userID, ok := opts.UserID(ctx)
+		if !ok {
+			return value, false
+		}
+		return strconv.FormatInt(userID, 10), true
+	}
+	return value, false
+}
+
+// maxPhoneNumberBodySize is how much of a request body is read for its
+// phone_number, well above any request that carries one.
+const maxPhoneNumberBodySize = 4 << 10
+
+// requestPhoneNumber reads the phone_number field of a JSON request body, in
+// its E.164 form so every way of writing a number shares a bucket, and puts
+// the body back for the handler. Bodies larger than maxPhoneNumberBodySize
+// are not read further and are keyed by client ip instead, so padding a
+// request cannot get it around the limit.
+func requestPhoneNumber(ctx echo.Context) (phoneNumber string, ok bool) {
+	req := ctx.Request()
+	if req.Body == nil {
+		return phoneNumber, false
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxPhoneNumberBodySize+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+	if err != nil {
+		return phoneNumber, false
+	}
+	if len(body) > maxPhoneNumberBodySize {
+		return "ip:" + ctx.RealIP(), true
+	}
+
+	var request struct {
+		PhoneNumber string `json:"phone_number"`
+	}
+	if json.Unmarshal(body, &request) != nil || request.PhoneNumber == "" {
+		return phoneNumber, false
+	}
+	return phone.Normalize(request.PhoneNumber), true
+}
+
+// normalizeOperationID lets "change-password" from api.yml match the
+// "ChangePassword" that oapi-codegen embeds in the generated spec.
+func normalizeOperationID(operationID string) string {
+	operationID = strings.ToLower(operationID)
+	operationID = strings.ReplaceAll(operationID, "-", "")
+	operationID = strings.ReplaceAll(operationID, "_", "")
+	return operationID
+}
+
+// OperationIDs maps "<METHOD> <path>" of every operation in the spec to its
+// operationId. Paths keep the spec's parameter syntax converted to echo's.
+func OperationIDs(swagger *openapi3.T) map[string]string {
+	res := map[string]string{}
+	for path, pathItem := range swagger.Paths {
+		for method, operation := range pathItem.Operations() {
+			res[fmt.Sprintf("%s %s", method, echoPath(path))] = operation.OperationID
+		}
+	}
+	return res
+}
+
+// echoPath converts "/users/{id}" to "/users/:id".
+func echoPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}")
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,290 @@
+package ratelimit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func Test_Middleware(t *testing.T) {
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	rules := Rules{
+		"login": {
+			{Key: KeyIP, Limit: Limit{Requests: 3, Per: time.Minute}},
+			{Key: KeyPhoneNumber, Limit: Limit{Requests: 1, Per: time.Minute}},
+		},
+		"get-profile": {
+			{Key: KeyUser, Limit: Limit{Requests: 1, Per: time.Minute}},
+		},
+	}
+	operations := map[string]string{
+		"POST /login":  "login",
+		"GET /profile": "get-profile",
+	}
+	type request struct {
+		method string
+		path   string
+		ip     string
+		body   string
+		userID int64
+	}
+	tests := []struct {
+		name           string
+		store          func(mockCtrl *gomock.Controller) Store
+		requests       []request
+		wantStatusCode int
+		wantRetryAfter string
+	}{
+		{
+			name: "not limited",
+			store: func(mockCtrl *gomock.Controller) Store {
+				return NewMemoryStore()
+			},
+			requests: []request{
+				{method: http.MethodGet, path: "/other", ip: "10.0.0.1"},
+				{method: http.MethodGet, path: "/other", ip: "10.0.0.1"},
+			},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name: "different phone numbers",
+			store: func(mockCtrl *gomock.Controller) Store {
+				return NewMemoryStore()
+			},
+			requests: []request{
+				{method: http.MethodPost, path: "/login", ip: "10.0.0.1", body: `{"phone_number": "+628223344551"}`},
+				{method: http.MethodPost, path: "/login", ip: "10.0.0.1", body: `{"phone_number": "+628223344552"}`},
+			},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name: "limited by phone number",
+			store: func(mockCtrl *gomock.Controller) Store {
+				return NewMemoryStore()
+			},
+			requests: []request{
+				{method: http.MethodPost, path: "/login", ip: "10.0.0.1", body: `{"phone_number": "+628223344551"}`},
+				{method: http.MethodPost, path: "/login", ip: "10.0.0.2", body: `{"phone_number": "+628223344551"}`},
+			},
+			wantStatusCode: http.StatusTooManyRequests,
+			wantRetryAfter: "60",
+		},
+		{
+			name: "oversized body limited by ip",
+			store: func(mockCtrl *gomock.Controller) Store {
+				return NewMemoryStore()
+			},
+			requests: []request{
+				{method: http.MethodPost, path: "/login", ip: "10.0.0.1", body: `{"phone_number": "+628223344551", "padding": "` + strings.Repeat("a", maxPhoneNumberBodySize) + `"}`},
+				{method: http.MethodPost, path: "/login", ip: "10.0.0.1", body: `{"phone_number": "+628223344552", "padding": "` + strings.Repeat("a", maxPhoneNumberBodySize) + `"}`},
+			},
+			wantStatusCode: http.StatusTooManyRequests,
+			wantRetryAfter: "60",
+		},
+		{
+			name: "limited by ip",
+			store: func(mockCtrl *gomock.Controller) Store {
+				return NewMemoryStore()
+			},
+			requests: []request{
+				{method: http.MethodPost, path: "/login", ip: "10.0.0.1"},
+				{method: http.MethodPost, path: "/login", ip: "10.0.0.1"},
+				{method: http.MethodPost, path: "/login", ip: "10.0.0.1"},
+				{method: http.MethodPost, path: "/login", ip: "10.0.0.1"},
+			},
+			wantStatusCode: http.StatusTooManyRequests,
+			wantRetryAfter: "20",
+		},
+		{
+			name: "limited by user",
+			store: func(mockCtrl *gomock.Controller) Store {
+				return NewMemoryStore()
+			},
+			requests: []request{
+				{method: http.MethodGet, path: "/profile", ip: "10.0.0.1", userID: 1},
+				{method: http.MethodGet, path: "/profile", ip: "10.0.0.2", userID: 1},
+			},
+			wantStatusCode: http.StatusTooManyRequests,
+			wantRetryAfter: "60",
+		},
+		{
+			name: "no session",
+			store: func(mockCtrl *gomock.Controller) Store {
+				return NewMemoryStore()
+			},
+			requests: []request{
+				{method: http.MethodGet, path: "/profile", ip: "10.0.0.1"},
+				{method: http.MethodGet, path: "/profile", ip: "10.0.0.1"},
+			},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name: "store error lets requests through",
+			store: func(mockCtrl *gomock.Controller) Store {
+				store := NewMockStore(mockCtrl)
+				store.EXPECT().Take(gomock.Any(), "getprofile:user:1", gomock.Any(), now).
+					Return(false, time.Duration(0), errors.New("expected Take error")).
+					Times(1)
+				return store
+			},
+			requests: []request{
+				{method: http.MethodGet, path: "/profile", ip: "10.0.0.1", userID: 1},
+			},
+			wantStatusCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			e := echo.New()
+			e.Use(Middleware(MiddlewareOptions{
+				Store:      tt.store(mockCtrl),
+				Rules:      rules,
+				Operations: operations,
+				UserID: func(ctx echo.Context) (int64, bool) {
+					return 1, ctx.Request().Header.Get("Authorization") != ""
+				},
+				Clock: func() time.Time {
+					return now
+				},
+			}))
+			handler := func(ctx echo.Context) error {
+				// the handler still gets the whole body
+				body, _ := io.ReadAll(ctx.Request().Body)
+				return ctx.String(http.StatusOK, string(body))
+			}
+			e.POST("/login", handler)
+			e.GET("/profile", handler)
+			e.GET("/other", handler)
+
+			var res *httptest.ResponseRecorder
+			for _, r := range tt.requests {
+				req := httptest.NewRequest(r.method, r.path, bytes.NewBufferString(r.body))
+				req.Header.Set("X-Real-IP", r.ip)
+				if r.userID != 0 {
+					req.Header.Set("Authorization", "Bearer token")
+				}
+				res = httptest.NewRecorder()
+				e.ServeHTTP(res, req)
+				if res.Code == http.StatusOK && res.Body.String() != r.body {
+					t.Errorf("Middleware() gotBody = %s, wantBody = %s", res.Body.String(), r.body)
+				}
+			}
+			if res.Code != tt.wantStatusCode {
+				t.Errorf("Middleware() gotStatusCode = %d, wantStatusCode = %d", res.Code, tt.wantStatusCode)
+			}
+			if got := res.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Middleware() gotRetryAfter = %s, wantRetryAfter = %s", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func Test_IPExtractor(t *testing.T) {
+	rules := Rules{
+		"login": {
+			{Key: KeyIP, Limit: Limit{Requests: 1, Per: time.Minute}},
+		},
+	}
+	type request struct {
+		remoteAddr     string
+		forwardedFor   string
+		realIP         string
+		wantStatusCode int
+	}
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		requests       []request
+	}{
+		{
+			name: "spoofed headers without trusted proxies",
+			requests: []request{
+				{remoteAddr: "203.0.113.1:1234", forwardedFor: "198.51.100.1", realIP: "198.51.100.1", wantStatusCode: http.StatusOK},
+				{remoteAddr: "203.0.113.1:1234", forwardedFor: "198.51.100.2", realIP: "198.51.100.2", wantStatusCode: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:           "spoofed headers through a trusted proxy",
+			trustedProxies: []string{"10.0.0.0/8"},
+			requests: []request{
+				{remoteAddr: "10.0.0.1:1234", forwardedFor: "198.51.100.1, 203.0.113.1", wantStatusCode: http.StatusOK},
+				{remoteAddr: "10.0.0.1:1234", forwardedFor: "198.51.100.2, 203.0.113.1", wantStatusCode: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:           "clients behind a trusted proxy",
+			trustedProxies: []string{"10.0.0.0/8"},
+			requests: []request{
+				{remoteAddr: "10.0.0.1:1234", forwardedFor: "203.0.113.1", wantStatusCode: http.StatusOK},
+				{remoteAddr: "10.0.0.1:1234", forwardedFor: "203.0.113.2", wantStatusCode: http.StatusOK},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ipExtractor, err := IPExtractor(tt.trustedProxies)
+			if err != nil {
+				t.Fatalf("IPExtractor() error = %s", err.Error())
+			}
+			e := echo.New()
+			e.IPExtractor = ipExtractor
+			e.Use(Middleware(MiddlewareOptions{
+				Store:      NewMemoryStore(),
+				Rules:      rules,
+				Operations: map[string]string{"POST /login": "login"},
+			}))
+			e.POST("/login", func(ctx echo.Context) error {
+				return ctx.NoContent(http.StatusOK)
+			})
+
+			for i, r := range tt.requests {
+				req := httptest.NewRequest(http.MethodPost, "/login", nil)
+				req.RemoteAddr = r.remoteAddr
+				req.Header.Set("X-Forwarded-For", r.forwardedFor)
+				if r.realIP != "" {
+					req.Header.Set("X-Real-IP", r.realIP)
+				}
+				res := httptest.NewRecorder()
+				e.ServeHTTP(res, req)
+				if res.Code != r.wantStatusCode {
+					t.Errorf("Middleware() request %d gotStatusCode = %d, wantStatusCode = %d", i, res.Code, r.wantStatusCode)
+				}
+			}
+		})
+	}
+}
+
+func Test_OperationIDs(t *testing.T) {
+	swagger, err := generated.GetSwagger()
+	if err != nil {
+		t.Fatalf("GetSwagger() error = %s", err.Error())
+	}
+	got := OperationIDs(swagger)
+	for route, want := range map[string]string{
+		"POST /login":           "login",
+		"PATCH /profile":        "update-profile",
+		"PUT /profile/password": "change-password",
+	} {
+		if normalizeOperationID(got[route]) != normalizeOperationID(want) {
+			t.Errorf("OperationIDs() got[%s] = %s, want = %s", route, got[route], want)
+		}
+	}
+}
+
+func Test_echoPath(t *testing.T) {
+	if got := echoPath("/users/{id}/sessions/{sessionId}"); got != "/users/:id/sessions/:sessionId" {
+		t.Errorf("echoPath() got = %s", got)
+	}
+}
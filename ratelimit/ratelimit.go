@@ -0,0 +1,110 @@
+// Package ratelimit limits requests per route with token buckets keyed by
+// client ip, phone number or user.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	KeyIP          = "ip"
+	KeyPhoneNumber = "phone_number"
+	KeyUser        = "user"
+)
+
+var ErrInvalidRule = errors.New("invalid rate limit rule")
+
+// Limit allows Requests requests per Per. A full bucket holds Requests
+// tokens and refills at a steady rate, so bursts up to Requests are allowed.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// emission returns the time it takes to refill one token.
+func (l Limit) emission() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+// Rule limits one operation by one key.
+type Rule struct {
+	Key   string
+	Limit Limit
+}
+
+// Rules holds the rules of every limited operation, by operationId.
+type Rules map[string][]Rule
+
+// ParseRules parses rules written as
+//
+//	<operationId>=<key>:<requests>/<per>[,<key>:<requests>/<per>...][;...]
+//
+// such as "login=ip:30/1m,phone_number:10/1m;register=ip:10/1h".
+func ParseRules(input string) (Rules, error) {
+	rules := Rules{}
+	for _, entry := range strings.Split(input, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		operationID, ruleList, found := strings.Cut(entry, "=")
+		operationID = strings.TrimSpace(operationID)
+		if !found || operationID == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRule, entry)
+		}
+		for _, item := range strings.Split(ruleList, ",") {
+			rule, err := parseRule(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			rules[operationID] = append(rules[operationID], rule)
+		}
+	}
+	return rules, nil
+}
+
+func parseRule(input string) (rule Rule, err error) {
+	key, limit, found := strings.Cut(input, ":")
+	if !found {
+		return rule, fmt.Errorf("%w: %q", ErrInvalidRule, input)
+	}
+	switch key {
+	case KeyIP, KeyPhoneNumber, KeyUser:
+	default:
+		return rule, fmt.Errorf("%w: unknown key %q", ErrInvalidRule, key)
+	}
+
+	requests, per, found := strings.Cut(limit, "/")
+	if !found {
+		return rule, fmt.Errorf("%w: %q", ErrInvalidRule, input)
+	}
+	rule.Limit.Requests, err = strconv.Atoi(requests)
+	if err != nil || rule.Limit.Requests < 1 {
+		return rule, fmt.Errorf("%w: invalid requests in %q", ErrInvalidRule, input)
+	}
+	rule.Limit.Per, err = time.ParseDuration(per)
+	if err != nil || rule.Limit.Per <= 0 {
+		return rule, fmt.Errorf("%w: invalid period in %q", ErrInvalidRule, input)
+	}
+	rule.Key = key
+
+	return rule, nil
+}
+
+// take applies one request to a bucket whose state is kept as the time it
+// becomes full again, the theoretical arrival time of the generic cell rate
+// algorithm. It returns the new state when the request is allowed.
+func take(tat time.Time, limit Limit, now time.Time) (newTat time.Time, allowed bool, retryAfter time.Duration) {
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat = tat.Add(limit.emission())
+	if wait := newTat.Sub(now) - limit.Per; wait > 0 {
+		return tat, false, wait
+	}
+	return newTat, true, 0
+}
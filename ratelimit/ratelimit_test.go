@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_ParseRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantRes Rules
+		wantErr error
+	}{
+		{
+			name:    "empty",
+			input:   "",
+			wantRes: Rules{},
+		},
+		{
+			name:  "passed",
+			input: " login=ip:30/1m, phone_number:10/1m ; register=ip:10/1h;",
+			wantRes: Rules{
+				"login": {
+					{Key: KeyIP, Limit: Limit{Requests: 30, Per: time.Minute}},
+					{Key: KeyPhoneNumber, Limit: Limit{Requests: 10, Per: time.Minute}},
+				},
+				"register": {
+					{Key: KeyIP, Limit: Limit{Requests: 10, Per: time.Hour}},
+				},
+			},
+		},
+		{
+			name:    "missing operation",
+			input:   "ip:30/1m",
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name:    "unknown key",
+			input:   "login=email:30/1m",
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name:    "invalid requests",
+			input:   "login=ip:0/1m",
+			wantErr: ErrInvalidRule,
+		},
+		{
+			name:    "invalid period",
+			input:   "login=ip:30/minute",
+			wantErr: ErrInvalidRule,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRes, gotErr := ParseRules(tt.input)
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Errorf("ParseRules() gotErr = %v, wantErr = %v", gotErr, tt.wantErr)
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("ParseRules() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_take(t *testing.T) {
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+	tests := []struct {
+		name           string
+		tat            time.Time
+		wantTat        time.Time
+		wantAllowed    bool
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:        "full bucket",
+			tat:         time.Time{},
+			wantTat:     now.Add(time.Second),
+			wantAllowed: true,
+		},
+		{
+			name:        "last token",
+			tat:         now.Add(2 * time.Second),
+			wantTat:     now.Add(3 * time.Second),
+			wantAllowed: true,
+		},
+		{
+			name:           "empty bucket",
+			tat:            now.Add(3 * time.Second),
+			wantTat:        now.Add(3 * time.Second),
+			wantAllowed:    false,
+			wantRetryAfter: time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTat, gotAllowed, gotRetryAfter := take(tt.tat, limit, now)
+			if !gotTat.Equal(tt.wantTat) {
+				t.Errorf("take() gotTat = %s, wantTat = %s", gotTat, tt.wantTat)
+			}
+			if gotAllowed != tt.wantAllowed {
+				t.Errorf("take() gotAllowed = %t, wantAllowed = %t", gotAllowed, tt.wantAllowed)
+			}
+			if gotRetryAfter != tt.wantRetryAfter {
+				t.Errorf("take() gotRetryAfter = %s, wantRetryAfter = %s", gotRetryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
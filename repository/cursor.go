@@ -0,0 +1,41 @@
+// This file contains the cursors continuing keyset paginated searches.
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// userCursor is the position of the last user of a page of SearchUsers,
+// handed out base64 encoded so callers treat it as opaque. The sort it was
+// made for is kept, a cursor only continues a search sorted the same way.
+type userCursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	// Value is the sort column of the user, empty when sorted by id
+	Value string `json:"v,omitempty"`
+	ID    int64  `json:"i"`
+}
+
+func encodeUserCursor(cursor userCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeUserCursor(s string) (cursor userCursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	err = json.Unmarshal(data, &cursor)
+	if err != nil || cursor.ID == 0 {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, so s matches itself.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_decodeUserCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    userCursor
+		wantErr error
+	}{
+		{
+			name: "round trip",
+			s:    encodeUserCursor(userCursor{Sort: UserSortPhoneNumber, Value: "+628223344556", ID: 1}),
+			want: userCursor{Sort: UserSortPhoneNumber, Value: "+628223344556", ID: 1},
+		},
+		{
+			name:    "not base64",
+			s:       "not a cursor",
+			wantErr: ErrInvalidCursor,
+		},
+		{
+			name:    "not json",
+			s:       "bm90IGpzb24",
+			wantErr: ErrInvalidCursor,
+		},
+		{
+			name:    "no id",
+			s:       encodeUserCursor(userCursor{Sort: UserSortID}),
+			wantErr: ErrInvalidCursor,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeUserCursor(tt.s)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("decodeUserCursor() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeUserCursor() got = %v, want = %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_escapeLike(t *testing.T) {
+	got := escapeLike(`50%_off\`)
+	if want := `50\%\_off\\`; got != want {
+		t.Errorf("escapeLike() got = %s, want = %s", got, want)
+	}
+}
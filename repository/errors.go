@@ -0,0 +1,89 @@
+// This file contains the errors returned by the repository layer.
+package repository
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned by lookups of a single record, such as
+	// GetUserByID, when nothing matches.
+	ErrNotFound = errors.New("repository: not found")
+	// ErrConflict is matched by errors of writes conflicting with existing
+	// records, see UniqueViolationError.
+	ErrConflict = errors.New("repository: conflict")
+	// ErrUnavailable wraps errors of a database that cannot be reached or
+	// does not accept queries for now, which are worth retrying later.
+	ErrUnavailable = errors.New("repository: unavailable")
+	// ErrInvalidCursor is returned by searches given a cursor they did not
+	// hand out, or one of a search sorted another way.
+	ErrInvalidCursor = errors.New("repository: invalid cursor")
+)
+
+// pqUniqueViolation is the Postgres error code of a unique_violation.
+const pqUniqueViolation = "23505"
+
+// pqUnavailableCodes lists the Postgres error codes, or classes of them, of
+// a database not accepting queries for now: connection exceptions,
+// insufficient resources and shutdowns.
+var pqUnavailableCodes = []string{"08", "53", "57P01", "57P02", "57P03"}
+
+// UniqueViolationError is returned when a write conflicts with a unique
+// index, such as two users claiming the same phone number at once. It
+// matches ErrConflict.
+type UniqueViolationError struct {
+	// Constraint is the name of the violated index, such as
+	// user_phone_number_unique
+	Constraint string
+	Err        error
+}
+
+func (e *UniqueViolationError) Error() string {
+	return fmt.Sprintf("repository: unique violation on %s: %s", e.Constraint, e.Err)
+}
+
+func (e *UniqueViolationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *UniqueViolationError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// translateError turns the driver errors callers act on into the repository
+// errors above, keeping the driver error wrapped, and returns any other
+// error unchanged.
+func translateError(err error) error {
+	if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) || errors.Is(err, ErrUnavailable) {
+		return err
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pqErr.Code == pqUniqueViolation {
+			return &UniqueViolationError{
+				Constraint: pqErr.Constraint,
+				Err:        err,
+			}
+		}
+		for _, code := range pqUnavailableCodes {
+			if strings.HasPrefix(string(pqErr.Code), code) {
+				return fmt.Errorf("%w: %w", ErrUnavailable, err)
+			}
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func Test_translateError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantErr        error
+		wantConstraint string
+	}{
+		{
+			name: "no error",
+		},
+		{
+			name: "unique violation",
+			err: &pq.Error{
+				Code:       "23505",
+				Constraint: "user_phone_number_unique",
+			},
+			wantErr:        ErrConflict,
+			wantConstraint: "user_phone_number_unique",
+		},
+		{
+			name: "connection exception",
+			err: &pq.Error{
+				Code: "08006",
+			},
+			wantErr: ErrUnavailable,
+		},
+		{
+			name: "too many connections",
+			err: &pq.Error{
+				Code: "53300",
+			},
+			wantErr: ErrUnavailable,
+		},
+		{
+			name:    "bad connection",
+			err:     driver.ErrBadConn,
+			wantErr: ErrUnavailable,
+		},
+		{
+			name: "network error",
+			err: &net.OpError{
+				Op:  "dial",
+				Net: "tcp",
+				Err: errors.New("connection refused"),
+			},
+			wantErr: ErrUnavailable,
+		},
+		{
+			name:    "not found",
+			err:     ErrNotFound,
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "other postgres error",
+			err: &pq.Error{
+				Code: "23503",
+			},
+		},
+		{
+			name: "other error",
+			err:  errors.New("expected error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr := translateError(tt.err)
+			if !errors.Is(gotErr, tt.err) {
+				t.Errorf("translateError() gotErr = %v, does not wrap %v", gotErr, tt.err)
+			}
+			for _, domainErr := range []error{ErrNotFound, ErrConflict, ErrUnavailable} {
+				if errors.Is(gotErr, domainErr) != (domainErr == tt.wantErr) {
+					t.Errorf("translateError() gotErr = %v, wantErr = %v", gotErr, tt.wantErr)
+				}
+			}
+			var uniqueErr *UniqueViolationError
+			if errors.As(gotErr, &uniqueErr) && uniqueErr.Constraint != tt.wantConstraint {
+				t.Errorf("translateError() gotConstraint = %s, wantConstraint = %s", uniqueErr.Constraint, tt.wantConstraint)
+			}
+		})
+	}
+}
@@ -2,11 +2,16 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/fenky-ng/swt-pro/phone"
 )
 
 func (r *Repository) GetUserByID(ctx context.Context, userID int64) (user User, err error) {
+	defer r.endCall(ctx, "GetUserByID", time.Now(), &err)
 	rows, err := r.Db.QueryContext(ctx, queryGetUserByID, userID)
 	if err != nil {
 		return user, err
@@ -14,33 +19,49 @@ func (r *Repository) GetUserByID(ctx context.Context, userID int64) (user User,
 
 	defer rows.Close()
 	for rows.Next() {
-		err = rows.Scan(&user.ID, &user.PhoneNumber, &user.Password, &user.FullName)
+		err = rows.Scan(&user.ID, &user.PhoneNumber, &user.Password, &user.FullName, &user.PhoneVerifiedAt, &user.Role, &user.LockedAt, &user.PasswordResetRequiredAt)
 		if err != nil {
 			return user, err
 		}
 	}
+	err = rows.Err()
+	if err != nil {
+		return user, err
+	}
+	if user.ID == 0 {
+		return user, ErrNotFound
+	}
 
 	return user, nil
 }
 
 func (r *Repository) GetUserByPhoneNumber(ctx context.Context, phoneNumber string) (user User, err error) {
-	rows, err := r.Db.QueryContext(ctx, queryGetUserByPhoneNumber, phoneNumber)
+	defer r.endCall(ctx, "GetUserByPhoneNumber", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryGetUserByPhoneNumber, phone.Normalize(phoneNumber))
 	if err != nil {
 		return user, err
 	}
 
 	defer rows.Close()
 	for rows.Next() {
-		err = rows.Scan(&user.ID, &user.PhoneNumber, &user.Password, &user.FullName)
+		err = rows.Scan(&user.ID, &user.PhoneNumber, &user.Password, &user.FullName, &user.PhoneVerifiedAt, &user.Role, &user.LockedAt, &user.PasswordResetRequiredAt)
 		if err != nil {
 			return user, err
 		}
 	}
+	err = rows.Err()
+	if err != nil {
+		return user, err
+	}
+	if user.ID == 0 {
+		return user, ErrNotFound
+	}
 
 	return user, nil
 }
 
 func (r *Repository) IncreaseLoginCount(ctx context.Context, userID int64) (err error) {
+	defer r.endCall(ctx, "IncreaseLoginCount", time.Now(), &err)
 	_, err = r.Db.ExecContext(ctx, queryIncreaseLoginCount, userID)
 	if err != nil {
 		return err
@@ -49,6 +70,7 @@ func (r *Repository) IncreaseLoginCount(ctx context.Context, userID int64) (err
 }
 
 func (r *Repository) InsertUser(ctx context.Context, data User) (userID int64, err error) {
+	defer r.endCall(ctx, "InsertUser", time.Now(), &err)
 	rows, err := r.Db.QueryContext(ctx, queryInsertUser,
 		data.PhoneNumber,
 		data.Password,
@@ -64,11 +86,17 @@ func (r *Repository) InsertUser(ctx context.Context, data User) (userID int64, e
 			return userID, err
 		}
 	}
+	// a unique violation is only reported once the rows are read
+	err = rows.Err()
+	if err != nil {
+		return userID, err
+	}
 
-	return userID, err
+	return userID, nil
 }
 
 func (r *Repository) UpdateUser(ctx context.Context, data User) (err error) {
+	defer r.endCall(ctx, "UpdateUser", time.Now(), &err)
 	var (
 		updatedFields []string
 		params        []any
@@ -93,3 +121,1219 @@ func (r *Repository) UpdateUser(ctx context.Context, data User) (err error) {
 	}
 	return nil
 }
+
+func (r *Repository) GetTokensValidAfter(ctx context.Context, userID int64) (validAfter time.Time, err error) {
+	defer r.endCall(ctx, "GetTokensValidAfter", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryGetTokensValidAfter, userID)
+	if err != nil {
+		return validAfter, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var value *time.Time
+		err = rows.Scan(&value)
+		if err != nil {
+			return validAfter, err
+		}
+		if value != nil {
+			validAfter = *value
+		}
+	}
+
+	return validAfter, nil
+}
+
+func (r *Repository) UpdateTokensValidAfter(ctx context.Context, userID int64, validAfter time.Time) (err error) {
+	defer r.endCall(ctx, "UpdateTokensValidAfter", time.Now(), &err)
+	_, err = r.Db.ExecContext(ctx, queryUpdateTokensValidAfter, userID, validAfter)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) UpdatePassword(ctx context.Context, data User, validAfter time.Time, keepFamilyID string) (err error) {
+	defer r.endCall(ctx, "UpdatePassword", time.Now(), &err)
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, queryUpdatePassword, data.ID, data.Password, validAfter)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, queryRevokeOtherRefreshTokens, data.ID, keepFamilyID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) GetDeletedUserByPhoneNumber(ctx context.Context, phoneNumber string, deletedAfter time.Time) (user User, err error) {
+	defer r.endCall(ctx, "GetDeletedUserByPhoneNumber", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryGetDeletedUserByPhoneNumber, phone.Normalize(phoneNumber), deletedAfter)
+	if err != nil {
+		return user, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&user.ID, &user.PhoneNumber, &user.Password, &user.FullName, &user.PhoneVerifiedAt, &user.Role, &user.LockedAt, &user.PasswordResetRequiredAt, &user.DeletedAt)
+		if err != nil {
+			return user, err
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return user, err
+	}
+	if user.ID == 0 {
+		return user, ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (r *Repository) DeleteUser(ctx context.Context, userID int64, deletedAt time.Time) (err error) {
+	defer r.endCall(ctx, "DeleteUser", time.Now(), &err)
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, queryDeleteUser, userID, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, queryRevokeRefreshTokensByUserID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) RestoreUser(ctx context.Context, userID int64, deletedAfter time.Time) (restored bool, err error) {
+	defer r.endCall(ctx, "RestoreUser", time.Now(), &err)
+	result, err := r.Db.ExecContext(ctx, queryRestoreUser, userID, deletedAfter)
+	if err != nil {
+		return restored, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return restored, err
+	}
+	return affected == 1, nil
+}
+
+func (r *Repository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, limit int) (purged int64, err error) {
+	defer r.endCall(ctx, "PurgeDeletedUsers", time.Now(), &err)
+	result, err := r.Db.ExecContext(ctx, queryPurgeDeletedUsers, deletedBefore, limit)
+	if err != nil {
+		return purged, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *Repository) PurgeUnverifiedUsers(ctx context.Context, createdBefore time.Time, limit int) (purged int64, err error) {
+	defer r.endCall(ctx, "PurgeUnverifiedUsers", time.Now(), &err)
+	result, err := r.Db.ExecContext(ctx, queryPurgeUnverifiedUsers, createdBefore, limit)
+	if err != nil {
+		return purged, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *Repository) ListUsers(ctx context.Context, filter UserFilter) (users []User, total int64, err error) {
+	defer r.endCall(ctx, "ListUsers", time.Now(), &err)
+	var (
+		conditions string
+		params     []any
+	)
+	if filter.Role != "" {
+		params = append(params, filter.Role)
+		conditions += fmt.Sprintf(" AND role = $%d", len(params))
+	}
+	if filter.Locked != nil && *filter.Locked {
+		conditions += " AND locked_at IS NOT NULL"
+	} else if filter.Locked != nil {
+		conditions += " AND locked_at IS NULL"
+	}
+	if filter.PhoneVerified != nil && *filter.PhoneVerified {
+		conditions += " AND phone_verified_at IS NOT NULL"
+	} else if filter.PhoneVerified != nil {
+		conditions += " AND phone_verified_at IS NULL"
+	}
+
+	// one snapshot, so the page agrees with the total
+	tx, err := r.Db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return users, total, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(queryCountUsers, conditions), params...)
+	if err != nil {
+		return users, total, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&total)
+		if err != nil {
+			return users, total, err
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return users, total, err
+	}
+
+	params = append(params, filter.Limit, filter.Offset)
+	rows, err = tx.QueryContext(ctx,
+		fmt.Sprintf(queryListUsers, conditions, fmt.Sprintf("$%d", len(params)-1), fmt.Sprintf("$%d", len(params))),
+		params...)
+	if err != nil {
+		return users, total, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user User
+		err = rows.Scan(
+			&user.ID,
+			&user.PhoneNumber,
+			&user.FullName,
+			&user.PhoneVerifiedAt,
+			&user.Role,
+			&user.LockedAt,
+			&user.PasswordResetRequiredAt,
+		)
+		if err != nil {
+			return users, total, err
+		}
+		users = append(users, user)
+	}
+	err = rows.Err()
+	if err != nil {
+		return users, total, err
+	}
+
+	return users, total, tx.Commit()
+}
+
+// userSortColumns maps the sorts of SearchUsers to the column ordered by
+// before the id, none when sorting by id alone.
+var userSortColumns = map[string]string{
+	UserSortID:          "",
+	UserSortFullName:    "full_name",
+	UserSortPhoneNumber: "phone_number",
+}
+
+func (r *Repository) SearchUsers(ctx context.Context, search UserSearch) (users []User, nextCursor string, err error) {
+	defer r.endCall(ctx, "SearchUsers", time.Now(), &err)
+	if search.Sort == "" {
+		search.Sort = UserSortID
+	}
+	column, ok := userSortColumns[search.Sort]
+	if !ok {
+		return users, nextCursor, fmt.Errorf("repository: unknown user sort %q", search.Sort)
+	}
+	if search.Limit <= 0 {
+		return users, nextCursor, fmt.Errorf("repository: invalid user search limit %d", search.Limit)
+	}
+
+	var (
+		conditions string
+		params     []any
+	)
+	if search.PhoneNumberPrefix != "" {
+		params = append(params, escapeLike(search.PhoneNumberPrefix)+"%")
+		conditions += fmt.Sprintf(" AND phone_number LIKE $%d", len(params))
+	}
+	if search.FullName != "" {
+		// the same expression as the user_full_name_search index
+		params = append(params, escapeLike(search.FullName))
+		conditions += fmt.Sprintf(" AND lower(immutable_unaccent(full_name)) LIKE '%%' || lower(immutable_unaccent($%d)) || '%%'", len(params))
+	}
+
+	comparison, direction := ">", ""
+	if search.Descending {
+		comparison, direction = "<", " DESC"
+	}
+	if search.Cursor != "" {
+		var cursor userCursor
+		cursor, err = decodeUserCursor(search.Cursor)
+		if err != nil {
+			return users, nextCursor, err
+		}
+		if cursor.Sort != search.Sort || cursor.Descending != search.Descending {
+			return users, nextCursor, ErrInvalidCursor
+		}
+		if column == "" {
+			params = append(params, cursor.ID)
+			conditions += fmt.Sprintf(" AND id %s $%d", comparison, len(params))
+		} else {
+			params = append(params, cursor.Value, cursor.ID)
+			conditions += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", column, comparison, len(params)-1, len(params))
+		}
+	}
+	orderBy := "id" + direction
+	if column != "" {
+		orderBy = column + direction + ", " + orderBy
+	}
+
+	// one more than asked tells whether there is a next page
+	params = append(params, search.Limit+1)
+	rows, err := r.Db.QueryContext(ctx,
+		fmt.Sprintf(querySearchUsers, conditions, orderBy, fmt.Sprintf("$%d", len(params))),
+		params...)
+	if err != nil {
+		return users, nextCursor, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user User
+		err = rows.Scan(
+			&user.ID,
+			&user.PhoneNumber,
+			&user.FullName,
+			&user.PhoneVerifiedAt,
+			&user.Role,
+			&user.LockedAt,
+			&user.PasswordResetRequiredAt,
+		)
+		if err != nil {
+			return users, nextCursor, err
+		}
+		users = append(users, user)
+	}
+	err = rows.Err()
+	if err != nil {
+		return users, nextCursor, err
+	}
+
+	if len(users) > search.Limit {
+		users = users[:search.Limit]
+		last := users[len(users)-1]
+		cursor := userCursor{
+			Sort:       search.Sort,
+			Descending: search.Descending,
+			ID:         last.ID,
+		}
+		switch search.Sort {
+		case UserSortFullName:
+			cursor.Value = last.FullName
+		case UserSortPhoneNumber:
+			cursor.Value = last.PhoneNumber
+		}
+		nextCursor = encodeUserCursor(cursor)
+	}
+
+	return users, nextCursor, nil
+}
+
+func (r *Repository) LockUser(ctx context.Context, userID int64, lockedAt time.Time) (err error) {
+	defer r.endCall(ctx, "LockUser", time.Now(), &err)
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, queryLockUser, userID, lockedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, queryRevokeRefreshTokensByUserID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) UnlockUser(ctx context.Context, userID int64) (err error) {
+	defer r.endCall(ctx, "UnlockUser", time.Now(), &err)
+	_, err = r.Db.ExecContext(ctx, queryUnlockUser, userID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) RequirePasswordReset(ctx context.Context, userID int64, requiredAt time.Time) (err error) {
+	defer r.endCall(ctx, "RequirePasswordReset", time.Now(), &err)
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, queryRequirePasswordReset, userID, requiredAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, queryRevokeRefreshTokensByUserID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) GetRolePermissions(ctx context.Context, role string) (permissions []string, err error) {
+	defer r.endCall(ctx, "GetRolePermissions", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryGetRolePermissions, role)
+	if err != nil {
+		return permissions, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var permission string
+		err = rows.Scan(&permission)
+		if err != nil {
+			return permissions, err
+		}
+		permissions = append(permissions, permission)
+	}
+	err = rows.Err()
+	if err != nil {
+		return permissions, err
+	}
+
+	return permissions, nil
+}
+
+func (r *Repository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (refreshToken RefreshToken, err error) {
+	defer r.endCall(ctx, "GetRefreshTokenByHash", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryGetRefreshTokenByHash, tokenHash)
+	if err != nil {
+		return refreshToken, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(
+			&refreshToken.ID,
+			&refreshToken.UserID,
+			&refreshToken.FamilyID,
+			&refreshToken.TokenHash,
+			&refreshToken.ExpiresAt,
+			&refreshToken.UsedAt,
+			&refreshToken.RevokedAt,
+		)
+		if err != nil {
+			return refreshToken, err
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return refreshToken, err
+	}
+	if refreshToken.ID == 0 {
+		return refreshToken, ErrNotFound
+	}
+
+	return refreshToken, nil
+}
+
+func (r *Repository) InsertRefreshToken(ctx context.Context, data RefreshToken) (refreshTokenID int64, err error) {
+	defer r.endCall(ctx, "InsertRefreshToken", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryInsertRefreshToken,
+		data.UserID,
+		data.FamilyID,
+		data.TokenHash,
+		data.ExpiresAt)
+	if err != nil {
+		return refreshTokenID, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&refreshTokenID)
+		if err != nil {
+			return refreshTokenID, err
+		}
+	}
+
+	return refreshTokenID, err
+}
+
+func (r *Repository) MarkRefreshTokenUsed(ctx context.Context, refreshTokenID int64) (marked bool, err error) {
+	defer r.endCall(ctx, "MarkRefreshTokenUsed", time.Now(), &err)
+	result, err := r.Db.ExecContext(ctx, queryMarkRefreshTokenUsed, refreshTokenID)
+	if err != nil {
+		return marked, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return marked, err
+	}
+	return affected == 1, nil
+}
+
+func (r *Repository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) (err error) {
+	defer r.endCall(ctx, "RevokeRefreshTokenFamily", time.Now(), &err)
+	_, err = r.Db.ExecContext(ctx, queryRevokeRefreshTokenFamily, familyID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) RevokeRefreshTokensByUserID(ctx context.Context, userID int64) (err error) {
+	defer r.endCall(ctx, "RevokeRefreshTokensByUserID", time.Now(), &err)
+	_, err = r.Db.ExecContext(ctx, queryRevokeRefreshTokensByUserID, userID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) InsertRevokedToken(ctx context.Context, data RevokedToken) (err error) {
+	defer r.endCall(ctx, "InsertRevokedToken", time.Now(), &err)
+	_, err = r.Db.ExecContext(ctx, queryInsertRevokedToken,
+		data.JTI,
+		data.UserID,
+		data.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) IsTokenRevoked(ctx context.Context, jti string) (revoked bool, err error) {
+	defer r.endCall(ctx, "IsTokenRevoked", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryIsTokenRevoked, jti)
+	if err != nil {
+		return revoked, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&revoked)
+		if err != nil {
+			return revoked, err
+		}
+	}
+
+	return revoked, nil
+}
+
+func (r *Repository) GetActivePasswordReset(ctx context.Context, userID int64) (passwordReset PasswordReset, err error) {
+	defer r.endCall(ctx, "GetActivePasswordReset", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryGetActivePasswordReset, userID)
+	if err != nil {
+		return passwordReset, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(
+			&passwordReset.ID,
+			&passwordReset.UserID,
+			&passwordReset.CodeHash,
+			&passwordReset.Attempts,
+			&passwordReset.ExpiresAt,
+			&passwordReset.CreatedAt,
+		)
+		if err != nil {
+			return passwordReset, err
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return passwordReset, err
+	}
+	if passwordReset.ID == 0 {
+		return passwordReset, ErrNotFound
+	}
+
+	return passwordReset, nil
+}
+
+func (r *Repository) InsertPasswordReset(ctx context.Context, data PasswordReset) (passwordResetID int64, err error) {
+	defer r.endCall(ctx, "InsertPasswordReset", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryInsertPasswordReset,
+		data.UserID,
+		data.CodeHash,
+		data.ExpiresAt)
+	if err != nil {
+		return passwordResetID, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&passwordResetID)
+		if err != nil {
+			return passwordResetID, err
+		}
+	}
+
+	return passwordResetID, err
+}
+
+func (r *Repository) IncreasePasswordResetAttempts(ctx context.Context, passwordResetID int64, maxAttempts int) (increased bool, err error) {
+	defer r.endCall(ctx, "IncreasePasswordResetAttempts", time.Now(), &err)
+	result, err := r.Db.ExecContext(ctx, queryIncreasePasswordResetAttempts, passwordResetID, maxAttempts)
+	if err != nil {
+		return increased, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return increased, err
+	}
+	return affected == 1, nil
+}
+
+func (r *Repository) ResetPassword(ctx context.Context, passwordResetID int64, data User, validAfter time.Time) (reset bool, err error) {
+	defer r.endCall(ctx, "ResetPassword", time.Now(), &err)
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return reset, err
+	}
+	defer tx.Rollback()
+
+	// consume the code first so it can only ever be redeemed once
+	result, err := tx.ExecContext(ctx, queryMarkPasswordResetUsed, passwordResetID)
+	if err != nil {
+		return reset, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return reset, err
+	}
+	if affected != 1 {
+		return reset, nil
+	}
+
+	_, err = tx.ExecContext(ctx, queryUpdatePassword, data.ID, data.Password, validAfter)
+	if err != nil {
+		return reset, err
+	}
+
+	_, err = tx.ExecContext(ctx, queryRevokeRefreshTokensByUserID, data.ID)
+	if err != nil {
+		return reset, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return reset, err
+	}
+	return true, nil
+}
+
+func (r *Repository) GetActivePhoneVerification(ctx context.Context, phoneNumber string) (phoneVerification PhoneVerification, err error) {
+	defer r.endCall(ctx, "GetActivePhoneVerification", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryGetActivePhoneVerification, phone.Normalize(phoneNumber))
+	if err != nil {
+		return phoneVerification, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(
+			&phoneVerification.ID,
+			&phoneVerification.UserID,
+			&phoneVerification.PhoneNumber,
+			&phoneVerification.CodeHash,
+			&phoneVerification.Attempts,
+			&phoneVerification.ExpiresAt,
+			&phoneVerification.CreatedAt,
+		)
+		if err != nil {
+			return phoneVerification, err
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return phoneVerification, err
+	}
+	if phoneVerification.ID == 0 {
+		return phoneVerification, ErrNotFound
+	}
+
+	return phoneVerification, nil
+}
+
+func (r *Repository) InsertPhoneVerification(ctx context.Context, data PhoneVerification) (phoneVerificationID int64, err error) {
+	defer r.endCall(ctx, "InsertPhoneVerification", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryInsertPhoneVerification,
+		data.UserID,
+		data.PhoneNumber,
+		data.CodeHash,
+		data.ExpiresAt)
+	if err != nil {
+		return phoneVerificationID, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&phoneVerificationID)
+		if err != nil {
+			return phoneVerificationID, err
+		}
+	}
+
+	return phoneVerificationID, err
+}
+
+func (r *Repository) IncreasePhoneVerificationAttempts(ctx context.Context, phoneVerificationID int64, maxAttempts int) (increased bool, err error) {
+	defer r.endCall(ctx, "IncreasePhoneVerificationAttempts", time.Now(), &err)
+	result, err := r.Db.ExecContext(ctx, queryIncreasePhoneVerificationAttempts, phoneVerificationID, maxAttempts)
+	if err != nil {
+		return increased, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return increased, err
+	}
+	return affected == 1, nil
+}
+
+func (r *Repository) ReleasePhoneNumber(ctx context.Context, phoneNumber string) (err error) {
+	defer r.endCall(ctx, "ReleasePhoneNumber", time.Now(), &err)
+	_, err = r.Db.ExecContext(ctx, queryReleasePhoneNumber, phone.Normalize(phoneNumber))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) VerifyPhoneNumber(ctx context.Context, phoneVerificationID int64, data User) (verified bool, err error) {
+	defer r.endCall(ctx, "VerifyPhoneNumber", time.Now(), &err)
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return verified, err
+	}
+	defer tx.Rollback()
+
+	// consume the code first so it can only ever be redeemed once
+	result, err := tx.ExecContext(ctx, queryMarkPhoneVerificationVerified, phoneVerificationID)
+	if err != nil {
+		return verified, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return verified, err
+	}
+	if affected != 1 {
+		return verified, nil
+	}
+
+	// the code proves the phone number belongs to the user, accounts that
+	// never verified a phone number lose it
+	_, err = tx.ExecContext(ctx, queryDeleteUnverifiedUsersByPhoneNumber, data.ID, data.PhoneNumber)
+	if err != nil {
+		return verified, err
+	}
+
+	_, err = tx.ExecContext(ctx, queryVerifyPhoneNumber, data.ID, data.PhoneNumber)
+	if err != nil {
+		return verified, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return verified, err
+	}
+	return true, nil
+}
+
+func (r *Repository) GetUserTOTP(ctx context.Context, userID int64) (userTOTP UserTOTP, err error) {
+	defer r.endCall(ctx, "GetUserTOTP", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryGetUserTOTP, userID)
+	if err != nil {
+		return userTOTP, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(
+			&userTOTP.UserID,
+			&userTOTP.Secret,
+			&userTOTP.LastUsedStep,
+			&userTOTP.ConfirmedAt,
+		)
+		if err != nil {
+			return userTOTP, err
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return userTOTP, err
+	}
+	if userTOTP.UserID == 0 {
+		return userTOTP, ErrNotFound
+	}
+
+	return userTOTP, nil
+}
+
+func (r *Repository) UpsertUserTOTP(ctx context.Context, data UserTOTP) (err error) {
+	defer r.endCall(ctx, "UpsertUserTOTP", time.Now(), &err)
+	_, err = r.Db.ExecContext(ctx, queryUpsertUserTOTP, data.UserID, data.Secret)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) ConfirmUserTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) (confirmed bool, err error) {
+	defer r.endCall(ctx, "ConfirmUserTOTP", time.Now(), &err)
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return confirmed, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, queryConfirmUserTOTP, userID, step)
+	if err != nil {
+		return confirmed, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return confirmed, err
+	}
+	if affected != 1 {
+		return confirmed, nil
+	}
+
+	// recovery codes of an earlier enrollment must not stay valid
+	_, err = tx.ExecContext(ctx, queryDeleteRecoveryCodes, userID)
+	if err != nil {
+		return confirmed, err
+	}
+	for _, codeHash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(ctx, queryInsertRecoveryCode, userID, codeHash)
+		if err != nil {
+			return confirmed, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return confirmed, err
+	}
+	return true, nil
+}
+
+func (r *Repository) UpdateTOTPLastUsedStep(ctx context.Context, userID int64, step int64) (updated bool, err error) {
+	defer r.endCall(ctx, "UpdateTOTPLastUsedStep", time.Now(), &err)
+	result, err := r.Db.ExecContext(ctx, queryUpdateTOTPLastUsedStep, userID, step)
+	if err != nil {
+		return updated, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return updated, err
+	}
+	return affected == 1, nil
+}
+
+func (r *Repository) DeleteUserTOTP(ctx context.Context, userID int64) (err error) {
+	defer r.endCall(ctx, "DeleteUserTOTP", time.Now(), &err)
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, queryDeleteUserTOTP, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, queryDeleteRecoveryCodes, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (used bool, err error) {
+	defer r.endCall(ctx, "UseRecoveryCode", time.Now(), &err)
+	result, err := r.Db.ExecContext(ctx, queryUseRecoveryCode, userID, codeHash)
+	if err != nil {
+		return used, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return used, err
+	}
+	return affected == 1, nil
+}
+
+func (r *Repository) InsertAuditEvent(ctx context.Context, event AuditEvent) (err error) {
+	defer r.endCall(ctx, "InsertAuditEvent", time.Now(), &err)
+	_, err = r.Db.ExecContext(ctx, queryInsertAuditEvent, event.UserID, event.ActorID, event.Action)
+	return err
+}
+
+func (r *Repository) GetUserData(ctx context.Context, userID int64) (userData UserData, err error) {
+	defer r.endCall(ctx, "GetUserData", time.Now(), &err)
+	// one snapshot, so the sections agree with each other
+	tx, err := r.Db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return userData, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, queryGetUserData, userID)
+	if err != nil {
+		return userData, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(
+			&userData.User.ID,
+			&userData.User.PhoneNumber,
+			&userData.User.FullName,
+			&userData.User.PhoneVerifiedAt,
+			&userData.LoginCount,
+			&userData.User.Role,
+			&userData.User.LockedAt,
+			&userData.User.PasswordResetRequiredAt,
+			&userData.User.DeletedAt,
+		)
+		if err != nil {
+			return userData, err
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return userData, err
+	}
+	if userData.User.ID == 0 {
+		return userData, ErrNotFound
+	}
+
+	rows, err = tx.QueryContext(ctx, queryGetRefreshTokensByUserID, userID)
+	if err != nil {
+		return userData, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		refreshToken := RefreshToken{UserID: userID}
+		err = rows.Scan(
+			&refreshToken.ID,
+			&refreshToken.FamilyID,
+			&refreshToken.ExpiresAt,
+			&refreshToken.CreatedAt,
+			&refreshToken.UsedAt,
+			&refreshToken.RevokedAt,
+		)
+		if err != nil {
+			return userData, err
+		}
+		userData.RefreshTokens = append(userData.RefreshTokens, refreshToken)
+	}
+	err = rows.Err()
+	if err != nil {
+		return userData, err
+	}
+
+	rows, err = tx.QueryContext(ctx, queryGetPasswordResetsByUserID, userID)
+	if err != nil {
+		return userData, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		passwordReset := PasswordReset{UserID: userID}
+		err = rows.Scan(
+			&passwordReset.ID,
+			&passwordReset.Attempts,
+			&passwordReset.ExpiresAt,
+			&passwordReset.CreatedAt,
+			&passwordReset.UsedAt,
+		)
+		if err != nil {
+			return userData, err
+		}
+		userData.PasswordResets = append(userData.PasswordResets, passwordReset)
+	}
+	err = rows.Err()
+	if err != nil {
+		return userData, err
+	}
+
+	rows, err = tx.QueryContext(ctx, queryGetPhoneVerificationsByUserID, userID)
+	if err != nil {
+		return userData, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		phoneVerification := PhoneVerification{UserID: userID}
+		err = rows.Scan(
+			&phoneVerification.ID,
+			&phoneVerification.PhoneNumber,
+			&phoneVerification.Attempts,
+			&phoneVerification.ExpiresAt,
+			&phoneVerification.CreatedAt,
+			&phoneVerification.VerifiedAt,
+		)
+		if err != nil {
+			return userData, err
+		}
+		userData.PhoneVerifications = append(userData.PhoneVerifications, phoneVerification)
+	}
+	err = rows.Err()
+	if err != nil {
+		return userData, err
+	}
+
+	rows, err = tx.QueryContext(ctx, queryGetUserTOTPDates, userID)
+	if err != nil {
+		return userData, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		userTOTP := UserTOTP{UserID: userID}
+		err = rows.Scan(&userTOTP.CreatedAt, &userTOTP.ConfirmedAt)
+		if err != nil {
+			return userData, err
+		}
+		userData.UserTOTP = &userTOTP
+	}
+	err = rows.Err()
+	if err != nil {
+		return userData, err
+	}
+
+	rows, err = tx.QueryContext(ctx, queryGetLoginAttempt, LoginAttemptUserKey(userID))
+	if err != nil {
+		return userData, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var loginAttempt LoginAttempt
+		err = rows.Scan(
+			&loginAttempt.Key,
+			&loginAttempt.FailedCount,
+			&loginAttempt.LastFailedAt,
+			&loginAttempt.BlockedUntil,
+		)
+		if err != nil {
+			return userData, err
+		}
+		userData.LoginAttempt = &loginAttempt
+	}
+	err = rows.Err()
+	if err != nil {
+		return userData, err
+	}
+
+	rows, err = tx.QueryContext(ctx, queryGetAuditEventsByUserID, userID)
+	if err != nil {
+		return userData, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		auditEvent := AuditEvent{UserID: userID}
+		err = rows.Scan(
+			&auditEvent.ID,
+			&auditEvent.ActorID,
+			&auditEvent.Action,
+			&auditEvent.CreatedAt,
+		)
+		if err != nil {
+			return userData, err
+		}
+		userData.AuditEvents = append(userData.AuditEvents, auditEvent)
+	}
+	err = rows.Err()
+	if err != nil {
+		return userData, err
+	}
+
+	return userData, tx.Commit()
+}
+
+func (r *Repository) InsertDataExport(ctx context.Context, data DataExport) (dataExportID int64, err error) {
+	defer r.endCall(ctx, "InsertDataExport", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryInsertDataExport,
+		data.UserID,
+		data.Format,
+		data.TokenHash,
+		data.ExpiresAt)
+	if err != nil {
+		return dataExportID, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&dataExportID)
+		if err != nil {
+			return dataExportID, err
+		}
+	}
+
+	return dataExportID, rows.Err()
+}
+
+func (r *Repository) GetDataExport(ctx context.Context, dataExportID int64) (dataExport DataExport, err error) {
+	defer r.endCall(ctx, "GetDataExport", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryGetDataExport, dataExportID)
+	if err != nil {
+		return dataExport, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(
+			&dataExport.ID,
+			&dataExport.UserID,
+			&dataExport.Format,
+			&dataExport.Status,
+			&dataExport.TokenHash,
+			&dataExport.Archive,
+			&dataExport.ExpiresAt,
+			&dataExport.CreatedAt,
+			&dataExport.StartedAt,
+			&dataExport.CompletedAt,
+		)
+		if err != nil {
+			return dataExport, err
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return dataExport, err
+	}
+	if dataExport.ID == 0 {
+		return dataExport, ErrNotFound
+	}
+
+	return dataExport, nil
+}
+
+func (r *Repository) ClaimDataExport(ctx context.Context, now time.Time, staleBefore time.Time) (dataExport DataExport, err error) {
+	defer r.endCall(ctx, "ClaimDataExport", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryClaimDataExport, now, staleBefore)
+	if err != nil {
+		return dataExport, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(
+			&dataExport.ID,
+			&dataExport.UserID,
+			&dataExport.Format,
+			&dataExport.Status,
+			&dataExport.TokenHash,
+			&dataExport.ExpiresAt,
+			&dataExport.CreatedAt,
+			&dataExport.StartedAt,
+		)
+		if err != nil {
+			return dataExport, err
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return dataExport, err
+	}
+	if dataExport.ID == 0 {
+		return dataExport, ErrNotFound
+	}
+
+	return dataExport, nil
+}
+
+func (r *Repository) FinishDataExport(ctx context.Context, data DataExport) (finished bool, err error) {
+	defer r.endCall(ctx, "FinishDataExport", time.Now(), &err)
+	result, err := r.Db.ExecContext(ctx, queryFinishDataExport,
+		data.ID,
+		data.StartedAt,
+		data.Status,
+		data.Archive,
+		data.CompletedAt)
+	if err != nil {
+		return finished, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return finished, err
+	}
+	return affected == 1, nil
+}
+
+func (r *Repository) DeleteExpiredDataExports(ctx context.Context, expiredBefore time.Time, limit int) (deleted int64, err error) {
+	defer r.endCall(ctx, "DeleteExpiredDataExports", time.Now(), &err)
+	result, err := r.Db.ExecContext(ctx, queryDeleteExpiredDataExports, expiredBefore, limit)
+	if err != nil {
+		return deleted, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *Repository) GetLoginAttempt(ctx context.Context, key string) (loginAttempt LoginAttempt, err error) {
+	defer r.endCall(ctx, "GetLoginAttempt", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryGetLoginAttempt, key)
+	if err != nil {
+		return loginAttempt, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(
+			&loginAttempt.Key,
+			&loginAttempt.FailedCount,
+			&loginAttempt.LastFailedAt,
+			&loginAttempt.BlockedUntil,
+		)
+		if err != nil {
+			return loginAttempt, err
+		}
+	}
+
+	return loginAttempt, nil
+}
+
+func (r *Repository) IncreaseFailedLoginCount(ctx context.Context, key string, windowStart time.Time, now time.Time) (failedCount int, err error) {
+	defer r.endCall(ctx, "IncreaseFailedLoginCount", time.Now(), &err)
+	rows, err := r.Db.QueryContext(ctx, queryIncreaseFailedLoginCount, key, windowStart, now)
+	if err != nil {
+		return failedCount, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&failedCount)
+		if err != nil {
+			return failedCount, err
+		}
+	}
+
+	return failedCount, err
+}
+
+func (r *Repository) BlockLogin(ctx context.Context, key string, blockedUntil time.Time) (err error) {
+	defer r.endCall(ctx, "BlockLogin", time.Now(), &err)
+	_, err = r.Db.ExecContext(ctx, queryBlockLogin, key, blockedUntil)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) ResetLoginAttempts(ctx context.Context, key string) (err error) {
+	defer r.endCall(ctx, "ResetLoginAttempts", time.Now(), &err)
+	_, err = r.Db.ExecContext(ctx, queryResetLoginAttempts, key)
+	if err != nil {
+		return err
+	}
+	return nil
+}
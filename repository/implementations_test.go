@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	errorHelper "github.com/fenky-ng/swt-pro/helper/error"
+	"github.com/lib/pq"
 )
 
 func Test_Repository_GetUserByID(t *testing.T) {
@@ -20,6 +23,7 @@ func Test_Repository_GetUserByID(t *testing.T) {
 		return
 	}
 	defer dbMock.Close()
+	phoneVerifiedAt := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
 	type fields struct {
 		Db *sql.DB
 	}
@@ -63,14 +67,14 @@ func Test_Repository_GetUserByID(t *testing.T) {
 			},
 			mock: func(fields *fields) {
 				resultRows := sqlmock.
-					NewRows([]string{"id", "phone_number", "password", "full_name"})
+					NewRows([]string{"id", "phone_number", "password", "full_name", "phone_verified_at", "role", "locked_at", "password_reset_required_at"})
 
 				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserByID)).
 					WithArgs(int64(1)).
 					WillReturnRows(resultRows)
 			},
 			wantRes: User{},
-			wantErr: nil,
+			wantErr: ErrNotFound,
 		},
 		{
 			name: "passed",
@@ -83,18 +87,20 @@ func Test_Repository_GetUserByID(t *testing.T) {
 			},
 			mock: func(fields *fields) {
 				resultRows := sqlmock.
-					NewRows([]string{"id", "phone_number", "password", "full_name"}).
-					AddRow(1, "+628223344556", "<password>", "Sawit")
+					NewRows([]string{"id", "phone_number", "password", "full_name", "phone_verified_at", "role", "locked_at", "password_reset_required_at"}).
+					AddRow(1, "+628223344556", "<password>", "Sawit", phoneVerifiedAt, "admin", nil, nil)
 
 				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserByID)).
 					WithArgs(int64(1)).
 					WillReturnRows(resultRows)
 			},
 			wantRes: User{
-				ID:          1,
-				PhoneNumber: "+628223344556",
-				Password:    "<password>",
-				FullName:    "Sawit",
+				ID:              1,
+				PhoneNumber:     "+628223344556",
+				Password:        "<password>",
+				FullName:        "Sawit",
+				PhoneVerifiedAt: &phoneVerifiedAt,
+				Role:            "admin",
 			},
 			wantErr: nil,
 		},
@@ -123,6 +129,7 @@ func Test_Repository_GetUserByPhoneNumber(t *testing.T) {
 		return
 	}
 	defer dbMock.Close()
+	phoneVerifiedAt := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
 	type fields struct {
 		Db *sql.DB
 	}
@@ -166,14 +173,14 @@ func Test_Repository_GetUserByPhoneNumber(t *testing.T) {
 			},
 			mock: func(fields *fields) {
 				resultRows := sqlmock.
-					NewRows([]string{"id", "phone_number", "password", "full_name"})
+					NewRows([]string{"id", "phone_number", "password", "full_name", "phone_verified_at", "role", "locked_at", "password_reset_required_at"})
 
 				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserByPhoneNumber)).
 					WithArgs("+628223344556").
 					WillReturnRows(resultRows)
 			},
 			wantRes: User{},
-			wantErr: nil,
+			wantErr: ErrNotFound,
 		},
 		{
 			name: "passed",
@@ -182,22 +189,23 @@ func Test_Repository_GetUserByPhoneNumber(t *testing.T) {
 			},
 			args: args{
 				ctx:         context.Background(),
-				phoneNumber: "+628223344556",
+				phoneNumber: "+62 822-3344-556",
 			},
 			mock: func(fields *fields) {
 				resultRows := sqlmock.
-					NewRows([]string{"id", "phone_number", "password", "full_name"}).
-					AddRow(1, "+628223344556", "<password>", "Sawit")
+					NewRows([]string{"id", "phone_number", "password", "full_name", "phone_verified_at", "role", "locked_at", "password_reset_required_at"}).
+					AddRow(1, "+628223344556", "<password>", "Sawit", phoneVerifiedAt, "", nil, nil)
 
 				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserByPhoneNumber)).
 					WithArgs("+628223344556").
 					WillReturnRows(resultRows)
 			},
 			wantRes: User{
-				ID:          1,
-				PhoneNumber: "+628223344556",
-				Password:    "<password>",
-				FullName:    "Sawit",
+				ID:              1,
+				PhoneNumber:     "+628223344556",
+				Password:        "<password>",
+				FullName:        "Sawit",
+				PhoneVerifiedAt: &phoneVerifiedAt,
 			},
 			wantErr: nil,
 		},
@@ -355,6 +363,36 @@ func Test_Repository_InsertUser(t *testing.T) {
 			wantRes: 1,
 			wantErr: nil,
 		},
+		{
+			name: "unique violation",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: User{
+					PhoneNumber: "+628223344556",
+					Password:    "<password>",
+					FullName:    "Sawit",
+				},
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id"}).
+					AddRow(1).
+					RowError(0, &pq.Error{
+						Code:       "23505",
+						Message:    "duplicate key value violates unique constraint",
+						Constraint: "user_phone_number_unique",
+					})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertUser)).
+					WithArgs("+628223344556", "<password>", "Sawit").
+					WillReturnRows(resultRows)
+			},
+			wantRes: 0,
+			wantErr: errors.New("repository: unique violation on user_phone_number_unique: pq: duplicate key value violates unique constraint"),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -373,6 +411,73 @@ func Test_Repository_InsertUser(t *testing.T) {
 	}
 }
 
+// Test_Repository_InsertUser_uniqueViolation checks a phone number caught by
+// the unique index is reported as a *UniqueViolationError matching
+// ErrConflict, whether Postgres refuses the statement or the returned row.
+func Test_Repository_InsertUser_uniqueViolation(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_InsertUser_uniqueViolation] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	pqErr := &pq.Error{
+		Code:       "23505",
+		Message:    "duplicate key value violates unique constraint",
+		Constraint: "user_phone_number_unique",
+	}
+	tests := []struct {
+		name string
+		mock func()
+	}{
+		{
+			name: "statement refused",
+			mock: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertUser)).
+					WithArgs("+628223344556", "<password>", "Sawit").
+					WillReturnError(pqErr)
+			},
+		},
+		{
+			name: "row refused",
+			mock: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertUser)).
+					WithArgs("+628223344556", "<password>", "Sawit").
+					WillReturnRows(sqlmock.
+						NewRows([]string{"id"}).
+						AddRow(1).
+						RowError(0, pqErr))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: dbMock,
+			}
+			tt.mock()
+			_, gotErr := r.InsertUser(context.Background(), User{
+				PhoneNumber: "+628223344556",
+				Password:    "<password>",
+				FullName:    "Sawit",
+			})
+			var uniqueErr *UniqueViolationError
+			if !errors.As(gotErr, &uniqueErr) || uniqueErr.Constraint != "user_phone_number_unique" {
+				t.Errorf("Repository.InsertUser() gotErr = %#v, want a *UniqueViolationError on user_phone_number_unique", gotErr)
+			}
+			if !errors.Is(gotErr, ErrConflict) {
+				t.Errorf("Repository.InsertUser() gotErr = %s, want ErrConflict", errorHelper.GetErrorMessage(gotErr))
+			}
+			if !errors.Is(gotErr, pqErr) {
+				t.Errorf("Repository.InsertUser() gotErr = %s, want it wrapping the driver error", errorHelper.GetErrorMessage(gotErr))
+			}
+		})
+	}
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Repository.InsertUser() %s", err.Error())
+	}
+}
+
 func Test_Repository_UpdateUser(t *testing.T) {
 	dbMock, sqlMock, err := sqlmock.New()
 	if err != nil {
@@ -450,6 +555,29 @@ func Test_Repository_UpdateUser(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name: "unique violation",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: User{
+					ID:          1,
+					PhoneNumber: "+62812345678",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(queryUpdateUser, "phone_number = $2"))).
+					WithArgs(int64(1), "+62812345678").
+					WillReturnError(&pq.Error{
+						Code:       "23505",
+						Message:    "duplicate key value violates unique constraint",
+						Constraint: "user_phone_number_unique",
+					})
+			},
+			wantErr: errors.New("repository: unique violation on user_phone_number_unique: pq: duplicate key value violates unique constraint"),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -464,3 +592,5095 @@ func Test_Repository_UpdateUser(t *testing.T) {
 		})
 	}
 }
+
+func Test_Repository_GetRefreshTokenByHash(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetRefreshTokenByHash] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	usedAt := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx       context.Context
+		tokenHash string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes RefreshToken
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				tokenHash: "<hash>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetRefreshTokenByHash)).
+					WithArgs("<hash>").
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: RefreshToken{},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				tokenHash: "<hash>",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "family_id", "token_hash", "expires_at", "used_at", "revoked_at"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetRefreshTokenByHash)).
+					WithArgs("<hash>").
+					WillReturnRows(resultRows)
+			},
+			wantRes: RefreshToken{},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				tokenHash: "<hash>",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "family_id", "token_hash", "expires_at", "used_at", "revoked_at"}).
+					AddRow(1, 2, "<family>", "<hash>", expiresAt, usedAt, nil)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetRefreshTokenByHash)).
+					WithArgs("<hash>").
+					WillReturnRows(resultRows)
+			},
+			wantRes: RefreshToken{
+				ID:        1,
+				UserID:    2,
+				FamilyID:  "<family>",
+				TokenHash: "<hash>",
+				ExpiresAt: expiresAt,
+				UsedAt:    &usedAt,
+				RevokedAt: nil,
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.GetRefreshTokenByHash(tt.args.ctx, tt.args.tokenHash)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.GetRefreshTokenByHash() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.GetRefreshTokenByHash() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_InsertRefreshToken(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_InsertRefreshToken] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		data RefreshToken
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes int64
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: RefreshToken{
+					UserID:    1,
+					FamilyID:  "<family>",
+					TokenHash: "<hash>",
+					ExpiresAt: expiresAt,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertRefreshToken)).
+					WithArgs(int64(1), "<family>", "<hash>", expiresAt).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: 0,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: RefreshToken{
+					UserID:    1,
+					FamilyID:  "<family>",
+					TokenHash: "<hash>",
+					ExpiresAt: expiresAt,
+				},
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id"}).
+					AddRow(1)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertRefreshToken)).
+					WithArgs(int64(1), "<family>", "<hash>", expiresAt).
+					WillReturnRows(resultRows)
+			},
+			wantRes: 1,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.InsertRefreshToken(tt.args.ctx, tt.args.data)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.InsertRefreshToken() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.InsertRefreshToken() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_MarkRefreshTokenUsed(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_MarkRefreshTokenUsed] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx            context.Context
+		refreshTokenID int64
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:            context.Background(),
+				refreshTokenID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkRefreshTokenUsed)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "already used",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:            context.Background(),
+				refreshTokenID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkRefreshTokenUsed)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:            context.Background(),
+				refreshTokenID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkRefreshTokenUsed)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.MarkRefreshTokenUsed(tt.args.ctx, tt.args.refreshTokenID)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.MarkRefreshTokenUsed() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.MarkRefreshTokenUsed() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_RevokeRefreshTokenFamily(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_RevokeRefreshTokenFamily] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx      context.Context
+		familyID string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				familyID: "<family>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokenFamily)).
+					WithArgs("<family>").
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				familyID: "<family>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokenFamily)).
+					WithArgs("<family>").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.RevokeRefreshTokenFamily(tt.args.ctx, tt.args.familyID)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.RevokeRefreshTokenFamily() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_GetTokensValidAfter(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetTokensValidAfter] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	validAfter := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes time.Time
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetTokensValidAfter)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: time.Time{},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "never set",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"tokens_valid_after"}).
+					AddRow(nil)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetTokensValidAfter)).
+					WithArgs(int64(1)).
+					WillReturnRows(resultRows)
+			},
+			wantRes: time.Time{},
+			wantErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"tokens_valid_after"}).
+					AddRow(validAfter)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetTokensValidAfter)).
+					WithArgs(int64(1)).
+					WillReturnRows(resultRows)
+			},
+			wantRes: validAfter,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.GetTokensValidAfter(tt.args.ctx, tt.args.userID)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.GetTokensValidAfter() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.GetTokensValidAfter() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_UpdateTokensValidAfter(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_UpdateTokensValidAfter] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	validAfter := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx        context.Context
+		userID     int64
+		validAfter time.Time
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:        context.Background(),
+				userID:     1,
+				validAfter: validAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdateTokensValidAfter)).
+					WithArgs(int64(1), validAfter).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:        context.Background(),
+				userID:     1,
+				validAfter: validAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdateTokensValidAfter)).
+					WithArgs(int64(1), validAfter).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.UpdateTokensValidAfter(tt.args.ctx, tt.args.userID, tt.args.validAfter)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.UpdateTokensValidAfter() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_UpdatePassword(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_UpdatePassword] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	validAfter := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx          context.Context
+		data         User
+		validAfter   time.Time
+		keepFamilyID string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "begin error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: User{
+					ID:       1,
+					Password: "<hashed>",
+				},
+				validAfter:   validAfter,
+				keepFamilyID: "<family>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "update password error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: User{
+					ID:       1,
+					Password: "<hashed>",
+				},
+				validAfter:   validAfter,
+				keepFamilyID: "<family>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdatePassword)).
+					WithArgs(int64(1), "<hashed>", validAfter).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "revoke refresh tokens error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: User{
+					ID:       1,
+					Password: "<hashed>",
+				},
+				validAfter:   validAfter,
+				keepFamilyID: "<family>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdatePassword)).
+					WithArgs(int64(1), "<hashed>", validAfter).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeOtherRefreshTokens)).
+					WithArgs(int64(1), "<family>").
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "commit error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: User{
+					ID:       1,
+					Password: "<hashed>",
+				},
+				validAfter:   validAfter,
+				keepFamilyID: "<family>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdatePassword)).
+					WithArgs(int64(1), "<hashed>", validAfter).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeOtherRefreshTokens)).
+					WithArgs(int64(1), "<family>").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				sqlMock.ExpectCommit().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: User{
+					ID:       1,
+					Password: "<hashed>",
+				},
+				validAfter:   validAfter,
+				keepFamilyID: "<family>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdatePassword)).
+					WithArgs(int64(1), "<hashed>", validAfter).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeOtherRefreshTokens)).
+					WithArgs(int64(1), "<family>").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				sqlMock.ExpectCommit()
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.UpdatePassword(tt.args.ctx, tt.args.data, tt.args.validAfter, tt.args.keepFamilyID)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.UpdatePassword() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_GetDeletedUserByPhoneNumber(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetDeletedUserByPhoneNumber] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	phoneVerifiedAt := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	deletedAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	deletedAfter := time.Date(2023, 11, 15, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx          context.Context
+		phoneNumber  string
+		deletedAfter time.Time
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes User
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				phoneNumber:  "+628223344556",
+				deletedAfter: deletedAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetDeletedUserByPhoneNumber)).
+					WithArgs("+628223344556", deletedAfter).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: User{},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				phoneNumber:  "+628223344556",
+				deletedAfter: deletedAfter,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "phone_number", "password", "full_name", "phone_verified_at", "role", "locked_at", "password_reset_required_at", "deleted_at"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetDeletedUserByPhoneNumber)).
+					WithArgs("+628223344556", deletedAfter).
+					WillReturnRows(resultRows)
+			},
+			wantRes: User{},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				phoneNumber:  "+62 822-3344-556",
+				deletedAfter: deletedAfter,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "phone_number", "password", "full_name", "phone_verified_at", "role", "locked_at", "password_reset_required_at", "deleted_at"}).
+					AddRow(1, "+628223344556", "<password>", "Sawit", phoneVerifiedAt, "", nil, nil, deletedAt)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetDeletedUserByPhoneNumber)).
+					WithArgs("+628223344556", deletedAfter).
+					WillReturnRows(resultRows)
+			},
+			wantRes: User{
+				ID:              1,
+				PhoneNumber:     "+628223344556",
+				Password:        "<password>",
+				FullName:        "Sawit",
+				PhoneVerifiedAt: &phoneVerifiedAt,
+				DeletedAt:       &deletedAt,
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.GetDeletedUserByPhoneNumber(tt.args.ctx, tt.args.phoneNumber, tt.args.deletedAfter)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.GetDeletedUserByPhoneNumber() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.GetDeletedUserByPhoneNumber() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_DeleteUser(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_DeleteUser] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	deletedAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx       context.Context
+		userID    int64
+		deletedAt time.Time
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "begin error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				userID:    1,
+				deletedAt: deletedAt,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "delete user error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				userID:    1,
+				deletedAt: deletedAt,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteUser)).
+					WithArgs(int64(1), deletedAt).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "revoke refresh tokens error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				userID:    1,
+				deletedAt: deletedAt,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteUser)).
+					WithArgs(int64(1), deletedAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				userID:    1,
+				deletedAt: deletedAt,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteUser)).
+					WithArgs(int64(1), deletedAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				sqlMock.ExpectCommit()
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.DeleteUser(tt.args.ctx, tt.args.userID, tt.args.deletedAt)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.DeleteUser() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_RestoreUser(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_RestoreUser] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	deletedAfter := time.Date(2023, 11, 15, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx          context.Context
+		userID       int64
+		deletedAfter time.Time
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				userID:       1,
+				deletedAfter: deletedAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRestoreUser)).
+					WithArgs(int64(1), deletedAfter).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "grace period over",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				userID:       1,
+				deletedAfter: deletedAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRestoreUser)).
+					WithArgs(int64(1), deletedAfter).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				userID:       1,
+				deletedAfter: deletedAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRestoreUser)).
+					WithArgs(int64(1), deletedAfter).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.RestoreUser(tt.args.ctx, tt.args.userID, tt.args.deletedAfter)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.RestoreUser() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.RestoreUser() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_PurgeDeletedUsers(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_PurgeDeletedUsers] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	deletedBefore := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx           context.Context
+		deletedBefore time.Time
+		limit         int
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes int64
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				deletedBefore: deletedBefore,
+				limit:         100,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryPurgeDeletedUsers)).
+					WithArgs(deletedBefore, 100).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: 0,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				deletedBefore: deletedBefore,
+				limit:         100,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryPurgeDeletedUsers)).
+					WithArgs(deletedBefore, 100).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+			wantRes: 3,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.PurgeDeletedUsers(tt.args.ctx, tt.args.deletedBefore, tt.args.limit)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.PurgeDeletedUsers() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.PurgeDeletedUsers() gotRes = %d, wantRes = %d", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_PurgeUnverifiedUsers(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_PurgeUnverifiedUsers] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	createdBefore := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx           context.Context
+		createdBefore time.Time
+		limit         int
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes int64
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				createdBefore: createdBefore,
+				limit:         100,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryPurgeUnverifiedUsers)).
+					WithArgs(createdBefore, 100).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: 0,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				createdBefore: createdBefore,
+				limit:         100,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryPurgeUnverifiedUsers)).
+					WithArgs(createdBefore, 100).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+			wantRes: 3,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.PurgeUnverifiedUsers(tt.args.ctx, tt.args.createdBefore, tt.args.limit)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.PurgeUnverifiedUsers() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.PurgeUnverifiedUsers() gotRes = %d, wantRes = %d", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_ListUsers(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_ListUsers] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	lockedAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	locked := true
+	phoneVerified := false
+	userColumns := []string{"id", "phone_number", "full_name", "phone_verified_at", "role", "locked_at", "password_reset_required_at"}
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		filter UserFilter
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		wantRes   []User
+		wantTotal int64
+		wantErr   error
+	}{
+		{
+			name: "begin error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				filter: UserFilter{Limit: 20},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes:   nil,
+			wantTotal: 0,
+			wantErr:   errors.New("expected error"),
+		},
+		{
+			name: "count error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				filter: UserFilter{Limit: 20},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(queryCountUsers, ""))).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes:   nil,
+			wantTotal: 0,
+			wantErr:   errors.New("expected error"),
+		},
+		{
+			name: "list error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				filter: UserFilter{Limit: 20},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(queryCountUsers, ""))).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(queryListUsers, "", "$1", "$2"))).
+					WithArgs(20, 0).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes:   nil,
+			wantTotal: 1,
+			wantErr:   errors.New("expected error"),
+		},
+		{
+			name: "passed without filter",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				filter: UserFilter{Limit: 20},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(queryCountUsers, ""))).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(queryListUsers, "", "$1", "$2"))).
+					WithArgs(20, 0).
+					WillReturnRows(sqlmock.NewRows(userColumns).
+						AddRow(1, "+628223344556", "Sawit", nil, "admin", nil, nil).
+						AddRow(2, "+628223344557", "Pro", nil, "", lockedAt, nil))
+				sqlMock.ExpectCommit()
+			},
+			wantRes: []User{
+				{ID: 1, PhoneNumber: "+628223344556", FullName: "Sawit", Role: "admin"},
+				{ID: 2, PhoneNumber: "+628223344557", FullName: "Pro", LockedAt: &lockedAt},
+			},
+			wantTotal: 2,
+			wantErr:   nil,
+		},
+		{
+			name: "passed with filters",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				filter: UserFilter{
+					Role:          "support",
+					Locked:        &locked,
+					PhoneVerified: &phoneVerified,
+					Offset:        20,
+					Limit:         20,
+				},
+			},
+			mock: func(fields *fields) {
+				conditions := " AND role = $1 AND locked_at IS NOT NULL AND phone_verified_at IS NULL"
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(queryCountUsers, conditions))).
+					WithArgs("support").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(20))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(queryListUsers, conditions, "$2", "$3"))).
+					WithArgs("support", 20, 20).
+					WillReturnRows(sqlmock.NewRows(userColumns))
+				sqlMock.ExpectCommit()
+			},
+			wantRes:   nil,
+			wantTotal: 20,
+			wantErr:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotTotal, gotErr := r.ListUsers(tt.args.ctx, tt.args.filter)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.ListUsers() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.ListUsers() gotRes = %v, wantRes = %v", gotRes, tt.wantRes)
+			}
+			if gotTotal != tt.wantTotal {
+				t.Errorf("Repository.ListUsers() gotTotal = %d, wantTotal = %d", gotTotal, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func Test_Repository_SearchUsers(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_SearchUsers] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	userColumns := []string{"id", "phone_number", "full_name", "phone_verified_at", "role", "locked_at", "password_reset_required_at"}
+	fullNameCursor := encodeUserCursor(userCursor{Sort: UserSortFullName, Descending: true, Value: "Sawit", ID: 2})
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		search UserSearch
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		wantRes        []User
+		wantNextCursor string
+		wantErr        error
+	}{
+		{
+			name: "unknown sort",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				search: UserSearch{Sort: "login_count", Limit: 2},
+			},
+			mock:           func(fields *fields) {},
+			wantRes:        nil,
+			wantNextCursor: "",
+			wantErr:        errors.New(`repository: unknown user sort "login_count"`),
+		},
+		{
+			name: "invalid limit",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				search: UserSearch{Limit: 0},
+			},
+			mock:           func(fields *fields) {},
+			wantRes:        nil,
+			wantNextCursor: "",
+			wantErr:        errors.New("repository: invalid user search limit 0"),
+		},
+		{
+			name: "invalid cursor",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				search: UserSearch{Cursor: "not a cursor", Limit: 2},
+			},
+			mock:           func(fields *fields) {},
+			wantRes:        nil,
+			wantNextCursor: "",
+			wantErr:        ErrInvalidCursor,
+		},
+		{
+			name: "cursor of another sort",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				search: UserSearch{Sort: UserSortFullName, Cursor: fullNameCursor, Limit: 2},
+			},
+			mock:           func(fields *fields) {},
+			wantRes:        nil,
+			wantNextCursor: "",
+			wantErr:        ErrInvalidCursor,
+		},
+		{
+			name: "query error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				search: UserSearch{Limit: 2},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(querySearchUsers, "", "id", "$1"))).
+					WithArgs(3).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes:        nil,
+			wantNextCursor: "",
+			wantErr:        errors.New("expected error"),
+		},
+		{
+			name: "passed last page",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				search: UserSearch{
+					PhoneNumberPrefix: "+62822",
+					Limit:             2,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(querySearchUsers, " AND phone_number LIKE $1", "id", "$2"))).
+					WithArgs("+62822%", 3).
+					WillReturnRows(sqlmock.NewRows(userColumns).
+						AddRow(1, "+628223344556", "Sawit", nil, "admin", nil, nil))
+			},
+			wantRes: []User{
+				{ID: 1, PhoneNumber: "+628223344556", FullName: "Sawit", Role: "admin"},
+			},
+			wantNextCursor: "",
+			wantErr:        nil,
+		},
+		{
+			name: "passed with next page",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				search: UserSearch{
+					FullName:   "100%",
+					Sort:       UserSortFullName,
+					Descending: true,
+					Cursor:     fullNameCursor,
+					Limit:      2,
+				},
+			},
+			mock: func(fields *fields) {
+				conditions := " AND lower(immutable_unaccent(full_name)) LIKE '%' || lower(immutable_unaccent($1)) || '%'" +
+					" AND (full_name, id) < ($2, $3)"
+				sqlMock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(querySearchUsers, conditions, "full_name DESC, id DESC", "$4"))).
+					WithArgs(`100\%`, "Sawit", 2, 3).
+					WillReturnRows(sqlmock.NewRows(userColumns).
+						AddRow(5, "+628223344560", "Pro 100%", nil, "", nil, nil).
+						AddRow(4, "+628223344559", "Pro 100%", nil, "", nil, nil).
+						AddRow(3, "+628223344558", "Dua 100%", nil, "", nil, nil))
+			},
+			wantRes: []User{
+				{ID: 5, PhoneNumber: "+628223344560", FullName: "Pro 100%"},
+				{ID: 4, PhoneNumber: "+628223344559", FullName: "Pro 100%"},
+			},
+			wantNextCursor: encodeUserCursor(userCursor{Sort: UserSortFullName, Descending: true, Value: "Pro 100%", ID: 4}),
+			wantErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotNextCursor, gotErr := r.SearchUsers(tt.args.ctx, tt.args.search)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.SearchUsers() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.SearchUsers() gotRes = %v, wantRes = %v", gotRes, tt.wantRes)
+			}
+			if gotNextCursor != tt.wantNextCursor {
+				t.Errorf("Repository.SearchUsers() gotNextCursor = %s, wantNextCursor = %s", gotNextCursor, tt.wantNextCursor)
+			}
+		})
+	}
+}
+
+func Test_Repository_LockUser(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_LockUser] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	lockedAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx      context.Context
+		userID   int64
+		lockedAt time.Time
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "begin error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				lockedAt: lockedAt,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "lock user error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				lockedAt: lockedAt,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockUser)).
+					WithArgs(int64(1), lockedAt).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "revoke refresh tokens error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				lockedAt: lockedAt,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockUser)).
+					WithArgs(int64(1), lockedAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				lockedAt: lockedAt,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockUser)).
+					WithArgs(int64(1), lockedAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				sqlMock.ExpectCommit()
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.LockUser(tt.args.ctx, tt.args.userID, tt.args.lockedAt)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.LockUser() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_UnlockUser(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_UnlockUser] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUnlockUser)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUnlockUser)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.UnlockUser(tt.args.ctx, tt.args.userID)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.UnlockUser() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_RequirePasswordReset(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_RequirePasswordReset] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	requiredAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx        context.Context
+		userID     int64
+		requiredAt time.Time
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "begin error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:        context.Background(),
+				userID:     1,
+				requiredAt: requiredAt,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "require password reset error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:        context.Background(),
+				userID:     1,
+				requiredAt: requiredAt,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRequirePasswordReset)).
+					WithArgs(int64(1), requiredAt).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "revoke refresh tokens error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:        context.Background(),
+				userID:     1,
+				requiredAt: requiredAt,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRequirePasswordReset)).
+					WithArgs(int64(1), requiredAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:        context.Background(),
+				userID:     1,
+				requiredAt: requiredAt,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRequirePasswordReset)).
+					WithArgs(int64(1), requiredAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				sqlMock.ExpectCommit()
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.RequirePasswordReset(tt.args.ctx, tt.args.userID, tt.args.requiredAt)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.RequirePasswordReset() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_GetRolePermissions(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetRolePermissions] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		role string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes []string
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:  context.Background(),
+				role: "support",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetRolePermissions)).
+					WithArgs("support").
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: nil,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "unknown role",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:  context.Background(),
+				role: "guest",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetRolePermissions)).
+					WithArgs("guest").
+					WillReturnRows(sqlmock.NewRows([]string{"permission"}))
+			},
+			wantRes: nil,
+			wantErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:  context.Background(),
+				role: "support",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetRolePermissions)).
+					WithArgs("support").
+					WillReturnRows(sqlmock.NewRows([]string{"permission"}).
+						AddRow("users:lock").
+						AddRow("users:read"))
+			},
+			wantRes: []string{"users:lock", "users:read"},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.GetRolePermissions(tt.args.ctx, tt.args.role)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.GetRolePermissions() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.GetRolePermissions() gotRes = %v, wantRes = %v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_RevokeRefreshTokensByUserID(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_RevokeRefreshTokensByUserID] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.RevokeRefreshTokensByUserID(tt.args.ctx, tt.args.userID)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.RevokeRefreshTokensByUserID() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_InsertRevokedToken(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_InsertRevokedToken] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		data RevokedToken
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: RevokedToken{
+					JTI:       "<jti>",
+					UserID:    1,
+					ExpiresAt: expiresAt,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertRevokedToken)).
+					WithArgs("<jti>", int64(1), expiresAt).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: RevokedToken{
+					JTI:       "<jti>",
+					UserID:    1,
+					ExpiresAt: expiresAt,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertRevokedToken)).
+					WithArgs("<jti>", int64(1), expiresAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.InsertRevokedToken(tt.args.ctx, tt.args.data)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.InsertRevokedToken() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_IsTokenRevoked(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_IsTokenRevoked] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx context.Context
+		jti string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				jti: "<jti>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryIsTokenRevoked)).
+					WithArgs("<jti>").
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "not revoked",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				jti: "<jti>",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"exists"}).
+					AddRow(false)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryIsTokenRevoked)).
+					WithArgs("<jti>").
+					WillReturnRows(resultRows)
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+		{
+			name: "revoked",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				jti: "<jti>",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"exists"}).
+					AddRow(true)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryIsTokenRevoked)).
+					WithArgs("<jti>").
+					WillReturnRows(resultRows)
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.IsTokenRevoked(tt.args.ctx, tt.args.jti)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.IsTokenRevoked() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.IsTokenRevoked() gotRes = %v, wantRes = %v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_GetActivePasswordReset(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetActivePasswordReset] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2023, 12, 1, 0, 10, 0, 0, time.UTC)
+	createdAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes PasswordReset
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActivePasswordReset)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: PasswordReset{},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "code_hash", "attempts", "expires_at", "created_at"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActivePasswordReset)).
+					WithArgs(int64(1)).
+					WillReturnRows(resultRows)
+			},
+			wantRes: PasswordReset{},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "code_hash", "attempts", "expires_at", "created_at"}).
+					AddRow(2, 1, "<hash>", 3, expiresAt, createdAt)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActivePasswordReset)).
+					WithArgs(int64(1)).
+					WillReturnRows(resultRows)
+			},
+			wantRes: PasswordReset{
+				ID:        2,
+				UserID:    1,
+				CodeHash:  "<hash>",
+				Attempts:  3,
+				ExpiresAt: expiresAt,
+				CreatedAt: createdAt,
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.GetActivePasswordReset(tt.args.ctx, tt.args.userID)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.GetActivePasswordReset() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.GetActivePasswordReset() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_InsertPasswordReset(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_InsertPasswordReset] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2023, 12, 1, 0, 10, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		data PasswordReset
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes int64
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: PasswordReset{
+					UserID:    1,
+					CodeHash:  "<hash>",
+					ExpiresAt: expiresAt,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertPasswordReset)).
+					WithArgs(int64(1), "<hash>", expiresAt).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: 0,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: PasswordReset{
+					UserID:    1,
+					CodeHash:  "<hash>",
+					ExpiresAt: expiresAt,
+				},
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id"}).
+					AddRow(2)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertPasswordReset)).
+					WithArgs(int64(1), "<hash>", expiresAt).
+					WillReturnRows(resultRows)
+			},
+			wantRes: 2,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.InsertPasswordReset(tt.args.ctx, tt.args.data)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.InsertPasswordReset() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.InsertPasswordReset() gotRes = %d, wantRes = %d", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_IncreasePasswordResetAttempts(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_IncreasePasswordResetAttempts] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx             context.Context
+		passwordResetID int64
+		maxAttempts     int
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:             context.Background(),
+				passwordResetID: 2,
+				maxAttempts:     5,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryIncreasePasswordResetAttempts)).
+					WithArgs(int64(2), 5).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "too many attempts",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:             context.Background(),
+				passwordResetID: 2,
+				maxAttempts:     5,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryIncreasePasswordResetAttempts)).
+					WithArgs(int64(2), 5).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:             context.Background(),
+				passwordResetID: 2,
+				maxAttempts:     5,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryIncreasePasswordResetAttempts)).
+					WithArgs(int64(2), 5).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.IncreasePasswordResetAttempts(tt.args.ctx, tt.args.passwordResetID, tt.args.maxAttempts)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.IncreasePasswordResetAttempts() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.IncreasePasswordResetAttempts() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_ResetPassword(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_ResetPassword] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	validAfter := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx             context.Context
+		passwordResetID int64
+		data            User
+		validAfter      time.Time
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "begin error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:             context.Background(),
+				passwordResetID: 2,
+				data: User{
+					ID:       1,
+					Password: "<hashed>",
+				},
+				validAfter: validAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "mark used error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:             context.Background(),
+				passwordResetID: 2,
+				data: User{
+					ID:       1,
+					Password: "<hashed>",
+				},
+				validAfter: validAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPasswordResetUsed)).
+					WithArgs(int64(2)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "already used",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:             context.Background(),
+				passwordResetID: 2,
+				data: User{
+					ID:       1,
+					Password: "<hashed>",
+				},
+				validAfter: validAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPasswordResetUsed)).
+					WithArgs(int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+		{
+			name: "update password error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:             context.Background(),
+				passwordResetID: 2,
+				data: User{
+					ID:       1,
+					Password: "<hashed>",
+				},
+				validAfter: validAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPasswordResetUsed)).
+					WithArgs(int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdatePassword)).
+					WithArgs(int64(1), "<hashed>", validAfter).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "revoke refresh tokens error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:             context.Background(),
+				passwordResetID: 2,
+				data: User{
+					ID:       1,
+					Password: "<hashed>",
+				},
+				validAfter: validAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPasswordResetUsed)).
+					WithArgs(int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdatePassword)).
+					WithArgs(int64(1), "<hashed>", validAfter).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "commit error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:             context.Background(),
+				passwordResetID: 2,
+				data: User{
+					ID:       1,
+					Password: "<hashed>",
+				},
+				validAfter: validAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPasswordResetUsed)).
+					WithArgs(int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdatePassword)).
+					WithArgs(int64(1), "<hashed>", validAfter).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				sqlMock.ExpectCommit().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:             context.Background(),
+				passwordResetID: 2,
+				data: User{
+					ID:       1,
+					Password: "<hashed>",
+				},
+				validAfter: validAfter,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPasswordResetUsed)).
+					WithArgs(int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdatePassword)).
+					WithArgs(int64(1), "<hashed>", validAfter).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				sqlMock.ExpectCommit()
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.ResetPassword(tt.args.ctx, tt.args.passwordResetID, tt.args.data, tt.args.validAfter)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.ResetPassword() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.ResetPassword() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_GetActivePhoneVerification(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetActivePhoneVerification] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2023, 12, 1, 0, 10, 0, 0, time.UTC)
+	createdAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx         context.Context
+		phoneNumber string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes PhoneVerification
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				phoneNumber: "+628123456789",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActivePhoneVerification)).
+					WithArgs("+628123456789").
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: PhoneVerification{},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				phoneNumber: "+628123456789",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "phone_number", "code_hash", "attempts", "expires_at", "created_at"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActivePhoneVerification)).
+					WithArgs("+628123456789").
+					WillReturnRows(resultRows)
+			},
+			wantRes: PhoneVerification{},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				phoneNumber: "+628123456789",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "phone_number", "code_hash", "attempts", "expires_at", "created_at"}).
+					AddRow(2, 1, "+628123456789", "<hash>", 3, expiresAt, createdAt)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActivePhoneVerification)).
+					WithArgs("+628123456789").
+					WillReturnRows(resultRows)
+			},
+			wantRes: PhoneVerification{
+				ID:          2,
+				UserID:      1,
+				PhoneNumber: "+628123456789",
+				CodeHash:    "<hash>",
+				Attempts:    3,
+				ExpiresAt:   expiresAt,
+				CreatedAt:   createdAt,
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.GetActivePhoneVerification(tt.args.ctx, tt.args.phoneNumber)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.GetActivePhoneVerification() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.GetActivePhoneVerification() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_InsertPhoneVerification(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_InsertPhoneVerification] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2023, 12, 1, 0, 10, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		data PhoneVerification
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes int64
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: PhoneVerification{
+					UserID:      1,
+					PhoneNumber: "+628123456789",
+					CodeHash:    "<hash>",
+					ExpiresAt:   expiresAt,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertPhoneVerification)).
+					WithArgs(int64(1), "+628123456789", "<hash>", expiresAt).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: 0,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: PhoneVerification{
+					UserID:      1,
+					PhoneNumber: "+628123456789",
+					CodeHash:    "<hash>",
+					ExpiresAt:   expiresAt,
+				},
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id"}).
+					AddRow(2)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertPhoneVerification)).
+					WithArgs(int64(1), "+628123456789", "<hash>", expiresAt).
+					WillReturnRows(resultRows)
+			},
+			wantRes: 2,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.InsertPhoneVerification(tt.args.ctx, tt.args.data)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.InsertPhoneVerification() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.InsertPhoneVerification() gotRes = %d, wantRes = %d", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_IncreasePhoneVerificationAttempts(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_IncreasePhoneVerificationAttempts] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx                 context.Context
+		phoneVerificationID int64
+		maxAttempts         int
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                 context.Background(),
+				phoneVerificationID: 2,
+				maxAttempts:         5,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryIncreasePhoneVerificationAttempts)).
+					WithArgs(int64(2), 5).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "too many attempts",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                 context.Background(),
+				phoneVerificationID: 2,
+				maxAttempts:         5,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryIncreasePhoneVerificationAttempts)).
+					WithArgs(int64(2), 5).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                 context.Background(),
+				phoneVerificationID: 2,
+				maxAttempts:         5,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryIncreasePhoneVerificationAttempts)).
+					WithArgs(int64(2), 5).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.IncreasePhoneVerificationAttempts(tt.args.ctx, tt.args.phoneVerificationID, tt.args.maxAttempts)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.IncreasePhoneVerificationAttempts() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.IncreasePhoneVerificationAttempts() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_ReleasePhoneNumber(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_ReleasePhoneNumber] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx         context.Context
+		phoneNumber string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				phoneNumber: "+62 08123456789",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryReleasePhoneNumber)).
+					WithArgs("+628123456789").
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				phoneNumber: "+62 08123456789",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryReleasePhoneNumber)).
+					WithArgs("+628123456789").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.ReleasePhoneNumber(tt.args.ctx, tt.args.phoneNumber)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.ReleasePhoneNumber() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+// Test_Repository_ReleasePhoneNumber_establishedAccount registers over the
+// phone number of an account an admin changed: it once verified a phone
+// number, so the statements freeing the number must spare it, as they must
+// spare accounts deleted within their grace period.
+func Test_Repository_ReleasePhoneNumber_establishedAccount(t *testing.T) {
+	guards := []string{
+		"deleted_at IS NULL",
+		"verified_at IS NOT NULL",
+	}
+	dbMock, sqlMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(func(expectedSQL, actualSQL string) error {
+		for _, guard := range guards {
+			if strings.HasPrefix(strings.TrimSpace(actualSQL), "DELETE") && !strings.Contains(actualSQL, guard) {
+				return fmt.Errorf("statement lacks %q: %s", guard, actualSQL)
+			}
+		}
+		return sqlmock.QueryMatcherEqual.Match(expectedSQL, actualSQL)
+	})))
+	if err != nil {
+		t.Errorf("[Test_Repository_ReleasePhoneNumber_establishedAccount] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	r := &Repository{
+		Db: dbMock,
+	}
+
+	// the account holding the number is left in place
+	sqlMock.ExpectExec(queryReleasePhoneNumber).
+		WithArgs("+628123456789").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	err = r.ReleasePhoneNumber(context.Background(), "+628123456789")
+	if err != nil {
+		t.Errorf("Repository.ReleasePhoneNumber() gotErr = %s", err.Error())
+	}
+
+	// and the verification of the new holder runs into it
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(queryMarkPhoneVerificationVerified).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec(queryDeleteUnverifiedUsersByPhoneNumber).
+		WithArgs(int64(2), "+628123456789").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec(queryVerifyPhoneNumber).
+		WithArgs(int64(2), "+628123456789").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "user_phone_number_unique"})
+	sqlMock.ExpectRollback()
+	verified, err := r.VerifyPhoneNumber(context.Background(), 1, User{ID: 2, PhoneNumber: "+628123456789"})
+	if verified || !errors.Is(err, ErrConflict) {
+		t.Errorf("Repository.VerifyPhoneNumber() gotRes = %t, gotErr = %v, want ErrConflict", verified, err)
+	}
+
+	err = sqlMock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("[Test_Repository_ReleasePhoneNumber_establishedAccount] %s", err.Error())
+	}
+}
+
+func Test_Repository_VerifyPhoneNumber(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_VerifyPhoneNumber] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx                 context.Context
+		phoneVerificationID int64
+		data                User
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "begin error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                 context.Background(),
+				phoneVerificationID: 2,
+				data: User{
+					ID:          1,
+					PhoneNumber: "+628123456789",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "mark verified error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                 context.Background(),
+				phoneVerificationID: 2,
+				data: User{
+					ID:          1,
+					PhoneNumber: "+628123456789",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPhoneVerificationVerified)).
+					WithArgs(int64(2)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "already verified",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                 context.Background(),
+				phoneVerificationID: 2,
+				data: User{
+					ID:          1,
+					PhoneNumber: "+628123456789",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPhoneVerificationVerified)).
+					WithArgs(int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+		{
+			name: "delete unverified users error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                 context.Background(),
+				phoneVerificationID: 2,
+				data: User{
+					ID:          1,
+					PhoneNumber: "+628123456789",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPhoneVerificationVerified)).
+					WithArgs(int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteUnverifiedUsersByPhoneNumber)).
+					WithArgs(int64(1), "+628123456789").
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "update user error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                 context.Background(),
+				phoneVerificationID: 2,
+				data: User{
+					ID:          1,
+					PhoneNumber: "+628123456789",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPhoneVerificationVerified)).
+					WithArgs(int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteUnverifiedUsersByPhoneNumber)).
+					WithArgs(int64(1), "+628123456789").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryVerifyPhoneNumber)).
+					WithArgs(int64(1), "+628123456789").
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "commit error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                 context.Background(),
+				phoneVerificationID: 2,
+				data: User{
+					ID:          1,
+					PhoneNumber: "+628123456789",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPhoneVerificationVerified)).
+					WithArgs(int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteUnverifiedUsersByPhoneNumber)).
+					WithArgs(int64(1), "+628123456789").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryVerifyPhoneNumber)).
+					WithArgs(int64(1), "+628123456789").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                 context.Background(),
+				phoneVerificationID: 2,
+				data: User{
+					ID:          1,
+					PhoneNumber: "+628123456789",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPhoneVerificationVerified)).
+					WithArgs(int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteUnverifiedUsersByPhoneNumber)).
+					WithArgs(int64(1), "+628123456789").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryVerifyPhoneNumber)).
+					WithArgs(int64(1), "+628123456789").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.VerifyPhoneNumber(tt.args.ctx, tt.args.phoneVerificationID, tt.args.data)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.VerifyPhoneNumber() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.VerifyPhoneNumber() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_GetUserTOTP(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetUserTOTP] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	confirmedAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes UserTOTP
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserTOTP)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: UserTOTP{},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"user_id", "secret", "last_used_step", "confirmed_at"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserTOTP)).
+					WithArgs(int64(1)).
+					WillReturnRows(resultRows)
+			},
+			wantRes: UserTOTP{},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"user_id", "secret", "last_used_step", "confirmed_at"}).
+					AddRow(1, "<secret>", 100, confirmedAt)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserTOTP)).
+					WithArgs(int64(1)).
+					WillReturnRows(resultRows)
+			},
+			wantRes: UserTOTP{
+				UserID:       1,
+				Secret:       "<secret>",
+				LastUsedStep: 100,
+				ConfirmedAt:  &confirmedAt,
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.GetUserTOTP(tt.args.ctx, tt.args.userID)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.GetUserTOTP() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.GetUserTOTP() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_UpsertUserTOTP(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_UpsertUserTOTP] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		data UserTOTP
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: UserTOTP{
+					UserID: 1,
+					Secret: "<secret>",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpsertUserTOTP)).
+					WithArgs(int64(1), "<secret>").
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: UserTOTP{
+					UserID: 1,
+					Secret: "<secret>",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpsertUserTOTP)).
+					WithArgs(int64(1), "<secret>").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.UpsertUserTOTP(tt.args.ctx, tt.args.data)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.UpsertUserTOTP() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_ConfirmUserTOTP(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_ConfirmUserTOTP] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx                context.Context
+		userID             int64
+		step               int64
+		recoveryCodeHashes []string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "begin error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                context.Background(),
+				userID:             1,
+				step:               100,
+				recoveryCodeHashes: []string{"<hash1>", "<hash2>"},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "confirm error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                context.Background(),
+				userID:             1,
+				step:               100,
+				recoveryCodeHashes: []string{"<hash1>", "<hash2>"},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryConfirmUserTOTP)).
+					WithArgs(int64(1), int64(100)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "already confirmed or code reused",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                context.Background(),
+				userID:             1,
+				step:               100,
+				recoveryCodeHashes: []string{"<hash1>", "<hash2>"},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryConfirmUserTOTP)).
+					WithArgs(int64(1), int64(100)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+		{
+			name: "delete recovery codes error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                context.Background(),
+				userID:             1,
+				step:               100,
+				recoveryCodeHashes: []string{"<hash1>", "<hash2>"},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryConfirmUserTOTP)).
+					WithArgs(int64(1), int64(100)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteRecoveryCodes)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "insert recovery code error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                context.Background(),
+				userID:             1,
+				step:               100,
+				recoveryCodeHashes: []string{"<hash1>", "<hash2>"},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryConfirmUserTOTP)).
+					WithArgs(int64(1), int64(100)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteRecoveryCodes)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 10))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertRecoveryCode)).
+					WithArgs(int64(1), "<hash1>").
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "commit error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                context.Background(),
+				userID:             1,
+				step:               100,
+				recoveryCodeHashes: []string{"<hash1>", "<hash2>"},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryConfirmUserTOTP)).
+					WithArgs(int64(1), int64(100)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteRecoveryCodes)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 10))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertRecoveryCode)).
+					WithArgs(int64(1), "<hash1>").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertRecoveryCode)).
+					WithArgs(int64(1), "<hash2>").
+					WillReturnResult(sqlmock.NewResult(2, 1))
+				sqlMock.ExpectCommit().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:                context.Background(),
+				userID:             1,
+				step:               100,
+				recoveryCodeHashes: []string{"<hash1>", "<hash2>"},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryConfirmUserTOTP)).
+					WithArgs(int64(1), int64(100)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteRecoveryCodes)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 10))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertRecoveryCode)).
+					WithArgs(int64(1), "<hash1>").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertRecoveryCode)).
+					WithArgs(int64(1), "<hash2>").
+					WillReturnResult(sqlmock.NewResult(2, 1))
+				sqlMock.ExpectCommit()
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.ConfirmUserTOTP(tt.args.ctx, tt.args.userID, tt.args.step, tt.args.recoveryCodeHashes)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.ConfirmUserTOTP() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.ConfirmUserTOTP() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_UpdateTOTPLastUsedStep(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_UpdateTOTPLastUsedStep] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+		step   int64
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				step:   100,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdateTOTPLastUsedStep)).
+					WithArgs(int64(1), int64(100)).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "code reused",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				step:   100,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdateTOTPLastUsedStep)).
+					WithArgs(int64(1), int64(100)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				step:   100,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdateTOTPLastUsedStep)).
+					WithArgs(int64(1), int64(100)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.UpdateTOTPLastUsedStep(tt.args.ctx, tt.args.userID, tt.args.step)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.UpdateTOTPLastUsedStep() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.UpdateTOTPLastUsedStep() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_DeleteUserTOTP(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_DeleteUserTOTP] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "begin error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "delete totp error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteUserTOTP)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "delete recovery codes error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteUserTOTP)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteRecoveryCodes)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteUserTOTP)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteRecoveryCodes)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 10))
+				sqlMock.ExpectCommit()
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.DeleteUserTOTP(tt.args.ctx, tt.args.userID)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.DeleteUserTOTP() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_UseRecoveryCode(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_UseRecoveryCode] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx      context.Context
+		userID   int64
+		codeHash string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				codeHash: "<hash>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUseRecoveryCode)).
+					WithArgs(int64(1), "<hash>").
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "unknown or used code",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				codeHash: "<hash>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUseRecoveryCode)).
+					WithArgs(int64(1), "<hash>").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				codeHash: "<hash>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUseRecoveryCode)).
+					WithArgs(int64(1), "<hash>").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.UseRecoveryCode(tt.args.ctx, tt.args.userID, tt.args.codeHash)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.UseRecoveryCode() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.UseRecoveryCode() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_InsertAuditEvent(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_InsertAuditEvent] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	actorID := int64(2)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx   context.Context
+		event AuditEvent
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:   context.Background(),
+				event: AuditEvent{UserID: 1, ActorID: &actorID, Action: AuditUserLocked},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertAuditEvent)).
+					WithArgs(int64(1), &actorID, AuditUserLocked).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:   context.Background(),
+				event: AuditEvent{UserID: 1, Action: AuditPasswordChanged},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertAuditEvent)).
+					WithArgs(int64(1), nil, AuditPasswordChanged).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.InsertAuditEvent(tt.args.ctx, tt.args.event)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.InsertAuditEvent() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_GetUserData(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetUserData] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	createdAt := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	actorID := int64(2)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes UserData
+		wantErr error
+	}{
+		{
+			name: "begin error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: UserData{},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "get user error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserData)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: UserData{},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserData)).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "phone_number", "full_name", "phone_verified_at", "login_count", "role", "locked_at", "password_reset_required_at", "deleted_at"}))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: UserData{},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "get refresh tokens error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserData)).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "phone_number", "full_name", "phone_verified_at", "login_count", "role", "locked_at", "password_reset_required_at", "deleted_at"}).
+						AddRow(1, "+628223344556", "Sawit", nil, 0, "", nil, nil, nil))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			wantRes: UserData{
+				User: User{
+					ID:          1,
+					PhoneNumber: "+628223344556",
+					FullName:    "Sawit",
+				},
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserData)).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "phone_number", "full_name", "phone_verified_at", "login_count", "role", "locked_at", "password_reset_required_at", "deleted_at"}).
+						AddRow(1, "+628223344556", "Sawit", createdAt, 3, "support", createdAt, createdAt, createdAt))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetRefreshTokensByUserID)).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "family_id", "expires_at", "created_at", "used_at", "revoked_at"}).
+						AddRow(1, "family", expiresAt, createdAt, createdAt, nil).
+						AddRow(2, "family", expiresAt, createdAt, nil, nil))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetPasswordResetsByUserID)).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "attempts", "expires_at", "created_at", "used_at"}).
+						AddRow(1, 2, expiresAt, createdAt, createdAt))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetPhoneVerificationsByUserID)).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "phone_number", "attempts", "expires_at", "created_at", "verified_at"}).
+						AddRow(1, "+628223344556", 0, expiresAt, createdAt, createdAt))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserTOTPDates)).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"created_at", "confirmed_at"}).
+						AddRow(createdAt, nil))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetLoginAttempt)).
+					WithArgs("user:1").
+					WillReturnRows(sqlmock.NewRows([]string{"attempt_key", "failed_count", "last_failed_at", "blocked_until"}).
+						AddRow("user:1", 2, createdAt, nil))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetAuditEventsByUserID)).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "actor_id", "action", "created_at"}).
+						AddRow(1, 2, AuditUserLocked, createdAt).
+						AddRow(2, nil, AuditPasswordChanged, createdAt))
+				sqlMock.ExpectCommit()
+			},
+			wantRes: UserData{
+				User: User{
+					ID:                      1,
+					PhoneNumber:             "+628223344556",
+					FullName:                "Sawit",
+					PhoneVerifiedAt:         &createdAt,
+					Role:                    "support",
+					LockedAt:                &createdAt,
+					PasswordResetRequiredAt: &createdAt,
+					DeletedAt:               &createdAt,
+				},
+				LoginCount: 3,
+				RefreshTokens: []RefreshToken{
+					{ID: 1, UserID: 1, FamilyID: "family", ExpiresAt: expiresAt, CreatedAt: createdAt, UsedAt: &createdAt},
+					{ID: 2, UserID: 1, FamilyID: "family", ExpiresAt: expiresAt, CreatedAt: createdAt},
+				},
+				PasswordResets: []PasswordReset{
+					{ID: 1, UserID: 1, Attempts: 2, ExpiresAt: expiresAt, CreatedAt: createdAt, UsedAt: &createdAt},
+				},
+				PhoneVerifications: []PhoneVerification{
+					{ID: 1, UserID: 1, PhoneNumber: "+628223344556", ExpiresAt: expiresAt, CreatedAt: createdAt, VerifiedAt: &createdAt},
+				},
+				UserTOTP: &UserTOTP{
+					UserID:    1,
+					CreatedAt: createdAt,
+				},
+				LoginAttempt: &LoginAttempt{
+					Key:          "user:1",
+					FailedCount:  2,
+					LastFailedAt: createdAt,
+				},
+				AuditEvents: []AuditEvent{
+					{ID: 1, UserID: 1, ActorID: &actorID, Action: AuditUserLocked, CreatedAt: createdAt},
+					{ID: 2, UserID: 1, Action: AuditPasswordChanged, CreatedAt: createdAt},
+				},
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.GetUserData(tt.args.ctx, tt.args.userID)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.GetUserData() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.GetUserData() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_InsertDataExport(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_InsertDataExport] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		data DataExport
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes int64
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: DataExport{
+					UserID:    1,
+					Format:    "json",
+					TokenHash: "<token_hash>",
+					ExpiresAt: expiresAt,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertDataExport)).
+					WithArgs(int64(1), "json", "<token_hash>", expiresAt).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: 0,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: DataExport{
+					UserID:    1,
+					Format:    "json",
+					TokenHash: "<token_hash>",
+					ExpiresAt: expiresAt,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertDataExport)).
+					WithArgs(int64(1), "json", "<token_hash>", expiresAt).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			},
+			wantRes: 1,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.InsertDataExport(tt.args.ctx, tt.args.data)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.InsertDataExport() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.InsertDataExport() gotRes = %d, wantRes = %d", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_GetDataExport(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetDataExport] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	createdAt := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2023, 11, 2, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx          context.Context
+		dataExportID int64
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes DataExport
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				dataExportID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetDataExport)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: DataExport{},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				dataExportID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetDataExport)).
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "format", "status", "token_hash", "archive", "expires_at", "created_at", "started_at", "completed_at"}))
+			},
+			wantRes: DataExport{},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				dataExportID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "format", "status", "token_hash", "archive", "expires_at", "created_at", "started_at", "completed_at"}).
+					AddRow(1, 1, "json", "ready", "<token_hash>", []byte("{}"), expiresAt, createdAt, createdAt, createdAt)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetDataExport)).
+					WithArgs(int64(1)).
+					WillReturnRows(resultRows)
+			},
+			wantRes: DataExport{
+				ID:          1,
+				UserID:      1,
+				Format:      "json",
+				Status:      DataExportReady,
+				TokenHash:   "<token_hash>",
+				Archive:     []byte("{}"),
+				ExpiresAt:   expiresAt,
+				CreatedAt:   createdAt,
+				StartedAt:   &createdAt,
+				CompletedAt: &createdAt,
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.GetDataExport(tt.args.ctx, tt.args.dataExportID)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.GetDataExport() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.GetDataExport() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_ClaimDataExport(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_ClaimDataExport] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	createdAt := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2023, 11, 2, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2023, 11, 1, 0, 1, 0, 0, time.UTC)
+	staleBefore := time.Date(2023, 11, 1, 0, 0, 30, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx         context.Context
+		now         time.Time
+		staleBefore time.Time
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes DataExport
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				now:         now,
+				staleBefore: staleBefore,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryClaimDataExport)).
+					WithArgs(now, staleBefore).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: DataExport{},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				now:         now,
+				staleBefore: staleBefore,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryClaimDataExport)).
+					WithArgs(now, staleBefore).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "format", "status", "token_hash", "expires_at", "created_at", "started_at"}))
+			},
+			wantRes: DataExport{},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				now:         now,
+				staleBefore: staleBefore,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "format", "status", "token_hash", "expires_at", "created_at", "started_at"}).
+					AddRow(1, 1, "csv", "processing", "<token_hash>", expiresAt, createdAt, now)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryClaimDataExport)).
+					WithArgs(now, staleBefore).
+					WillReturnRows(resultRows)
+			},
+			wantRes: DataExport{
+				ID:        1,
+				UserID:    1,
+				Format:    "csv",
+				Status:    DataExportProcessing,
+				TokenHash: "<token_hash>",
+				ExpiresAt: expiresAt,
+				CreatedAt: createdAt,
+				StartedAt: &now,
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.ClaimDataExport(tt.args.ctx, tt.args.now, tt.args.staleBefore)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.ClaimDataExport() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.ClaimDataExport() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_FinishDataExport(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_FinishDataExport] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	startedAt := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	completedAt := time.Date(2023, 11, 1, 0, 0, 1, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		data DataExport
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes bool
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: DataExport{
+					ID:          1,
+					Status:      DataExportReady,
+					Archive:     []byte("{}"),
+					StartedAt:   &startedAt,
+					CompletedAt: &completedAt,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryFinishDataExport)).
+					WithArgs(int64(1), &startedAt, DataExportReady, []byte("{}"), &completedAt).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: false,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "claimed again",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: DataExport{
+					ID:          1,
+					Status:      DataExportReady,
+					Archive:     []byte("{}"),
+					StartedAt:   &startedAt,
+					CompletedAt: &completedAt,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryFinishDataExport)).
+					WithArgs(int64(1), &startedAt, DataExportReady, []byte("{}"), &completedAt).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantRes: false,
+			wantErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: DataExport{
+					ID:          1,
+					Status:      DataExportReady,
+					Archive:     []byte("{}"),
+					StartedAt:   &startedAt,
+					CompletedAt: &completedAt,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryFinishDataExport)).
+					WithArgs(int64(1), &startedAt, DataExportReady, []byte("{}"), &completedAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantRes: true,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.FinishDataExport(tt.args.ctx, tt.args.data)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.FinishDataExport() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.FinishDataExport() gotRes = %t, wantRes = %t", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_DeleteExpiredDataExports(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_DeleteExpiredDataExports] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiredBefore := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx           context.Context
+		expiredBefore time.Time
+		limit         int
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes int64
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				expiredBefore: expiredBefore,
+				limit:         100,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteExpiredDataExports)).
+					WithArgs(expiredBefore, 100).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: 0,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				expiredBefore: expiredBefore,
+				limit:         100,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteExpiredDataExports)).
+					WithArgs(expiredBefore, 100).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+			wantRes: 2,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.DeleteExpiredDataExports(tt.args.ctx, tt.args.expiredBefore, tt.args.limit)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.DeleteExpiredDataExports() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.DeleteExpiredDataExports() gotRes = %d, wantRes = %d", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_GetLoginAttempt(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetLoginAttempt] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx context.Context
+		key string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes LoginAttempt
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				key: "user:1",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetLoginAttempt)).
+					WithArgs("user:1").
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: LoginAttempt{},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "not found",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				key: "user:1",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetLoginAttempt)).
+					WithArgs("user:1").
+					WillReturnRows(sqlmock.
+						NewRows([]string{"attempt_key", "failed_count", "last_failed_at", "blocked_until"}))
+			},
+			wantRes: LoginAttempt{},
+			wantErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				key: "user:1",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetLoginAttempt)).
+					WithArgs("user:1").
+					WillReturnRows(sqlmock.
+						NewRows([]string{"attempt_key", "failed_count", "last_failed_at", "blocked_until"}).
+						AddRow("user:1", 3, now, now))
+			},
+			wantRes: LoginAttempt{
+				Key:          "user:1",
+				FailedCount:  3,
+				LastFailedAt: now,
+				BlockedUntil: &now,
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.GetLoginAttempt(tt.args.ctx, tt.args.key)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.GetLoginAttempt() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("Repository.GetLoginAttempt() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_IncreaseFailedLoginCount(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_IncreaseFailedLoginCount] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx         context.Context
+		key         string
+		windowStart time.Time
+		now         time.Time
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantRes int
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				key:         "ip:10.0.0.1",
+				windowStart: now.Add(-time.Minute),
+				now:         now,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryIncreaseFailedLoginCount)).
+					WithArgs("ip:10.0.0.1", now.Add(-time.Minute), now).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantRes: 0,
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				key:         "ip:10.0.0.1",
+				windowStart: now.Add(-time.Minute),
+				now:         now,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryIncreaseFailedLoginCount)).
+					WithArgs("ip:10.0.0.1", now.Add(-time.Minute), now).
+					WillReturnRows(sqlmock.
+						NewRows([]string{"failed_count"}).
+						AddRow(4))
+			},
+			wantRes: 4,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotRes, gotErr := r.IncreaseFailedLoginCount(tt.args.ctx, tt.args.key, tt.args.windowStart, tt.args.now)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.IncreaseFailedLoginCount() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("Repository.IncreaseFailedLoginCount() gotRes = %d, wantRes = %d", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_BlockLogin(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_BlockLogin] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx          context.Context
+		key          string
+		blockedUntil time.Time
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				key:          "user:1",
+				blockedUntil: now,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryBlockLogin)).
+					WithArgs("user:1", now).
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				key:          "user:1",
+				blockedUntil: now,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryBlockLogin)).
+					WithArgs("user:1", now).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.BlockLogin(tt.args.ctx, tt.args.key, tt.args.blockedUntil)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.BlockLogin() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_ResetLoginAttempts(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_ResetLoginAttempts] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx context.Context
+		key string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				key: "user:1",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryResetLoginAttempts)).
+					WithArgs("user:1").
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				key: "user:1",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryResetLoginAttempts)).
+					WithArgs("user:1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.ResetLoginAttempts(tt.args.ctx, tt.args.key)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.ResetLoginAttempts() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fenky-ng/swt-pro/metrics"
+	"github.com/fenky-ng/swt-pro/tracing"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentedRepository wraps a RepositoryInterface to observe the latency
+// and count the errors of every call, by method, and to trace every call in
+// a span named by its method.
+type InstrumentedRepository struct {
+	next     RepositoryInterface
+	duration *metrics.Histogram
+	errors   *metrics.Counter
+}
+
+var _ RepositoryInterface = (*InstrumentedRepository)(nil)
+
+func NewInstrumentedRepository(next RepositoryInterface, registry *metrics.Registry) *InstrumentedRepository {
+	return &InstrumentedRepository{
+		next: next,
+		duration: registry.NewHistogram(
+			"repository_call_duration_seconds",
+			"Latency of repository calls, by method.",
+			metrics.DefaultBuckets,
+			"method",
+		),
+		errors: registry.NewCounter(
+			"repository_call_errors_total",
+			"Number of repository calls that returned an error, by method.",
+			"method",
+		),
+	}
+}
+
+// start begins the span of a call. The returned func ends it and observes
+// the call once it returned err.
+func (r *InstrumentedRepository) start(ctx context.Context, method string) (context.Context, func(err *error)) {
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, "repository."+method, trace.WithAttributes(
+		semconv.DBOperation(method),
+	))
+	return ctx, func(err *error) {
+		r.duration.Observe(time.Since(start).Seconds(), method)
+		// nothing matching is an answer rather than a failure
+		if *err != nil && !errors.Is(*err, ErrNotFound) {
+			r.errors.Inc(method)
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, method+" failed")
+		}
+		span.End()
+	}
+}
+
+// health
+
+func (r *InstrumentedRepository) Ping(ctx context.Context) (err error) {
+	ctx, end := r.start(ctx, "Ping")
+	defer end(&err)
+	return r.next.Ping(ctx)
+}
+
+// user
+
+func (r *InstrumentedRepository) GetUserByID(ctx context.Context, userID int64) (user User, err error) {
+	ctx, end := r.start(ctx, "GetUserByID")
+	defer end(&err)
+	return r.next.GetUserByID(ctx, userID)
+}
+
+func (r *InstrumentedRepository) GetUserByPhoneNumber(ctx context.Context, phoneNumber string) (user User, err error) {
+	ctx, end := r.start(ctx, "GetUserByPhoneNumber")
+	defer end(&err)
+	return r.next.GetUserByPhoneNumber(ctx, phoneNumber)
+}
+
+func (r *InstrumentedRepository) IncreaseLoginCount(ctx context.Context, userID int64) (err error) {
+	ctx, end := r.start(ctx, "IncreaseLoginCount")
+	defer end(&err)
+	return r.next.IncreaseLoginCount(ctx, userID)
+}
+
+func (r *InstrumentedRepository) InsertUser(ctx context.Context, data User) (userID int64, err error) {
+	ctx, end := r.start(ctx, "InsertUser")
+	defer end(&err)
+	return r.next.InsertUser(ctx, data)
+}
+
+func (r *InstrumentedRepository) UpdateUser(ctx context.Context, data User) (err error) {
+	ctx, end := r.start(ctx, "UpdateUser")
+	defer end(&err)
+	return r.next.UpdateUser(ctx, data)
+}
+
+func (r *InstrumentedRepository) GetTokensValidAfter(ctx context.Context, userID int64) (validAfter time.Time, err error) {
+	ctx, end := r.start(ctx, "GetTokensValidAfter")
+	defer end(&err)
+	return r.next.GetTokensValidAfter(ctx, userID)
+}
+
+func (r *InstrumentedRepository) UpdateTokensValidAfter(ctx context.Context, userID int64, validAfter time.Time) (err error) {
+	ctx, end := r.start(ctx, "UpdateTokensValidAfter")
+	defer end(&err)
+	return r.next.UpdateTokensValidAfter(ctx, userID, validAfter)
+}
+
+func (r *InstrumentedRepository) UpdatePassword(ctx context.Context, data User, validAfter time.Time, keepFamilyID string) (err error) {
+	ctx, end := r.start(ctx, "UpdatePassword")
+	defer end(&err)
+	return r.next.UpdatePassword(ctx, data, validAfter, keepFamilyID)
+}
+
+// deleted user
+
+func (r *InstrumentedRepository) GetDeletedUserByPhoneNumber(ctx context.Context, phoneNumber string, deletedAfter time.Time) (user User, err error) {
+	ctx, end := r.start(ctx, "GetDeletedUserByPhoneNumber")
+	defer end(&err)
+	return r.next.GetDeletedUserByPhoneNumber(ctx, phoneNumber, deletedAfter)
+}
+
+func (r *InstrumentedRepository) DeleteUser(ctx context.Context, userID int64, deletedAt time.Time) (err error) {
+	ctx, end := r.start(ctx, "DeleteUser")
+	defer end(&err)
+	return r.next.DeleteUser(ctx, userID, deletedAt)
+}
+
+func (r *InstrumentedRepository) RestoreUser(ctx context.Context, userID int64, deletedAfter time.Time) (restored bool, err error) {
+	ctx, end := r.start(ctx, "RestoreUser")
+	defer end(&err)
+	return r.next.RestoreUser(ctx, userID, deletedAfter)
+}
+
+func (r *InstrumentedRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, limit int) (purged int64, err error) {
+	ctx, end := r.start(ctx, "PurgeDeletedUsers")
+	defer end(&err)
+	return r.next.PurgeDeletedUsers(ctx, deletedBefore, limit)
+}
+
+func (r *InstrumentedRepository) PurgeUnverifiedUsers(ctx context.Context, createdBefore time.Time, limit int) (purged int64, err error) {
+	ctx, end := r.start(ctx, "PurgeUnverifiedUsers")
+	defer end(&err)
+	return r.next.PurgeUnverifiedUsers(ctx, createdBefore, limit)
+}
+
+// refresh token
+
+func (r *InstrumentedRepository) ListUsers(ctx context.Context, filter UserFilter) (users []User, total int64, err error) {
+	ctx, end := r.start(ctx, "ListUsers")
+	defer end(&err)
+	return r.next.ListUsers(ctx, filter)
+}
+
+func (r *InstrumentedRepository) SearchUsers(ctx context.Context, search UserSearch) (users []User, nextCursor string, err error) {
+	ctx, end := r.start(ctx, "SearchUsers")
+	defer end(&err)
+	return r.next.SearchUsers(ctx, search)
+}
+
+func (r *InstrumentedRepository) LockUser(ctx context.Context, userID int64, lockedAt time.Time) (err error) {
+	ctx, end := r.start(ctx, "LockUser")
+	defer end(&err)
+	return r.next.LockUser(ctx, userID, lockedAt)
+}
+
+func (r *InstrumentedRepository) UnlockUser(ctx context.Context, userID int64) (err error) {
+	ctx, end := r.start(ctx, "UnlockUser")
+	defer end(&err)
+	return r.next.UnlockUser(ctx, userID)
+}
+
+func (r *InstrumentedRepository) RequirePasswordReset(ctx context.Context, userID int64, requiredAt time.Time) (err error) {
+	ctx, end := r.start(ctx, "RequirePasswordReset")
+	defer end(&err)
+	return r.next.RequirePasswordReset(ctx, userID, requiredAt)
+}
+
+func (r *InstrumentedRepository) GetRolePermissions(ctx context.Context, role string) (permissions []string, err error) {
+	ctx, end := r.start(ctx, "GetRolePermissions")
+	defer end(&err)
+	return r.next.GetRolePermissions(ctx, role)
+}
+
+func (r *InstrumentedRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (refreshToken RefreshToken, err error) {
+	ctx, end := r.start(ctx, "GetRefreshTokenByHash")
+	defer end(&err)
+	return r.next.GetRefreshTokenByHash(ctx, tokenHash)
+}
+
+func (r *InstrumentedRepository) InsertRefreshToken(ctx context.Context, data RefreshToken) (refreshTokenID int64, err error) {
+	ctx, end := r.start(ctx, "InsertRefreshToken")
+	defer end(&err)
+	return r.next.InsertRefreshToken(ctx, data)
+}
+
+func (r *InstrumentedRepository) MarkRefreshTokenUsed(ctx context.Context, refreshTokenID int64) (marked bool, err error) {
+	ctx, end := r.start(ctx, "MarkRefreshTokenUsed")
+	defer end(&err)
+	return r.next.MarkRefreshTokenUsed(ctx, refreshTokenID)
+}
+
+func (r *InstrumentedRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) (err error) {
+	ctx, end := r.start(ctx, "RevokeRefreshTokenFamily")
+	defer end(&err)
+	return r.next.RevokeRefreshTokenFamily(ctx, familyID)
+}
+
+func (r *InstrumentedRepository) RevokeRefreshTokensByUserID(ctx context.Context, userID int64) (err error) {
+	ctx, end := r.start(ctx, "RevokeRefreshTokensByUserID")
+	defer end(&err)
+	return r.next.RevokeRefreshTokensByUserID(ctx, userID)
+}
+
+// revoked token
+
+func (r *InstrumentedRepository) InsertRevokedToken(ctx context.Context, data RevokedToken) (err error) {
+	ctx, end := r.start(ctx, "InsertRevokedToken")
+	defer end(&err)
+	return r.next.InsertRevokedToken(ctx, data)
+}
+
+func (r *InstrumentedRepository) IsTokenRevoked(ctx context.Context, jti string) (revoked bool, err error) {
+	ctx, end := r.start(ctx, "IsTokenRevoked")
+	defer end(&err)
+	return r.next.IsTokenRevoked(ctx, jti)
+}
+
+// password reset
+
+func (r *InstrumentedRepository) GetActivePasswordReset(ctx context.Context, userID int64) (passwordReset PasswordReset, err error) {
+	ctx, end := r.start(ctx, "GetActivePasswordReset")
+	defer end(&err)
+	return r.next.GetActivePasswordReset(ctx, userID)
+}
+
+func (r *InstrumentedRepository) InsertPasswordReset(ctx context.Context, data PasswordReset) (passwordResetID int64, err error) {
+	ctx, end := r.start(ctx, "InsertPasswordReset")
+	defer end(&err)
+	return r.next.InsertPasswordReset(ctx, data)
+}
+
+func (r *InstrumentedRepository) IncreasePasswordResetAttempts(ctx context.Context, passwordResetID int64, maxAttempts int) (increased bool, err error) {
+	ctx, end := r.start(ctx, "IncreasePasswordResetAttempts")
+	defer end(&err)
+	return r.next.IncreasePasswordResetAttempts(ctx, passwordResetID, maxAttempts)
+}
+
+func (r *InstrumentedRepository) ResetPassword(ctx context.Context, passwordResetID int64, data User, validAfter time.Time) (reset bool, err error) {
+	ctx, end := r.start(ctx, "ResetPassword")
+	defer end(&err)
+	return r.next.ResetPassword(ctx, passwordResetID, data, validAfter)
+}
+
+// phone verification
+
+func (r *InstrumentedRepository) GetActivePhoneVerification(ctx context.Context, phoneNumber string) (phoneVerification PhoneVerification, err error) {
+	ctx, end := r.start(ctx, "GetActivePhoneVerification")
+	defer end(&err)
+	return r.next.GetActivePhoneVerification(ctx, phoneNumber)
+}
+
+func (r *InstrumentedRepository) InsertPhoneVerification(ctx context.Context, data PhoneVerification) (phoneVerificationID int64, err error) {
+	ctx, end := r.start(ctx, "InsertPhoneVerification")
+	defer end(&err)
+	return r.next.InsertPhoneVerification(ctx, data)
+}
+
+func (r *InstrumentedRepository) IncreasePhoneVerificationAttempts(ctx context.Context, phoneVerificationID int64, maxAttempts int) (increased bool, err error) {
+	ctx, end := r.start(ctx, "IncreasePhoneVerificationAttempts")
+	defer end(&err)
+	return r.next.IncreasePhoneVerificationAttempts(ctx, phoneVerificationID, maxAttempts)
+}
+
+func (r *InstrumentedRepository) ReleasePhoneNumber(ctx context.Context, phoneNumber string) (err error) {
+	ctx, end := r.start(ctx, "ReleasePhoneNumber")
+	defer end(&err)
+	return r.next.ReleasePhoneNumber(ctx, phoneNumber)
+}
+
+func (r *InstrumentedRepository) VerifyPhoneNumber(ctx context.Context, phoneVerificationID int64, data User) (verified bool, err error) {
+	ctx, end := r.start(ctx, "VerifyPhoneNumber")
+	defer end(&err)
+	return r.next.VerifyPhoneNumber(ctx, phoneVerificationID, data)
+}
+
+// totp
+
+func (r *InstrumentedRepository) GetUserTOTP(ctx context.Context, userID int64) (userTOTP UserTOTP, err error) {
+	ctx, end := r.start(ctx, "GetUserTOTP")
+	defer end(&err)
+	return r.next.GetUserTOTP(ctx, userID)
+}
+
+func (r *InstrumentedRepository) UpsertUserTOTP(ctx context.Context, data UserTOTP) (err error) {
+	ctx, end := r.start(ctx, "UpsertUserTOTP")
+	defer end(&err)
+	return r.next.UpsertUserTOTP(ctx, data)
+}
+
+func (r *InstrumentedRepository) ConfirmUserTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) (confirmed bool, err error) {
+	ctx, end := r.start(ctx, "ConfirmUserTOTP")
+	defer end(&err)
+	return r.next.ConfirmUserTOTP(ctx, userID, step, recoveryCodeHashes)
+}
+
+func (r *InstrumentedRepository) UpdateTOTPLastUsedStep(ctx context.Context, userID int64, step int64) (updated bool, err error) {
+	ctx, end := r.start(ctx, "UpdateTOTPLastUsedStep")
+	defer end(&err)
+	return r.next.UpdateTOTPLastUsedStep(ctx, userID, step)
+}
+
+func (r *InstrumentedRepository) DeleteUserTOTP(ctx context.Context, userID int64) (err error) {
+	ctx, end := r.start(ctx, "DeleteUserTOTP")
+	defer end(&err)
+	return r.next.DeleteUserTOTP(ctx, userID)
+}
+
+func (r *InstrumentedRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (used bool, err error) {
+	ctx, end := r.start(ctx, "UseRecoveryCode")
+	defer end(&err)
+	return r.next.UseRecoveryCode(ctx, userID, codeHash)
+}
+
+// audit
+
+func (r *InstrumentedRepository) InsertAuditEvent(ctx context.Context, event AuditEvent) (err error) {
+	ctx, end := r.start(ctx, "InsertAuditEvent")
+	defer end(&err)
+	return r.next.InsertAuditEvent(ctx, event)
+}
+
+// data export
+
+func (r *InstrumentedRepository) GetUserData(ctx context.Context, userID int64) (userData UserData, err error) {
+	ctx, end := r.start(ctx, "GetUserData")
+	defer end(&err)
+	return r.next.GetUserData(ctx, userID)
+}
+
+func (r *InstrumentedRepository) InsertDataExport(ctx context.Context, data DataExport) (dataExportID int64, err error) {
+	ctx, end := r.start(ctx, "InsertDataExport")
+	defer end(&err)
+	return r.next.InsertDataExport(ctx, data)
+}
+
+func (r *InstrumentedRepository) GetDataExport(ctx context.Context, dataExportID int64) (dataExport DataExport, err error) {
+	ctx, end := r.start(ctx, "GetDataExport")
+	defer end(&err)
+	return r.next.GetDataExport(ctx, dataExportID)
+}
+
+func (r *InstrumentedRepository) ClaimDataExport(ctx context.Context, now time.Time, staleBefore time.Time) (dataExport DataExport, err error) {
+	ctx, end := r.start(ctx, "ClaimDataExport")
+	defer end(&err)
+	return r.next.ClaimDataExport(ctx, now, staleBefore)
+}
+
+func (r *InstrumentedRepository) FinishDataExport(ctx context.Context, data DataExport) (finished bool, err error) {
+	ctx, end := r.start(ctx, "FinishDataExport")
+	defer end(&err)
+	return r.next.FinishDataExport(ctx, data)
+}
+
+func (r *InstrumentedRepository) DeleteExpiredDataExports(ctx context.Context, expiredBefore time.Time, limit int) (deleted int64, err error) {
+	ctx, end := r.start(ctx, "DeleteExpiredDataExports")
+	defer end(&err)
+	return r.next.DeleteExpiredDataExports(ctx, expiredBefore, limit)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fenky-ng/swt-pro/metrics"
+	"github.com/fenky-ng/swt-pro/tracing"
+	"github.com/golang/mock/gomock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_InstrumentedRepository(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	next := NewMockRepositoryInterface(mockCtrl)
+	registry := metrics.NewRegistry()
+	r := NewInstrumentedRepository(next, registry)
+
+	exporter := tracetest.NewInMemoryExporter()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(tracing.NewProvider(tracing.ProviderOptions{
+		Exporter:    exporter,
+		Sync:        true,
+		ServiceName: "test",
+		SampleRatio: 1,
+	}))
+
+	next.EXPECT().GetUserByID(gomock.Any(), int64(1)).
+		Return(User{ID: 1}, nil)
+	next.EXPECT().GetUserByID(gomock.Any(), int64(2)).
+		Return(User{}, errors.New("expected error"))
+	next.EXPECT().GetUserByID(gomock.Any(), int64(3)).
+		Return(User{}, ErrNotFound)
+
+	user, err := r.GetUserByID(context.Background(), 1)
+	if err != nil || user.ID != 1 {
+		t.Errorf("InstrumentedRepository.GetUserByID() gotRes = %+v, gotErr = %v", user, err)
+	}
+	_, err = r.GetUserByID(context.Background(), 2)
+	if err == nil || err.Error() != "expected error" {
+		t.Errorf("InstrumentedRepository.GetUserByID() gotErr = %v, wantErr = expected error", err)
+	}
+	_, err = r.GetUserByID(context.Background(), 3)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("InstrumentedRepository.GetUserByID() gotErr = %v, wantErr = %v", err, ErrNotFound)
+	}
+
+	var buf bytes.Buffer
+	err = registry.Write(&buf)
+	if err != nil {
+		t.Fatalf("Registry.Write() gotErr = %s", err.Error())
+	}
+	for _, want := range []string{
+		`repository_call_duration_seconds_count{method="GetUserByID"} 3`,
+		`repository_call_errors_total{method="GetUserByID"} 1`,
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("InstrumentedRepository output lacks %s, got =\n%s", want, buf.String())
+		}
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("InstrumentedRepository got %d spans, want 3", len(spans))
+	}
+	for i, wantCode := range []codes.Code{codes.Unset, codes.Error, codes.Unset} {
+		if spans[i].Name != "repository.GetUserByID" || spans[i].Status.Code != wantCode {
+			t.Errorf("InstrumentedRepository span = %s with status %s, want repository.GetUserByID with status %s", spans[i].Name, spans[i].Status.Code, wantCode)
+		}
+	}
+}
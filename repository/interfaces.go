@@ -4,14 +4,137 @@
 // interfaces using mockgen. See the Makefile for more information.
 package repository
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 //go:generate mockgen -source=interfaces.go -destination=interfaces.mock.gen.go -package=repository
 type RepositoryInterface interface {
+	// health
+	Ping(ctx context.Context) (err error)
+
 	// user
 	GetUserByID(ctx context.Context, userID int64) (user User, err error)
+	// GetUserByPhoneNumber looks phoneNumber up in its E.164 form, see
+	// phone.Normalize.
 	GetUserByPhoneNumber(ctx context.Context, phoneNumber string) (user User, err error)
 	IncreaseLoginCount(ctx context.Context, userID int64) (err error)
+	// InsertUser and UpdateUser return a *UniqueViolationError when the
+	// phone number belongs to another user.
 	InsertUser(ctx context.Context, data User) (userID int64, err error)
 	UpdateUser(ctx context.Context, data User) (err error)
+	GetTokensValidAfter(ctx context.Context, userID int64) (validAfter time.Time, err error)
+	UpdateTokensValidAfter(ctx context.Context, userID int64, validAfter time.Time) (err error)
+	UpdatePassword(ctx context.Context, data User, validAfter time.Time, keepFamilyID string) (err error)
+
+	// deleted user
+	// GetUserByID and GetUserByPhoneNumber leave deleted users out. Until
+	// they are purged, deleted users keep their phone number.
+	// GetDeletedUserByPhoneNumber returns the user deleted after
+	// deletedAfter, ErrNotFound for any other.
+	GetDeletedUserByPhoneNumber(ctx context.Context, phoneNumber string, deletedAfter time.Time) (user User, err error)
+	// DeleteUser marks the user deleted at deletedAt, invalidating its
+	// access tokens and revoking its refresh tokens.
+	DeleteUser(ctx context.Context, userID int64, deletedAt time.Time) (err error)
+	RestoreUser(ctx context.Context, userID int64, deletedAfter time.Time) (restored bool, err error)
+	// PurgeDeletedUsers removes up to limit users deleted at or before
+	// deletedBefore, along with everything that belongs to them.
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, limit int) (purged int64, err error)
+	// PurgeUnverifiedUsers removes up to limit users created at or before
+	// createdBefore that never verified a phone number.
+	PurgeUnverifiedUsers(ctx context.Context, createdBefore time.Time, limit int) (purged int64, err error)
+
+	// user administration
+	// ListUsers returns a page of the users matching filter, ordered by id,
+	// along with how many match in total. Passwords are left empty.
+	ListUsers(ctx context.Context, filter UserFilter) (users []User, total int64, err error)
+	// SearchUsers returns up to search.Limit users matching search, with the
+	// cursor of the next page, empty on the last one. Passwords are left
+	// empty.
+	SearchUsers(ctx context.Context, search UserSearch) (users []User, nextCursor string, err error)
+	// LockUser and RequirePasswordReset invalidate the access tokens of the
+	// user and revoke its refresh tokens.
+	LockUser(ctx context.Context, userID int64, lockedAt time.Time) (err error)
+	UnlockUser(ctx context.Context, userID int64) (err error)
+	RequirePasswordReset(ctx context.Context, userID int64, requiredAt time.Time) (err error)
+	// GetRolePermissions returns no permissions for an unknown role.
+	GetRolePermissions(ctx context.Context, role string) (permissions []string, err error)
+
+	// refresh token
+	// GetRefreshTokenByHash returns ErrNotFound for an unknown token.
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (refreshToken RefreshToken, err error)
+	InsertRefreshToken(ctx context.Context, data RefreshToken) (refreshTokenID int64, err error)
+	MarkRefreshTokenUsed(ctx context.Context, refreshTokenID int64) (marked bool, err error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) (err error)
+	RevokeRefreshTokensByUserID(ctx context.Context, userID int64) (err error)
+
+	// revoked token
+	InsertRevokedToken(ctx context.Context, data RevokedToken) (err error)
+	IsTokenRevoked(ctx context.Context, jti string) (revoked bool, err error)
+
+	// password reset
+	// GetActivePasswordReset returns ErrNotFound when the user has no
+	// active password reset.
+	GetActivePasswordReset(ctx context.Context, userID int64) (passwordReset PasswordReset, err error)
+	InsertPasswordReset(ctx context.Context, data PasswordReset) (passwordResetID int64, err error)
+	IncreasePasswordResetAttempts(ctx context.Context, passwordResetID int64, maxAttempts int) (increased bool, err error)
+	ResetPassword(ctx context.Context, passwordResetID int64, data User, validAfter time.Time) (reset bool, err error)
+
+	// phone verification
+	// GetActivePhoneVerification looks phoneNumber up in its E.164 form
+	// and returns ErrNotFound when it has no active verification.
+	GetActivePhoneVerification(ctx context.Context, phoneNumber string) (phoneVerification PhoneVerification, err error)
+	InsertPhoneVerification(ctx context.Context, data PhoneVerification) (phoneVerificationID int64, err error)
+	IncreasePhoneVerificationAttempts(ctx context.Context, phoneVerificationID int64, maxAttempts int) (increased bool, err error)
+	// ReleasePhoneNumber removes the user holding phoneNumber without ever
+	// verifying a phone number once none of its codes can be redeemed
+	// anymore, so the phone number can be registered again. Deleted users
+	// are kept.
+	ReleasePhoneNumber(ctx context.Context, phoneNumber string) (err error)
+	// VerifyPhoneNumber removes the other users holding the phone number that
+	// never verified one and are not deleted. It returns a
+	// *UniqueViolationError when the phone number is held by another user.
+	VerifyPhoneNumber(ctx context.Context, phoneVerificationID int64, data User) (verified bool, err error)
+
+	// totp
+	// GetUserTOTP returns ErrNotFound when the user never enrolled.
+	GetUserTOTP(ctx context.Context, userID int64) (userTOTP UserTOTP, err error)
+	UpsertUserTOTP(ctx context.Context, data UserTOTP) (err error)
+	ConfirmUserTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) (confirmed bool, err error)
+	UpdateTOTPLastUsedStep(ctx context.Context, userID int64, step int64) (updated bool, err error)
+	DeleteUserTOTP(ctx context.Context, userID int64) (err error)
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (used bool, err error)
+
+	// audit
+	InsertAuditEvent(ctx context.Context, event AuditEvent) (err error)
+
+	// data export
+	// GetUserData reads everything held about the user at once, including
+	// users deleted within their grace period, ErrNotFound once purged.
+	GetUserData(ctx context.Context, userID int64) (userData UserData, err error)
+	InsertDataExport(ctx context.Context, data DataExport) (dataExportID int64, err error)
+	// GetDataExport returns ErrNotFound for the exports of deleted users.
+	GetDataExport(ctx context.Context, dataExportID int64) (dataExport DataExport, err error)
+	// ClaimDataExport marks the oldest pending export processing as of now
+	// and returns it, ErrNotFound when there is none. Exports processing
+	// since before staleBefore are claimed again, their worker is presumed
+	// gone.
+	ClaimDataExport(ctx context.Context, now time.Time, staleBefore time.Time) (dataExport DataExport, err error)
+	// FinishDataExport stores the status, archive and completion time of an
+	// export, unless it was claimed again since data.StartedAt.
+	FinishDataExport(ctx context.Context, data DataExport) (finished bool, err error)
+	// DeleteExpiredDataExports removes up to limit exports expired at or
+	// before expiredBefore.
+	DeleteExpiredDataExports(ctx context.Context, expiredBefore time.Time, limit int) (deleted int64, err error)
+}
+
+// LoginAttemptRepositoryInterface keeps failed login counters keyed by user
+// or client ip. It is implemented by Repository on Postgres and by
+// MemoryLoginAttemptRepository for a single instance or tests.
+type LoginAttemptRepositoryInterface interface {
+	GetLoginAttempt(ctx context.Context, key string) (loginAttempt LoginAttempt, err error)
+	IncreaseFailedLoginCount(ctx context.Context, key string, windowStart time.Time, now time.Time) (failedCount int, err error)
+	BlockLogin(ctx context.Context, key string, blockedUntil time.Time) (err error)
+	ResetLoginAttempts(ctx context.Context, key string) (err error)
 }
@@ -7,6 +7,7 @@ package repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,6 +35,199 @@ func (m *MockRepositoryInterface) EXPECT() *MockRepositoryInterfaceMockRecorder
 	return m.recorder
 }
 
+// ClaimDataExport mocks base method.
+func (m *MockRepositoryInterface) ClaimDataExport(ctx context.Context, now, staleBefore time.Time) (DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDataExport", ctx, now, staleBefore)
+	ret0, _ := ret[0].(DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDataExport indicates an expected call of ClaimDataExport.
+func (mr *MockRepositoryInterfaceMockRecorder) ClaimDataExport(ctx, now, staleBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDataExport", reflect.TypeOf((*MockRepositoryInterface)(nil).ClaimDataExport), ctx, now, staleBefore)
+}
+
+// ConfirmUserTOTP mocks base method.
+func (m *MockRepositoryInterface) ConfirmUserTOTP(ctx context.Context, userID, step int64, recoveryCodeHashes []string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmUserTOTP", ctx, userID, step, recoveryCodeHashes)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmUserTOTP indicates an expected call of ConfirmUserTOTP.
+func (mr *MockRepositoryInterfaceMockRecorder) ConfirmUserTOTP(ctx, userID, step, recoveryCodeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmUserTOTP", reflect.TypeOf((*MockRepositoryInterface)(nil).ConfirmUserTOTP), ctx, userID, step, recoveryCodeHashes)
+}
+
+// DeleteExpiredDataExports mocks base method.
+func (m *MockRepositoryInterface) DeleteExpiredDataExports(ctx context.Context, expiredBefore time.Time, limit int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredDataExports", ctx, expiredBefore, limit)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredDataExports indicates an expected call of DeleteExpiredDataExports.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteExpiredDataExports(ctx, expiredBefore, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredDataExports", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteExpiredDataExports), ctx, expiredBefore, limit)
+}
+
+// DeleteUser mocks base method.
+func (m *MockRepositoryInterface) DeleteUser(ctx context.Context, userID int64, deletedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, userID, deletedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteUser(ctx, userID, deletedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteUser), ctx, userID, deletedAt)
+}
+
+// DeleteUserTOTP mocks base method.
+func (m *MockRepositoryInterface) DeleteUserTOTP(ctx context.Context, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserTOTP", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserTOTP indicates an expected call of DeleteUserTOTP.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteUserTOTP(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserTOTP", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteUserTOTP), ctx, userID)
+}
+
+// FinishDataExport mocks base method.
+func (m *MockRepositoryInterface) FinishDataExport(ctx context.Context, data DataExport) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishDataExport", ctx, data)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FinishDataExport indicates an expected call of FinishDataExport.
+func (mr *MockRepositoryInterfaceMockRecorder) FinishDataExport(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishDataExport", reflect.TypeOf((*MockRepositoryInterface)(nil).FinishDataExport), ctx, data)
+}
+
+// GetActivePasswordReset mocks base method.
+func (m *MockRepositoryInterface) GetActivePasswordReset(ctx context.Context, userID int64) (PasswordReset, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActivePasswordReset", ctx, userID)
+	ret0, _ := ret[0].(PasswordReset)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActivePasswordReset indicates an expected call of GetActivePasswordReset.
+func (mr *MockRepositoryInterfaceMockRecorder) GetActivePasswordReset(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActivePasswordReset", reflect.TypeOf((*MockRepositoryInterface)(nil).GetActivePasswordReset), ctx, userID)
+}
+
+// GetActivePhoneVerification mocks base method.
+func (m *MockRepositoryInterface) GetActivePhoneVerification(ctx context.Context, phoneNumber string) (PhoneVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActivePhoneVerification", ctx, phoneNumber)
+	ret0, _ := ret[0].(PhoneVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActivePhoneVerification indicates an expected call of GetActivePhoneVerification.
+func (mr *MockRepositoryInterfaceMockRecorder) GetActivePhoneVerification(ctx, phoneNumber interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActivePhoneVerification", reflect.TypeOf((*MockRepositoryInterface)(nil).GetActivePhoneVerification), ctx, phoneNumber)
+}
+
+// GetDataExport mocks base method.
+func (m *MockRepositoryInterface) GetDataExport(ctx context.Context, dataExportID int64) (DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataExport", ctx, dataExportID)
+	ret0, _ := ret[0].(DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDataExport indicates an expected call of GetDataExport.
+func (mr *MockRepositoryInterfaceMockRecorder) GetDataExport(ctx, dataExportID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataExport", reflect.TypeOf((*MockRepositoryInterface)(nil).GetDataExport), ctx, dataExportID)
+}
+
+// GetDeletedUserByPhoneNumber mocks base method.
+func (m *MockRepositoryInterface) GetDeletedUserByPhoneNumber(ctx context.Context, phoneNumber string, deletedAfter time.Time) (User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletedUserByPhoneNumber", ctx, phoneNumber, deletedAfter)
+	ret0, _ := ret[0].(User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeletedUserByPhoneNumber indicates an expected call of GetDeletedUserByPhoneNumber.
+func (mr *MockRepositoryInterfaceMockRecorder) GetDeletedUserByPhoneNumber(ctx, phoneNumber, deletedAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletedUserByPhoneNumber", reflect.TypeOf((*MockRepositoryInterface)(nil).GetDeletedUserByPhoneNumber), ctx, phoneNumber, deletedAfter)
+}
+
+// GetRefreshTokenByHash mocks base method.
+func (m *MockRepositoryInterface) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshTokenByHash", ctx, tokenHash)
+	ret0, _ := ret[0].(RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshTokenByHash indicates an expected call of GetRefreshTokenByHash.
+func (mr *MockRepositoryInterfaceMockRecorder) GetRefreshTokenByHash(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshTokenByHash", reflect.TypeOf((*MockRepositoryInterface)(nil).GetRefreshTokenByHash), ctx, tokenHash)
+}
+
+// GetRolePermissions mocks base method.
+func (m *MockRepositoryInterface) GetRolePermissions(ctx context.Context, role string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRolePermissions", ctx, role)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRolePermissions indicates an expected call of GetRolePermissions.
+func (mr *MockRepositoryInterfaceMockRecorder) GetRolePermissions(ctx, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRolePermissions", reflect.TypeOf((*MockRepositoryInterface)(nil).GetRolePermissions), ctx, role)
+}
+
+// GetTokensValidAfter mocks base method.
+func (m *MockRepositoryInterface) GetTokensValidAfter(ctx context.Context, userID int64) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTokensValidAfter", ctx, userID)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTokensValidAfter indicates an expected call of GetTokensValidAfter.
+func (mr *MockRepositoryInterfaceMockRecorder) GetTokensValidAfter(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokensValidAfter", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTokensValidAfter), ctx, userID)
+}
+
 // GetUserByID mocks base method.
 func (m *MockRepositoryInterface) GetUserByID(ctx context.Context, userID int64) (User, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +258,36 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetUserByPhoneNumber(ctx, phoneNu
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByPhoneNumber", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserByPhoneNumber), ctx, phoneNumber)
 }
 
+// GetUserData mocks base method.
+func (m *MockRepositoryInterface) GetUserData(ctx context.Context, userID int64) (UserData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserData", ctx, userID)
+	ret0, _ := ret[0].(UserData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserData indicates an expected call of GetUserData.
+func (mr *MockRepositoryInterfaceMockRecorder) GetUserData(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserData", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserData), ctx, userID)
+}
+
+// GetUserTOTP mocks base method.
+func (m *MockRepositoryInterface) GetUserTOTP(ctx context.Context, userID int64) (UserTOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserTOTP", ctx, userID)
+	ret0, _ := ret[0].(UserTOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserTOTP indicates an expected call of GetUserTOTP.
+func (mr *MockRepositoryInterfaceMockRecorder) GetUserTOTP(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserTOTP", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserTOTP), ctx, userID)
+}
+
 // IncreaseLoginCount mocks base method.
 func (m *MockRepositoryInterface) IncreaseLoginCount(ctx context.Context, userID int64) error {
 	m.ctrl.T.Helper()
@@ -78,6 +302,124 @@ func (mr *MockRepositoryInterfaceMockRecorder) IncreaseLoginCount(ctx, userID in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncreaseLoginCount", reflect.TypeOf((*MockRepositoryInterface)(nil).IncreaseLoginCount), ctx, userID)
 }
 
+// IncreasePasswordResetAttempts mocks base method.
+func (m *MockRepositoryInterface) IncreasePasswordResetAttempts(ctx context.Context, passwordResetID int64, maxAttempts int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncreasePasswordResetAttempts", ctx, passwordResetID, maxAttempts)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncreasePasswordResetAttempts indicates an expected call of IncreasePasswordResetAttempts.
+func (mr *MockRepositoryInterfaceMockRecorder) IncreasePasswordResetAttempts(ctx, passwordResetID, maxAttempts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncreasePasswordResetAttempts", reflect.TypeOf((*MockRepositoryInterface)(nil).IncreasePasswordResetAttempts), ctx, passwordResetID, maxAttempts)
+}
+
+// IncreasePhoneVerificationAttempts mocks base method.
+func (m *MockRepositoryInterface) IncreasePhoneVerificationAttempts(ctx context.Context, phoneVerificationID int64, maxAttempts int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncreasePhoneVerificationAttempts", ctx, phoneVerificationID, maxAttempts)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncreasePhoneVerificationAttempts indicates an expected call of IncreasePhoneVerificationAttempts.
+func (mr *MockRepositoryInterfaceMockRecorder) IncreasePhoneVerificationAttempts(ctx, phoneVerificationID, maxAttempts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncreasePhoneVerificationAttempts", reflect.TypeOf((*MockRepositoryInterface)(nil).IncreasePhoneVerificationAttempts), ctx, phoneVerificationID, maxAttempts)
+}
+
+// InsertAuditEvent mocks base method.
+func (m *MockRepositoryInterface) InsertAuditEvent(ctx context.Context, event AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertAuditEvent", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertAuditEvent indicates an expected call of InsertAuditEvent.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertAuditEvent(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertAuditEvent", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertAuditEvent), ctx, event)
+}
+
+// InsertDataExport mocks base method.
+func (m *MockRepositoryInterface) InsertDataExport(ctx context.Context, data DataExport) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertDataExport", ctx, data)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertDataExport indicates an expected call of InsertDataExport.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertDataExport(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertDataExport", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertDataExport), ctx, data)
+}
+
+// InsertPasswordReset mocks base method.
+func (m *MockRepositoryInterface) InsertPasswordReset(ctx context.Context, data PasswordReset) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertPasswordReset", ctx, data)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertPasswordReset indicates an expected call of InsertPasswordReset.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertPasswordReset(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertPasswordReset", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertPasswordReset), ctx, data)
+}
+
+// InsertPhoneVerification mocks base method.
+func (m *MockRepositoryInterface) InsertPhoneVerification(ctx context.Context, data PhoneVerification) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertPhoneVerification", ctx, data)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertPhoneVerification indicates an expected call of InsertPhoneVerification.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertPhoneVerification(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertPhoneVerification", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertPhoneVerification), ctx, data)
+}
+
+// InsertRefreshToken mocks base method.
+func (m *MockRepositoryInterface) InsertRefreshToken(ctx context.Context, data RefreshToken) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertRefreshToken", ctx, data)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertRefreshToken indicates an expected call of InsertRefreshToken.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertRefreshToken(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertRefreshToken", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertRefreshToken), ctx, data)
+}
+
+// InsertRevokedToken mocks base method.
+func (m *MockRepositoryInterface) InsertRevokedToken(ctx context.Context, data RevokedToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertRevokedToken", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertRevokedToken indicates an expected call of InsertRevokedToken.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertRevokedToken(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertRevokedToken", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertRevokedToken), ctx, data)
+}
+
 // InsertUser mocks base method.
 func (m *MockRepositoryInterface) InsertUser(ctx context.Context, data User) (int64, error) {
 	m.ctrl.T.Helper()
@@ -93,6 +435,269 @@ func (mr *MockRepositoryInterfaceMockRecorder) InsertUser(ctx, data interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUser", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertUser), ctx, data)
 }
 
+// IsTokenRevoked mocks base method.
+func (m *MockRepositoryInterface) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsTokenRevoked", ctx, jti)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsTokenRevoked indicates an expected call of IsTokenRevoked.
+func (mr *MockRepositoryInterfaceMockRecorder) IsTokenRevoked(ctx, jti interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsTokenRevoked", reflect.TypeOf((*MockRepositoryInterface)(nil).IsTokenRevoked), ctx, jti)
+}
+
+// ListUsers mocks base method.
+func (m *MockRepositoryInterface) ListUsers(ctx context.Context, filter UserFilter) ([]User, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", ctx, filter)
+	ret0, _ := ret[0].([]User)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockRepositoryInterfaceMockRecorder) ListUsers(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockRepositoryInterface)(nil).ListUsers), ctx, filter)
+}
+
+// LockUser mocks base method.
+func (m *MockRepositoryInterface) LockUser(ctx context.Context, userID int64, lockedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockUser", ctx, userID, lockedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockUser indicates an expected call of LockUser.
+func (mr *MockRepositoryInterfaceMockRecorder) LockUser(ctx, userID, lockedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUser", reflect.TypeOf((*MockRepositoryInterface)(nil).LockUser), ctx, userID, lockedAt)
+}
+
+// MarkRefreshTokenUsed mocks base method.
+func (m *MockRepositoryInterface) MarkRefreshTokenUsed(ctx context.Context, refreshTokenID int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRefreshTokenUsed", ctx, refreshTokenID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkRefreshTokenUsed indicates an expected call of MarkRefreshTokenUsed.
+func (mr *MockRepositoryInterfaceMockRecorder) MarkRefreshTokenUsed(ctx, refreshTokenID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRefreshTokenUsed", reflect.TypeOf((*MockRepositoryInterface)(nil).MarkRefreshTokenUsed), ctx, refreshTokenID)
+}
+
+// Ping mocks base method.
+func (m *MockRepositoryInterface) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockRepositoryInterfaceMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockRepositoryInterface)(nil).Ping), ctx)
+}
+
+// PurgeDeletedUsers mocks base method.
+func (m *MockRepositoryInterface) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, limit int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedUsers", ctx, deletedBefore, limit)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedUsers indicates an expected call of PurgeDeletedUsers.
+func (mr *MockRepositoryInterfaceMockRecorder) PurgeDeletedUsers(ctx, deletedBefore, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedUsers", reflect.TypeOf((*MockRepositoryInterface)(nil).PurgeDeletedUsers), ctx, deletedBefore, limit)
+}
+
+// PurgeUnverifiedUsers mocks base method.
+func (m *MockRepositoryInterface) PurgeUnverifiedUsers(ctx context.Context, createdBefore time.Time, limit int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeUnverifiedUsers", ctx, createdBefore, limit)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeUnverifiedUsers indicates an expected call of PurgeUnverifiedUsers.
+func (mr *MockRepositoryInterfaceMockRecorder) PurgeUnverifiedUsers(ctx, createdBefore, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeUnverifiedUsers", reflect.TypeOf((*MockRepositoryInterface)(nil).PurgeUnverifiedUsers), ctx, createdBefore, limit)
+}
+
+// ReleasePhoneNumber mocks base method.
+func (m *MockRepositoryInterface) ReleasePhoneNumber(ctx context.Context, phoneNumber string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleasePhoneNumber", ctx, phoneNumber)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleasePhoneNumber indicates an expected call of ReleasePhoneNumber.
+func (mr *MockRepositoryInterfaceMockRecorder) ReleasePhoneNumber(ctx, phoneNumber interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleasePhoneNumber", reflect.TypeOf((*MockRepositoryInterface)(nil).ReleasePhoneNumber), ctx, phoneNumber)
+}
+
+// RequirePasswordReset mocks base method.
+func (m *MockRepositoryInterface) RequirePasswordReset(ctx context.Context, userID int64, requiredAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequirePasswordReset", ctx, userID, requiredAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequirePasswordReset indicates an expected call of RequirePasswordReset.
+func (mr *MockRepositoryInterfaceMockRecorder) RequirePasswordReset(ctx, userID, requiredAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequirePasswordReset", reflect.TypeOf((*MockRepositoryInterface)(nil).RequirePasswordReset), ctx, userID, requiredAt)
+}
+
+// ResetPassword mocks base method.
+func (m *MockRepositoryInterface) ResetPassword(ctx context.Context, passwordResetID int64, data User, validAfter time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, passwordResetID, data, validAfter)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockRepositoryInterfaceMockRecorder) ResetPassword(ctx, passwordResetID, data, validAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockRepositoryInterface)(nil).ResetPassword), ctx, passwordResetID, data, validAfter)
+}
+
+// RestoreUser mocks base method.
+func (m *MockRepositoryInterface) RestoreUser(ctx context.Context, userID int64, deletedAfter time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUser", ctx, userID, deletedAfter)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreUser indicates an expected call of RestoreUser.
+func (mr *MockRepositoryInterfaceMockRecorder) RestoreUser(ctx, userID, deletedAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUser", reflect.TypeOf((*MockRepositoryInterface)(nil).RestoreUser), ctx, userID, deletedAfter)
+}
+
+// RevokeRefreshTokenFamily mocks base method.
+func (m *MockRepositoryInterface) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshTokenFamily", ctx, familyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRefreshTokenFamily indicates an expected call of RevokeRefreshTokenFamily.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeRefreshTokenFamily(ctx, familyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshTokenFamily", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeRefreshTokenFamily), ctx, familyID)
+}
+
+// RevokeRefreshTokensByUserID mocks base method.
+func (m *MockRepositoryInterface) RevokeRefreshTokensByUserID(ctx context.Context, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshTokensByUserID", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRefreshTokensByUserID indicates an expected call of RevokeRefreshTokensByUserID.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeRefreshTokensByUserID(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshTokensByUserID", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeRefreshTokensByUserID), ctx, userID)
+}
+
+// SearchUsers mocks base method.
+func (m *MockRepositoryInterface) SearchUsers(ctx context.Context, search UserSearch) ([]User, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", ctx, search)
+	ret0, _ := ret[0].([]User)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockRepositoryInterfaceMockRecorder) SearchUsers(ctx, search interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockRepositoryInterface)(nil).SearchUsers), ctx, search)
+}
+
+// UnlockUser mocks base method.
+func (m *MockRepositoryInterface) UnlockUser(ctx context.Context, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockUser", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockUser indicates an expected call of UnlockUser.
+func (mr *MockRepositoryInterfaceMockRecorder) UnlockUser(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockUser", reflect.TypeOf((*MockRepositoryInterface)(nil).UnlockUser), ctx, userID)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockRepositoryInterface) UpdatePassword(ctx context.Context, data User, validAfter time.Time, keepFamilyID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, data, validAfter, keepFamilyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdatePassword(ctx, data, validAfter, keepFamilyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdatePassword), ctx, data, validAfter, keepFamilyID)
+}
+
+// UpdateTOTPLastUsedStep mocks base method.
+func (m *MockRepositoryInterface) UpdateTOTPLastUsedStep(ctx context.Context, userID, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTOTPLastUsedStep", ctx, userID, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateTOTPLastUsedStep indicates an expected call of UpdateTOTPLastUsedStep.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateTOTPLastUsedStep(ctx, userID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTOTPLastUsedStep", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateTOTPLastUsedStep), ctx, userID, step)
+}
+
+// UpdateTokensValidAfter mocks base method.
+func (m *MockRepositoryInterface) UpdateTokensValidAfter(ctx context.Context, userID int64, validAfter time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTokensValidAfter", ctx, userID, validAfter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateTokensValidAfter indicates an expected call of UpdateTokensValidAfter.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateTokensValidAfter(ctx, userID, validAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTokensValidAfter", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateTokensValidAfter), ctx, userID, validAfter)
+}
+
 // UpdateUser mocks base method.
 func (m *MockRepositoryInterface) UpdateUser(ctx context.Context, data User) error {
 	m.ctrl.T.Helper()
@@ -106,3 +711,128 @@ func (mr *MockRepositoryInterfaceMockRecorder) UpdateUser(ctx, data interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateUser), ctx, data)
 }
+
+// UpsertUserTOTP mocks base method.
+func (m *MockRepositoryInterface) UpsertUserTOTP(ctx context.Context, data UserTOTP) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertUserTOTP", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertUserTOTP indicates an expected call of UpsertUserTOTP.
+func (mr *MockRepositoryInterfaceMockRecorder) UpsertUserTOTP(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertUserTOTP", reflect.TypeOf((*MockRepositoryInterface)(nil).UpsertUserTOTP), ctx, data)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockRepositoryInterface) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userID, codeHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockRepositoryInterfaceMockRecorder) UseRecoveryCode(ctx, userID, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockRepositoryInterface)(nil).UseRecoveryCode), ctx, userID, codeHash)
+}
+
+// VerifyPhoneNumber mocks base method.
+func (m *MockRepositoryInterface) VerifyPhoneNumber(ctx context.Context, phoneVerificationID int64, data User) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyPhoneNumber", ctx, phoneVerificationID, data)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyPhoneNumber indicates an expected call of VerifyPhoneNumber.
+func (mr *MockRepositoryInterfaceMockRecorder) VerifyPhoneNumber(ctx, phoneVerificationID, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyPhoneNumber", reflect.TypeOf((*MockRepositoryInterface)(nil).VerifyPhoneNumber), ctx, phoneVerificationID, data)
+}
+
+// MockLoginAttemptRepositoryInterface is a mock of LoginAttemptRepositoryInterface interface.
+type MockLoginAttemptRepositoryInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginAttemptRepositoryInterfaceMockRecorder
+}
+
+// MockLoginAttemptRepositoryInterfaceMockRecorder is the mock recorder for MockLoginAttemptRepositoryInterface.
+type MockLoginAttemptRepositoryInterfaceMockRecorder struct {
+	mock *MockLoginAttemptRepositoryInterface
+}
+
+// NewMockLoginAttemptRepositoryInterface creates a new mock instance.
+func NewMockLoginAttemptRepositoryInterface(ctrl *gomock.Controller) *MockLoginAttemptRepositoryInterface {
+	mock := &MockLoginAttemptRepositoryInterface{ctrl: ctrl}
+	mock.recorder = &MockLoginAttemptRepositoryInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginAttemptRepositoryInterface) EXPECT() *MockLoginAttemptRepositoryInterfaceMockRecorder {
+	return m.recorder
+}
+
+// BlockLogin mocks base method.
+func (m *MockLoginAttemptRepositoryInterface) BlockLogin(ctx context.Context, key string, blockedUntil time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockLogin", ctx, key, blockedUntil)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BlockLogin indicates an expected call of BlockLogin.
+func (mr *MockLoginAttemptRepositoryInterfaceMockRecorder) BlockLogin(ctx, key, blockedUntil interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockLogin", reflect.TypeOf((*MockLoginAttemptRepositoryInterface)(nil).BlockLogin), ctx, key, blockedUntil)
+}
+
+// GetLoginAttempt mocks base method.
+func (m *MockLoginAttemptRepositoryInterface) GetLoginAttempt(ctx context.Context, key string) (LoginAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoginAttempt", ctx, key)
+	ret0, _ := ret[0].(LoginAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoginAttempt indicates an expected call of GetLoginAttempt.
+func (mr *MockLoginAttemptRepositoryInterfaceMockRecorder) GetLoginAttempt(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginAttempt", reflect.TypeOf((*MockLoginAttemptRepositoryInterface)(nil).GetLoginAttempt), ctx, key)
+}
+
+// IncreaseFailedLoginCount mocks base method.
+func (m *MockLoginAttemptRepositoryInterface) IncreaseFailedLoginCount(ctx context.Context, key string, windowStart, now time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncreaseFailedLoginCount", ctx, key, windowStart, now)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncreaseFailedLoginCount indicates an expected call of IncreaseFailedLoginCount.
+func (mr *MockLoginAttemptRepositoryInterfaceMockRecorder) IncreaseFailedLoginCount(ctx, key, windowStart, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncreaseFailedLoginCount", reflect.TypeOf((*MockLoginAttemptRepositoryInterface)(nil).IncreaseFailedLoginCount), ctx, key, windowStart, now)
+}
+
+// ResetLoginAttempts mocks base method.
+func (m *MockLoginAttemptRepositoryInterface) ResetLoginAttempts(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetLoginAttempts", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetLoginAttempts indicates an expected call of ResetLoginAttempts.
+func (mr *MockLoginAttemptRepositoryInterfaceMockRecorder) ResetLoginAttempts(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginAttempts", reflect.TypeOf((*MockLoginAttemptRepositoryInterface)(nil).ResetLoginAttempts), ctx, key)
+}
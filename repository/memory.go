@@ -0,0 +1,62 @@
+// This file contains an in-memory implementation of the login attempt
+// repository. Counters are not shared between instances and are lost on
+// restart, so it is only meant for a single instance or tests.
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type MemoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]LoginAttempt
+}
+
+func NewMemoryLoginAttemptRepository() *MemoryLoginAttemptRepository {
+	return &MemoryLoginAttemptRepository{
+		attempts: make(map[string]LoginAttempt),
+	}
+}
+
+func (r *MemoryLoginAttemptRepository) GetLoginAttempt(ctx context.Context, key string) (loginAttempt LoginAttempt, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.attempts[key], nil
+}
+
+func (r *MemoryLoginAttemptRepository) IncreaseFailedLoginCount(ctx context.Context, key string, windowStart time.Time, now time.Time) (failedCount int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	loginAttempt, ok := r.attempts[key]
+	if !ok {
+		loginAttempt.Key = key
+	}
+	if loginAttempt.LastFailedAt.Before(windowStart) {
+		loginAttempt.FailedCount = 0
+	}
+	loginAttempt.FailedCount++
+	loginAttempt.LastFailedAt = now
+	r.attempts[key] = loginAttempt
+	return loginAttempt.FailedCount, nil
+}
+
+func (r *MemoryLoginAttemptRepository) BlockLogin(ctx context.Context, key string, blockedUntil time.Time) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	loginAttempt, ok := r.attempts[key]
+	if !ok {
+		return nil
+	}
+	loginAttempt.BlockedUntil = &blockedUntil
+	r.attempts[key] = loginAttempt
+	return nil
+}
+
+func (r *MemoryLoginAttemptRepository) ResetLoginAttempts(ctx context.Context, key string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, key)
+	return nil
+}
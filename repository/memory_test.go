@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_MemoryLoginAttemptRepository(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	blockedUntil := now.Add(time.Minute)
+	tests := []struct {
+		name    string
+		run     func(r *MemoryLoginAttemptRepository)
+		wantRes LoginAttempt
+	}{
+		{
+			name:    "not found",
+			run:     func(r *MemoryLoginAttemptRepository) {},
+			wantRes: LoginAttempt{},
+		},
+		{
+			name: "failures within window are counted",
+			run: func(r *MemoryLoginAttemptRepository) {
+				r.IncreaseFailedLoginCount(ctx, "user:1", now.Add(-time.Hour), now.Add(-time.Second))
+				r.IncreaseFailedLoginCount(ctx, "user:1", now.Add(-time.Hour), now)
+				r.IncreaseFailedLoginCount(ctx, "user:2", now.Add(-time.Hour), now)
+			},
+			wantRes: LoginAttempt{
+				Key:          "user:1",
+				FailedCount:  2,
+				LastFailedAt: now,
+			},
+		},
+		{
+			name: "failures before window are dropped",
+			run: func(r *MemoryLoginAttemptRepository) {
+				r.IncreaseFailedLoginCount(ctx, "user:1", now.Add(-2*time.Hour), now.Add(-time.Hour))
+				r.IncreaseFailedLoginCount(ctx, "user:1", now.Add(-time.Minute), now)
+			},
+			wantRes: LoginAttempt{
+				Key:          "user:1",
+				FailedCount:  1,
+				LastFailedAt: now,
+			},
+		},
+		{
+			name: "blocked",
+			run: func(r *MemoryLoginAttemptRepository) {
+				r.IncreaseFailedLoginCount(ctx, "user:1", now.Add(-time.Hour), now)
+				r.BlockLogin(ctx, "user:1", blockedUntil)
+			},
+			wantRes: LoginAttempt{
+				Key:          "user:1",
+				FailedCount:  1,
+				LastFailedAt: now,
+				BlockedUntil: &blockedUntil,
+			},
+		},
+		{
+			name: "reset",
+			run: func(r *MemoryLoginAttemptRepository) {
+				r.IncreaseFailedLoginCount(ctx, "user:1", now.Add(-time.Hour), now)
+				r.BlockLogin(ctx, "user:1", blockedUntil)
+				r.ResetLoginAttempts(ctx, "user:1")
+			},
+			wantRes: LoginAttempt{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewMemoryLoginAttemptRepository()
+			tt.run(r)
+			gotRes, gotErr := r.GetLoginAttempt(ctx, "user:1")
+			if gotErr != nil {
+				t.Errorf("MemoryLoginAttemptRepository.GetLoginAttempt() gotErr = %s", gotErr.Error())
+			}
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("MemoryLoginAttemptRepository.GetLoginAttempt() gotRes = %+v, wantRes = %+v", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
@@ -6,9 +6,14 @@ const (
 			id,
 			phone_number,
 			password,
-			full_name
+			full_name,
+			phone_verified_at,
+			COALESCE(role, ''),
+			locked_at,
+			password_reset_required_at
 		FROM "user"
-		WHERE id = $1;
+		WHERE id = $1
+			AND deleted_at IS NULL;
 	`
 
 	queryGetUserByPhoneNumber = `
@@ -16,9 +21,30 @@ const (
 			id,
 			phone_number,
 			password,
-			full_name
+			full_name,
+			phone_verified_at,
+			COALESCE(role, ''),
+			locked_at,
+			password_reset_required_at
 		FROM "user"
-		WHERE phone_number = $1;
+		WHERE phone_number = $1
+			AND deleted_at IS NULL;
+	`
+
+	queryGetDeletedUserByPhoneNumber = `
+		SELECT
+			id,
+			phone_number,
+			password,
+			full_name,
+			phone_verified_at,
+			COALESCE(role, ''),
+			locked_at,
+			password_reset_required_at,
+			deleted_at
+		FROM "user"
+		WHERE phone_number = $1
+			AND deleted_at > $2;
 	`
 
 	queryIncreaseLoginCount = `
@@ -41,4 +67,529 @@ const (
 		SET %s
 		WHERE id = $1;
 	`
+
+	queryGetTokensValidAfter = `
+		SELECT tokens_valid_after
+		FROM "user"
+		WHERE id = $1;
+	`
+
+	queryUpdateTokensValidAfter = `
+		UPDATE "user"
+		SET tokens_valid_after = $2
+		WHERE id = $1;
+	`
+
+	queryDeleteUser = `
+		UPDATE "user"
+		SET deleted_at = $2,
+			tokens_valid_after = $2
+		WHERE id = $1
+			AND deleted_at IS NULL;
+	`
+
+	queryRestoreUser = `
+		UPDATE "user"
+		SET deleted_at = NULL
+		WHERE id = $1
+			AND deleted_at > $2;
+	`
+
+	queryPurgeDeletedUsers = `
+		DELETE FROM "user"
+		WHERE id IN (
+			SELECT id
+			FROM "user"
+			WHERE deleted_at <= $1
+			ORDER BY deleted_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		);
+	`
+
+	queryPurgeUnverifiedUsers = `
+		DELETE FROM "user"
+		WHERE id IN (
+			SELECT id
+			FROM "user"
+			WHERE phone_verified_at IS NULL
+				AND created_at <= $1
+				AND NOT EXISTS (
+					SELECT 1
+					FROM phone_verification
+					WHERE phone_verification.user_id = "user".id
+						AND verified_at IS NOT NULL
+				)
+			ORDER BY created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		);
+	`
+
+	queryListUsers = `
+		SELECT
+			id,
+			phone_number,
+			full_name,
+			phone_verified_at,
+			COALESCE(role, ''),
+			locked_at,
+			password_reset_required_at
+		FROM "user"
+		WHERE deleted_at IS NULL%s
+		ORDER BY id
+		LIMIT %s OFFSET %s;
+	`
+
+	querySearchUsers = `
+		SELECT
+			id,
+			phone_number,
+			full_name,
+			phone_verified_at,
+			COALESCE(role, ''),
+			locked_at,
+			password_reset_required_at
+		FROM "user"
+		WHERE deleted_at IS NULL%s
+		ORDER BY %s
+		LIMIT %s;
+	`
+
+	queryCountUsers = `
+		SELECT COUNT(*)
+		FROM "user"
+		WHERE deleted_at IS NULL%s;
+	`
+
+	queryLockUser = `
+		UPDATE "user"
+		SET locked_at = COALESCE(locked_at, $2),
+			tokens_valid_after = $2
+		WHERE id = $1
+			AND deleted_at IS NULL;
+	`
+
+	queryUnlockUser = `
+		UPDATE "user"
+		SET locked_at = NULL
+		WHERE id = $1
+			AND deleted_at IS NULL;
+	`
+
+	queryRequirePasswordReset = `
+		UPDATE "user"
+		SET password_reset_required_at = $2,
+			tokens_valid_after = $2
+		WHERE id = $1
+			AND deleted_at IS NULL;
+	`
+
+	queryGetRolePermissions = `
+		SELECT permission
+		FROM role_permission
+		WHERE role = $1
+		ORDER BY permission;
+	`
+
+	queryGetRefreshTokenByHash = `
+		SELECT
+			id,
+			user_id,
+			family_id,
+			token_hash,
+			expires_at,
+			used_at,
+			revoked_at
+		FROM refresh_token
+		WHERE token_hash = $1;
+	`
+
+	queryInsertRefreshToken = `
+		INSERT INTO refresh_token (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id;
+	`
+
+	queryMarkRefreshTokenUsed = `
+		UPDATE refresh_token
+		SET used_at = NOW()
+		WHERE id = $1
+			AND used_at IS NULL
+			AND revoked_at IS NULL;
+	`
+
+	queryRevokeRefreshTokenFamily = `
+		UPDATE refresh_token
+		SET revoked_at = NOW()
+		WHERE family_id = $1
+			AND revoked_at IS NULL;
+	`
+
+	queryRevokeRefreshTokensByUserID = `
+		UPDATE refresh_token
+		SET revoked_at = NOW()
+		WHERE user_id = $1
+			AND revoked_at IS NULL;
+	`
+
+	queryRevokeOtherRefreshTokens = `
+		UPDATE refresh_token
+		SET revoked_at = NOW()
+		WHERE user_id = $1
+			AND family_id <> $2
+			AND revoked_at IS NULL;
+	`
+
+	queryInsertRevokedToken = `
+		INSERT INTO revoked_token (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING;
+	`
+
+	queryIsTokenRevoked = `
+		SELECT EXISTS (
+			SELECT 1
+			FROM revoked_token
+			WHERE jti = $1
+		);
+	`
+
+	queryGetActivePasswordReset = `
+		SELECT
+			id,
+			user_id,
+			code_hash,
+			attempts,
+			expires_at,
+			created_at
+		FROM password_reset
+		WHERE user_id = $1
+			AND used_at IS NULL
+			AND expires_at > NOW()
+		ORDER BY id DESC
+		LIMIT 1;
+	`
+
+	queryInsertPasswordReset = `
+		INSERT INTO password_reset (user_id, code_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id;
+	`
+
+	queryIncreasePasswordResetAttempts = `
+		UPDATE password_reset
+		SET attempts = attempts + 1
+		WHERE id = $1
+			AND attempts < $2;
+	`
+
+	queryMarkPasswordResetUsed = `
+		UPDATE password_reset
+		SET used_at = NOW()
+		WHERE id = $1
+			AND used_at IS NULL;
+	`
+
+	queryUpdatePassword = `
+		UPDATE "user"
+		SET password = $2,
+			tokens_valid_after = $3,
+			password_reset_required_at = NULL
+		WHERE id = $1;
+	`
+
+	queryGetActivePhoneVerification = `
+		SELECT
+			id,
+			user_id,
+			phone_number,
+			code_hash,
+			attempts,
+			expires_at,
+			created_at
+		FROM phone_verification
+		WHERE phone_number = $1
+			AND verified_at IS NULL
+			AND expires_at > NOW()
+		ORDER BY id DESC
+		LIMIT 1;
+	`
+
+	queryInsertPhoneVerification = `
+		INSERT INTO phone_verification (user_id, phone_number, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id;
+	`
+
+	queryIncreasePhoneVerificationAttempts = `
+		UPDATE phone_verification
+		SET attempts = attempts + 1
+		WHERE id = $1
+			AND attempts < $2;
+	`
+
+	queryMarkPhoneVerificationVerified = `
+		UPDATE phone_verification
+		SET verified_at = NOW()
+		WHERE id = $1
+			AND verified_at IS NULL;
+	`
+
+	queryReleasePhoneNumber = `
+		DELETE FROM "user"
+		WHERE phone_number = $1
+			AND phone_verified_at IS NULL
+			AND deleted_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1
+				FROM phone_verification
+				WHERE phone_verification.user_id = "user".id
+					AND (verified_at IS NOT NULL OR expires_at > NOW())
+			);
+	`
+
+	queryDeleteUnverifiedUsersByPhoneNumber = `
+		DELETE FROM "user"
+		WHERE phone_number = $2
+			AND id <> $1
+			AND phone_verified_at IS NULL
+			AND deleted_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1
+				FROM phone_verification
+				WHERE phone_verification.user_id = "user".id
+					AND verified_at IS NOT NULL
+			);
+	`
+
+	queryVerifyPhoneNumber = `
+		UPDATE "user"
+		SET phone_number = $2,
+			phone_verified_at = NOW()
+		WHERE id = $1;
+	`
+
+	queryGetUserTOTP = `
+		SELECT
+			user_id,
+			secret,
+			last_used_step,
+			confirmed_at
+		FROM user_totp
+		WHERE user_id = $1;
+	`
+
+	queryUpsertUserTOTP = `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret,
+			last_used_step = 0,
+			created_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL;
+	`
+
+	queryConfirmUserTOTP = `
+		UPDATE user_totp
+		SET confirmed_at = NOW(),
+			last_used_step = $2
+		WHERE user_id = $1
+			AND confirmed_at IS NULL
+			AND last_used_step < $2;
+	`
+
+	queryUpdateTOTPLastUsedStep = `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1
+			AND last_used_step < $2;
+	`
+
+	queryDeleteUserTOTP = `
+		DELETE FROM user_totp
+		WHERE user_id = $1;
+	`
+
+	queryDeleteRecoveryCodes = `
+		DELETE FROM recovery_code
+		WHERE user_id = $1;
+	`
+
+	queryInsertRecoveryCode = `
+		INSERT INTO recovery_code (user_id, code_hash)
+		VALUES ($1, $2);
+	`
+
+	queryUseRecoveryCode = `
+		UPDATE recovery_code
+		SET used_at = NOW()
+		WHERE user_id = $1
+			AND code_hash = $2
+			AND used_at IS NULL;
+	`
+
+	queryGetLoginAttempt = `
+		SELECT attempt_key, failed_count, last_failed_at, blocked_until
+		FROM login_attempt
+		WHERE attempt_key = $1;
+	`
+
+	queryIncreaseFailedLoginCount = `
+		INSERT INTO login_attempt (attempt_key, failed_count, last_failed_at)
+		VALUES ($1, 1, $3)
+		ON CONFLICT (attempt_key) DO UPDATE
+		SET failed_count = CASE
+				WHEN login_attempt.last_failed_at < $2 THEN 1
+				ELSE login_attempt.failed_count + 1
+			END,
+			last_failed_at = $3
+		RETURNING failed_count;
+	`
+
+	queryBlockLogin = `
+		UPDATE login_attempt
+		SET blocked_until = $2
+		WHERE attempt_key = $1;
+	`
+
+	queryResetLoginAttempts = `
+		DELETE FROM login_attempt
+		WHERE attempt_key = $1;
+	`
+
+	queryGetUserData = `
+		SELECT
+			id,
+			phone_number,
+			full_name,
+			phone_verified_at,
+			COALESCE(login_count, 0),
+			COALESCE(role, ''),
+			locked_at,
+			password_reset_required_at,
+			deleted_at
+		FROM "user"
+		WHERE id = $1;
+	`
+
+	queryGetRefreshTokensByUserID = `
+		SELECT
+			id,
+			family_id,
+			expires_at,
+			created_at,
+			used_at,
+			revoked_at
+		FROM refresh_token
+		WHERE user_id = $1
+		ORDER BY id;
+	`
+
+	queryGetPasswordResetsByUserID = `
+		SELECT
+			id,
+			attempts,
+			expires_at,
+			created_at,
+			used_at
+		FROM password_reset
+		WHERE user_id = $1
+		ORDER BY id;
+	`
+
+	queryGetPhoneVerificationsByUserID = `
+		SELECT
+			id,
+			phone_number,
+			attempts,
+			expires_at,
+			created_at,
+			verified_at
+		FROM phone_verification
+		WHERE user_id = $1
+		ORDER BY id;
+	`
+
+	queryGetUserTOTPDates = `
+		SELECT
+			created_at,
+			confirmed_at
+		FROM user_totp
+		WHERE user_id = $1;
+	`
+
+	queryInsertAuditEvent = `
+		INSERT INTO audit_event (user_id, actor_id, action)
+		VALUES ($1, $2, $3);
+	`
+
+	queryGetAuditEventsByUserID = `
+		SELECT id, actor_id, action, created_at
+		FROM audit_event
+		WHERE user_id = $1
+		ORDER BY id;
+	`
+
+	queryInsertDataExport = `
+		INSERT INTO data_export (user_id, format, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id;
+	`
+
+	queryGetDataExport = `
+		SELECT
+			e.id,
+			e.user_id,
+			e.format,
+			e.status,
+			e.token_hash,
+			e.archive,
+			e.expires_at,
+			e.created_at,
+			e.started_at,
+			e.completed_at
+		FROM data_export AS e
+		JOIN "user" AS u ON u.id = e.user_id
+		WHERE e.id = $1
+			AND u.deleted_at IS NULL;
+	`
+
+	queryClaimDataExport = `
+		UPDATE data_export
+		SET status = 'processing',
+			started_at = $1
+		WHERE id = (
+			SELECT id
+			FROM data_export
+			WHERE (status = 'pending' OR (status = 'processing' AND started_at < $2))
+				AND expires_at > $1
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, format, status, token_hash, expires_at, created_at, started_at;
+	`
+
+	queryFinishDataExport = `
+		UPDATE data_export
+		SET status = $3,
+			archive = $4,
+			completed_at = $5
+		WHERE id = $1
+			AND status = 'processing'
+			AND started_at = $2;
+	`
+
+	queryDeleteExpiredDataExports = `
+		DELETE FROM data_export
+		WHERE id IN (
+			SELECT id
+			FROM data_export
+			WHERE expires_at <= $1
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		);
+	`
 )
@@ -2,17 +2,27 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"log/slog"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
 type Repository struct {
-	Db *sql.DB
+	Db     *sql.DB
+	Logger *slog.Logger
 }
 
 type NewRepositoryOptions struct {
 	Dsn string
+	// connection pool limits, left to database/sql defaults when zero
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// Logger defaults to slog.Default
+	Logger *slog.Logger
 }
 
 func NewRepository(opts NewRepositoryOptions) *Repository {
@@ -20,7 +30,56 @@ func NewRepository(opts NewRepositoryOptions) *Repository {
 	if err != nil {
 		panic(err)
 	}
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
 	return &Repository{
-		Db: db,
+		Db:     db,
+		Logger: opts.Logger,
+	}
+}
+
+// Ping checks that the database can be reached.
+func (r *Repository) Ping(ctx context.Context) (err error) {
+	defer r.endCall(ctx, "Ping", time.Now(), &err)
+	return r.Db.PingContext(ctx)
+}
+
+// Close closes the database, waiting for running queries to finish.
+func (r *Repository) Close() (err error) {
+	return r.Db.Close()
+}
+
+// endCall translates the error of a call into the repository errors, see
+// translateError, and logs the call at debug level with its duration and
+// error, along with the request fields of ctx, so a request can be traced to
+// its queries.
+func (r *Repository) endCall(ctx context.Context, method string, start time.Time, err *error) {
+	*err = translateError(*err)
+
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if *err != nil {
+		attrs = append(attrs, slog.String("error", (*err).Error()))
 	}
+	logger.LogAttrs(ctx, slog.LevelDebug, "repository call", attrs...)
 }
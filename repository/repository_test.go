@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	errorHelper "github.com/fenky-ng/swt-pro/helper/error"
+)
+
+func Test_Repository_Ping(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Errorf("[Test_Repository_Ping] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx context.Context
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mock    func(fields *fields)
+		wantErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectPing().
+					WillReturnError(errors.New("expected error"))
+			},
+			wantErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectPing()
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			gotErr := r.Ping(tt.args.ctx)
+			if errorHelper.GetErrorMessage(gotErr) != errorHelper.GetErrorMessage(tt.wantErr) {
+				t.Errorf("Repository.Ping() gotErr = %s, wantErr = %s", errorHelper.GetErrorMessage(gotErr), errorHelper.GetErrorMessage(tt.wantErr))
+			}
+		})
+	}
+}
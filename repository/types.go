@@ -1,9 +1,175 @@
 // This file contains types that are used in the repository layer.
 package repository
 
+import (
+	"fmt"
+	"time"
+)
+
 type User struct {
+	ID              int64
+	PhoneNumber     string
+	Password        string
+	FullName        string
+	PhoneVerifiedAt *time.Time
+	// Role names the permissions of the user, see GetRolePermissions. It is
+	// empty for users without any.
+	Role string
+	// LockedAt is set while the account is locked by support
+	LockedAt *time.Time
+	// PasswordResetRequiredAt is set until the user resets its password
+	PasswordResetRequiredAt *time.Time
+	// DeletedAt is only set on users returned by GetDeletedUserByPhoneNumber
+	DeletedAt *time.Time
+}
+
+// UserFilter selects the users returned by ListUsers. Unset fields do not
+// filter.
+type UserFilter struct {
+	Role          string
+	Locked        *bool
+	PhoneVerified *bool
+	Offset        int
+	Limit         int
+}
+
+const (
+	UserSortID          = "id"
+	UserSortFullName    = "full_name"
+	UserSortPhoneNumber = "phone_number"
+)
+
+// UserSearch selects the users returned by SearchUsers. Unset fields do not
+// filter.
+type UserSearch struct {
+	// PhoneNumberPrefix matches the start of phone numbers, in E.164 form
+	PhoneNumberPrefix string
+	// FullName matches anywhere in full names, ignoring case and accents
+	FullName string
+	// Sort is one of the UserSort constants, UserSortID when empty. Ties are
+	// broken by id.
+	Sort       string
+	Descending bool
+	// Cursor continues the search after the page it was returned with
+	Cursor string
+	// Limit caps the users of a page, it must be positive
+	Limit int
+}
+
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+type RevokedToken struct {
+	JTI       string
+	UserID    int64
+	ExpiresAt time.Time
+}
+
+type PasswordReset struct {
+	ID        int64
+	UserID    int64
+	CodeHash  string
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+type PhoneVerification struct {
 	ID          int64
+	UserID      int64
 	PhoneNumber string
-	Password    string
-	FullName    string
+	CodeHash    string
+	Attempts    int
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+	VerifiedAt  *time.Time
+}
+
+type UserTOTP struct {
+	UserID       int64
+	Secret       string
+	LastUsedStep int64
+	CreatedAt    time.Time
+	ConfirmedAt  *time.Time
+}
+
+type LoginAttempt struct {
+	Key          string
+	FailedCount  int
+	LastFailedAt time.Time
+	BlockedUntil *time.Time
+}
+
+// LoginAttemptUserKey keys the failed logins to the account of userID.
+func LoginAttemptUserKey(userID int64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// UserData is everything held about a user, see GetUserData. Secrets such
+// as password and code hashes are left empty.
+type UserData struct {
+	User               User
+	LoginCount         int64
+	RefreshTokens      []RefreshToken
+	PasswordResets     []PasswordReset
+	PhoneVerifications []PhoneVerification
+	// UserTOTP is nil when two-factor authentication was never set up
+	UserTOTP *UserTOTP
+	// LoginAttempt is nil when no failed login to the account is on record
+	LoginAttempt *LoginAttempt
+	AuditEvents  []AuditEvent
+}
+
+const (
+	DataExportPending    = "pending"
+	DataExportProcessing = "processing"
+	DataExportReady      = "ready"
+	DataExportFailed     = "failed"
+)
+
+// Audit events name the administrative and security changes recorded for
+// an account.
+const (
+	AuditUserUpdated         = "user_updated"
+	AuditPasswordResetForced = "password_reset_forced"
+	AuditUserLocked          = "user_locked"
+	AuditUserUnlocked        = "user_unlocked"
+	AuditSessionsRevoked     = "sessions_revoked"
+	AuditPasswordChanged     = "password_changed"
+	AuditPasswordReset       = "password_reset"
+	AuditTOTPEnabled         = "totp_enabled"
+	AuditTOTPDisabled        = "totp_disabled"
+)
+
+// AuditEvent is a change made to the account of UserID. ActorID is the staff
+// member who made it, nil when the user did.
+type AuditEvent struct {
+	ID        int64
+	UserID    int64
+	ActorID   *int64
+	Action    string
+	CreatedAt time.Time
+}
+
+type DataExport struct {
+	ID        int64
+	UserID    int64
+	Format    string
+	Status    string
+	TokenHash string
+	// Archive is only set on ready exports returned by GetDataExport
+	Archive     []byte
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+	StartedAt   *time.Time
+	CompletedAt *time.Time
 }
@@ -0,0 +1,188 @@
+// Package totp implements time-based one-time passwords as described in
+// RFC 6238, compatible with common authenticator apps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Algorithm string
+
+const (
+	AlgorithmSHA1   Algorithm = "SHA1"
+	AlgorithmSHA256 Algorithm = "SHA256"
+	AlgorithmSHA512 Algorithm = "SHA512"
+)
+
+const (
+	DefaultDigits = 6
+	DefaultPeriod = time.Duration(30) * time.Second
+	DefaultSkew   = 1
+
+	// secretSize follows the 160 bit key length recommended by RFC 4226.
+	secretSize = 20
+)
+
+var (
+	ErrInvalidSecret    = errors.New("totp: invalid secret")
+	ErrInvalidAlgorithm = errors.New("totp: invalid algorithm")
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP generates and validates codes. The zero value is not usable, create
+// one with New.
+type TOTP struct {
+	Digits    int
+	Period    time.Duration
+	Algorithm Algorithm
+	// Skew is the number of periods before and after the current one that
+	// are still accepted, to tolerate clock drift.
+	Skew int
+	// Clock returns the current time. It is replaced in tests.
+	Clock func() time.Time
+}
+
+// New returns a TOTP using the parameters every authenticator app supports:
+// SHA1, 6 digits and a 30 second period.
+func New() *TOTP {
+	return &TOTP{
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+		Algorithm: AlgorithmSHA1,
+		Skew:      DefaultSkew,
+		Clock:     time.Now,
+	}
+}
+
+// GenerateSecret returns a new random base32 encoded secret.
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// Step returns the time step the given time falls in.
+func (t *TOTP) Step(at time.Time) int64 {
+	return at.Unix() / int64(t.Period/time.Second)
+}
+
+// GenerateCode returns the code for the current time.
+func (t *TOTP) GenerateCode(secret string) (string, error) {
+	return t.GenerateCodeAt(secret, t.Clock())
+}
+
+// GenerateCodeAt returns the code for the given time.
+func (t *TOTP) GenerateCodeAt(secret string, at time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return t.generate(key, t.Step(at))
+}
+
+// Validate checks the code against the current time step and the steps
+// allowed by Skew. On success it returns the matching step so callers can
+// refuse to accept the same code twice.
+func (t *TOTP) Validate(secret string, code string) (step int64, ok bool, err error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(code) != t.Digits {
+		return 0, false, nil
+	}
+
+	current := t.Step(t.Clock())
+	for i := -t.Skew; i <= t.Skew; i++ {
+		candidate, err := t.generate(key, current+int64(i))
+		if err != nil {
+			return 0, false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(code)) == 1 {
+			return current + int64(i), true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// URI returns the otpauth:// key URI understood by authenticator apps.
+func (t *TOTP) URI(secret string, issuer string, accountName string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", string(t.Algorithm))
+	params.Set("digits", fmt.Sprint(t.Digits))
+	params.Set("period", fmt.Sprint(int64(t.Period/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: params.Encode(),
+	}
+	return u.String()
+}
+
+// generate implements the HOTP truncation of RFC 4226 section 5.3.
+func (t *TOTP) generate(key []byte, step int64) (string, error) {
+	newHash, err := t.hash()
+	if err != nil {
+		return "", err
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(newHash, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < t.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", t.Digits, value%mod), nil
+}
+
+func (t *TOTP) hash() (func() hash.Hash, error) {
+	switch t.Algorithm {
+	case AlgorithmSHA1:
+		return sha1.New, nil
+	case AlgorithmSHA256:
+		return sha256.New, nil
+	case AlgorithmSHA512:
+		return sha512.New, nil
+	}
+	return nil, ErrInvalidAlgorithm
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	key, err := encoding.DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
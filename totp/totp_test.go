@@ -0,0 +1,158 @@
+package totp
+
+import (
+	"encoding/base32"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B seeds, one per algorithm.
+var (
+	seedSHA1   = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	seedSHA256 = base32.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012"))
+	seedSHA512 = base32.StdEncoding.EncodeToString([]byte("1234567890123456789012345678901234567890123456789012345678901234"))
+)
+
+func fixedClock(unix int64) func() time.Time {
+	return func() time.Time {
+		return time.Unix(unix, 0)
+	}
+}
+
+func Test_TOTP_GenerateCodeAt(t *testing.T) {
+	type args struct {
+		algorithm Algorithm
+		secret    string
+		unix      int64
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantRes string
+		wantErr error
+	}{
+		{name: "rfc 6238 sha1 59", args: args{algorithm: AlgorithmSHA1, secret: seedSHA1, unix: 59}, wantRes: "94287082"},
+		{name: "rfc 6238 sha256 59", args: args{algorithm: AlgorithmSHA256, secret: seedSHA256, unix: 59}, wantRes: "46119246"},
+		{name: "rfc 6238 sha512 59", args: args{algorithm: AlgorithmSHA512, secret: seedSHA512, unix: 59}, wantRes: "90693936"},
+		{name: "rfc 6238 sha1 1111111109", args: args{algorithm: AlgorithmSHA1, secret: seedSHA1, unix: 1111111109}, wantRes: "07081804"},
+		{name: "rfc 6238 sha256 1111111109", args: args{algorithm: AlgorithmSHA256, secret: seedSHA256, unix: 1111111109}, wantRes: "68084774"},
+		{name: "rfc 6238 sha512 1111111109", args: args{algorithm: AlgorithmSHA512, secret: seedSHA512, unix: 1111111109}, wantRes: "25091201"},
+		{name: "rfc 6238 sha1 1111111111", args: args{algorithm: AlgorithmSHA1, secret: seedSHA1, unix: 1111111111}, wantRes: "14050471"},
+		{name: "rfc 6238 sha256 1111111111", args: args{algorithm: AlgorithmSHA256, secret: seedSHA256, unix: 1111111111}, wantRes: "67062674"},
+		{name: "rfc 6238 sha512 1111111111", args: args{algorithm: AlgorithmSHA512, secret: seedSHA512, unix: 1111111111}, wantRes: "99943326"},
+		{name: "rfc 6238 sha1 1234567890", args: args{algorithm: AlgorithmSHA1, secret: seedSHA1, unix: 1234567890}, wantRes: "89005924"},
+		{name: "rfc 6238 sha256 1234567890", args: args{algorithm: AlgorithmSHA256, secret: seedSHA256, unix: 1234567890}, wantRes: "91819424"},
+		{name: "rfc 6238 sha512 1234567890", args: args{algorithm: AlgorithmSHA512, secret: seedSHA512, unix: 1234567890}, wantRes: "93441116"},
+		{name: "rfc 6238 sha1 2000000000", args: args{algorithm: AlgorithmSHA1, secret: seedSHA1, unix: 2000000000}, wantRes: "69279037"},
+		{name: "rfc 6238 sha256 2000000000", args: args{algorithm: AlgorithmSHA256, secret: seedSHA256, unix: 2000000000}, wantRes: "90698825"},
+		{name: "rfc 6238 sha512 2000000000", args: args{algorithm: AlgorithmSHA512, secret: seedSHA512, unix: 2000000000}, wantRes: "38618901"},
+		{name: "rfc 6238 sha1 20000000000", args: args{algorithm: AlgorithmSHA1, secret: seedSHA1, unix: 20000000000}, wantRes: "65353130"},
+		{name: "rfc 6238 sha256 20000000000", args: args{algorithm: AlgorithmSHA256, secret: seedSHA256, unix: 20000000000}, wantRes: "77737706"},
+		{name: "rfc 6238 sha512 20000000000", args: args{algorithm: AlgorithmSHA512, secret: seedSHA512, unix: 20000000000}, wantRes: "47863826"},
+		{name: "invalid secret", args: args{algorithm: AlgorithmSHA1, secret: "not base32!", unix: 59}, wantErr: ErrInvalidSecret},
+		{name: "invalid algorithm", args: args{algorithm: "MD5", secret: seedSHA1, unix: 59}, wantErr: ErrInvalidAlgorithm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New()
+			g.Digits = 8
+			g.Algorithm = tt.args.algorithm
+			gotRes, gotErr := g.GenerateCodeAt(tt.args.secret, time.Unix(tt.args.unix, 0))
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Errorf("TOTP.GenerateCodeAt() gotErr = %v, wantErr = %v", gotErr, tt.wantErr)
+			}
+			if gotRes != tt.wantRes {
+				t.Errorf("TOTP.GenerateCodeAt() gotRes = %s, wantRes = %s", gotRes, tt.wantRes)
+			}
+		})
+	}
+}
+
+func Test_TOTP_Validate(t *testing.T) {
+	// the sha1 seed at unix 59 falls in step 1 and produces 287082 with six
+	// digits, step 0 produces 755224 and step 2 produces 359152
+	type args struct {
+		code string
+		unix int64
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantStep int64
+		wantOk   bool
+	}{
+		{name: "current step", args: args{code: "287082", unix: 59}, wantStep: 1, wantOk: true},
+		{name: "previous step within skew", args: args{code: "755224", unix: 59}, wantStep: 0, wantOk: true},
+		{name: "next step within skew", args: args{code: "359152", unix: 59}, wantStep: 2, wantOk: true},
+		{name: "outside skew", args: args{code: "755224", unix: 95}, wantOk: false},
+		{name: "wrong code", args: args{code: "000000", unix: 59}, wantOk: false},
+		{name: "wrong length", args: args{code: "28708", unix: 59}, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New()
+			g.Clock = fixedClock(tt.args.unix)
+			gotStep, gotOk, gotErr := g.Validate(seedSHA1, tt.args.code)
+			if gotErr != nil {
+				t.Errorf("TOTP.Validate() gotErr = %s, wantErr = nil", gotErr.Error())
+			}
+			if gotOk != tt.wantOk {
+				t.Errorf("TOTP.Validate() gotOk = %t, wantOk = %t", gotOk, tt.wantOk)
+			}
+			if gotStep != tt.wantStep {
+				t.Errorf("TOTP.Validate() gotStep = %d, wantStep = %d", gotStep, tt.wantStep)
+			}
+		})
+	}
+}
+
+func Test_TOTP_GenerateCode(t *testing.T) {
+	g := New()
+	g.Clock = fixedClock(59)
+	gotRes, gotErr := g.GenerateCode(seedSHA1)
+	if gotErr != nil {
+		t.Fatalf("TOTP.GenerateCode() gotErr = %s, wantErr = nil", gotErr.Error())
+	}
+	if gotRes != "287082" {
+		t.Errorf("TOTP.GenerateCode() gotRes = %s, wantRes = 287082", gotRes)
+	}
+}
+
+func Test_GenerateSecret(t *testing.T) {
+	gotRes, gotErr := GenerateSecret()
+	if gotErr != nil {
+		t.Fatalf("GenerateSecret() gotErr = %s, wantErr = nil", gotErr.Error())
+	}
+	key, err := decodeSecret(gotRes)
+	if err != nil {
+		t.Fatalf("GenerateSecret() returned an undecodable secret %s", gotRes)
+	}
+	if len(key) != secretSize {
+		t.Errorf("GenerateSecret() gotSize = %d, wantSize = %d", len(key), secretSize)
+	}
+}
+
+func Test_TOTP_URI(t *testing.T) {
+	g := New()
+	gotRes := g.URI("JBSWY3DPEHPK3PXP", "swt-pro", "+628123456789")
+
+	u, err := url.Parse(gotRes)
+	if err != nil {
+		t.Fatalf("TOTP.URI() returned an invalid url %s", gotRes)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/swt-pro:+628123456789" {
+		t.Errorf("TOTP.URI() gotRes = %s", gotRes)
+	}
+	wantQuery := url.Values{
+		"secret":    {"JBSWY3DPEHPK3PXP"},
+		"issuer":    {"swt-pro"},
+		"algorithm": {"SHA1"},
+		"digits":    {"6"},
+		"period":    {"30"},
+	}
+	if u.Query().Encode() != wantQuery.Encode() {
+		t.Errorf("TOTP.URI() gotQuery = %s, wantQuery = %s", u.Query().Encode(), wantQuery.Encode())
+	}
+}
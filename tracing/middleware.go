@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type MiddlewareOptions struct {
+	// TracerProvider defaults to the global one
+	TracerProvider trace.TracerProvider
+	// Propagator reads the parent span of a request from its headers,
+	// defaults to the global one
+	Propagator propagation.TextMapPropagator
+	// Operations maps "<METHOD> <path>" of every route to its operationId,
+	// see ratelimit.OperationIDs. Spans of routes outside the spec are
+	// named by their path.
+	Operations map[string]string
+}
+
+// Middleware starts a server span for every request, named by its
+// operation, and makes it the parent of the spans started while handling
+// the request.
+func Middleware(opts MiddlewareOptions) echo.MiddlewareFunc {
+	if opts.TracerProvider == nil {
+		opts.TracerProvider = otel.GetTracerProvider()
+	}
+	if opts.Propagator == nil {
+		opts.Propagator = otel.GetTextMapPropagator()
+	}
+	tracer := opts.TracerProvider.Tracer(InstrumentationName)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			operation := opts.Operations[req.Method+" "+ctx.Path()]
+			if operation == "" {
+				operation = ctx.Path()
+			}
+
+			parent := opts.Propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			spanCtx, span := tracer.Start(parent, operation,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.HTTPRoute(ctx.Path()),
+					semconv.URLPath(req.URL.Path),
+					semconv.ClientAddress(ctx.RealIP()),
+				),
+			)
+			defer span.End()
+			ctx.SetRequest(req.WithContext(spanCtx))
+
+			err := next(ctx)
+
+			code := ctx.Response().Status
+			if err != nil {
+				code = http.StatusInternalServerError
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					code = httpErr.Code
+				}
+				span.RecordError(err)
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(code))
+			if code >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(code))
+			}
+			return err
+		}
+	}
+}
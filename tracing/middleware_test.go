@@ -0,0 +1,119 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_Middleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		traceparent   string
+		wantName      string
+		wantStatus    int64
+		wantCode      codes.Code
+		wantTraceID   string
+		wantChildSpan bool
+	}{
+		{
+			name:          "operation of the spec",
+			path:          "/profile",
+			wantName:      "GetProfile",
+			wantStatus:    http.StatusOK,
+			wantCode:      codes.Unset,
+			wantChildSpan: true,
+		},
+		{
+			name:          "parent from the traceparent header",
+			path:          "/profile",
+			traceparent:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantName:      "GetProfile",
+			wantStatus:    http.StatusOK,
+			wantCode:      codes.Unset,
+			wantTraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantChildSpan: true,
+		},
+		{
+			name:       "route outside the spec failing",
+			path:       "/failing",
+			wantName:   "/failing",
+			wantStatus: http.StatusBadGateway,
+			wantCode:   codes.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			provider := NewProvider(ProviderOptions{
+				Exporter:    exporter,
+				Sync:        true,
+				ServiceName: "test",
+				SampleRatio: 1,
+			})
+			defer provider.Shutdown(context.Background())
+
+			e := echo.New()
+			e.Use(Middleware(MiddlewareOptions{
+				TracerProvider: provider,
+				Propagator:     propagation.TraceContext{},
+				Operations: map[string]string{
+					"GET /profile": "GetProfile",
+				},
+			}))
+			e.GET("/profile", func(ctx echo.Context) error {
+				_, span := provider.Tracer(InstrumentationName).Start(ctx.Request().Context(), "child")
+				span.End()
+				return ctx.NoContent(http.StatusOK)
+			})
+			e.GET("/failing", func(ctx echo.Context) error {
+				return echo.NewHTTPError(http.StatusBadGateway)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			e.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := exporter.GetSpans()
+			server := spans[len(spans)-1]
+			if server.Name != tt.wantName || server.SpanKind != trace.SpanKindServer {
+				t.Errorf("Middleware() span = %s of kind %s, want %s", server.Name, server.SpanKind, tt.wantName)
+			}
+			if server.Status.Code != tt.wantCode {
+				t.Errorf("Middleware() status = %s, want %s", server.Status.Code, tt.wantCode)
+			}
+			gotStatus := attributeValue(server.Attributes, "http.response.status_code")
+			if gotStatus.AsInt64() != tt.wantStatus {
+				t.Errorf("Middleware() http.response.status_code = %d, want %d", gotStatus.AsInt64(), tt.wantStatus)
+			}
+			if tt.wantTraceID != "" && server.SpanContext.TraceID().String() != tt.wantTraceID {
+				t.Errorf("Middleware() trace id = %s, want %s", server.SpanContext.TraceID(), tt.wantTraceID)
+			}
+			if tt.wantChildSpan {
+				if len(spans) != 2 || spans[0].Parent.SpanID() != server.SpanContext.SpanID() {
+					t.Errorf("Middleware() child span is not a child of the request span")
+				}
+			}
+		})
+	}
+}
+
+func attributeValue(attributes []attribute.KeyValue, key attribute.Key) attribute.Value {
+	for _, kv := range attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
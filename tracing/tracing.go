@@ -0,0 +1,90 @@
+// Package tracing sets up OpenTelemetry tracing: a tracer provider handing
+// finished spans to a pluggable exporter, and an Echo middleware starting a
+// span for every request.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName names the tracer of the service.
+const InstrumentationName = "github.com/fenky-ng/swt-pro"
+
+// Tracer returns the tracer of the service from the global provider, see
+// otel.SetTracerProvider. Spans are dropped until a provider is set.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+type ExporterOptions struct {
+	// Name is one of none, stdout or otlp
+	Name string
+	// Writer receives the spans of the stdout exporter
+	Writer io.Writer
+	// OTLPEndpoint and OTLPInsecure override the OTEL_EXPORTER_OTLP_*
+	// environment variables when set
+	OTLPEndpoint string
+	OTLPInsecure bool
+}
+
+// NewExporter returns the exporter named by opts, nil for none.
+func NewExporter(ctx context.Context, opts ExporterOptions) (exporter sdktrace.SpanExporter, err error) {
+	switch opts.Name {
+	case "", "none":
+		return nil, nil
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(opts.Writer))
+	case "otlp":
+		var otlpOpts []otlptracehttp.Option
+		if opts.OTLPEndpoint != "" {
+			otlpOpts = append(otlpOpts, otlptracehttp.WithEndpoint(opts.OTLPEndpoint))
+		}
+		if opts.OTLPInsecure {
+			otlpOpts = append(otlpOpts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, otlpOpts...)
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", opts.Name)
+}
+
+type ProviderOptions struct {
+	// Exporter receives the finished spans, none are recorded when nil
+	Exporter sdktrace.SpanExporter
+	// Sync exports every span as it ends instead of in batches, which
+	// tests reading an in-memory exporter rely on
+	Sync        bool
+	ServiceName string
+	// SampleRatio is the share of new traces recorded, a request joining
+	// a trace follows the decision of its parent
+	SampleRatio float64
+}
+
+// NewProvider returns a tracer provider to pass to otel.SetTracerProvider.
+// It must be shut down to flush the spans still batched.
+func NewProvider(opts ProviderOptions) *sdktrace.TracerProvider {
+	sampler := sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))
+	if opts.Exporter == nil {
+		sampler = sdktrace.NeverSample()
+	}
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(opts.ServiceName))),
+		sdktrace.WithSampler(sampler),
+	}
+	switch {
+	case opts.Exporter != nil && opts.Sync:
+		providerOpts = append(providerOpts, sdktrace.WithSyncer(opts.Exporter))
+	case opts.Exporter != nil:
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(opts.Exporter))
+	}
+	return sdktrace.NewTracerProvider(providerOpts...)
+}
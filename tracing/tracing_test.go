@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_NewExporter(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         ExporterOptions
+		wantExporter bool
+		wantErr      bool
+	}{
+		{
+			name: "none",
+			opts: ExporterOptions{Name: "none"},
+		},
+		{
+			name:         "stdout",
+			opts:         ExporterOptions{Name: "stdout", Writer: &bytes.Buffer{}},
+			wantExporter: true,
+		},
+		{
+			name:         "otlp",
+			opts:         ExporterOptions{Name: "otlp", OTLPEndpoint: "localhost:4318", OTLPInsecure: true},
+			wantExporter: true,
+		},
+		{
+			name:    "unknown",
+			opts:    ExporterOptions{Name: "jaeger"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotExporter, gotErr := NewExporter(context.Background(), tt.opts)
+			if (gotErr != nil) != tt.wantErr {
+				t.Fatalf("NewExporter() gotErr = %v, wantErr = %t", gotErr, tt.wantErr)
+			}
+			if (gotExporter != nil) != tt.wantExporter {
+				t.Errorf("NewExporter() gotExporter = %v, wantExporter = %t", gotExporter, tt.wantExporter)
+			}
+		})
+	}
+}
+
+func Test_NewProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		exporter    bool
+		sampleRatio float64
+		wantSpans   int
+	}{
+		{
+			name:        "sampled",
+			exporter:    true,
+			sampleRatio: 1,
+			wantSpans:   1,
+		},
+		{
+			name:        "not sampled",
+			exporter:    true,
+			sampleRatio: 0,
+			wantSpans:   0,
+		},
+		{
+			name:        "without exporter",
+			sampleRatio: 1,
+			wantSpans:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			opts := ProviderOptions{
+				Sync:        true,
+				ServiceName: "test",
+				SampleRatio: tt.sampleRatio,
+			}
+			if tt.exporter {
+				opts.Exporter = exporter
+			}
+			provider := NewProvider(opts)
+			defer provider.Shutdown(context.Background())
+
+			_, span := provider.Tracer(InstrumentationName).Start(context.Background(), "span")
+			span.End()
+
+			gotSpans := exporter.GetSpans()
+			if len(gotSpans) != tt.wantSpans {
+				t.Fatalf("NewProvider() got %d spans, want %d", len(gotSpans), tt.wantSpans)
+			}
+			for _, span := range gotSpans {
+				if !strings.Contains(span.Resource.String(), "service.name=test") {
+					t.Errorf("NewProvider() span resource = %s", span.Resource.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// describeError lists the fields of a request rejected by
+// openapi3filter.ValidateRequest, in the order they were found, explained in
+// lang. Errors not tied to a field, such as a body that is not JSON, are
+// left out.
+func describeError(lang string, err error) []generated.FieldError {
+	fieldErrors := []generated.FieldError{}
+
+	switch err := err.(type) {
+	case openapi3.MultiError:
+		for _, err := range err {
+			fieldErrors = append(fieldErrors, describeError(lang, err)...)
+		}
+	case *openapi3filter.RequestError:
+		if err.Parameter == nil {
+			fieldErrors = append(fieldErrors, describeError(lang, err.Err)...)
+			break
+		}
+		var schemaErr *openapi3.SchemaError
+		if errors.As(err.Err, &schemaErr) {
+			fieldErrors = append(fieldErrors, describeSchemaError(lang, err.Parameter.Name, schemaErr))
+			break
+		}
+		fieldErrors = append(fieldErrors, newFieldError(lang, err.Parameter.Name, i18n.M(i18n.FieldInvalidValue, err.Parameter.Name)))
+	case *openapi3.SchemaError:
+		fieldErrors = append(fieldErrors, describeSchemaError(lang, "", err))
+	}
+
+	return fieldErrors
+}
+
+// describeSchemaError names the field of err, below prefix, and explains
+// what is wrong with it.
+func describeSchemaError(lang string, prefix string, err *openapi3.SchemaError) generated.FieldError {
+	path := err.JSONPointer()
+	if prefix != "" {
+		path = append([]string{prefix}, path...)
+	}
+
+	// unknown properties are reported on their object
+	var unknown string
+	if err.SchemaField == "properties" {
+		fmt.Sscanf(err.Reason, "property %q is unsupported", &unknown)
+		path = append(path, unknown)
+	}
+
+	field := strings.Join(path, ".")
+	if field == "" {
+		field = "body"
+	}
+	return newFieldError(lang, field, explainSchemaError(field, err, unknown != ""))
+}
+
+func explainSchemaError(field string, err *openapi3.SchemaError, unknown bool) i18n.Message {
+	schema := err.Schema
+	switch {
+	case unknown:
+		return i18n.M(i18n.FieldUnknown, field)
+	case err.SchemaField == "required":
+		return i18n.M(i18n.FieldRequired, field)
+	case err.SchemaField == "type":
+		return i18n.M(i18n.FieldType, field, schema.Type)
+	case err.SchemaField == "minLength" && schema.MinLength == 1:
+		return i18n.M(i18n.FieldEmpty, field)
+	case err.SchemaField == "minLength":
+		return i18n.M(i18n.FieldMinLength, field, schema.MinLength)
+	case err.SchemaField == "maxLength" && schema.MaxLength != nil:
+		return i18n.M(i18n.FieldMaxLength, field, *schema.MaxLength)
+	case err.SchemaField == "enum":
+		values := make([]string, 0, len(schema.Enum))
+		for _, value := range schema.Enum {
+			values = append(values, fmt.Sprint(value))
+		}
+		return i18n.M(i18n.FieldEnum, field, strings.Join(values, ", "))
+	case err.SchemaField == "pattern" || err.SchemaField == "format":
+		return i18n.M(i18n.FieldFormat, field)
+	}
+	return i18n.M(i18n.FieldInvalid, field, err.Reason)
+}
+
+func newFieldError(lang string, field string, message i18n.Message) generated.FieldError {
+	return generated.FieldError{
+		Field:     field,
+		Message:   i18n.Localize(lang, message),
+		MessageId: message.ID,
+	}
+}
@@ -0,0 +1,159 @@
+// Package validation checks requests, and optionally responses, against the
+// OpenAPI spec of the service before they reach the handlers, so handlers
+// are left with the business rules.
+package validation
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/fenky-ng/swt-pro/constant"
+	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/labstack/echo/v4"
+)
+
+type MiddlewareOptions struct {
+	Swagger *openapi3.T
+	// Operations maps "<METHOD> <path>" of every route to its operationId,
+	// see ratelimit.OperationIDs. Routes outside the spec are not checked.
+	Operations map[string]string
+	// ValidateResponses logs the responses that do not match the spec.
+	// They are still sent, since the handler has already done its work.
+	ValidateResponses bool
+	// Logger defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+type errorResponse struct {
+	Header generated.ResponseHeader `json:"header"`
+}
+
+// Middleware answers requests not matching the spec with 400, listing the
+// failing fields. Authentication is left to the handlers.
+func Middleware(opts MiddlewareOptions) echo.MiddlewareFunc {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	routesByOperation := map[string]*routers.Route{}
+	for path, pathItem := range opts.Swagger.Paths {
+		for method, operation := range pathItem.Operations() {
+			routesByOperation[operation.OperationID] = &routers.Route{
+				Spec:      opts.Swagger,
+				Path:      path,
+				PathItem:  pathItem,
+				Method:    method,
+				Operation: operation,
+			}
+		}
+	}
+	filterOpts := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			route := routesByOperation[opts.Operations[ctx.Request().Method+" "+ctx.Path()]]
+			if route == nil {
+				return next(ctx)
+			}
+
+			pathParams := map[string]string{}
+			for i, name := range ctx.ParamNames() {
+				pathParams[name] = ctx.ParamValues()[i]
+			}
+			input := &openapi3filter.RequestValidationInput{
+				Request:    ctx.Request(),
+				PathParams: pathParams,
+				Route:      route,
+				Options:    filterOpts,
+			}
+			err := openapi3filter.ValidateRequest(ctx.Request().Context(), input)
+			if err != nil {
+				return respondInvalid(ctx, err)
+			}
+
+			if !opts.ValidateResponses {
+				return next(ctx)
+			}
+			return validateResponse(ctx, next, input, opts.Logger)
+		}
+	}
+}
+
+func respondInvalid(ctx echo.Context, err error) error {
+	lang := i18n.FromContext(ctx.Request().Context())
+	fieldErrors := describeError(lang, err)
+
+	errorCode := constant.ErrorCodeValidation
+	errorMessageIDs := make([]string, 0, len(fieldErrors))
+	errorMessages := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		errorMessageIDs = append(errorMessageIDs, fieldError.MessageId)
+		errorMessages = append(errorMessages, fieldError.Message)
+	}
+	header := generated.ResponseHeader{
+		ErrorCode:       &errorCode,
+		ErrorMessageIds: &errorMessageIDs,
+		ErrorMessages:   &errorMessages,
+		ErrorFields:     &fieldErrors,
+	}
+	// the body could not be read as JSON at all, as handlers used to report
+	if len(fieldErrors) == 0 {
+		errorCode = constant.ErrorCodeUnmarshal
+		errorMessageIDs, errorMessages = i18n.LocalizeAll(lang, []i18n.Message{i18n.M(i18n.BadRequest)})
+		header.ErrorFields = nil
+	}
+	return ctx.JSON(http.StatusBadRequest, errorResponse{Header: header})
+}
+
+// validateResponse holds the response of next back until it has been
+// checked against the spec.
+func validateResponse(ctx echo.Context, next echo.HandlerFunc, input *openapi3filter.RequestValidationInput, logger *slog.Logger) error {
+	res := ctx.Response()
+	writer := &bufferedWriter{ResponseWriter: res.Writer, status: http.StatusOK}
+	res.Writer = writer
+	err := next(ctx)
+	res.Writer = writer.ResponseWriter
+	if !res.Committed {
+		return err
+	}
+
+	validationErr := openapi3filter.ValidateResponse(ctx.Request().Context(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 writer.status,
+		Header:                 res.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(writer.body.Bytes())),
+		Options:                input.Options,
+	})
+	if validationErr != nil {
+		logger.ErrorContext(ctx.Request().Context(), "Response does not match the spec", "func", "ValidateResponse", "operation", input.Route.Operation.OperationID, "error", validationErr)
+	}
+
+	res.Writer.WriteHeader(writer.status)
+	_, writeErr := res.Writer.Write(writer.body.Bytes())
+	if err == nil {
+		err = writeErr
+	}
+	return err
+}
+
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
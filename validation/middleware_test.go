@@ -0,0 +1,232 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fenky-ng/swt-pro/constant"
+	"github.com/fenky-ng/swt-pro/generated"
+	"github.com/fenky-ng/swt-pro/i18n"
+	"github.com/fenky-ng/swt-pro/ratelimit"
+	"github.com/labstack/echo/v4"
+)
+
+func newTestEcho(t *testing.T, opts MiddlewareOptions, handler echo.HandlerFunc) *echo.Echo {
+	swagger, err := generated.GetSwagger()
+	if err != nil {
+		t.Fatalf("GetSwagger() gotErr = %s", err.Error())
+	}
+	opts.Swagger = swagger
+	opts.Operations = ratelimit.OperationIDs(swagger)
+
+	e := echo.New()
+	e.Use(Middleware(opts))
+	e.POST("/login", handler)
+	e.POST("/token/refresh", handler)
+	e.GET("/healthz", handler)
+	e.GET("/metrics", handler)
+	return e
+}
+
+func Test_Middleware(t *testing.T) {
+	type response struct {
+		Header generated.ResponseHeader `json:"header"`
+	}
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		contentType        string
+		body               string
+		wantStatusCode     int
+		wantErrorCode      int
+		wantErrorFields    []generated.FieldError
+		wantErrorMessageID []string
+	}{
+		{
+			name:           "valid request",
+			method:         http.MethodPost,
+			path:           "/login",
+			contentType:    echo.MIMEApplicationJSON,
+			body:           `{"phone_number": "+628123456789", "password": "Sawit@Pr0"}`,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "missing field",
+			method:         http.MethodPost,
+			path:           "/login",
+			contentType:    echo.MIMEApplicationJSON,
+			body:           `{"phone_number": "+628123456789"}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrorCode:  constant.ErrorCodeValidation,
+			wantErrorFields: []generated.FieldError{
+				{Field: "password", MessageId: i18n.FieldRequired},
+			},
+			wantErrorMessageID: []string{i18n.FieldRequired},
+		},
+		{
+			name:           "wrong type and unknown field",
+			method:         http.MethodPost,
+			path:           "/login",
+			contentType:    echo.MIMEApplicationJSON,
+			body:           `{"phone_number": 628123456789, "password": "Sawit@Pr0", "remember_me": true}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrorCode:  constant.ErrorCodeValidation,
+			wantErrorFields: []generated.FieldError{
+				{Field: "phone_number", MessageId: i18n.FieldType},
+				{Field: "remember_me", MessageId: i18n.FieldUnknown},
+			},
+			wantErrorMessageID: []string{i18n.FieldType, i18n.FieldUnknown},
+		},
+		{
+			name:           "empty field",
+			method:         http.MethodPost,
+			path:           "/token/refresh",
+			contentType:    echo.MIMEApplicationJSON,
+			body:           `{"refresh_token": ""}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrorCode:  constant.ErrorCodeValidation,
+			wantErrorFields: []generated.FieldError{
+				{Field: "refresh_token", MessageId: i18n.FieldEmpty},
+			},
+			wantErrorMessageID: []string{i18n.FieldEmpty},
+		},
+		{
+			name:               "body is not json",
+			method:             http.MethodPost,
+			path:               "/login",
+			contentType:        echo.MIMEApplicationJSON,
+			body:               `{"phone_number": `,
+			wantStatusCode:     http.StatusBadRequest,
+			wantErrorCode:      constant.ErrorCodeUnmarshal,
+			wantErrorMessageID: []string{i18n.BadRequest},
+		},
+		{
+			name:               "missing body",
+			method:             http.MethodPost,
+			path:               "/login",
+			contentType:        echo.MIMEApplicationJSON,
+			wantStatusCode:     http.StatusBadRequest,
+			wantErrorCode:      constant.ErrorCodeUnmarshal,
+			wantErrorMessageID: []string{i18n.BadRequest},
+		},
+		{
+			name:           "operation without request body",
+			method:         http.MethodGet,
+			path:           "/healthz",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "route outside the spec",
+			method:         http.MethodGet,
+			path:           "/metrics",
+			wantStatusCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody string
+			e := newTestEcho(t, MiddlewareOptions{}, func(ctx echo.Context) error {
+				// the body is left for the handler to decode
+				body := new(bytes.Buffer)
+				body.ReadFrom(ctx.Request().Body)
+				gotBody = body.String()
+				return ctx.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set(echo.HeaderContentType, tt.contentType)
+			}
+			res := httptest.NewRecorder()
+			e.ServeHTTP(res, req)
+
+			if res.Code != tt.wantStatusCode {
+				t.Fatalf("Middleware() gotStatusCode = %d, wantStatusCode = %d, body = %s", res.Code, tt.wantStatusCode, res.Body.String())
+			}
+			if tt.wantStatusCode == http.StatusOK {
+				if gotBody != tt.body {
+					t.Errorf("Middleware() handler got body = %s, want = %s", gotBody, tt.body)
+				}
+				return
+			}
+
+			var gotRes response
+			err := json.Unmarshal(res.Body.Bytes(), &gotRes)
+			if err != nil {
+				t.Fatalf("json.Unmarshal() gotErr = %s", err.Error())
+			}
+			if gotRes.Header.ErrorCode == nil || *gotRes.Header.ErrorCode != tt.wantErrorCode {
+				t.Errorf("Middleware() gotErrorCode = %v, wantErrorCode = %d", gotRes.Header.ErrorCode, tt.wantErrorCode)
+			}
+			if !reflect.DeepEqual(*gotRes.Header.ErrorMessageIds, tt.wantErrorMessageID) {
+				t.Errorf("Middleware() gotErrorMessageIds = %q, wantErrorMessageIds = %q", *gotRes.Header.ErrorMessageIds, tt.wantErrorMessageID)
+			}
+			if len(*gotRes.Header.ErrorMessages) != len(tt.wantErrorMessageID) {
+				t.Errorf("Middleware() gotErrorMessages = %q, want one per ID", *gotRes.Header.ErrorMessages)
+			}
+			var gotErrorFields []generated.FieldError
+			if gotRes.Header.ErrorFields != nil {
+				gotErrorFields = *gotRes.Header.ErrorFields
+			}
+			// the messages are localized, their IDs are what is checked
+			for i := range gotErrorFields {
+				gotErrorFields[i].Message = ""
+			}
+			if !reflect.DeepEqual(gotErrorFields, tt.wantErrorFields) {
+				t.Errorf("Middleware() gotErrorFields = %+v, wantErrorFields = %+v", gotErrorFields, tt.wantErrorFields)
+			}
+		})
+	}
+}
+
+func Test_Middleware_validateResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    interface{}
+		wantLog bool
+	}{
+		{
+			name: "valid response",
+			body: map[string]interface{}{
+				"header": map[string]interface{}{"successful": true},
+			},
+		},
+		{
+			name: "response lacking its header",
+			body: map[string]interface{}{
+				"status": "ok",
+			},
+			wantLog: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			e := newTestEcho(t, MiddlewareOptions{
+				ValidateResponses: true,
+				Logger:            slog.New(slog.NewJSONHandler(&logs, nil)),
+			}, func(ctx echo.Context) error {
+				return ctx.JSON(http.StatusOK, tt.body)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+			res := httptest.NewRecorder()
+			e.ServeHTTP(res, req)
+
+			wantBody, _ := json.Marshal(tt.body)
+			if res.Code != http.StatusOK || strings.TrimSpace(res.Body.String()) != string(wantBody) {
+				t.Errorf("Middleware() response = %d %s, want = 200 %s", res.Code, res.Body.String(), wantBody)
+			}
+			if gotLog := strings.Contains(logs.String(), "Response does not match the spec"); gotLog != tt.wantLog {
+				t.Errorf("Middleware() logged = %t, wantLog = %t, logs = %s", gotLog, tt.wantLog, logs.String())
+			}
+		})
+	}
+}